@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"golang-assignment/config"
+	"golang-assignment/internal/database"
+)
+
+const coursesUsage = "usage: courses legacy | map <from> <to>"
+
+// Courses runs the courses subcommand, which finishes the move of the free
+// text courses to the catalog: courses legacy lists the courses the migration
+// took over, courses map <from> <to> moves the students of one to another
+func Courses(args []string) error {
+	if len(args) == 0 {
+		return errors.New(coursesUsage)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+	// mapping courses must not change the schema
+	cfg.AutoMigrate = false
+
+	db, err := database.InitDatabase(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	store := database.NewCourseStore(db)
+	ctx := context.Background()
+	switch args[0] {
+	case "legacy":
+		legacy, err := store.LegacyCourses(ctx)
+		if err != nil {
+			return err
+		}
+		for _, c := range legacy {
+			fmt.Printf("%-40s %d student(s)\n", c.Code, c.Students)
+		}
+	case "map":
+		if len(args) != 3 {
+			return errors.New(coursesUsage)
+		}
+		moved, err := store.MapCourse(ctx, args[1], args[2], "migration")
+		if err != nil {
+			return err
+		}
+		fmt.Printf("moved %d student(s) from %s to %s\n", moved, args[1], args[2])
+	default:
+		return errors.New(coursesUsage)
+	}
+	return nil
+}
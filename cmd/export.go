@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"golang-assignment/config"
+	"golang-assignment/internal/cohort"
+	"golang-assignment/internal/customfield"
+	"golang-assignment/internal/database"
+	"golang-assignment/internal/export"
+	"golang-assignment/internal/student"
+	"golang-assignment/internal/tag"
+	"golang-assignment/internal/transport"
+	"io"
+	"os"
+	"strings"
+)
+
+const exportUsage = "usage: export [-format csv|jsonl|xlsx] [-o file] [-cohort id | [-q text] [-tag name] [-course name] [-created-by user] [-min-age n] [-max-age n] [-sort [-]column]]"
+
+// Export runs the export subcommand, writing the students of the database
+// to a file or to the standard output
+func Export(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := flags.String("format", export.FormatCSV, "csv, jsonl or xlsx")
+	output := flags.String("o", "", "file to write, the standard output when empty")
+	var filter student.SearchFilter
+	flags.StringVar(&filter.Query, "q", "", "partial name or email")
+	flags.StringVar(&filter.Course, "course", "", "course")
+	flags.StringVar(&filter.CreatedBy, "created-by", "", "user who created the students")
+	flags.IntVar(&filter.MinAge, "min-age", 0, "minimum age")
+	flags.IntVar(&filter.MaxAge, "max-age", 0, "maximum age")
+	sort := flags.String("sort", "", "column to sort on, prefixed with - for descending order")
+	tagName := flags.String("tag", "", "tag the students have")
+	cohortID := flags.Int64("cohort", 0, "saved cohort whose students are exported, instead of the filters")
+	if err := flags.Parse(args); err != nil || flags.NArg() > 0 {
+		return errors.New(exportUsage)
+	}
+	if *sort != "" {
+		filter.SortDesc = strings.HasPrefix(*sort, "-")
+		filter.SortBy = strings.TrimPrefix(*sort, "-")
+	}
+	if *tagName != "" {
+		filter.Tags = []string{tag.NormalizeName(*tagName)}
+	}
+	if *cohortID != 0 && !filter.IsEmpty() {
+		return errors.New(exportUsage)
+	}
+	if err := filter.Validate(); err != nil {
+		return err
+	}
+	if _, ok := export.ContentType(*format); !ok {
+		return export.ErrUnsupportedFormat
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+	// exporting must not change the schema
+	cfg.AutoMigrate = false
+
+	db, err := database.InitDatabase(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	// the students are read through the service like the API reads them,
+	// which parses the custom field filters with their definitions
+	ctx := context.Background()
+	fields := customfield.NewService(database.NewCustomFieldStore(db))
+	students := student.NewService(database.NewStudentStore(db), nil, nil, nil, nil)
+	students.Fields = fields
+	each := func(emit func(student.Student) error) error {
+		return students.ExportStudents(ctx, filter, emit)
+	}
+	if *cohortID != 0 {
+		cohorts := cohort.NewService(database.NewCohortStore(db, transport.SearchFilterFromText), students)
+		each = func(emit func(student.Student) error) error {
+			return cohorts.EachStudent(ctx, *cohortID, emit)
+		}
+	}
+
+	definitions, err := fields.ListFields(ctx)
+	if err != nil {
+		return err
+	}
+	names := make([]string, len(definitions))
+	for i, d := range definitions {
+		names[i] = d.Name
+	}
+
+	var dst io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		dst = f
+	}
+	buf := bufio.NewWriter(dst)
+
+	out, err := export.NewWriter(*format, buf, names)
+	if err != nil {
+		return err
+	}
+	count := 0
+	err = each(func(s student.Student) error {
+		count++
+		return out.Write(s)
+	})
+	if err != nil {
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	if err := buf.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "exported %d student(s)\n", count)
+	return nil
+}
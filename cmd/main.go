@@ -2,11 +2,24 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"golang-assignment/config"
+	"golang-assignment/internal/attachment"
+	"golang-assignment/internal/attendance"
+	"golang-assignment/internal/auth"
+	"golang-assignment/internal/blob"
+	"golang-assignment/internal/cohort"
+	"golang-assignment/internal/contact"
+	"golang-assignment/internal/course"
+	"golang-assignment/internal/customfield"
 	"golang-assignment/internal/database"
+	"golang-assignment/internal/enrollment"
+	"golang-assignment/internal/memory"
 	"golang-assignment/internal/student"
+	"golang-assignment/internal/tag"
 	"golang-assignment/internal/transport"
 	"os"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -29,23 +42,128 @@ func Run() error {
 		return err
 	}
 
-	// Initialize database connection
-	db, err := database.InitDatabase(cfg)
+	// Initialize the student store and service
+	var studentStore student.StudentStore
+	var courseStore course.CourseStore
+	var enrollmentStore enrollment.EnrollmentStore
+	var gradeStore student.GradeStore
+	var attendanceStore attendance.AttendanceStore
+	var contactStore contact.ContactStore
+	var attachmentStore attachment.AttachmentStore
+	var customFieldStore customfield.FieldStore
+	var tagStore tag.TagStore
+	var cohortStore cohort.CohortStore
+	var auditStore student.AuditStore
+	var userStore student.UserStore
+	var tokenStore student.TokenStore
+	var keyStore auth.KeyStore
+	switch cfg.StoreBackend {
+	case "mysql":
+		db, err := database.InitDatabase(cfg)
+		if err != nil {
+			log.Error("failed to setup connection to the database")
+			return err
+		}
+		studentStore = database.NewStudentStore(db)
+		courseStore = database.NewCourseStore(db)
+		enrollmentStore = database.NewEnrollmentStore(db)
+		gradeStore = database.NewGradeStore(db)
+		attendanceStore = database.NewAttendanceStore(db)
+		contactStore = database.NewContactStore(db)
+		attachmentStore = database.NewAttachmentStore(db)
+		customFieldStore = database.NewCustomFieldStore(db)
+		tagStore = database.NewTagStore(db)
+		cohortStore = database.NewCohortStore(db, transport.SearchFilterFromText)
+		auditStore = database.NewAuditStore(db)
+		userStore = database.NewUserStore(db)
+		tokenStore = database.NewTokenStore(db)
+		keyStore = database.NewSigningKeyStore(db)
+	case "memory":
+		log.Warn("using the in-memory store, data is lost on exit")
+		memoryAudit := memory.NewAuditStore()
+		memoryStudents := memory.NewStudentStore(memoryAudit)
+		studentStore = memoryStudents
+		memoryCourses := memory.NewCourseStore(memoryStudents)
+		courseStore = memoryCourses
+		enrollmentStore = memory.NewEnrollmentStore(memoryStudents, memoryCourses)
+		gradeStore = memory.NewGradeStore()
+		attendanceStore = memory.NewAttendanceStore(memoryStudents)
+		contactStore = memory.NewContactStore(memoryStudents)
+		attachmentStore = memory.NewAttachmentStore(memoryStudents)
+		customFieldStore = memory.NewCustomFieldStore(memoryStudents)
+		tagStore = memory.NewTagStore(memoryStudents)
+		cohortStore = memory.NewCohortStore()
+		auditStore = memoryAudit
+		userStore = memory.NewUserStore()
+		tokenStore = memory.NewTokenStore()
+		keyStore = memory.NewSigningKeyStore()
+	default:
+		return fmt.Errorf("unknown STORE_BACKEND %q, expected mysql or memory", cfg.StoreBackend)
+	}
+	tokenService, err := auth.NewTokenService(cfg, keyStore)
 	if err != nil {
-		log.Error("failed to setup connection to the database")
+		log.Error("failed to setup the token service")
 		return err
 	}
-
-	// Initialize the student store and service
-	studentStore := database.NewStudentStore(db)
-	studentService := student.NewService(studentStore)
+	studentService := student.NewService(studentStore, auditStore, userStore, tokenStore, tokenService)
+	studentService.AccessTokenTTL = cfg.AccessTokenTTL
+	studentService.RefreshTokenTTL = cfg.RefreshTokenTTL
+	if studentService.NewID, err = student.NewIDGenerator(cfg.StudentIDFormat); err != nil {
+		return fmt.Errorf("invalid STUDENT_ID_FORMAT %q: %w", cfg.StudentIDFormat, err)
+	}
+	studentService.AllowClientIDs = cfg.AllowClientIDs
+	studentService.ImportBatchSize = cfg.ImportBatchSize
+	courseService := course.NewService(courseStore)
+	studentService.Courses = courseService
+	customFieldService := customfield.NewService(customFieldStore)
+	studentService.Fields = customFieldService
+	tagService := tag.NewService(tagStore, studentService)
+	cohortService := cohort.NewService(cohortStore, studentService)
+	enrollmentService := enrollment.NewService(enrollmentStore, studentService, courseService)
+	courseService.Seats = enrollmentService
+	studentService.Enrollments = enrollmentService
+	studentService.Seats = enrollmentService
+	studentService.Grades = gradeStore
+	if _, ok := student.FindGradeScale(cfg.GradeScale); !ok {
+		return fmt.Errorf("invalid GRADE_SCALE %q, expected letter or pass_fail", cfg.GradeScale)
+	}
+	studentService.GradeScale = cfg.GradeScale
+	attendanceService := attendance.NewService(attendanceStore, enrollmentService, studentService)
+	attendanceService.AlertThreshold = cfg.AttendanceAlertThreshold
+	attendanceService.AlertMinSessions = cfg.AttendanceAlertMinSessions
+	contactService := contact.NewService(contactStore, studentService)
+	var blobStore attachment.BlobStore
+	switch cfg.BlobStore {
+	case "file":
+		if blobStore, err = blob.NewFileStore(cfg.BlobDir); err != nil {
+			return err
+		}
+	case "memory":
+		log.Warn("using the in-memory blob store, attachments are lost on exit")
+		blobStore = memory.NewBlobStore()
+	default:
+		return fmt.Errorf("invalid BLOB_STORE %q, expected file or memory", cfg.BlobStore)
+	}
+	attachmentService := attachment.NewService(attachmentStore, blobStore, studentService)
+	attachmentService.MaxSize = cfg.AttachmentMaxSize
 
 	ctx := context.Background()
 	if err := studentStore.Ping(ctx); err != nil {
 		log.Fatalf("Database ping failed: %v", err)
 	}
+	if err := studentService.BootstrapUser(ctx, cfg.AdminUserID, cfg.AdminPassword); err != nil {
+		log.Error("failed to create the initial user")
+		return err
+	}
+	go studentService.PurgeExpiredTokens(ctx, time.Hour)
+	go tokenService.Run(ctx)
+	if cfg.TrashRetention > 0 {
+		go studentService.PurgeDeletedStudents(ctx, cfg.TrashPurgeInterval, cfg.TrashRetention)
+		go attachmentService.SweepOrphans(ctx, cfg.TrashPurgeInterval)
+	}
+
 	// Initialize the HTTP handler
-	handler := transport.NewHandler(studentService)
+	handler := transport.NewHandler(studentService, courseService, enrollmentService, attendanceService, contactService, attachmentService, customFieldService, tagService, cohortService, tokenService)
 
 	// Start the HTTP server
 	if err := handler.Serve(); err != nil {
@@ -57,6 +175,25 @@ func Run() error {
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := Migrate(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "export" {
+		if err := Export(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "courses" {
+		if err := Courses(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	if err := Run(); err != nil {
 		log.Error(err)
 		log.Fatal("Error starting up our REST API")
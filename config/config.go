@@ -1,21 +1,48 @@
 package config
 
 import (
+	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	DatabaseUser     string
-	DatabasePassword string
-	DatabaseHost     string
-	DatabasePort     string
-	DatabaseName     string
-	JWTSecret        string
-	ServerPort       string
-	LogLevel         string
+	DatabaseUser       string
+	DatabasePassword   string
+	DatabaseHost       string
+	DatabasePort       string
+	DatabaseName       string
+	JWTSecret          string
+	JWTAlgorithm       string
+	JWTPrivateKeyFile  string
+	JWTKeyRotation     time.Duration
+	ServerPort         string
+	LogLevel           string
+	AutoMigrate        bool
+	StoreBackend       string
+	AdminUserID        string
+	AdminPassword      string
+	AccessTokenTTL     time.Duration
+	RefreshTokenTTL    time.Duration
+	TrashRetention     time.Duration
+	TrashPurgeInterval time.Duration
+	StudentIDFormat    string
+	AllowClientIDs     bool
+	ImportBatchSize    int
+	GradeScale         string
+	// AttendanceAlertThreshold is the attendance percentage below which a
+	// student gets an alert, once AttendanceAlertMinSessions were counted
+	AttendanceAlertThreshold   float64
+	AttendanceAlertMinSessions int
+	// BlobStore is where the content of attachments goes, file (under
+	// BlobDir) or memory
+	BlobStore         string
+	BlobDir           string
+	AttachmentMaxSize int64
 }
 
 func LoadConfig() (*Config, error) {
@@ -24,14 +51,58 @@ func LoadConfig() (*Config, error) {
 	}
 
 	cfg := &Config{
-		DatabaseUser:     getEnv("DATABASE_USER", "root"),
-		DatabasePassword: getEnv("DATABASE_PASSWORD", "Monu@2002"),
-		DatabaseHost:     getEnv("DATABASE_HOST", "localhost"),
-		DatabasePort:     getEnv("DATABASE_PORT", "3306"),
-		DatabaseName:     getEnv("DATABASE_NAME", "student"),
-		JWTSecret:        getEnv("JWT_SECRET", "3x@mP1e$eCr3t!VeRy$l0Ng@p@$sw0Rd"),
-		ServerPort:       getEnv("SERVER_PORT", "8080"),
-		LogLevel:         getEnv("LOG_LEVEL", "info"),
+		DatabaseUser:      getEnv("DATABASE_USER", "root"),
+		DatabasePassword:  getEnv("DATABASE_PASSWORD", "Monu@2002"),
+		DatabaseHost:      getEnv("DATABASE_HOST", "localhost"),
+		DatabasePort:      getEnv("DATABASE_PORT", "3306"),
+		DatabaseName:      getEnv("DATABASE_NAME", "student"),
+		JWTSecret:         getEnv("JWT_SECRET", ""),
+		JWTAlgorithm:      getEnv("JWT_ALGORITHM", "HS256"),
+		JWTPrivateKeyFile: getEnv("JWT_PRIVATE_KEY_FILE", ""),
+		ServerPort:        getEnv("SERVER_PORT", "8080"),
+		LogLevel:          getEnv("LOG_LEVEL", "info"),
+		AutoMigrate:       getEnv("DATABASE_AUTO_MIGRATE", "true") == "true",
+		StoreBackend:      getEnv("STORE_BACKEND", "mysql"),
+		AdminUserID:       getEnv("ADMIN_USER_ID", ""),
+		AdminPassword:     getEnv("ADMIN_PASSWORD", ""),
+		StudentIDFormat:   getEnv("STUDENT_ID_FORMAT", "uuidv7"),
+		AllowClientIDs:    getEnv("ALLOW_CLIENT_STUDENT_IDS", "false") == "true",
+		GradeScale:        getEnv("GRADE_SCALE", "letter"),
+		BlobStore:         getEnv("BLOB_STORE", "file"),
+		BlobDir:           getEnv("BLOB_DIR", "attachments"),
+	}
+
+	var err error
+	if cfg.AccessTokenTTL, err = time.ParseDuration(getEnv("ACCESS_TOKEN_TTL", "15m")); err != nil {
+		return nil, fmt.Errorf("invalid ACCESS_TOKEN_TTL: %w", err)
+	}
+	if cfg.JWTKeyRotation, err = time.ParseDuration(getEnv("JWT_KEY_ROTATION", "0")); err != nil {
+		return nil, fmt.Errorf("invalid JWT_KEY_ROTATION: %w", err)
+	}
+	if cfg.RefreshTokenTTL, err = time.ParseDuration(getEnv("REFRESH_TOKEN_TTL", "168h")); err != nil {
+		return nil, fmt.Errorf("invalid REFRESH_TOKEN_TTL: %w", err)
+	}
+	if cfg.TrashRetention, err = time.ParseDuration(getEnv("TRASH_RETENTION", "720h")); err != nil {
+		return nil, fmt.Errorf("invalid TRASH_RETENTION: %w", err)
+	}
+	if cfg.TrashPurgeInterval, err = time.ParseDuration(getEnv("TRASH_PURGE_INTERVAL", "1h")); err != nil {
+		return nil, fmt.Errorf("invalid TRASH_PURGE_INTERVAL: %w", err)
+	}
+	if cfg.TrashPurgeInterval <= 0 {
+		return nil, fmt.Errorf("invalid TRASH_PURGE_INTERVAL: must be positive")
+	}
+	if cfg.ImportBatchSize, err = strconv.Atoi(getEnv("IMPORT_BATCH_SIZE", "0")); err != nil || cfg.ImportBatchSize < 0 {
+		return nil, fmt.Errorf("invalid IMPORT_BATCH_SIZE: must be 0 or more")
+	}
+	if cfg.AttendanceAlertThreshold, err = strconv.ParseFloat(getEnv("ATTENDANCE_ALERT_THRESHOLD", "75"), 64); err != nil ||
+		cfg.AttendanceAlertThreshold < 0 || cfg.AttendanceAlertThreshold > 100 {
+		return nil, fmt.Errorf("invalid ATTENDANCE_ALERT_THRESHOLD: must be a percentage between 0 and 100")
+	}
+	if cfg.AttendanceAlertMinSessions, err = strconv.Atoi(getEnv("ATTENDANCE_ALERT_MIN_SESSIONS", "3")); err != nil || cfg.AttendanceAlertMinSessions < 1 {
+		return nil, fmt.Errorf("invalid ATTENDANCE_ALERT_MIN_SESSIONS: must be 1 or more")
+	}
+	if cfg.AttachmentMaxSize, err = strconv.ParseInt(getEnv("ATTACHMENT_MAX_SIZE", "10485760"), 10, 64); err != nil || cfg.AttachmentMaxSize < 1 {
+		return nil, fmt.Errorf("invalid ATTACHMENT_MAX_SIZE: must be a positive number of bytes")
 	}
 
 	return cfg, nil
@@ -0,0 +1,395 @@
+// Package attachment keeps the files attached to students: a profile photo,
+// ID scans, certificates and other documents. The content of the files goes
+// to a BlobStore, their metadata, checksum included, to an AttachmentStore.
+// Attachments follow their student: they are hidden while it is in the
+// trash, and their blobs are swept once it is purged.
+package attachment
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+	"unicode"
+
+	"golang-assignment/internal/student"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	ErrAttachmentNotFound  = errors.New("no attachment found")
+	ErrInvalidAttachment   = errors.New("invalid attachment")
+	ErrUnsupportedType     = errors.New("unsupported attachment type")
+	ErrAttachmentTooLarge  = errors.New("attachment too large")
+	ErrBlobNotFound        = errors.New("no blob found")
+	ErrManagingAttachments = errors.New("could not manage attachments")
+)
+
+// domainErrors are passed through by the service, any other store error is
+// hidden behind ErrManagingAttachments
+var domainErrors = []error{
+	ErrAttachmentNotFound,
+	ErrInvalidAttachment,
+	ErrUnsupportedType,
+	ErrAttachmentTooLarge,
+	student.ErrNoStudentFound,
+	student.ErrStoreUnavailable,
+}
+
+func serviceError(err error) error {
+	return student.PassThrough(err, ErrManagingAttachments, domainErrors...)
+}
+
+// Kind is what the file is. A student has at most one photo, uploading
+// another one replaces it.
+type Kind string
+
+const (
+	KindPhoto       Kind = "photo"
+	KindIDScan      Kind = "id_scan"
+	KindCertificate Kind = "certificate"
+	KindDocument    Kind = "document"
+)
+
+var (
+	imageTypes    = []string{"image/jpeg", "image/png", "image/webp"}
+	documentTypes = []string{"application/pdf", "image/jpeg", "image/png", "image/webp"}
+)
+
+// ContentTypes returns the content types files of the kind may have, nil
+// for an unknown kind
+func (k Kind) ContentTypes() []string {
+	switch k {
+	case KindPhoto:
+		return imageTypes
+	case KindIDScan, KindCertificate, KindDocument:
+		return documentTypes
+	}
+	return nil
+}
+
+// DefaultMaxSize is the size limit of an attachment unless told otherwise
+const DefaultMaxSize = 10 << 20
+
+// Attachment is the metadata of a file. The content type is the one sniffed
+// from the content, whatever the client declared, and SHA256 is the hex
+// checksum of the content.
+type Attachment struct {
+	ID          int64     `json:"id"`
+	StudentID   string    `json:"student_id"`
+	Kind        Kind      `json:"kind"`
+	FileName    string    `json:"file_name"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	SHA256      string    `json:"sha256"`
+	BlobKey     string    `json:"-"`
+	UploadedBy  string    `json:"uploaded_by"`
+	UploadedOn  time.Time `json:"uploaded_on"`
+}
+
+// Blob is the content of a stored file, seekable so that it can be served
+// by ranges
+type Blob interface {
+	io.ReadSeekCloser
+}
+
+// BlobStore keeps the content of files under opaque keys
+type BlobStore interface {
+	// Put stores the content read from r under the key, or nothing when
+	// reading fails, and returns its size
+	Put(ctx context.Context, key string, r io.Reader) (int64, error)
+	// Open fails with ErrBlobNotFound for an unknown key
+	Open(ctx context.Context, key string) (Blob, error)
+	// Delete removes the blob, an unknown key is not an error
+	Delete(ctx context.Context, key string) error
+}
+
+// AttachmentStore keeps the metadata of the attachments
+type AttachmentStore interface {
+	CreateAttachment(context.Context, Attachment) (Attachment, error)
+	GetAttachment(ctx context.Context, studentID string, id int64) (Attachment, error)
+	// ListAttachments returns the attachments of the student in the order
+	// they were uploaded
+	ListAttachments(ctx context.Context, studentID string) ([]Attachment, error)
+	DeleteAttachment(ctx context.Context, studentID string, id int64) error
+	// ListOrphanedAttachments returns the attachments of purged students,
+	// whose blobs are still to delete
+	ListOrphanedAttachments(context.Context) ([]Attachment, error)
+}
+
+// StudentGetter finds the live student attachments belong to
+type StudentGetter interface {
+	GetStudent(ctx context.Context, id string) (student.Student, error)
+}
+
+type Service struct {
+	Store    AttachmentStore
+	Blobs    BlobStore
+	Students StudentGetter
+	// MaxSize is the size limit of an attachment in bytes
+	MaxSize int64
+}
+
+func NewService(store AttachmentStore, blobs BlobStore, students StudentGetter) *Service {
+	return &Service{Store: store, Blobs: blobs, Students: students, MaxSize: DefaultMaxSize}
+}
+
+// Upload is a file sent for a student. SHA256, when set, is the checksum the
+// content must have.
+type Upload struct {
+	StudentID string
+	Kind      Kind
+	FileName  string
+	SHA256    string
+	Content   io.Reader
+}
+
+// cleanFileName keeps the last element of the name the client sent, without
+// control characters
+func cleanFileName(name string) string {
+	name = path.Base(strings.ReplaceAll(name, `\`, "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == '"' {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+	if name == "." || name == "/" {
+		return ""
+	}
+	if runes := []rune(name); len(runes) > 255 {
+		name = string(runes[len(runes)-255:])
+	}
+	return name
+}
+
+// limitedReader fails with ErrAttachmentTooLarge as soon as more than n
+// bytes are read
+type limitedReader struct {
+	r io.Reader
+	n int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	if l.n < 0 {
+		return n, ErrAttachmentTooLarge
+	}
+	return n, err
+}
+
+// newBlobKey returns a key no other blob has, under the prefix of the
+// student
+func newBlobKey(studentID string) (string, error) {
+	id, err := student.NewUUIDv7()
+	if err != nil {
+		return "", err
+	}
+	return "students/" + studentID + "/" + id, nil
+}
+
+// sniffLength is the number of bytes http.DetectContentType looks at
+const sniffLength = 512
+
+// Upload stores a file of a live student. The content is streamed to the
+// blob store while its checksum is computed, it is refused when its sniffed
+// type does not suit the kind or when it grows past MaxSize. A new photo
+// replaces the previous one.
+func (s *Service) Upload(ctx context.Context, u Upload, uploadedBy string) (Attachment, error) {
+	allowed := u.Kind.ContentTypes()
+	if u.Kind == "" {
+		return Attachment{}, fmt.Errorf("%w: kind is required", ErrInvalidAttachment)
+	}
+	if allowed == nil {
+		return Attachment{}, fmt.Errorf("%w: unknown kind %s", ErrInvalidAttachment, u.Kind)
+	}
+	u.SHA256 = strings.ToLower(strings.TrimSpace(u.SHA256))
+	if u.SHA256 != "" {
+		if b, err := hex.DecodeString(u.SHA256); err != nil || len(b) != sha256.Size {
+			return Attachment{}, fmt.Errorf("%w: sha256 must be 64 hexadecimal digits", ErrInvalidAttachment)
+		}
+	}
+	if _, err := s.Students.GetStudent(ctx, u.StudentID); err != nil {
+		return Attachment{}, err
+	}
+
+	head := make([]byte, sniffLength)
+	n, err := io.ReadFull(u.Content, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return Attachment{}, fmt.Errorf("%w: %v", ErrInvalidAttachment, err)
+	}
+	if n == 0 {
+		return Attachment{}, fmt.Errorf("%w: the file is empty", ErrInvalidAttachment)
+	}
+	head = head[:n]
+	contentType, _, _ := strings.Cut(http.DetectContentType(head), ";")
+	if !contains(allowed, contentType) {
+		return Attachment{}, fmt.Errorf("%w: a %s must be one of %s, not %s", ErrUnsupportedType, u.Kind, strings.Join(allowed, ", "), contentType)
+	}
+
+	a := Attachment{
+		StudentID:   u.StudentID,
+		Kind:        u.Kind,
+		FileName:    cleanFileName(u.FileName),
+		ContentType: contentType,
+		UploadedBy:  uploadedBy,
+	}
+	if a.FileName == "" {
+		a.FileName = string(u.Kind)
+	}
+	if a.BlobKey, err = newBlobKey(u.StudentID); err != nil {
+		return Attachment{}, fmt.Errorf("could not generate a blob key: %w", err)
+	}
+	hash := sha256.New()
+	content := io.TeeReader(&limitedReader{r: io.MultiReader(bytes.NewReader(head), u.Content), n: s.MaxSize}, hash)
+	if a.Size, err = s.Blobs.Put(ctx, a.BlobKey, content); err != nil {
+		if errors.Is(err, ErrAttachmentTooLarge) {
+			return Attachment{}, fmt.Errorf("%w: the file must be at most %d bytes", ErrAttachmentTooLarge, s.MaxSize)
+		}
+		log.Errorf("an error occurred storing the attachment content: %s", err.Error())
+		return Attachment{}, ErrManagingAttachments
+	}
+	a.SHA256 = hex.EncodeToString(hash.Sum(nil))
+	if u.SHA256 != "" && u.SHA256 != a.SHA256 {
+		s.deleteBlob(ctx, a.BlobKey)
+		return Attachment{}, fmt.Errorf("%w: the content does not match the sha256 checksum", ErrInvalidAttachment)
+	}
+
+	var replaced []Attachment
+	if a.Kind == KindPhoto {
+		if replaced, err = s.Store.ListAttachments(ctx, a.StudentID); err != nil {
+			s.deleteBlob(ctx, a.BlobKey)
+			log.Errorf("an error occurred listing the attachments: %s", err.Error())
+			return Attachment{}, serviceError(err)
+		}
+	}
+	created, err := s.Store.CreateAttachment(ctx, a)
+	if err != nil {
+		s.deleteBlob(ctx, a.BlobKey)
+		log.Errorf("an error occurred creating the attachment: %s", err.Error())
+		return Attachment{}, serviceError(err)
+	}
+	for _, old := range replaced {
+		if old.Kind == KindPhoto {
+			s.remove(ctx, old)
+		}
+	}
+	return created, nil
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+// deleteBlob removes a blob nothing refers to, a failure is only logged
+func (s *Service) deleteBlob(ctx context.Context, key string) {
+	if err := s.Blobs.Delete(ctx, key); err != nil {
+		log.Errorf("an error occurred deleting the blob %s: %s", key, err.Error())
+	}
+}
+
+// remove deletes a replaced or orphaned attachment. The metadata goes first,
+// so that a blob is never referred to once it is deleted.
+func (s *Service) remove(ctx context.Context, a Attachment) {
+	if err := s.Store.DeleteAttachment(ctx, a.StudentID, a.ID); err != nil && !errors.Is(err, ErrAttachmentNotFound) {
+		log.Errorf("an error occurred deleting the attachment %d: %s", a.ID, err.Error())
+		return
+	}
+	s.deleteBlob(ctx, a.BlobKey)
+}
+
+func (s *Service) ListAttachments(ctx context.Context, studentID string) ([]Attachment, error) {
+	if _, err := s.Students.GetStudent(ctx, studentID); err != nil {
+		return nil, err
+	}
+	attachments, err := s.Store.ListAttachments(ctx, studentID)
+	if err != nil {
+		log.Errorf("an error occurred listing the attachments: %s", err.Error())
+		return nil, serviceError(err)
+	}
+	return attachments, nil
+}
+
+func (s *Service) GetAttachment(ctx context.Context, studentID string, id int64) (Attachment, error) {
+	if _, err := s.Students.GetStudent(ctx, studentID); err != nil {
+		return Attachment{}, err
+	}
+	a, err := s.Store.GetAttachment(ctx, studentID, id)
+	if err != nil {
+		if !errors.Is(err, ErrAttachmentNotFound) {
+			log.Errorf("an error occurred fetching the attachment: %s", err.Error())
+		}
+		return Attachment{}, serviceError(err)
+	}
+	return a, nil
+}
+
+// OpenAttachment returns the metadata and the content of an attachment, the
+// caller closes the content
+func (s *Service) OpenAttachment(ctx context.Context, studentID string, id int64) (Attachment, Blob, error) {
+	a, err := s.GetAttachment(ctx, studentID, id)
+	if err != nil {
+		return Attachment{}, nil, err
+	}
+	blob, err := s.Blobs.Open(ctx, a.BlobKey)
+	if err != nil {
+		log.Errorf("an error occurred opening the blob of attachment %d: %s", id, err.Error())
+		return Attachment{}, nil, ErrManagingAttachments
+	}
+	return a, blob, nil
+}
+
+func (s *Service) DeleteAttachment(ctx context.Context, studentID string, id int64) error {
+	a, err := s.GetAttachment(ctx, studentID, id)
+	if err != nil {
+		return err
+	}
+	if err := s.Store.DeleteAttachment(ctx, studentID, id); err != nil {
+		if !errors.Is(err, ErrAttachmentNotFound) {
+			log.Errorf("an error occurred deleting the attachment: %s", err.Error())
+		}
+		return serviceError(err)
+	}
+	s.deleteBlob(ctx, a.BlobKey)
+	return nil
+}
+
+// SweepOrphans periodically deletes the attachments of purged students and
+// their blobs, until ctx is done
+func (s *Service) SweepOrphans(ctx context.Context, every time.Duration) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			orphans, err := s.Store.ListOrphanedAttachments(ctx)
+			if err != nil {
+				log.Errorf("an error occurred listing orphaned attachments: %s", err.Error())
+				continue
+			}
+			for _, a := range orphans {
+				s.remove(ctx, a)
+			}
+			if len(orphans) > 0 {
+				log.Infof("swept %d attachments of purged students", len(orphans))
+			}
+		}
+	}
+}
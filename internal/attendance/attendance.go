@@ -0,0 +1,390 @@
+// Package attendance records whether the students enrolled in a course were
+// at each of its sessions, and raises an alert when the attendance of a
+// student in a course for a term falls below a threshold.
+package attendance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"golang-assignment/internal/course"
+	"golang-assignment/internal/enrollment"
+	"golang-assignment/internal/student"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	ErrInvalidAttendance = errors.New("invalid attendance")
+	ErrNotEnrolled       = errors.New("student is not enrolled in the course for the term")
+	ErrMarkingAttendance = errors.New("could not manage attendance")
+)
+
+// domainErrors are passed through by the service, any other store error is
+// hidden behind ErrMarkingAttendance
+var domainErrors = []error{
+	ErrInvalidAttendance,
+	ErrNotEnrolled,
+	student.ErrNoStudentFound,
+	course.ErrCourseNotFound,
+	enrollment.ErrInvalidEnrollment,
+	student.ErrStoreUnavailable,
+}
+
+func serviceError(err error) error {
+	return student.PassThrough(err, ErrMarkingAttendance, domainErrors...)
+}
+
+type Status string
+
+const (
+	StatusPresent Status = "present"
+	StatusAbsent  Status = "absent"
+	StatusLate    Status = "late"
+	StatusExcused Status = "excused"
+)
+
+// Valid reports whether the status is one of the known ones
+func (st Status) Valid() bool {
+	switch st {
+	case StatusPresent, StatusAbsent, StatusLate, StatusExcused:
+		return true
+	}
+	return false
+}
+
+// DateLayout is the layout of session dates
+const DateLayout = "2006-01-02"
+
+// Record is the attendance of a student at the session a course had on Date
+type Record struct {
+	StudentID  string    `json:"student_id"`
+	Course     string    `json:"course"`
+	Term       string    `json:"term"`
+	Date       string    `json:"date"`
+	Status     Status    `json:"status"`
+	Note       string    `json:"note,omitempty"`
+	RecordedBy string    `json:"recorded_by"`
+	RecordedOn time.Time `json:"recorded_on"`
+}
+
+// Summary counts the sessions of a student. Late counts as attended and
+// excused sessions are left out of the percentage, which is nil until there
+// is a session to count.
+type Summary struct {
+	Sessions   int      `json:"sessions"`
+	Present    int      `json:"present"`
+	Absent     int      `json:"absent"`
+	Late       int      `json:"late"`
+	Excused    int      `json:"excused"`
+	Percentage *float64 `json:"percentage"`
+}
+
+// NewSummary sums up the sessions counted by status
+func NewSummary(counts map[Status]int) Summary {
+	s := Summary{
+		Present: counts[StatusPresent],
+		Absent:  counts[StatusAbsent],
+		Late:    counts[StatusLate],
+		Excused: counts[StatusExcused],
+	}
+	s.Sessions = s.Present + s.Absent + s.Late + s.Excused
+	if counted := s.Sessions - s.Excused; counted > 0 {
+		p := math.Round(10000*float64(s.Present+s.Late)/float64(counted)) / 100
+		s.Percentage = &p
+	}
+	return s
+}
+
+// Summarize sums up the records
+func Summarize(records []Record) Summary {
+	counts := map[Status]int{}
+	for _, r := range records {
+		counts[r.Status]++
+	}
+	return NewSummary(counts)
+}
+
+// Alert is raised when the attendance of a student in a course for a term
+// falls below the threshold, and resolved once it is back above
+type Alert struct {
+	ID         int64      `json:"id"`
+	StudentID  string     `json:"student_id"`
+	Course     string     `json:"course"`
+	Term       string     `json:"term"`
+	Percentage float64    `json:"percentage"`
+	Threshold  float64    `json:"threshold"`
+	RaisedOn   time.Time  `json:"raised_on"`
+	ResolvedOn *time.Time `json:"resolved_on,omitempty"`
+}
+
+// AlertFilter narrows the alerts listed, empty fields match everything
+type AlertFilter struct {
+	StudentID string
+	Course    string
+	OpenOnly  bool
+}
+
+// RecordFilter narrows the records of a student, empty fields match
+// everything and the dates are inclusive
+type RecordFilter struct {
+	Course string
+	From   string
+	To     string
+}
+
+// AttendanceStore keeps the attendance records, one per student, course and
+// session date, and the alerts
+type AttendanceStore interface {
+	// MarkAttendance stores every record or none, replacing the ones the
+	// students already had for the session
+	MarkAttendance(context.Context, []Record) error
+	// ListStudentAttendance returns the records of a student ordered by date
+	// and course
+	ListStudentAttendance(ctx context.Context, studentID string, filter RecordFilter) ([]Record, error)
+	// ListSessionAttendance returns the records of a session of the course
+	ListSessionAttendance(ctx context.Context, code, date string) ([]Record, error)
+	// Summarize returns the summaries of the students in the course for
+	// the term
+	Summarize(ctx context.Context, code, term string, studentIDs []string) (map[string]Summary, error)
+	// RaiseAlert stores the alert unless the student already has an open
+	// one for the course and term, and reports whether it did
+	RaiseAlert(context.Context, Alert) (Alert, bool, error)
+	// ResolveAlerts closes the open alerts of the students in the course for
+	// the term
+	ResolveAlerts(ctx context.Context, code, term string, studentIDs []string, resolvedOn time.Time) error
+	// ListAlerts returns the alerts, newest first
+	ListAlerts(context.Context, AlertFilter) ([]Alert, error)
+}
+
+// Roster lists the enrollments of a course, it is implemented by the
+// enrollment service
+type Roster interface {
+	ListRoster(ctx context.Context, code string, filter enrollment.RosterFilter) ([]enrollment.Enrollment, error)
+}
+
+// StudentGetter finds the live student whose attendance is read
+type StudentGetter interface {
+	GetStudent(ctx context.Context, id string) (student.Student, error)
+}
+
+const (
+	DefaultAlertThreshold   = 75.0
+	DefaultAlertMinSessions = 3
+)
+
+type Service struct {
+	Store    AttendanceStore
+	Roster   Roster
+	Students StudentGetter
+	// AlertThreshold is the attendance percentage in a course below which a
+	// student gets an alert, once they had AlertMinSessions counted sessions
+	AlertThreshold   float64
+	AlertMinSessions int
+}
+
+func NewService(store AttendanceStore, roster Roster, students StudentGetter) *Service {
+	return &Service{
+		Store:            store,
+		Roster:           roster,
+		Students:         students,
+		AlertThreshold:   DefaultAlertThreshold,
+		AlertMinSessions: DefaultAlertMinSessions,
+	}
+}
+
+// ClassMarking marks the attendance of a session of a course. Students of
+// the roster left out of Records get DefaultStatus, or no record when it is
+// empty.
+type ClassMarking struct {
+	Course        string
+	Term          string
+	Date          string
+	DefaultStatus Status
+	Records       []Record
+}
+
+func validateDate(date string) error {
+	day, err := time.Parse(DateLayout, date)
+	if err != nil {
+		return fmt.Errorf("%w: date must be formatted as YYYY-MM-DD", ErrInvalidAttendance)
+	}
+	if day.After(time.Now()) {
+		return fmt.Errorf("%w: date %s is in the future", ErrInvalidAttendance, date)
+	}
+	return nil
+}
+
+// MarkClass records the attendance of the students actively enrolled in the
+// course for the term at the session of the date, then raises or resolves
+// their alerts. It returns the records stored.
+func (s *Service) MarkClass(ctx context.Context, m ClassMarking, recordedBy string) ([]Record, error) {
+	m.Term = enrollment.NormalizeTerm(m.Term)
+	if err := validateDate(m.Date); err != nil {
+		return nil, err
+	}
+	if m.DefaultStatus != "" && !m.DefaultStatus.Valid() {
+		return nil, fmt.Errorf("%w: unknown default status %s", ErrInvalidAttendance, m.DefaultStatus)
+	}
+	roster, err := s.Roster.ListRoster(ctx, m.Course, enrollment.RosterFilter{Term: m.Term, Status: enrollment.StatusActive})
+	if err != nil {
+		return nil, err
+	}
+	enrolled := make(map[string]bool, len(roster))
+	for _, e := range roster {
+		enrolled[e.StudentID] = true
+	}
+
+	now := time.Now()
+	marked := map[string]bool{}
+	records := make([]Record, 0, len(roster))
+	for _, r := range m.Records {
+		switch {
+		case !r.Status.Valid():
+			return nil, fmt.Errorf("%w: unknown status %s for student %s", ErrInvalidAttendance, r.Status, r.StudentID)
+		case !enrolled[r.StudentID]:
+			return nil, fmt.Errorf("%w: student %s", ErrNotEnrolled, r.StudentID)
+		case marked[r.StudentID]:
+			return nil, fmt.Errorf("%w: student %s is marked twice", ErrInvalidAttendance, r.StudentID)
+		}
+		marked[r.StudentID] = true
+		records = append(records, Record{StudentID: r.StudentID, Status: r.Status, Note: r.Note})
+	}
+	if m.DefaultStatus != "" {
+		for _, e := range roster {
+			if !marked[e.StudentID] {
+				records = append(records, Record{StudentID: e.StudentID, Status: m.DefaultStatus})
+			}
+		}
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("%w: nobody to mark", ErrInvalidAttendance)
+	}
+	course := roster[0].Course
+	for i := range records {
+		records[i].Course = course
+		records[i].Term = m.Term
+		records[i].Date = m.Date
+		records[i].RecordedBy = recordedBy
+		records[i].RecordedOn = now
+	}
+
+	if err := s.Store.MarkAttendance(ctx, records); err != nil {
+		log.Errorf("an error occurred marking the attendance: %s", err.Error())
+		return nil, serviceError(err)
+	}
+	s.checkAlerts(ctx, course, m.Term, records)
+	return records, nil
+}
+
+// checkAlerts raises an alert for every marked student now below the
+// threshold and resolves the alerts of the others. The attendance is stored
+// by then, so failures are only logged.
+func (s *Service) checkAlerts(ctx context.Context, code, term string, records []Record) {
+	ids := make([]string, 0, len(records))
+	for _, r := range records {
+		ids = append(ids, r.StudentID)
+	}
+	summaries, err := s.Store.Summarize(ctx, code, term, ids)
+	if err != nil {
+		log.Errorf("an error occurred summarizing the attendance of %s: %s", code, err.Error())
+		return
+	}
+
+	now := time.Now()
+	var recovered []string
+	for _, id := range ids {
+		summary := summaries[id]
+		if summary.Percentage == nil || summary.Sessions-summary.Excused < s.AlertMinSessions || *summary.Percentage >= s.AlertThreshold {
+			recovered = append(recovered, id)
+			continue
+		}
+		alert, raised, err := s.Store.RaiseAlert(ctx, Alert{
+			StudentID:  id,
+			Course:     code,
+			Term:       term,
+			Percentage: *summary.Percentage,
+			Threshold:  s.AlertThreshold,
+			RaisedOn:   now,
+		})
+		if err != nil {
+			log.Errorf("an error occurred raising the attendance alert of %s in %s: %s", id, code, err.Error())
+			continue
+		}
+		if raised {
+			log.Warnf("attendance alert %d: student %s is at %.2f%% in %s for %s, below %.2f%%", alert.ID, id, alert.Percentage, code, term, alert.Threshold)
+		}
+	}
+	if len(recovered) > 0 {
+		if err := s.Store.ResolveAlerts(ctx, code, term, recovered, now); err != nil {
+			log.Errorf("an error occurred resolving the attendance alerts of %s: %s", code, err.Error())
+		}
+	}
+}
+
+// StudentAttendance is the attendance of a student with the summary of all
+// the records and of each course
+type StudentAttendance struct {
+	StudentID string             `json:"student_id"`
+	Summary   Summary            `json:"summary"`
+	Courses   map[string]Summary `json:"courses"`
+	Records   []Record           `json:"records"`
+}
+
+func (s *Service) StudentAttendance(ctx context.Context, studentID string, filter RecordFilter) (StudentAttendance, error) {
+	for _, date := range []string{filter.From, filter.To} {
+		if _, err := time.Parse(DateLayout, date); date != "" && err != nil {
+			return StudentAttendance{}, fmt.Errorf("%w: dates must be formatted as YYYY-MM-DD", ErrInvalidAttendance)
+		}
+	}
+	if _, err := s.Students.GetStudent(ctx, studentID); err != nil {
+		return StudentAttendance{}, err
+	}
+	if filter.Course != "" {
+		filter.Course = course.NormalizeCode(filter.Course)
+	}
+	records, err := s.Store.ListStudentAttendance(ctx, studentID, filter)
+	if err != nil {
+		log.Errorf("an error occurred listing the attendance: %s", err.Error())
+		return StudentAttendance{}, serviceError(err)
+	}
+
+	byCourse := map[string][]Record{}
+	for _, r := range records {
+		byCourse[r.Course] = append(byCourse[r.Course], r)
+	}
+	courses := make(map[string]Summary, len(byCourse))
+	for code, courseRecords := range byCourse {
+		courses[code] = Summarize(courseRecords)
+	}
+	return StudentAttendance{StudentID: studentID, Summary: Summarize(records), Courses: courses, Records: records}, nil
+}
+
+// SessionAttendance returns the records of the session the course had on
+// the date
+func (s *Service) SessionAttendance(ctx context.Context, code, date string) ([]Record, error) {
+	if err := validateDate(date); err != nil {
+		return nil, err
+	}
+	records, err := s.Store.ListSessionAttendance(ctx, course.NormalizeCode(code), date)
+	if err != nil {
+		log.Errorf("an error occurred listing the attendance: %s", err.Error())
+		return nil, serviceError(err)
+	}
+	return records, nil
+}
+
+func (s *Service) ListAlerts(ctx context.Context, filter AlertFilter) ([]Alert, error) {
+	if filter.Course != "" {
+		filter.Course = course.NormalizeCode(filter.Course)
+	}
+	alerts, err := s.Store.ListAlerts(ctx, filter)
+	if err != nil {
+		log.Errorf("an error occurred listing the attendance alerts: %s", err.Error())
+		return nil, serviceError(err)
+	}
+	return alerts, nil
+}
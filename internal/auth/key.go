@@ -0,0 +1,210 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+var ErrUnsupportedAlgorithm = errors.New("algorithm must be one of HS256, RS256 or EdDSA")
+
+// Key is a signing key as it is persisted. Material is the base64 secret of
+// an HS256 key or the PKCS #8 PEM private key of an RS256 or EdDSA key.
+type Key struct {
+	ID        string
+	Algorithm string
+	Material  string
+	CreatedOn time.Time
+}
+
+// loadedKey is a Key whose material was decoded
+type loadedKey struct {
+	Key
+	method    jwt.SigningMethod
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+func randomKeyID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate key ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// GenerateKey creates a new random key for the algorithm
+func GenerateKey(algorithm string) (Key, error) {
+	id, err := randomKeyID()
+	if err != nil {
+		return Key{}, err
+	}
+	key := Key{ID: id, Algorithm: algorithm, CreatedOn: time.Now()}
+
+	switch algorithm {
+	case AlgHS256:
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return Key{}, fmt.Errorf("failed to generate secret: %w", err)
+		}
+		key.Material = base64.StdEncoding.EncodeToString(secret)
+		return key, nil
+	case AlgRS256:
+		private, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return Key{}, fmt.Errorf("failed to generate RSA key: %w", err)
+		}
+		key.Material, err = encodePrivateKey(private)
+		return key, err
+	case AlgEdDSA:
+		_, private, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return Key{}, fmt.Errorf("failed to generate Ed25519 key: %w", err)
+		}
+		key.Material, err = encodePrivateKey(private)
+		return key, err
+	}
+	return Key{}, ErrUnsupportedAlgorithm
+}
+
+func encodePrivateKey(private interface{}) (string, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode private key: %w", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})), nil
+}
+
+// keyFromPEM builds a key from a PEM private key, the algorithm follows from
+// the type of the key and the ID from its public part
+func keyFromPEM(data []byte) (Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return Key{}, errors.New("no PEM block found")
+	}
+
+	var private interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		private, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		private, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return Key{}, fmt.Errorf("failed to parse private key: %w", err)
+	}
+
+	key := Key{CreatedOn: time.Now()}
+	var public []byte
+	switch k := private.(type) {
+	case *rsa.PrivateKey:
+		key.Algorithm = AlgRS256
+		public = k.PublicKey.N.Bytes()
+	case ed25519.PrivateKey:
+		key.Algorithm = AlgEdDSA
+		public = k.Public().(ed25519.PublicKey)
+	default:
+		return Key{}, fmt.Errorf("unsupported private key type %T", private)
+	}
+	sum := sha256.Sum256(public)
+	key.ID = "static-" + hex.EncodeToString(sum[:8])
+
+	key.Material, err = encodePrivateKey(private)
+	return key, err
+}
+
+func loadKey(key Key) (*loadedKey, error) {
+	loaded := &loadedKey{Key: key}
+
+	if key.Algorithm == AlgHS256 {
+		secret, err := base64.StdEncoding.DecodeString(key.Material)
+		if err != nil {
+			return nil, fmt.Errorf("key %s has an invalid secret: %w", key.ID, err)
+		}
+		loaded.method = jwt.SigningMethodHS256
+		loaded.signKey = secret
+		loaded.verifyKey = secret
+		return loaded, nil
+	}
+
+	block, _ := pem.Decode([]byte(key.Material))
+	if block == nil {
+		return nil, fmt.Errorf("key %s has no PEM block", key.ID)
+	}
+	private, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("key %s has an invalid private key: %w", key.ID, err)
+	}
+
+	switch k := private.(type) {
+	case *rsa.PrivateKey:
+		if key.Algorithm != AlgRS256 {
+			break
+		}
+		loaded.method = jwt.SigningMethodRS256
+		loaded.signKey = k
+		loaded.verifyKey = &k.PublicKey
+		return loaded, nil
+	case ed25519.PrivateKey:
+		if key.Algorithm != AlgEdDSA {
+			break
+		}
+		loaded.method = jwt.SigningMethodEdDSA
+		loaded.signKey = k
+		loaded.verifyKey = k.Public()
+		return loaded, nil
+	}
+	return nil, fmt.Errorf("key %s does not match its algorithm %s", key.ID, key.Algorithm)
+}
+
+// JWK is the public part of a key as published in the JWKS (RFC 7517)
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// jwk returns the public key, HS256 secrets are never published
+func (k *loadedKey) jwk() (JWK, bool) {
+	jwk := JWK{KeyID: k.ID, Use: "sig", Algorithm: k.Algorithm}
+	switch public := k.verifyKey.(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		return jwk, true
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		return jwk, true
+	}
+	return JWK{}, false
+}
@@ -0,0 +1,331 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"golang-assignment/config"
+
+	"github.com/golang-jwt/jwt/v5"
+	log "github.com/sirupsen/logrus"
+)
+
+var ErrUnknownKey = errors.New("token signed with an unknown key")
+
+// how long clocks of different instances may disagree
+const clockSkew = time.Minute
+
+type Claims struct {
+	UserID string `json:"user_id"`
+	Role   string `json:"role"`
+	jwt.RegisteredClaims
+}
+
+// KeyStore persists the rotated keys so every instance signs and verifies
+// with the same ones
+type KeyStore interface {
+	ListSigningKeys(context.Context) ([]Key, error)
+	CreateSigningKey(context.Context, Key) error
+	DeleteSigningKey(context.Context, string) error
+}
+
+// MinSecretLength is the shortest JWT_SECRET accepted for HS256, in bytes
+const MinSecretLength = 32
+
+// TokenService signs and verifies the access tokens with keys of
+// JWT_ALGORITHM only, taken from a single source. When JWT_KEY_ROTATION is
+// set, the keys are the ones generated in the KeyStore at that interval,
+// older keys verifying until the tokens they signed have expired. Otherwise
+// the one static key comes from the configuration: JWT_SECRET for HS256,
+// JWT_PRIVATE_KEY_FILE for RS256 and EdDSA.
+type TokenService struct {
+	algorithm string
+	rotation  time.Duration
+	retention time.Duration
+	store     KeyStore
+
+	mu         sync.RWMutex
+	static     *loadedKey
+	rotated    []*loadedKey
+	lastReload time.Time
+}
+
+func NewTokenService(cfg *config.Config, store KeyStore) (*TokenService, error) {
+	t := &TokenService{
+		algorithm: cfg.JWTAlgorithm,
+		rotation:  cfg.JWTKeyRotation,
+		retention: cfg.AccessTokenTTL + clockSkew,
+		store:     store,
+	}
+	if t.algorithm != AlgHS256 && t.algorithm != AlgRS256 && t.algorithm != AlgEdDSA {
+		return nil, ErrUnsupportedAlgorithm
+	}
+
+	if t.rotation > 0 {
+		if err := t.RotateKeys(context.Background()); err != nil {
+			return nil, err
+		}
+		return t, nil
+	}
+
+	key, err := staticKey(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if t.static, err = loadKey(key); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// staticKey returns the key of the configuration for JWT_ALGORITHM
+func staticKey(cfg *config.Config) (Key, error) {
+	if cfg.JWTAlgorithm == AlgHS256 {
+		// a secret known to anyone would let them sign any token
+		if len(cfg.JWTSecret) < MinSecretLength {
+			return Key{}, fmt.Errorf("JWT_SECRET of at least %d bytes is required with JWT_ALGORITHM=HS256", MinSecretLength)
+		}
+		return Key{
+			ID:        "static-hs256",
+			Algorithm: AlgHS256,
+			Material:  base64.StdEncoding.EncodeToString([]byte(cfg.JWTSecret)),
+		}, nil
+	}
+
+	if cfg.JWTPrivateKeyFile == "" {
+		// tokens will not survive a restart
+		log.Warnf("no JWT_PRIVATE_KEY_FILE configured, using a generated %s key", cfg.JWTAlgorithm)
+		return GenerateKey(cfg.JWTAlgorithm)
+	}
+	data, err := os.ReadFile(cfg.JWTPrivateKeyFile)
+	if err != nil {
+		return Key{}, fmt.Errorf("failed to read JWT_PRIVATE_KEY_FILE: %w", err)
+	}
+	key, err := keyFromPEM(data)
+	if err != nil {
+		return Key{}, fmt.Errorf("invalid JWT_PRIVATE_KEY_FILE: %w", err)
+	}
+	if key.Algorithm != cfg.JWTAlgorithm {
+		return Key{}, fmt.Errorf("JWT_PRIVATE_KEY_FILE holds a %s key but JWT_ALGORITHM is %s", key.Algorithm, cfg.JWTAlgorithm)
+	}
+	return key, nil
+}
+
+func (t *TokenService) newestRotatedKey() *loadedKey {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	for i := len(t.rotated) - 1; i >= 0; i-- {
+		if t.rotated[i].Algorithm == t.algorithm {
+			return t.rotated[i]
+		}
+	}
+	return nil
+}
+
+// signingKey is the newest rotated key of the configured algorithm, or the
+// static one without rotation
+func (t *TokenService) signingKey() *loadedKey {
+	if t.rotation > 0 {
+		return t.newestRotatedKey()
+	}
+	return t.static
+}
+
+func (t *TokenService) findKey(id string) *loadedKey {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if t.static != nil && t.static.ID == id {
+		return t.static
+	}
+	for _, key := range t.rotated {
+		if key.ID == id {
+			return key
+		}
+	}
+	return nil
+}
+
+// Sign issues an access token valid for ttl, with a unique ID (jti) so that
+// it can be revoked
+func (t *TokenService) Sign(userID, role string, ttl time.Duration) (string, error) {
+	key := t.signingKey()
+	if key == nil {
+		return "", fmt.Errorf("no %s signing key", t.algorithm)
+	}
+
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	claims := &Claims{
+		UserID: userID,
+		Role:   role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        hex.EncodeToString(jti),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	}
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.signKey)
+}
+
+// Parse verifies a token with the key named by its kid header. Only tokens
+// of the configured algorithm carrying a kid are accepted.
+func (t *TokenService) Parse(ctx context.Context, tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		if kid == "" {
+			return nil, ErrUnknownKey
+		}
+		key := t.findKey(kid)
+		if key == nil && t.reloadForUnknownKey(ctx) {
+			// the key may have been rotated by another instance
+			key = t.findKey(kid)
+		}
+		if key == nil {
+			return nil, ErrUnknownKey
+		}
+		if token.Method.Alg() != key.method.Alg() {
+			return nil, errors.New("unexpected signing method")
+		}
+		return key.verifyKey, nil
+	}, jwt.WithValidMethods([]string{t.algorithm}), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// reloadForUnknownKey reloads the rotated keys, at most every few seconds so
+// that tokens with made up kids cannot hammer the store
+func (t *TokenService) reloadForUnknownKey(ctx context.Context) bool {
+	if t.rotation <= 0 {
+		return false
+	}
+	t.mu.RLock()
+	recent := time.Since(t.lastReload) < 5*time.Second
+	t.mu.RUnlock()
+	if recent {
+		return false
+	}
+	if err := t.reload(ctx); err != nil {
+		log.Errorf("failed to reload signing keys: %v", err)
+		return false
+	}
+	return true
+}
+
+func (t *TokenService) reload(ctx context.Context) error {
+	keys, err := t.store.ListSigningKeys(ctx)
+	if err != nil {
+		return err
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].CreatedOn.Before(keys[j].CreatedOn) })
+
+	rotated := make([]*loadedKey, 0, len(keys))
+	for _, key := range keys {
+		loaded, err := loadKey(key)
+		if err != nil {
+			log.Errorf("skipping signing key: %v", err)
+			continue
+		}
+		rotated = append(rotated, loaded)
+	}
+
+	t.mu.Lock()
+	t.rotated = rotated
+	t.lastReload = time.Now()
+	t.mu.Unlock()
+	return nil
+}
+
+// RotateKeys reloads the rotated keys, creates a new one when the newest is
+// older than the rotation interval and deletes the keys that were replaced
+// long enough ago for all their tokens to have expired
+func (t *TokenService) RotateKeys(ctx context.Context) error {
+	if err := t.reload(ctx); err != nil {
+		return fmt.Errorf("failed to load signing keys: %w", err)
+	}
+
+	now := time.Now()
+	newest := t.newestRotatedKey()
+	if newest == nil || now.Sub(newest.CreatedOn) >= t.rotation {
+		key, err := GenerateKey(t.algorithm)
+		if err != nil {
+			return err
+		}
+		if err := t.store.CreateSigningKey(ctx, key); err != nil {
+			return fmt.Errorf("failed to store signing key: %w", err)
+		}
+		log.Infof("rotated the %s signing key, new kid %s", key.Algorithm, key.ID)
+		if err := t.reload(ctx); err != nil {
+			return fmt.Errorf("failed to load signing keys: %w", err)
+		}
+	}
+
+	t.mu.RLock()
+	rotated := t.rotated
+	t.mu.RUnlock()
+	for i := 0; i+1 < len(rotated); i++ {
+		// rotated[i] stopped signing when rotated[i+1] was created
+		if now.Sub(rotated[i+1].CreatedOn) > t.retention {
+			if err := t.store.DeleteSigningKey(ctx, rotated[i].ID); err != nil {
+				return fmt.Errorf("failed to delete signing key: %w", err)
+			}
+			log.Infof("deleted the expired signing key %s", rotated[i].ID)
+		}
+	}
+	return t.reload(ctx)
+}
+
+// Run rotates the keys on schedule until ctx is done
+func (t *TokenService) Run(ctx context.Context) {
+	if t.rotation <= 0 {
+		return
+	}
+	ticker := time.NewTicker(min(t.rotation, time.Minute))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := t.RotateKeys(ctx); err != nil {
+				log.Errorf("failed to rotate signing keys: %v", err)
+			}
+		}
+	}
+}
+
+// JWKS lists the public keys able to verify our tokens
+func (t *TokenService) JWKS() JWKS {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	jwks := JWKS{Keys: []JWK{}}
+	keys := t.rotated
+	if t.static != nil {
+		keys = append([]*loadedKey{t.static}, keys...)
+	}
+	for _, key := range keys {
+		if jwk, ok := key.jwk(); ok {
+			jwks.Keys = append(jwks.Keys, jwk)
+		}
+	}
+	return jwks
+}
@@ -0,0 +1,192 @@
+// Package cohort saves student searches under a name. A cohort stores the
+// search filter rather than its students, so every use finds the students
+// matching it at that time.
+package cohort
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang-assignment/internal/student"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	ErrCohortNotFound  = errors.New("no cohort found")
+	ErrDuplicateCohort = errors.New("cohort already exists")
+	ErrInvalidCohort   = errors.New("invalid cohort")
+	ErrManagingCohorts = errors.New("could not manage cohorts")
+)
+
+// domainErrors are passed through by the service, any other store error is
+// hidden behind ErrManagingCohorts
+var domainErrors = []error{
+	ErrCohortNotFound,
+	ErrDuplicateCohort,
+	ErrInvalidCohort,
+	student.ErrInvalidSearch,
+	student.ErrInvalidPageSize,
+	student.ErrInvalidPageToken,
+	student.ErrStoreUnavailable,
+}
+
+func serviceError(err error) error {
+	return student.PassThrough(err, ErrManagingCohorts, domainErrors...)
+}
+
+// Cohort is a named search, Filter is the search of GET /students it saves
+type Cohort struct {
+	ID          int64                `json:"id"`
+	Name        string               `json:"name"`
+	Description string               `json:"description"`
+	Filter      student.SearchFilter `json:"-"`
+	CreatedBy   string               `json:"created_by"`
+	CreatedOn   time.Time            `json:"created_on"`
+	UpdatedBy   string               `json:"updated_by"`
+	UpdatedOn   time.Time            `json:"updated_on"`
+}
+
+// CohortStore keeps the cohorts, names are unique ignoring case
+type CohortStore interface {
+	ListCohorts(context.Context) ([]Cohort, error)
+	GetCohort(context.Context, int64) (Cohort, error)
+	CreateCohort(context.Context, Cohort) (Cohort, error)
+	UpdateCohort(context.Context, Cohort) (Cohort, error)
+	DeleteCohort(context.Context, int64) error
+}
+
+// StudentSearcher finds the students of a cohort, it is implemented by the
+// student service
+type StudentSearcher interface {
+	CheckSearchFilter(ctx context.Context, filter *student.SearchFilter) error
+	SearchStudents(ctx context.Context, filter student.SearchFilter, pageToken string, pageSize int) (student.SearchPage, error)
+	ExportStudents(ctx context.Context, filter student.SearchFilter, emit func(student.Student) error) error
+}
+
+type Service struct {
+	Store    CohortStore
+	Students StudentSearcher
+}
+
+func NewService(store CohortStore, students StudentSearcher) *Service {
+	return &Service{Store: store, Students: students}
+}
+
+func (s *Service) checkCohort(ctx context.Context, c *Cohort) error {
+	c.Name = strings.TrimSpace(c.Name)
+	if c.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidCohort)
+	}
+	// a cohort of every student is a plain export
+	if c.Filter.IsEmpty() {
+		return fmt.Errorf("%w: the query must filter the students", ErrInvalidCohort)
+	}
+	if err := s.Students.CheckSearchFilter(ctx, &c.Filter); err != nil {
+		if !errors.Is(err, student.ErrInvalidSearch) {
+			return serviceError(err)
+		}
+		return fmt.Errorf("%w: %v", ErrInvalidCohort, err)
+	}
+	c.Filter.Offset = 0
+	c.Filter.Limit = 0
+	return nil
+}
+
+func (s *Service) ListCohorts(ctx context.Context) ([]Cohort, error) {
+	cohorts, err := s.Store.ListCohorts(ctx)
+	if err != nil {
+		log.Errorf("an error occurred listing the cohorts: %s", err.Error())
+		return nil, serviceError(err)
+	}
+	return cohorts, nil
+}
+
+func (s *Service) GetCohort(ctx context.Context, id int64) (Cohort, error) {
+	c, err := s.Store.GetCohort(ctx, id)
+	if err != nil {
+		if !errors.Is(err, ErrCohortNotFound) {
+			log.Errorf("an error occurred fetching the cohort: %s", err.Error())
+		}
+		return Cohort{}, serviceError(err)
+	}
+	return c, nil
+}
+
+func (s *Service) CreateCohort(ctx context.Context, c Cohort, createdBy string) (Cohort, error) {
+	if err := s.checkCohort(ctx, &c); err != nil {
+		return Cohort{}, err
+	}
+	c.CreatedBy = createdBy
+	c.UpdatedBy = createdBy
+	c, err := s.Store.CreateCohort(ctx, c)
+	if err != nil {
+		log.Errorf("an error occurred adding the cohort: %s", err.Error())
+		return Cohort{}, serviceError(err)
+	}
+	return c, nil
+}
+
+// UpdateCohort replaces the name, description and filter of the cohort
+func (s *Service) UpdateCohort(ctx context.Context, id int64, c Cohort, updatedBy string) (Cohort, error) {
+	existing, err := s.GetCohort(ctx, id)
+	if err != nil {
+		return Cohort{}, err
+	}
+	if err := s.checkCohort(ctx, &c); err != nil {
+		return Cohort{}, err
+	}
+	existing.Name = c.Name
+	existing.Description = c.Description
+	existing.Filter = c.Filter
+	existing.UpdatedBy = updatedBy
+	updated, err := s.Store.UpdateCohort(ctx, existing)
+	if err != nil {
+		log.Errorf("an error occurred updating the cohort: %s", err.Error())
+		return Cohort{}, serviceError(err)
+	}
+	return updated, nil
+}
+
+func (s *Service) DeleteCohort(ctx context.Context, id int64) error {
+	if err := s.Store.DeleteCohort(ctx, id); err != nil {
+		if !errors.Is(err, ErrCohortNotFound) {
+			log.Errorf("an error occurred deleting the cohort: %s", err.Error())
+		}
+		return serviceError(err)
+	}
+	return nil
+}
+
+// CohortFilter returns the search of the cohort. A custom field dropped since
+// the cohort was saved makes the search fail with ErrInvalidSearch.
+func (s *Service) CohortFilter(ctx context.Context, id int64) (student.SearchFilter, error) {
+	c, err := s.GetCohort(ctx, id)
+	if err != nil {
+		return student.SearchFilter{}, err
+	}
+	return c.Filter, nil
+}
+
+// CohortStudents returns a page of the students of the cohort as they are now
+func (s *Service) CohortStudents(ctx context.Context, id int64, pageToken string, pageSize int) (student.SearchPage, error) {
+	filter, err := s.CohortFilter(ctx, id)
+	if err != nil {
+		return student.SearchPage{}, err
+	}
+	return s.Students.SearchStudents(ctx, filter, pageToken, pageSize)
+}
+
+// EachStudent calls emit with every student of the cohort as they are now,
+// stopping at the first error. It is how a bulk action such as a notification
+// reaches the cohort.
+func (s *Service) EachStudent(ctx context.Context, id int64, emit func(student.Student) error) error {
+	filter, err := s.CohortFilter(ctx, id)
+	if err != nil {
+		return err
+	}
+	return s.Students.ExportStudents(ctx, filter, emit)
+}
@@ -0,0 +1,285 @@
+// Package contact keeps the guardians and emergency contacts of students.
+// Contacts follow their student: they are hidden while the student is in the
+// trash, come back when it is restored and are removed when it is purged.
+package contact
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"regexp"
+	"strings"
+	"time"
+
+	"golang-assignment/internal/student"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	ErrContactNotFound  = errors.New("no contact found")
+	ErrInvalidContact   = errors.New("invalid contact")
+	ErrManagingContacts = errors.New("could not manage contacts")
+)
+
+// domainErrors are passed through by the service, any other store error is
+// hidden behind ErrManagingContacts
+var domainErrors = []error{
+	ErrContactNotFound,
+	ErrInvalidContact,
+	student.ErrNoStudentFound,
+	student.ErrStoreUnavailable,
+}
+
+func serviceError(err error) error {
+	return student.PassThrough(err, ErrManagingContacts, domainErrors...)
+}
+
+// Relationship is what the contact is to the student
+type Relationship string
+
+const (
+	RelationshipParent      Relationship = "parent"
+	RelationshipGuardian    Relationship = "guardian"
+	RelationshipGrandparent Relationship = "grandparent"
+	RelationshipSibling     Relationship = "sibling"
+	RelationshipRelative    Relationship = "relative"
+	RelationshipOther       Relationship = "other"
+)
+
+// Valid reports whether the relationship is one of the known ones
+func (r Relationship) Valid() bool {
+	switch r {
+	case RelationshipParent, RelationshipGuardian, RelationshipGrandparent, RelationshipSibling, RelationshipRelative, RelationshipOther:
+		return true
+	}
+	return false
+}
+
+// Guardian reports whether the contact has legal responsibility for the
+// student
+func (r Relationship) Guardian() bool {
+	return r == RelationshipParent || r == RelationshipGuardian
+}
+
+// MinorAge is the age from which a student no longer needs a guardian
+const MinorAge = 18
+
+// Consents are what the contact agreed to, or is allowed to do
+type Consents struct {
+	// Pickup allows the contact to collect the student
+	Pickup bool `json:"pickup"`
+	// Medical allows the contact to consent to medical treatment
+	Medical bool `json:"medical"`
+	// Records allows the contact to receive grades and attendance
+	Records bool `json:"records"`
+}
+
+// Contact is a person to reach about a student. At most one contact of a
+// student is primary, Emergency ones are called when something happens.
+type Contact struct {
+	ID           int64        `json:"id"`
+	StudentID    string       `json:"student_id"`
+	Name         string       `json:"name"`
+	Relationship Relationship `json:"relationship"`
+	Phone        string       `json:"phone,omitempty"`
+	Email        string       `json:"email,omitempty"`
+	Address      string       `json:"address,omitempty"`
+	Primary      bool         `json:"primary"`
+	Emergency    bool         `json:"emergency"`
+	Consents     Consents     `json:"consents"`
+	CreatedBy    string       `json:"created_by"`
+	CreatedOn    time.Time    `json:"created_on"`
+	UpdatedBy    string       `json:"updated_by"`
+	UpdatedOn    time.Time    `json:"updated_on"`
+}
+
+// ContactStore keeps the contacts. Storing a primary contact makes the other
+// contacts of the student secondary in the same step.
+type ContactStore interface {
+	// ListContacts returns the contacts of the student, the primary one
+	// first and the others in the order they were added
+	ListContacts(ctx context.Context, studentID string) ([]Contact, error)
+	GetContact(ctx context.Context, studentID string, id int64) (Contact, error)
+	CreateContact(context.Context, Contact) (Contact, error)
+	UpdateContact(context.Context, Contact) (Contact, error)
+	DeleteContact(ctx context.Context, studentID string, id int64) error
+}
+
+// StudentGetter finds the live student contacts belong to
+type StudentGetter interface {
+	GetStudent(ctx context.Context, id string) (student.Student, error)
+}
+
+type Service struct {
+	Store    ContactStore
+	Students StudentGetter
+}
+
+func NewService(store ContactStore, students StudentGetter) *Service {
+	return &Service{Store: store, Students: students}
+}
+
+var (
+	// phoneSeparators may be used to group the digits of a phone number,
+	// they are not stored
+	phoneSeparators = strings.NewReplacer(" ", "", "-", "", ".", "", "(", "", ")", "")
+	phonePattern    = regexp.MustCompile(`^\+?[0-9]{7,15}$`)
+)
+
+// NormalizePhone returns the phone number as it is stored, without the
+// separators grouping its digits
+func NormalizePhone(phone string) string {
+	return phoneSeparators.Replace(strings.TrimSpace(phone))
+}
+
+// normalize trims the contact and checks the rules every stored contact
+// follows
+func normalize(c Contact) (Contact, error) {
+	c.Name = strings.TrimSpace(c.Name)
+	c.Phone = NormalizePhone(c.Phone)
+	c.Email = strings.TrimSpace(c.Email)
+	c.Address = strings.TrimSpace(c.Address)
+	switch {
+	case c.Name == "":
+		return Contact{}, fmt.Errorf("%w: name is required", ErrInvalidContact)
+	case !c.Relationship.Valid():
+		return Contact{}, fmt.Errorf("%w: unknown relationship %s", ErrInvalidContact, c.Relationship)
+	case c.Phone == "" && c.Email == "":
+		return Contact{}, fmt.Errorf("%w: a phone or an email is required", ErrInvalidContact)
+	case c.Phone != "" && !phonePattern.MatchString(c.Phone):
+		return Contact{}, fmt.Errorf("%w: phone must have 7 to 15 digits, optionally after a +", ErrInvalidContact)
+	case c.Emergency && c.Phone == "":
+		return Contact{}, fmt.Errorf("%w: an emergency contact needs a phone", ErrInvalidContact)
+	}
+	if c.Email != "" {
+		if addr, err := mail.ParseAddress(c.Email); err != nil || addr.Address != c.Email {
+			return Contact{}, fmt.Errorf("%w: email must be a valid email address", ErrInvalidContact)
+		}
+	}
+	return c, nil
+}
+
+func (s *Service) ListContacts(ctx context.Context, studentID string) ([]Contact, error) {
+	if _, err := s.Students.GetStudent(ctx, studentID); err != nil {
+		return nil, err
+	}
+	contacts, err := s.Store.ListContacts(ctx, studentID)
+	if err != nil {
+		log.Errorf("an error occurred listing the contacts: %s", err.Error())
+		return nil, serviceError(err)
+	}
+	return contacts, nil
+}
+
+func (s *Service) GetContact(ctx context.Context, studentID string, id int64) (Contact, error) {
+	if _, err := s.Students.GetStudent(ctx, studentID); err != nil {
+		return Contact{}, err
+	}
+	c, err := s.Store.GetContact(ctx, studentID, id)
+	if err != nil {
+		if !errors.Is(err, ErrContactNotFound) {
+			log.Errorf("an error occurred fetching the contact: %s", err.Error())
+		}
+		return Contact{}, serviceError(err)
+	}
+	return c, nil
+}
+
+// CreateContact adds a contact to a live student. The first contact of a
+// student is primary whatever the request says.
+func (s *Service) CreateContact(ctx context.Context, c Contact, createdBy string) (Contact, error) {
+	c, err := normalize(c)
+	if err != nil {
+		return Contact{}, err
+	}
+	contacts, err := s.ListContacts(ctx, c.StudentID)
+	if err != nil {
+		return Contact{}, err
+	}
+	if len(contacts) == 0 {
+		c.Primary = true
+	}
+
+	c.CreatedBy = createdBy
+	c.UpdatedBy = createdBy
+	c, err = s.Store.CreateContact(ctx, c)
+	if err != nil {
+		log.Errorf("an error occurred creating the contact: %s", err.Error())
+		return Contact{}, serviceError(err)
+	}
+	return c, nil
+}
+
+// UpdateContact replaces a contact of a live student
+func (s *Service) UpdateContact(ctx context.Context, c Contact, updatedBy string) (Contact, error) {
+	c, err := normalize(c)
+	if err != nil {
+		return Contact{}, err
+	}
+	stud, err := s.Students.GetStudent(ctx, c.StudentID)
+	if err != nil {
+		return Contact{}, err
+	}
+	if !c.Relationship.Guardian() {
+		if err := s.keepGuardian(ctx, stud, c.ID); err != nil {
+			return Contact{}, err
+		}
+	}
+
+	c.UpdatedBy = updatedBy
+	c, err = s.Store.UpdateContact(ctx, c)
+	if err != nil {
+		if !errors.Is(err, ErrContactNotFound) {
+			log.Errorf("an error occurred updating the contact: %s", err.Error())
+		}
+		return Contact{}, serviceError(err)
+	}
+	return c, nil
+}
+
+// DeleteContact removes a contact of a live student
+func (s *Service) DeleteContact(ctx context.Context, studentID string, id int64) error {
+	stud, err := s.Students.GetStudent(ctx, studentID)
+	if err != nil {
+		return err
+	}
+	if err := s.keepGuardian(ctx, stud, id); err != nil {
+		return err
+	}
+	if err := s.Store.DeleteContact(ctx, studentID, id); err != nil {
+		if !errors.Is(err, ErrContactNotFound) {
+			log.Errorf("an error occurred deleting the contact: %s", err.Error())
+		}
+		return serviceError(err)
+	}
+	return nil
+}
+
+// keepGuardian refuses to take away the last parent or guardian of a minor,
+// the contact id is about to be removed or to stop being one
+func (s *Service) keepGuardian(ctx context.Context, stud student.Student, id int64) error {
+	if stud.Age >= MinorAge {
+		return nil
+	}
+	contacts, err := s.Store.ListContacts(ctx, stud.ID)
+	if err != nil {
+		log.Errorf("an error occurred listing the contacts: %s", err.Error())
+		return serviceError(err)
+	}
+	leaving := false
+	for _, c := range contacts {
+		if !c.Relationship.Guardian() {
+			continue
+		}
+		if c.ID != id {
+			return nil
+		}
+		leaving = true
+	}
+	if leaving {
+		return fmt.Errorf("%w: a student under %d keeps at least one parent or guardian", ErrInvalidContact, MinorAge)
+	}
+	return nil
+}
@@ -0,0 +1,214 @@
+// Package course keeps the catalog of courses students can be enrolled in.
+// A student's course is the code of a catalog course.
+package course
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"golang-assignment/internal/student"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	ErrCourseNotFound  = errors.New("no course found")
+	ErrDuplicateCourse = errors.New("course already exists")
+	ErrInvalidCourse   = errors.New("invalid course")
+	ErrCourseInUse     = errors.New("course is used by students")
+	ErrManagingCourses = errors.New("could not manage courses")
+)
+
+// domainErrors are passed through by the service, any other store error is
+// hidden behind ErrManagingCourses
+var domainErrors = []error{
+	ErrCourseNotFound,
+	ErrDuplicateCourse,
+	ErrInvalidCourse,
+	ErrCourseInUse,
+	student.ErrStoreUnavailable,
+}
+
+func serviceError(err error) error {
+	return student.PassThrough(err, ErrManagingCourses, domainErrors...)
+}
+
+// MaxCodeLength is the longest code a new course may have
+const MaxCodeLength = 32
+
+var codePattern = regexp.MustCompile(`^[A-Z0-9][A-Z0-9_-]*$`)
+
+// Course is an entry of the catalog. A Capacity of 0 means the course has no
+// seat limit. Inactive courses stay in the catalog for the students already
+// taking them but no student can be moved into one.
+type Course struct {
+	Code      string    `json:"code"`
+	Title     string    `json:"title"`
+	Credits   int       `json:"credits"`
+	Capacity  int       `json:"capacity"`
+	Active    bool      `json:"active"`
+	CreatedBy string    `json:"created_by"`
+	CreatedOn time.Time `json:"created_on"`
+	UpdatedBy string    `json:"updated_by"`
+	UpdatedOn time.Time `json:"updated_on"`
+}
+
+// CourseStore keeps the catalog. Codes are compared ignoring case.
+type CourseStore interface {
+	GetCourse(context.Context, string) (Course, error)
+	ListCourses(ctx context.Context, includeInactive bool) ([]Course, error)
+	CreateCourse(context.Context, Course) (Course, error)
+	UpdateCourse(context.Context, Course) (Course, error)
+	// DeleteCourse fails with ErrCourseInUse while a student, even one in
+	// the trash, has the course
+	DeleteCourse(context.Context, string) error
+}
+
+// SeatFiller gives the seats a course has free to the students waiting for
+// one, it is implemented by the enrollment service
+type SeatFiller interface {
+	FillSeats(ctx context.Context, code, updatedBy string) error
+}
+
+type Service struct {
+	Store CourseStore
+	// Seats is told about every course whose capacity grew, nil when no
+	// enrollment is kept
+	Seats SeatFiller
+}
+
+func NewService(store CourseStore) *Service {
+	return &Service{Store: store}
+}
+
+// NormalizeCode returns the code as it is stored, codes are upper case
+func NormalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func validateCode(code string) error {
+	if len(code) > MaxCodeLength || !codePattern.MatchString(code) {
+		return fmt.Errorf("%w: code must be at most %d letters, digits, - or _", ErrInvalidCourse, MaxCodeLength)
+	}
+	return nil
+}
+
+// validateDetails checks everything but the code, which only new courses
+// are held to since the courses the migration took over keep their free text
+func validateDetails(c Course) error {
+	switch {
+	case strings.TrimSpace(c.Title) == "":
+		return fmt.Errorf("%w: title is required", ErrInvalidCourse)
+	case c.Credits < 0:
+		return fmt.Errorf("%w: credits must not be negative", ErrInvalidCourse)
+	case c.Capacity < 0:
+		return fmt.Errorf("%w: capacity must not be negative", ErrInvalidCourse)
+	}
+	return nil
+}
+
+func (s *Service) GetCourse(ctx context.Context, code string) (Course, error) {
+	c, err := s.Store.GetCourse(ctx, code)
+	if err != nil {
+		if !errors.Is(err, ErrCourseNotFound) {
+			log.Errorf("an error occurred fetching the course: %s", err.Error())
+		}
+		return Course{}, serviceError(err)
+	}
+	return c, nil
+}
+
+// ListCourses returns the catalog ordered by code, the inactive courses only
+// when includeInactive is set
+func (s *Service) ListCourses(ctx context.Context, includeInactive bool) ([]Course, error) {
+	courses, err := s.Store.ListCourses(ctx, includeInactive)
+	if err != nil {
+		log.Errorf("an error occurred listing the courses: %s", err.Error())
+		return nil, serviceError(err)
+	}
+	return courses, nil
+}
+
+func (s *Service) CreateCourse(ctx context.Context, c Course, createdBy string) (Course, error) {
+	c.Code = NormalizeCode(c.Code)
+	if err := validateCode(c.Code); err != nil {
+		return Course{}, err
+	}
+	if err := validateDetails(c); err != nil {
+		return Course{}, err
+	}
+	c.CreatedBy = createdBy
+	c.UpdatedBy = createdBy
+	c, err := s.Store.CreateCourse(ctx, c)
+	if err != nil {
+		log.Errorf("an error occurred adding the course: %s", err.Error())
+		return Course{}, serviceError(err)
+	}
+	return c, nil
+}
+
+// UpdateCourse replaces the title, credits, capacity and active flag of the
+// course, its code never changes
+func (s *Service) UpdateCourse(ctx context.Context, code string, c Course, updatedBy string) (Course, error) {
+	existing, err := s.GetCourse(ctx, code)
+	if err != nil {
+		return Course{}, err
+	}
+	grew := existing.Capacity != 0 && (c.Capacity == 0 || c.Capacity > existing.Capacity)
+	existing.Title = c.Title
+	existing.Credits = c.Credits
+	existing.Capacity = c.Capacity
+	existing.Active = c.Active
+	existing.UpdatedBy = updatedBy
+	if err := validateDetails(existing); err != nil {
+		return Course{}, err
+	}
+	updated, err := s.Store.UpdateCourse(ctx, existing)
+	if err != nil {
+		log.Errorf("an error occurred updating the course: %s", err.Error())
+		return Course{}, serviceError(err)
+	}
+	// the course is updated either way, a waitlist left behind moves up
+	// with the next enrollment or withdrawal in the course
+	if grew && s.Seats != nil {
+		s.Seats.FillSeats(ctx, updated.Code, updatedBy)
+	}
+	return updated, nil
+}
+
+// DeleteCourse removes a course no student has, deactivating it is the way to
+// retire a course that was taken
+func (s *Service) DeleteCourse(ctx context.Context, code string) error {
+	if err := s.Store.DeleteCourse(ctx, code); err != nil {
+		if !errors.Is(err, ErrCourseNotFound) && !errors.Is(err, ErrCourseInUse) {
+			log.Errorf("an error occurred deleting the course: %s", err.Error())
+		}
+		return serviceError(err)
+	}
+	return nil
+}
+
+// ResolveCourse returns the catalog code a student may be given for code. It
+// implements student.CourseCatalog, an unknown or inactive course is an
+// invalid student.
+func (s *Service) ResolveCourse(ctx context.Context, code string) (string, error) {
+	c, err := s.Store.GetCourse(ctx, NormalizeCode(code))
+	if err != nil {
+		if errors.Is(err, ErrCourseNotFound) {
+			return "", fmt.Errorf("%w: course %s is not in the catalog", student.ErrInvalidStudent, code)
+		}
+		log.Errorf("an error occurred fetching the course: %s", err.Error())
+		if errors.Is(err, student.ErrStoreUnavailable) {
+			return "", err
+		}
+		return "", ErrManagingCourses
+	}
+	if !c.Active {
+		return "", fmt.Errorf("%w: course %s is not active", student.ErrInvalidStudent, c.Code)
+	}
+	return c.Code, nil
+}
@@ -0,0 +1,421 @@
+// Package customfield keeps the custom fields an admin defines for students.
+// A student stores its values as a JSON object checked against the
+// definitions, so adding an attribute needs no change to the schema.
+package customfield
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang-assignment/internal/student"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	ErrFieldNotFound  = errors.New("no custom field found")
+	ErrDuplicateField = errors.New("custom field already exists")
+	ErrInvalidField   = errors.New("invalid custom field")
+	ErrManagingFields = errors.New("could not manage custom fields")
+)
+
+// domainErrors are passed through by the service, any other store error is
+// hidden behind ErrManagingFields
+var domainErrors = []error{
+	ErrFieldNotFound,
+	ErrDuplicateField,
+	ErrInvalidField,
+	student.ErrStoreUnavailable,
+}
+
+func serviceError(err error) error {
+	return student.PassThrough(err, ErrManagingFields, domainErrors...)
+}
+
+// Type is the kind of value a custom field holds
+type Type string
+
+const (
+	TypeString  Type = "string"
+	TypeNumber  Type = "number"
+	TypeInteger Type = "integer"
+	TypeBoolean Type = "boolean"
+	// TypeDate values are days written 2006-01-02
+	TypeDate Type = "date"
+	// TypeEnum values are one of the EnumValues of the definition
+	TypeEnum Type = "enum"
+)
+
+func (t Type) Valid() bool {
+	switch t {
+	case TypeString, TypeNumber, TypeInteger, TypeBoolean, TypeDate, TypeEnum:
+		return true
+	}
+	return false
+}
+
+const (
+	// MaxNameLength is the longest name a field may have
+	MaxNameLength = 64
+	// MaxStringLength is the longest value a string field may hold
+	MaxStringLength = 1000
+	// MaxPatternLength is the longest pattern a string field may have
+	MaxPatternLength = 500
+	// maxInteger is the largest integer a JSON number holds exactly
+	maxInteger = 1 << 53
+)
+
+var namePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// Definition describes a custom field. Its name is the key of the value in
+// the custom_fields object of a student, and like its type never changes.
+type Definition struct {
+	Name     string `json:"name"`
+	Label    string `json:"label"`
+	Type     Type   `json:"type"`
+	Required bool   `json:"required"`
+	// EnumValues are the values an enum field accepts
+	EnumValues []string `json:"enum_values,omitempty"`
+	// Pattern is a regular expression the whole value of a string field
+	// matches, in the syntax of the Go regexp package
+	Pattern   string    `json:"pattern,omitempty"`
+	CreatedBy string    `json:"created_by"`
+	CreatedOn time.Time `json:"created_on"`
+	UpdatedBy string    `json:"updated_by"`
+	UpdatedOn time.Time `json:"updated_on"`
+}
+
+// FieldStore keeps the definitions
+type FieldStore interface {
+	// ListFields returns the definitions ordered by name
+	ListFields(context.Context) ([]Definition, error)
+	GetField(context.Context, string) (Definition, error)
+	CreateField(context.Context, Definition) (Definition, error)
+	UpdateField(context.Context, Definition) (Definition, error)
+	// DeleteField removes the definition along with the values every
+	// student, even one in the trash, has for it
+	DeleteField(context.Context, string) error
+}
+
+type Service struct {
+	Store FieldStore
+}
+
+func NewService(store FieldStore) *Service {
+	return &Service{Store: store}
+}
+
+func validateName(name string) error {
+	if len(name) > MaxNameLength || !namePattern.MatchString(name) {
+		return fmt.Errorf("%w: name must be at most %d lower case letters, digits or _, starting with a letter", ErrInvalidField, MaxNameLength)
+	}
+	return nil
+}
+
+// validateDefinition checks everything but the name
+func validateDefinition(d Definition) error {
+	switch {
+	case strings.TrimSpace(d.Label) == "":
+		return fmt.Errorf("%w: label is required", ErrInvalidField)
+	case !d.Type.Valid():
+		return fmt.Errorf("%w: type must be one of string, number, integer, boolean, date or enum", ErrInvalidField)
+	case d.Type == TypeEnum && len(d.EnumValues) == 0:
+		return fmt.Errorf("%w: an enum field needs enum_values", ErrInvalidField)
+	case d.Type != TypeEnum && len(d.EnumValues) > 0:
+		return fmt.Errorf("%w: only an enum field has enum_values", ErrInvalidField)
+	case d.Type != TypeString && d.Pattern != "":
+		return fmt.Errorf("%w: only a string field has a pattern", ErrInvalidField)
+	case len(d.Pattern) > MaxPatternLength:
+		return fmt.Errorf("%w: pattern must be at most %d characters", ErrInvalidField, MaxPatternLength)
+	}
+	seen := make(map[string]bool, len(d.EnumValues))
+	for _, v := range d.EnumValues {
+		if strings.TrimSpace(v) == "" {
+			return fmt.Errorf("%w: enum_values must not be blank", ErrInvalidField)
+		}
+		if seen[v] {
+			return fmt.Errorf("%w: enum value %q appears twice", ErrInvalidField, v)
+		}
+		seen[v] = true
+	}
+	if d.Pattern != "" {
+		if _, err := compilePattern(d.Pattern); err != nil {
+			return fmt.Errorf("%w: invalid pattern: %v", ErrInvalidField, err)
+		}
+	}
+	return nil
+}
+
+// compilePattern anchors the pattern so that it matches whole values
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	return regexp.Compile(`^(?:` + pattern + `)$`)
+}
+
+func (s *Service) ListFields(ctx context.Context) ([]Definition, error) {
+	fields, err := s.Store.ListFields(ctx)
+	if err != nil {
+		log.Errorf("an error occurred listing the custom fields: %s", err.Error())
+		return nil, serviceError(err)
+	}
+	return fields, nil
+}
+
+func (s *Service) GetField(ctx context.Context, name string) (Definition, error) {
+	d, err := s.Store.GetField(ctx, name)
+	if err != nil {
+		if !errors.Is(err, ErrFieldNotFound) {
+			log.Errorf("an error occurred fetching the custom field: %s", err.Error())
+		}
+		return Definition{}, serviceError(err)
+	}
+	return d, nil
+}
+
+// CreateField adds a definition. A new required field does not apply to the
+// students already stored until they are next written.
+func (s *Service) CreateField(ctx context.Context, d Definition, createdBy string) (Definition, error) {
+	if err := validateName(d.Name); err != nil {
+		return Definition{}, err
+	}
+	if err := validateDefinition(d); err != nil {
+		return Definition{}, err
+	}
+	d.CreatedBy = createdBy
+	d.UpdatedBy = createdBy
+	d, err := s.Store.CreateField(ctx, d)
+	if err != nil {
+		log.Errorf("an error occurred adding the custom field: %s", err.Error())
+		return Definition{}, serviceError(err)
+	}
+	return d, nil
+}
+
+// UpdateField replaces the label, the required flag, the enum values and the
+// pattern of a field. Stored values the new rules refuse are kept until their
+// student is next written.
+func (s *Service) UpdateField(ctx context.Context, name string, d Definition, updatedBy string) (Definition, error) {
+	existing, err := s.GetField(ctx, name)
+	if err != nil {
+		return Definition{}, err
+	}
+	existing.Label = d.Label
+	existing.Required = d.Required
+	existing.EnumValues = d.EnumValues
+	existing.Pattern = d.Pattern
+	existing.UpdatedBy = updatedBy
+	if err := validateDefinition(existing); err != nil {
+		return Definition{}, err
+	}
+	updated, err := s.Store.UpdateField(ctx, existing)
+	if err != nil {
+		log.Errorf("an error occurred updating the custom field: %s", err.Error())
+		return Definition{}, serviceError(err)
+	}
+	return updated, nil
+}
+
+// DeleteField removes a field and the values the students have for it
+func (s *Service) DeleteField(ctx context.Context, name string) error {
+	if err := s.Store.DeleteField(ctx, name); err != nil {
+		if !errors.Is(err, ErrFieldNotFound) {
+			log.Errorf("an error occurred deleting the custom field: %s", err.Error())
+		}
+		return serviceError(err)
+	}
+	return nil
+}
+
+// CheckCustomFields implements student.FieldSchema. Every value must be of
+// a defined field and follow its rules, and every required field must have
+// one. A null value is the same as a missing one.
+func (s *Service) CheckCustomFields(ctx context.Context, values student.CustomFields) (student.CustomFields, error) {
+	fields, defined, err := s.definitions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for name := range values {
+		if _, ok := defined[name]; !ok {
+			return nil, fmt.Errorf("%w: custom field %q is not defined", student.ErrInvalidStudent, name)
+		}
+	}
+
+	checked := student.CustomFields{}
+	for _, d := range fields {
+		value := values[d.Name]
+		if value == nil {
+			if d.Required {
+				return nil, fmt.Errorf("%w: custom field %q is required", student.ErrInvalidStudent, d.Name)
+			}
+			continue
+		}
+		v, err := checkValue(d, value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: custom field %q %s", student.ErrInvalidStudent, d.Name, err.Error())
+		}
+		checked[d.Name] = v
+	}
+	if len(checked) == 0 {
+		return nil, nil
+	}
+	return checked, nil
+}
+
+// ParseCustomFields implements student.FieldSchema, it reads the cells of
+// the custom field columns of an import file as values of the fields. An
+// empty cell is a missing value.
+func (s *Service) ParseCustomFields(ctx context.Context, texts map[string]string) (student.CustomFields, error) {
+	_, defined, err := s.definitions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(texts))
+	for name := range texts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	values := student.CustomFields{}
+	for _, name := range names {
+		d, ok := defined[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: custom field %q is not defined", student.ErrInvalidStudent, name)
+		}
+		if texts[name] == "" {
+			continue
+		}
+		value, err := parseText(d.Type, texts[name])
+		if err != nil {
+			return nil, fmt.Errorf("%w: custom field %q %s", student.ErrInvalidStudent, name, err.Error())
+		}
+		values[name] = value
+	}
+	return values, nil
+}
+
+// definitions returns the custom fields in order and keyed by name
+func (s *Service) definitions(ctx context.Context) ([]Definition, map[string]Definition, error) {
+	fields, err := s.Store.ListFields(ctx)
+	if err != nil {
+		log.Errorf("an error occurred listing the custom fields: %s", err.Error())
+		if errors.Is(err, student.ErrStoreUnavailable) {
+			return nil, nil, err
+		}
+		return nil, nil, ErrManagingFields
+	}
+	defined := make(map[string]Definition, len(fields))
+	for _, d := range fields {
+		defined[d.Name] = d
+	}
+	return fields, defined, nil
+}
+
+// parseText reads a value of the type written as text, checkValue tells
+// whether it follows the other rules of the field
+func parseText(t Type, text string) (interface{}, error) {
+	switch t {
+	case TypeNumber, TypeInteger:
+		n, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return nil, errors.New("must be a number")
+		}
+		return n, nil
+	case TypeBoolean:
+		b, err := strconv.ParseBool(text)
+		if err != nil {
+			return nil, errors.New("must be true or false")
+		}
+		return b, nil
+	}
+	return text, nil
+}
+
+// checkValue returns the value as it is stored, numbers are float64 as JSON
+// decodes them
+func checkValue(d Definition, value interface{}) (interface{}, error) {
+	switch d.Type {
+	case TypeString:
+		s, ok := value.(string)
+		if !ok {
+			return nil, errors.New("must be a string")
+		}
+		if len(s) > MaxStringLength {
+			return nil, fmt.Errorf("must be at most %d characters", MaxStringLength)
+		}
+		if d.Pattern != "" {
+			re, err := compilePattern(d.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("has an invalid pattern: %v", err)
+			}
+			if !re.MatchString(s) {
+				return nil, fmt.Errorf("must match %s", d.Pattern)
+			}
+		}
+		return s, nil
+	case TypeNumber, TypeInteger:
+		n, ok := value.(float64)
+		if !ok || math.IsNaN(n) || math.IsInf(n, 0) {
+			return nil, errors.New("must be a number")
+		}
+		if d.Type == TypeInteger && (n != math.Trunc(n) || math.Abs(n) > maxInteger) {
+			return nil, errors.New("must be a whole number")
+		}
+		return n, nil
+	case TypeBoolean:
+		b, ok := value.(bool)
+		if !ok {
+			return nil, errors.New("must be true or false")
+		}
+		return b, nil
+	case TypeDate:
+		s, ok := value.(string)
+		if !ok {
+			return nil, errors.New("must be a date written 2006-01-02")
+		}
+		if _, err := time.Parse("2006-01-02", s); err != nil {
+			return nil, errors.New("must be a date written 2006-01-02")
+		}
+		return s, nil
+	case TypeEnum:
+		s, _ := value.(string)
+		for _, v := range d.EnumValues {
+			if s == v {
+				return s, nil
+			}
+		}
+		return nil, fmt.Errorf("must be one of %s", strings.Join(d.EnumValues, ", "))
+	}
+	return nil, fmt.Errorf("has the unknown type %q", d.Type)
+}
+
+// ParseCustomFieldFilter implements student.FieldSchema, it reads the text of
+// a search filter as a value of the field
+func (s *Service) ParseCustomFieldFilter(ctx context.Context, name, text string) (interface{}, error) {
+	d, err := s.Store.GetField(ctx, name)
+	if err != nil {
+		if errors.Is(err, ErrFieldNotFound) {
+			return nil, fmt.Errorf("%w: custom field %q is not defined", student.ErrInvalidSearch, name)
+		}
+		log.Errorf("an error occurred fetching the custom field: %s", err.Error())
+		if errors.Is(err, student.ErrStoreUnavailable) {
+			return nil, err
+		}
+		return nil, ErrManagingFields
+	}
+
+	value, err := parseText(d.Type, text)
+	if err != nil {
+		return nil, fmt.Errorf("%w: cf.%s %s", student.ErrInvalidSearch, name, err.Error())
+	}
+	// a filter no value could match is a mistake of the caller
+	if _, err := checkValue(Definition{Type: d.Type, EnumValues: d.EnumValues}, value); err != nil {
+		return nil, fmt.Errorf("%w: cf.%s %s", student.ErrInvalidSearch, name, err.Error())
+	}
+	return value, nil
+}
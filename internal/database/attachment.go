@@ -0,0 +1,122 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang-assignment/internal/attachment"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type AttachmentStore struct {
+	DB *sqlx.DB
+}
+
+func NewAttachmentStore(db *sqlx.DB) *AttachmentStore {
+	return &AttachmentStore{DB: db}
+}
+
+type AttachmentRow struct {
+	ID          int64          `db:"id"`
+	StudentID   string         `db:"student_id"`
+	Kind        string         `db:"kind"`
+	FileName    string         `db:"file_name"`
+	ContentType string         `db:"content_type"`
+	Size        int64          `db:"size"`
+	SHA256      string         `db:"sha256"`
+	BlobKey     string         `db:"blob_key"`
+	UploadedBy  sql.NullString `db:"uploaded_by"`
+	UploadedOn  time.Time      `db:"uploaded_on"`
+}
+
+func convertAttachmentRowToAttachment(r AttachmentRow) attachment.Attachment {
+	return attachment.Attachment{
+		ID:          r.ID,
+		StudentID:   r.StudentID,
+		Kind:        attachment.Kind(r.Kind),
+		FileName:    r.FileName,
+		ContentType: r.ContentType,
+		Size:        r.Size,
+		SHA256:      r.SHA256,
+		BlobKey:     r.BlobKey,
+		UploadedBy:  r.UploadedBy.String,
+		UploadedOn:  r.UploadedOn,
+	}
+}
+
+func convertAttachmentRows(rows []AttachmentRow) []attachment.Attachment {
+	attachments := make([]attachment.Attachment, 0, len(rows))
+	for _, r := range rows {
+		attachments = append(attachments, convertAttachmentRowToAttachment(r))
+	}
+	return attachments
+}
+
+const attachmentColumns = "id, student_id, kind, file_name, content_type, size, sha256, blob_key, uploaded_by, uploaded_on"
+
+func (s *AttachmentStore) CreateAttachment(ctx context.Context, a attachment.Attachment) (attachment.Attachment, error) {
+	a.UploadedOn = time.Now()
+	result, err := s.DB.ExecContext(ctx,
+		`INSERT INTO attachments (student_id, kind, file_name, content_type, size, sha256, blob_key, uploaded_by, uploaded_on)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		a.StudentID, a.Kind, a.FileName, a.ContentType, a.Size, a.SHA256, a.BlobKey, a.UploadedBy, a.UploadedOn)
+	if err != nil {
+		return attachment.Attachment{}, storeError("failed to insert attachment", err)
+	}
+	if a.ID, err = result.LastInsertId(); err != nil {
+		return attachment.Attachment{}, fmt.Errorf("could not determine the attachment id: %w", err)
+	}
+	return a, nil
+}
+
+func (s *AttachmentStore) GetAttachment(ctx context.Context, studentID string, id int64) (attachment.Attachment, error) {
+	var row AttachmentRow
+	err := s.DB.GetContext(ctx, &row, "SELECT "+attachmentColumns+" FROM attachments WHERE id = ? AND student_id = ?", id, studentID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return attachment.Attachment{}, fmt.Errorf("attachment %d of student %s not found: %w", id, studentID, attachment.ErrAttachmentNotFound)
+		}
+		return attachment.Attachment{}, storeError("failed to fetch attachment", err)
+	}
+	return convertAttachmentRowToAttachment(row), nil
+}
+
+func (s *AttachmentStore) ListAttachments(ctx context.Context, studentID string) ([]attachment.Attachment, error) {
+	var rows []AttachmentRow
+	err := s.DB.SelectContext(ctx, &rows, "SELECT "+attachmentColumns+" FROM attachments WHERE student_id = ? ORDER BY id", studentID)
+	if err != nil {
+		return nil, storeError("failed to list attachments", err)
+	}
+	return convertAttachmentRows(rows), nil
+}
+
+func (s *AttachmentStore) DeleteAttachment(ctx context.Context, studentID string, id int64) error {
+	result, err := s.DB.ExecContext(ctx, "DELETE FROM attachments WHERE id = ? AND student_id = ?", id, studentID)
+	if err != nil {
+		return storeError("failed to delete attachment", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("could not determine rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("attachment %d of student %s not found: %w", id, studentID, attachment.ErrAttachmentNotFound)
+	}
+	return nil
+}
+
+func (s *AttachmentStore) ListOrphanedAttachments(ctx context.Context) ([]attachment.Attachment, error) {
+	var rows []AttachmentRow
+	err := s.DB.SelectContext(ctx, &rows,
+		`SELECT a.id, a.student_id, a.kind, a.file_name, a.content_type, a.size, a.sha256, a.blob_key, a.uploaded_by, a.uploaded_on
+        FROM attachments a LEFT JOIN students s ON s.id = a.student_id
+        WHERE s.id IS NULL ORDER BY a.id`)
+	if err != nil {
+		return nil, storeError("failed to list orphaned attachments", err)
+	}
+	return convertAttachmentRows(rows), nil
+}
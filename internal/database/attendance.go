@@ -0,0 +1,248 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"golang-assignment/internal/attendance"
+	"golang-assignment/internal/student"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type AttendanceStore struct {
+	DB *sqlx.DB
+}
+
+func NewAttendanceStore(db *sqlx.DB) *AttendanceStore {
+	return &AttendanceStore{DB: db}
+}
+
+type AttendanceRow struct {
+	StudentID   string         `db:"student_id"`
+	Course      string         `db:"course"`
+	SessionDate time.Time      `db:"session_date"`
+	Term        string         `db:"term"`
+	Status      string         `db:"status"`
+	Note        sql.NullString `db:"note"`
+	RecordedBy  sql.NullString `db:"recorded_by"`
+	RecordedOn  time.Time      `db:"recorded_on"`
+}
+
+func convertAttendanceRowToRecord(r AttendanceRow) attendance.Record {
+	return attendance.Record{
+		StudentID:  r.StudentID,
+		Course:     r.Course,
+		Term:       r.Term,
+		Date:       r.SessionDate.Format(attendance.DateLayout),
+		Status:     attendance.Status(r.Status),
+		Note:       r.Note.String,
+		RecordedBy: r.RecordedBy.String,
+		RecordedOn: r.RecordedOn,
+	}
+}
+
+func convertAttendanceRows(rows []AttendanceRow) []attendance.Record {
+	records := make([]attendance.Record, 0, len(rows))
+	for _, r := range rows {
+		records = append(records, convertAttendanceRowToRecord(r))
+	}
+	return records
+}
+
+type AlertRow struct {
+	ID         int64        `db:"id"`
+	StudentID  string       `db:"student_id"`
+	Course     string       `db:"course"`
+	Term       string       `db:"term"`
+	Percentage float64      `db:"percentage"`
+	Threshold  float64      `db:"threshold"`
+	RaisedOn   time.Time    `db:"raised_on"`
+	ResolvedOn sql.NullTime `db:"resolved_on"`
+}
+
+func convertAlertRowToAlert(r AlertRow) attendance.Alert {
+	a := attendance.Alert{
+		ID:         r.ID,
+		StudentID:  r.StudentID,
+		Course:     r.Course,
+		Term:       r.Term,
+		Percentage: r.Percentage,
+		Threshold:  r.Threshold,
+		RaisedOn:   r.RaisedOn,
+	}
+	if r.ResolvedOn.Valid {
+		a.ResolvedOn = &r.ResolvedOn.Time
+	}
+	return a
+}
+
+const (
+	attendanceColumns = "student_id, course, session_date, term, status, note, recorded_by, recorded_on"
+	alertColumns      = "id, student_id, course, term, percentage, threshold, raised_on, resolved_on"
+)
+
+func (s *AttendanceStore) MarkAttendance(ctx context.Context, records []attendance.Record) error {
+	tx, err := s.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return storeError("failed to begin the attendance transaction", err)
+	}
+	// a no-op once committed
+	defer tx.Rollback()
+
+	for _, r := range records {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO attendance (`+attendanceColumns+`)
+            VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+            ON DUPLICATE KEY UPDATE term = VALUES(term), status = VALUES(status), note = VALUES(note),
+                recorded_by = VALUES(recorded_by), recorded_on = VALUES(recorded_on)`,
+			r.StudentID, r.Course, r.Date, r.Term, r.Status, sql.NullString{String: r.Note, Valid: r.Note != ""},
+			r.RecordedBy, r.RecordedOn)
+		if err != nil {
+			if isMissingReference(err) {
+				// the student was purged since the service read the roster
+				return fmt.Errorf("student with ID %s not found: %w", r.StudentID, student.ErrNoStudentFound)
+			}
+			return storeError("failed to store attendance", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return storeError("failed to commit the attendance transaction", err)
+	}
+	return nil
+}
+
+func (s *AttendanceStore) ListStudentAttendance(ctx context.Context, studentID string, filter attendance.RecordFilter) ([]attendance.Record, error) {
+	query := "SELECT " + attendanceColumns + " FROM attendance WHERE student_id = ?"
+	args := []interface{}{studentID}
+	if filter.Course != "" {
+		query += " AND course = ?"
+		args = append(args, filter.Course)
+	}
+	if filter.From != "" {
+		query += " AND session_date >= ?"
+		args = append(args, filter.From)
+	}
+	if filter.To != "" {
+		query += " AND session_date <= ?"
+		args = append(args, filter.To)
+	}
+	var rows []AttendanceRow
+	if err := s.DB.SelectContext(ctx, &rows, query+" ORDER BY session_date, course", args...); err != nil {
+		return nil, storeError("failed to list attendance", err)
+	}
+	return convertAttendanceRows(rows), nil
+}
+
+func (s *AttendanceStore) ListSessionAttendance(ctx context.Context, code, date string) ([]attendance.Record, error) {
+	var rows []AttendanceRow
+	err := s.DB.SelectContext(ctx, &rows,
+		"SELECT "+attendanceColumns+" FROM attendance WHERE course = ? AND session_date = ? ORDER BY student_id", code, date)
+	if err != nil {
+		return nil, storeError("failed to list attendance", err)
+	}
+	return convertAttendanceRows(rows), nil
+}
+
+func (s *AttendanceStore) Summarize(ctx context.Context, code, term string, studentIDs []string) (map[string]attendance.Summary, error) {
+	summaries := make(map[string]attendance.Summary, len(studentIDs))
+	if len(studentIDs) == 0 {
+		return summaries, nil
+	}
+	query, args, err := sqlx.In(
+		`SELECT student_id, status, COUNT(*) AS sessions FROM attendance
+        WHERE course = ? AND term = ? AND student_id IN (?) GROUP BY student_id, status`,
+		code, term, studentIDs)
+	if err != nil {
+		return nil, err
+	}
+	var rows []struct {
+		StudentID string `db:"student_id"`
+		Status    string `db:"status"`
+		Sessions  int    `db:"sessions"`
+	}
+	if err := s.DB.SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, storeError("failed to summarize attendance", err)
+	}
+
+	counts := map[string]map[attendance.Status]int{}
+	for _, r := range rows {
+		if counts[r.StudentID] == nil {
+			counts[r.StudentID] = map[attendance.Status]int{}
+		}
+		counts[r.StudentID][attendance.Status(r.Status)] = r.Sessions
+	}
+	for id, studentCounts := range counts {
+		summaries[id] = attendance.NewSummary(studentCounts)
+	}
+	return summaries, nil
+}
+
+func (s *AttendanceStore) RaiseAlert(ctx context.Context, a attendance.Alert) (attendance.Alert, bool, error) {
+	result, err := s.DB.ExecContext(ctx,
+		`INSERT INTO attendance_alerts (student_id, course, term, percentage, threshold, raised_on)
+        VALUES (?, ?, ?, ?, ?, ?)`,
+		a.StudentID, a.Course, a.Term, a.Percentage, a.Threshold, a.RaisedOn)
+	if err != nil {
+		if !isDuplicateKey(err) {
+			return attendance.Alert{}, false, storeError("failed to insert attendance alert", err)
+		}
+		// the student already has an open alert
+		var row AlertRow
+		err := s.DB.GetContext(ctx, &row,
+			"SELECT "+alertColumns+" FROM attendance_alerts WHERE student_id = ? AND course = ? AND term = ? AND resolved_on IS NULL",
+			a.StudentID, a.Course, a.Term)
+		if err != nil {
+			return attendance.Alert{}, false, storeError("failed to fetch attendance alert", err)
+		}
+		return convertAlertRowToAlert(row), false, nil
+	}
+	if a.ID, err = result.LastInsertId(); err != nil {
+		return attendance.Alert{}, false, fmt.Errorf("could not determine the alert id: %w", err)
+	}
+	return a, true, nil
+}
+
+func (s *AttendanceStore) ResolveAlerts(ctx context.Context, code, term string, studentIDs []string, resolvedOn time.Time) error {
+	if len(studentIDs) == 0 {
+		return nil
+	}
+	query, args, err := sqlx.In(
+		`UPDATE attendance_alerts SET resolved_on = ?
+        WHERE course = ? AND term = ? AND resolved_on IS NULL AND student_id IN (?)`,
+		resolvedOn, code, term, studentIDs)
+	if err != nil {
+		return err
+	}
+	if _, err := s.DB.ExecContext(ctx, query, args...); err != nil {
+		return storeError("failed to resolve attendance alerts", err)
+	}
+	return nil
+}
+
+func (s *AttendanceStore) ListAlerts(ctx context.Context, filter attendance.AlertFilter) ([]attendance.Alert, error) {
+	query := "SELECT " + alertColumns + " FROM attendance_alerts WHERE 1 = 1"
+	var args []interface{}
+	if filter.StudentID != "" {
+		query += " AND student_id = ?"
+		args = append(args, filter.StudentID)
+	}
+	if filter.Course != "" {
+		query += " AND course = ?"
+		args = append(args, filter.Course)
+	}
+	if filter.OpenOnly {
+		query += " AND resolved_on IS NULL"
+	}
+	var rows []AlertRow
+	if err := s.DB.SelectContext(ctx, &rows, query+" ORDER BY id DESC", args...); err != nil {
+		return nil, storeError("failed to list attendance alerts", err)
+	}
+	alerts := make([]attendance.Alert, 0, len(rows))
+	for _, r := range rows {
+		alerts = append(alerts, convertAlertRowToAlert(r))
+	}
+	return alerts, nil
+}
@@ -0,0 +1,113 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang-assignment/internal/student"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// AuditStore reads the audit_log table. Entries are only ever appended, by the
+// StudentStore in the transaction of the change they record.
+type AuditStore struct {
+	DB *sqlx.DB
+}
+
+func NewAuditStore(db *sqlx.DB) *AuditStore {
+	return &AuditStore{DB: db}
+}
+
+type AuditEntryRow struct {
+	ID         int64          `db:"id"`
+	StudentID  string         `db:"student_id"`
+	Action     string         `db:"action"`
+	Actor      string         `db:"actor"`
+	RequestID  sql.NullString `db:"request_id"`
+	OccurredOn time.Time      `db:"occurred_on"`
+	Changes    []byte         `db:"changes"`
+}
+
+func convertAuditEntryRowToAuditEntry(r AuditEntryRow) (student.AuditEntry, error) {
+	entry := student.AuditEntry{
+		ID:         r.ID,
+		StudentID:  r.StudentID,
+		Action:     student.AuditAction(r.Action),
+		Actor:      r.Actor,
+		RequestID:  r.RequestID.String,
+		OccurredOn: r.OccurredOn,
+	}
+	if err := json.Unmarshal(r.Changes, &entry.Changes); err != nil {
+		return student.AuditEntry{}, fmt.Errorf("audit entry %d has invalid changes: %w", r.ID, err)
+	}
+	return entry, nil
+}
+
+// insertAuditEntry appends the entry within the transaction of the change it
+// records, so one is never stored without the other
+func insertAuditEntry(ctx context.Context, tx *sqlx.Tx, entry student.AuditEntry) error {
+	changes, err := json.Marshal(entry.Changes)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit changes: %w", err)
+	}
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO audit_log (student_id, action, actor, request_id, occurred_on, changes)
+        VALUES (?, ?, ?, ?, ?, ?)`,
+		entry.StudentID, string(entry.Action), entry.Actor,
+		sql.NullString{String: entry.RequestID, Valid: entry.RequestID != ""}, entry.OccurredOn, changes)
+	if err != nil {
+		return storeError("failed to insert audit entry", err)
+	}
+	return nil
+}
+
+func (s *AuditStore) ListAuditEntries(ctx context.Context, f student.AuditFilter) ([]student.AuditEntry, error) {
+	var conds []string
+	var args []interface{}
+	if f.StudentID != "" {
+		conds = append(conds, "student_id = ?")
+		args = append(args, f.StudentID)
+	}
+	if f.Actor != "" {
+		conds = append(conds, "actor = ?")
+		args = append(args, f.Actor)
+	}
+	if !f.From.IsZero() {
+		conds = append(conds, "occurred_on >= ?")
+		args = append(args, f.From)
+	}
+	if !f.To.IsZero() {
+		conds = append(conds, "occurred_on <= ?")
+		args = append(args, f.To)
+	}
+	if f.BeforeID > 0 {
+		conds = append(conds, "id < ?")
+		args = append(args, f.BeforeID)
+	}
+
+	query := "SELECT id, student_id, action, actor, request_id, occurred_on, changes FROM audit_log"
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	query += " ORDER BY id DESC LIMIT ?"
+	args = append(args, f.Limit)
+
+	var rows []AuditEntryRow
+	if err := s.DB.SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, storeError("failed to list audit entries", err)
+	}
+	entries := make([]student.AuditEntry, 0, len(rows))
+	for _, r := range rows {
+		entry, err := convertAuditEntryRowToAuditEntry(r)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
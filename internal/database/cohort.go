@@ -0,0 +1,155 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang-assignment/internal/cohort"
+	"golang-assignment/internal/student"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type CohortStore struct {
+	DB *sqlx.DB
+	// ParseQuery reads the search parameters the cohorts saved before
+	// migration 0018 hold instead of a filter
+	ParseQuery func(string) (student.SearchFilter, error)
+}
+
+func NewCohortStore(db *sqlx.DB, parseQuery func(string) (student.SearchFilter, error)) *CohortStore {
+	return &CohortStore{DB: db, ParseQuery: parseQuery}
+}
+
+type CohortRow struct {
+	ID          int64          `db:"id"`
+	Name        string         `db:"name"`
+	Description string         `db:"description"`
+	Filter      []byte         `db:"filter"`
+	Query       sql.NullString `db:"query"`
+	CreatedBy   sql.NullString `db:"created_by"`
+	CreatedOn   time.Time      `db:"created_on"`
+	UpdatedBy   sql.NullString `db:"updated_by"`
+	UpdatedOn   time.Time      `db:"updated_on"`
+}
+
+func (s *CohortStore) convertCohortRowToCohort(r CohortRow) (cohort.Cohort, error) {
+	c := cohort.Cohort{
+		ID:          r.ID,
+		Name:        r.Name,
+		Description: r.Description,
+		CreatedBy:   r.CreatedBy.String,
+		CreatedOn:   r.CreatedOn,
+		UpdatedBy:   r.UpdatedBy.String,
+		UpdatedOn:   r.UpdatedOn,
+	}
+	if r.Filter == nil {
+		// saved before migration 0018, until it is saved again
+		filter, err := s.ParseQuery(r.Query.String)
+		if err != nil {
+			return cohort.Cohort{}, fmt.Errorf("cohort %d has an invalid query: %w", r.ID, err)
+		}
+		c.Filter = filter
+		return c, nil
+	}
+	if err := json.Unmarshal(r.Filter, &c.Filter); err != nil {
+		return cohort.Cohort{}, fmt.Errorf("cohort %d has an invalid filter: %w", r.ID, err)
+	}
+	return c, nil
+}
+
+const cohortColumns = "id, name, description, filter, query, created_by, created_on, updated_by, updated_on"
+
+func (s *CohortStore) ListCohorts(ctx context.Context) ([]cohort.Cohort, error) {
+	var rows []CohortRow
+	if err := s.DB.SelectContext(ctx, &rows, "SELECT "+cohortColumns+" FROM cohorts ORDER BY name"); err != nil {
+		return nil, storeError("failed to list cohorts", err)
+	}
+	cohorts := make([]cohort.Cohort, 0, len(rows))
+	for _, r := range rows {
+		c, err := s.convertCohortRowToCohort(r)
+		if err != nil {
+			return nil, err
+		}
+		cohorts = append(cohorts, c)
+	}
+	return cohorts, nil
+}
+
+func (s *CohortStore) GetCohort(ctx context.Context, id int64) (cohort.Cohort, error) {
+	var row CohortRow
+	err := s.DB.GetContext(ctx, &row, "SELECT "+cohortColumns+" FROM cohorts WHERE id = ?", id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return cohort.Cohort{}, fmt.Errorf("cohort %d not found: %w", id, cohort.ErrCohortNotFound)
+		}
+		return cohort.Cohort{}, storeError("failed to fetch cohort", err)
+	}
+	return s.convertCohortRowToCohort(row)
+}
+
+func (s *CohortStore) CreateCohort(ctx context.Context, c cohort.Cohort) (cohort.Cohort, error) {
+	filter, err := json.Marshal(c.Filter)
+	if err != nil {
+		return cohort.Cohort{}, fmt.Errorf("failed to encode the cohort filter: %w", err)
+	}
+	c.CreatedOn = time.Now()
+	c.UpdatedOn = c.CreatedOn
+	result, err := s.DB.ExecContext(ctx,
+		"INSERT INTO cohorts (name, description, filter, created_by, created_on, updated_by, updated_on) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		c.Name, c.Description, string(filter), c.CreatedBy, c.CreatedOn, c.UpdatedBy, c.UpdatedOn)
+	if err != nil {
+		if isDuplicateKey(err) {
+			return cohort.Cohort{}, fmt.Errorf("cohort %s: %w", c.Name, cohort.ErrDuplicateCohort)
+		}
+		return cohort.Cohort{}, storeError("failed to insert cohort", err)
+	}
+	if c.ID, err = result.LastInsertId(); err != nil {
+		return cohort.Cohort{}, fmt.Errorf("could not determine the cohort id: %w", err)
+	}
+	return c, nil
+}
+
+func (s *CohortStore) UpdateCohort(ctx context.Context, c cohort.Cohort) (cohort.Cohort, error) {
+	filter, err := json.Marshal(c.Filter)
+	if err != nil {
+		return cohort.Cohort{}, fmt.Errorf("failed to encode the cohort filter: %w", err)
+	}
+	c.UpdatedOn = time.Now()
+	result, err := s.DB.ExecContext(ctx,
+		"UPDATE cohorts SET name = ?, description = ?, filter = ?, query = NULL, updated_by = ?, updated_on = ? WHERE id = ?",
+		c.Name, c.Description, string(filter), c.UpdatedBy, c.UpdatedOn, c.ID)
+	if err != nil {
+		if isDuplicateKey(err) {
+			return cohort.Cohort{}, fmt.Errorf("cohort %s: %w", c.Name, cohort.ErrDuplicateCohort)
+		}
+		return cohort.Cohort{}, storeError("failed to update cohort", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return cohort.Cohort{}, fmt.Errorf("could not determine rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return cohort.Cohort{}, fmt.Errorf("no rows were updated, cohort %d might not exist: %w", c.ID, cohort.ErrCohortNotFound)
+	}
+	return c, nil
+}
+
+func (s *CohortStore) DeleteCohort(ctx context.Context, id int64) error {
+	result, err := s.DB.ExecContext(ctx, "DELETE FROM cohorts WHERE id = ?", id)
+	if err != nil {
+		return storeError("failed to delete cohort", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("could not determine rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("cohort %d not found: %w", id, cohort.ErrCohortNotFound)
+	}
+	return nil
+}
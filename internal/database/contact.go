@@ -0,0 +1,202 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang-assignment/internal/contact"
+	"golang-assignment/internal/student"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type ContactStore struct {
+	DB *sqlx.DB
+}
+
+func NewContactStore(db *sqlx.DB) *ContactStore {
+	return &ContactStore{DB: db}
+}
+
+type ContactRow struct {
+	ID             int64          `db:"id"`
+	StudentID      string         `db:"student_id"`
+	Name           string         `db:"name"`
+	Relationship   string         `db:"relationship"`
+	Phone          sql.NullString `db:"phone"`
+	Email          sql.NullString `db:"email"`
+	Address        sql.NullString `db:"address"`
+	Primary        bool           `db:"is_primary"`
+	Emergency      bool           `db:"emergency"`
+	ConsentPickup  bool           `db:"consent_pickup"`
+	ConsentMedical bool           `db:"consent_medical"`
+	ConsentRecords bool           `db:"consent_records"`
+	CreatedBy      sql.NullString `db:"created_by"`
+	CreatedOn      time.Time      `db:"created_on"`
+	UpdatedBy      sql.NullString `db:"updated_by"`
+	UpdatedOn      time.Time      `db:"updated_on"`
+}
+
+func convertContactRowToContact(r ContactRow) contact.Contact {
+	return contact.Contact{
+		ID:           r.ID,
+		StudentID:    r.StudentID,
+		Name:         r.Name,
+		Relationship: contact.Relationship(r.Relationship),
+		Phone:        r.Phone.String,
+		Email:        r.Email.String,
+		Address:      r.Address.String,
+		Primary:      r.Primary,
+		Emergency:    r.Emergency,
+		Consents: contact.Consents{
+			Pickup:  r.ConsentPickup,
+			Medical: r.ConsentMedical,
+			Records: r.ConsentRecords,
+		},
+		CreatedBy: r.CreatedBy.String,
+		CreatedOn: r.CreatedOn,
+		UpdatedBy: r.UpdatedBy.String,
+		UpdatedOn: r.UpdatedOn,
+	}
+}
+
+// nullString stores an empty optional field as NULL
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+const contactColumns = `id, student_id, name, relationship, phone, email, address, is_primary, emergency,
+    consent_pickup, consent_medical, consent_records, created_by, created_on, updated_by, updated_on`
+
+func (s *ContactStore) ListContacts(ctx context.Context, studentID string) ([]contact.Contact, error) {
+	var rows []ContactRow
+	err := s.DB.SelectContext(ctx, &rows,
+		"SELECT "+contactColumns+" FROM contacts WHERE student_id = ? ORDER BY is_primary DESC, id", studentID)
+	if err != nil {
+		return nil, storeError("failed to list contacts", err)
+	}
+	contacts := make([]contact.Contact, 0, len(rows))
+	for _, r := range rows {
+		contacts = append(contacts, convertContactRowToContact(r))
+	}
+	return contacts, nil
+}
+
+func getContact(ctx context.Context, q sqlx.QueryerContext, studentID string, id int64) (contact.Contact, error) {
+	var row ContactRow
+	err := sqlx.GetContext(ctx, q, &row, "SELECT "+contactColumns+" FROM contacts WHERE id = ? AND student_id = ?", id, studentID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return contact.Contact{}, fmt.Errorf("contact %d of student %s not found: %w", id, studentID, contact.ErrContactNotFound)
+		}
+		return contact.Contact{}, storeError("failed to fetch contact", err)
+	}
+	return convertContactRowToContact(row), nil
+}
+
+func (s *ContactStore) GetContact(ctx context.Context, studentID string, id int64) (contact.Contact, error) {
+	return getContact(ctx, s.DB, studentID, id)
+}
+
+// clearPrimary makes every contact of the student but id secondary
+func clearPrimary(ctx context.Context, tx *sqlx.Tx, studentID string, id int64) error {
+	_, err := tx.ExecContext(ctx, "UPDATE contacts SET is_primary = 0 WHERE student_id = ? AND id <> ? AND is_primary = 1", studentID, id)
+	if err != nil {
+		return storeError("failed to clear the primary contact", err)
+	}
+	return nil
+}
+
+func (s *ContactStore) CreateContact(ctx context.Context, c contact.Contact) (contact.Contact, error) {
+	tx, err := s.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return contact.Contact{}, storeError("failed to begin the contact transaction", err)
+	}
+	// a no-op once committed
+	defer tx.Rollback()
+
+	if c.Primary {
+		if err := clearPrimary(ctx, tx, c.StudentID, 0); err != nil {
+			return contact.Contact{}, err
+		}
+	}
+	c.CreatedOn = time.Now()
+	c.UpdatedOn = c.CreatedOn
+	result, err := tx.ExecContext(ctx,
+		`INSERT INTO contacts (student_id, name, relationship, phone, email, address, is_primary, emergency,
+            consent_pickup, consent_medical, consent_records, created_by, created_on, updated_by, updated_on)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		c.StudentID, c.Name, c.Relationship, nullString(c.Phone), nullString(c.Email), nullString(c.Address), c.Primary, c.Emergency,
+		c.Consents.Pickup, c.Consents.Medical, c.Consents.Records, c.CreatedBy, c.CreatedOn, c.UpdatedBy, c.UpdatedOn)
+	if err != nil {
+		if isMissingReference(err) {
+			// the student was purged since the service checked it
+			return contact.Contact{}, fmt.Errorf("student with ID %s not found: %w", c.StudentID, student.ErrNoStudentFound)
+		}
+		return contact.Contact{}, storeError("failed to insert contact", err)
+	}
+	if c.ID, err = result.LastInsertId(); err != nil {
+		return contact.Contact{}, fmt.Errorf("could not determine the contact id: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return contact.Contact{}, storeError("failed to commit the contact transaction", err)
+	}
+	return c, nil
+}
+
+func (s *ContactStore) UpdateContact(ctx context.Context, c contact.Contact) (contact.Contact, error) {
+	tx, err := s.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return contact.Contact{}, storeError("failed to begin the contact transaction", err)
+	}
+	// a no-op once committed
+	defer tx.Rollback()
+
+	if c.Primary {
+		if err := clearPrimary(ctx, tx, c.StudentID, c.ID); err != nil {
+			return contact.Contact{}, err
+		}
+	}
+	result, err := tx.ExecContext(ctx,
+		`UPDATE contacts SET name = ?, relationship = ?, phone = ?, email = ?, address = ?, is_primary = ?, emergency = ?,
+            consent_pickup = ?, consent_medical = ?, consent_records = ?, updated_by = ?, updated_on = ?
+        WHERE id = ? AND student_id = ?`,
+		c.Name, c.Relationship, nullString(c.Phone), nullString(c.Email), nullString(c.Address), c.Primary, c.Emergency,
+		c.Consents.Pickup, c.Consents.Medical, c.Consents.Records, c.UpdatedBy, time.Now(), c.ID, c.StudentID)
+	if err != nil {
+		return contact.Contact{}, storeError("failed to update contact", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return contact.Contact{}, fmt.Errorf("could not determine rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return contact.Contact{}, fmt.Errorf("contact %d of student %s not found: %w", c.ID, c.StudentID, contact.ErrContactNotFound)
+	}
+	// read back for the creation fields
+	if c, err = getContact(ctx, tx, c.StudentID, c.ID); err != nil {
+		return contact.Contact{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return contact.Contact{}, storeError("failed to commit the contact transaction", err)
+	}
+	return c, nil
+}
+
+func (s *ContactStore) DeleteContact(ctx context.Context, studentID string, id int64) error {
+	result, err := s.DB.ExecContext(ctx, "DELETE FROM contacts WHERE id = ? AND student_id = ?", id, studentID)
+	if err != nil {
+		return storeError("failed to delete contact", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("could not determine rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("contact %d of student %s not found: %w", id, studentID, contact.ErrContactNotFound)
+	}
+	return nil
+}
@@ -0,0 +1,225 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang-assignment/internal/course"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type CourseStore struct {
+	DB *sqlx.DB
+}
+
+func NewCourseStore(db *sqlx.DB) *CourseStore {
+	return &CourseStore{DB: db}
+}
+
+type CourseRow struct {
+	Code      string         `db:"code"`
+	Title     string         `db:"title"`
+	Credits   int            `db:"credits"`
+	Capacity  int            `db:"capacity"`
+	Active    bool           `db:"active"`
+	CreatedBy sql.NullString `db:"created_by"`
+	CreatedOn time.Time      `db:"created_on"`
+	UpdatedBy sql.NullString `db:"updated_by"`
+	UpdatedOn time.Time      `db:"updated_on"`
+}
+
+func convertCourseRowToCourse(r CourseRow) course.Course {
+	return course.Course{
+		Code:      r.Code,
+		Title:     r.Title,
+		Credits:   r.Credits,
+		Capacity:  r.Capacity,
+		Active:    r.Active,
+		CreatedBy: r.CreatedBy.String,
+		CreatedOn: r.CreatedOn,
+		UpdatedBy: r.UpdatedBy.String,
+		UpdatedOn: r.UpdatedOn,
+	}
+}
+
+const courseColumns = "code, title, credits, capacity, active, created_by, created_on, updated_by, updated_on"
+
+// the collation of the code column makes every lookup ignore case
+func (s *CourseStore) GetCourse(ctx context.Context, code string) (course.Course, error) {
+	var row CourseRow
+	err := s.DB.GetContext(ctx, &row, "SELECT "+courseColumns+" FROM courses WHERE code = ?", code)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return course.Course{}, fmt.Errorf("course %s not found: %w", code, course.ErrCourseNotFound)
+		}
+		return course.Course{}, storeError("failed to fetch course", err)
+	}
+	return convertCourseRowToCourse(row), nil
+}
+
+func (s *CourseStore) ListCourses(ctx context.Context, includeInactive bool) ([]course.Course, error) {
+	query := "SELECT " + courseColumns + " FROM courses"
+	if !includeInactive {
+		query += " WHERE active"
+	}
+	var rows []CourseRow
+	if err := s.DB.SelectContext(ctx, &rows, query+" ORDER BY code"); err != nil {
+		return nil, storeError("failed to list courses", err)
+	}
+	courses := make([]course.Course, 0, len(rows))
+	for _, r := range rows {
+		courses = append(courses, convertCourseRowToCourse(r))
+	}
+	return courses, nil
+}
+
+func (s *CourseStore) CreateCourse(ctx context.Context, c course.Course) (course.Course, error) {
+	c.CreatedOn = time.Now()
+	c.UpdatedOn = c.CreatedOn
+	_, err := s.DB.ExecContext(ctx, "INSERT INTO courses ("+courseColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		c.Code, c.Title, c.Credits, c.Capacity, c.Active, c.CreatedBy, c.CreatedOn, c.UpdatedBy, c.UpdatedOn)
+	if err != nil {
+		if isDuplicateKey(err) {
+			return course.Course{}, fmt.Errorf("course %s: %w", c.Code, course.ErrDuplicateCourse)
+		}
+		return course.Course{}, storeError("failed to insert course", err)
+	}
+	return c, nil
+}
+
+func (s *CourseStore) UpdateCourse(ctx context.Context, c course.Course) (course.Course, error) {
+	c.UpdatedOn = time.Now()
+	result, err := s.DB.ExecContext(ctx,
+		"UPDATE courses SET title = ?, credits = ?, capacity = ?, active = ?, updated_by = ?, updated_on = ? WHERE code = ?",
+		c.Title, c.Credits, c.Capacity, c.Active, c.UpdatedBy, c.UpdatedOn, c.Code)
+	if err != nil {
+		return course.Course{}, storeError("failed to update course", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return course.Course{}, fmt.Errorf("could not determine rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return course.Course{}, fmt.Errorf("no rows were updated, course %s might not exist: %w", c.Code, course.ErrCourseNotFound)
+	}
+	return c, nil
+}
+
+func (s *CourseStore) DeleteCourse(ctx context.Context, code string) error {
+	result, err := s.DB.ExecContext(ctx, "DELETE FROM courses WHERE code = ?", code)
+	if err != nil {
+		if isReferenced(err) {
+			return fmt.Errorf("course %s: %w", code, course.ErrCourseInUse)
+		}
+		return storeError("failed to delete course", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("could not determine rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("course %s not found: %w", code, course.ErrCourseNotFound)
+	}
+	return nil
+}
+
+// LegacyCourse is a free-text course the migration to the catalog took over,
+// with the number of students still having it
+type LegacyCourse struct {
+	Code     string `db:"code"`
+	Students int    `db:"students"`
+}
+
+// LegacyCourses lists the courses the migration to the catalog took over and
+// that are still in the catalog
+func (s *CourseStore) LegacyCourses(ctx context.Context) ([]LegacyCourse, error) {
+	var legacy []LegacyCourse
+	err := s.DB.SelectContext(ctx, &legacy,
+		`SELECT c.code, COUNT(s.id) AS students FROM courses c LEFT JOIN students s ON s.course = c.code
+        WHERE c.created_by = 'migration' GROUP BY c.code ORDER BY c.code`)
+	if err != nil {
+		return nil, storeError("failed to list legacy courses", err)
+	}
+	return legacy, nil
+}
+
+// MapCourse moves every student, enrollment and attendance record of the
+// from course, trash included, to the to course and removes from from the catalog. Each moved student gets a new
+// version and an audit entry, all in one transaction.
+func (s *CourseStore) MapCourse(ctx context.Context, from, to, mappedBy string) (int64, error) {
+	if strings.EqualFold(from, to) {
+		return 0, fmt.Errorf("%w: a course cannot be mapped to itself", course.ErrInvalidCourse)
+	}
+
+	tx, err := s.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, storeError("failed to begin the mapping transaction", err)
+	}
+	// a no-op once committed
+	defer tx.Rollback()
+
+	var target CourseRow
+	err = tx.GetContext(ctx, &target, "SELECT "+courseColumns+" FROM courses WHERE code = ? FOR UPDATE", to)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, fmt.Errorf("course %s not found: %w", to, course.ErrCourseNotFound)
+		}
+		return 0, storeError("failed to fetch course", err)
+	}
+	var source CourseRow
+	err = tx.GetContext(ctx, &source, "SELECT "+courseColumns+" FROM courses WHERE code = ? FOR UPDATE", from)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, fmt.Errorf("course %s not found: %w", from, course.ErrCourseNotFound)
+		}
+		return 0, storeError("failed to fetch course", err)
+	}
+
+	now := time.Now()
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO audit_log (student_id, action, actor, occurred_on, changes)
+        SELECT id, 'update', ?, ?, JSON_ARRAY(JSON_OBJECT('field', 'course', 'before', course, 'after', ?))
+        FROM students WHERE course = ?`,
+		mappedBy, now, target.Code, source.Code)
+	if err != nil {
+		return 0, storeError("failed to audit the mapped students", err)
+	}
+	result, err := tx.ExecContext(ctx,
+		"UPDATE students SET course = ?, version = version + 1, updated_by = ?, updated_on = ? WHERE course = ?",
+		target.Code, mappedBy, now, source.Code)
+	if err != nil {
+		return 0, storeError("failed to move the students", err)
+	}
+	moved, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("could not determine rows affected: %w", err)
+	}
+	_, err = tx.ExecContext(ctx, "UPDATE enrollments SET course = ?, updated_by = ?, updated_on = ? WHERE course = ?",
+		target.Code, mappedBy, now, source.Code)
+	if err != nil {
+		if isDuplicateKey(err) {
+			return 0, fmt.Errorf("%w: a student is enrolled in both courses for the same term", course.ErrInvalidCourse)
+		}
+		return 0, storeError("failed to move the enrollments", err)
+	}
+	for _, table := range []string{"attendance", "attendance_alerts"} {
+		if _, err := tx.ExecContext(ctx, "UPDATE "+table+" SET course = ? WHERE course = ?", target.Code, source.Code); err != nil {
+			if isDuplicateKey(err) {
+				return 0, fmt.Errorf("%w: a student has attendance in both courses for the same session", course.ErrInvalidCourse)
+			}
+			return 0, storeError("failed to move the "+table, err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM courses WHERE code = ?", source.Code); err != nil {
+		return 0, storeError("failed to delete course", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, storeError("failed to commit the mapping transaction", err)
+	}
+	return moved, nil
+}
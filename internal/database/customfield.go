@@ -0,0 +1,176 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang-assignment/internal/customfield"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type CustomFieldStore struct {
+	DB *sqlx.DB
+}
+
+func NewCustomFieldStore(db *sqlx.DB) *CustomFieldStore {
+	return &CustomFieldStore{DB: db}
+}
+
+type CustomFieldRow struct {
+	Name       string         `db:"name"`
+	Label      string         `db:"label"`
+	Type       string         `db:"type"`
+	Required   bool           `db:"required"`
+	EnumValues []byte         `db:"enum_values"`
+	Pattern    sql.NullString `db:"pattern"`
+	CreatedBy  sql.NullString `db:"created_by"`
+	CreatedOn  time.Time      `db:"created_on"`
+	UpdatedBy  sql.NullString `db:"updated_by"`
+	UpdatedOn  time.Time      `db:"updated_on"`
+}
+
+func convertCustomFieldRowToDefinition(r CustomFieldRow) (customfield.Definition, error) {
+	d := customfield.Definition{
+		Name:      r.Name,
+		Label:     r.Label,
+		Type:      customfield.Type(r.Type),
+		Required:  r.Required,
+		Pattern:   r.Pattern.String,
+		CreatedBy: r.CreatedBy.String,
+		CreatedOn: r.CreatedOn,
+		UpdatedBy: r.UpdatedBy.String,
+		UpdatedOn: r.UpdatedOn,
+	}
+	if len(r.EnumValues) > 0 {
+		if err := json.Unmarshal(r.EnumValues, &d.EnumValues); err != nil {
+			return customfield.Definition{}, fmt.Errorf("could not read the enum values of custom field %s: %w", r.Name, err)
+		}
+	}
+	return d, nil
+}
+
+const customFieldColumns = "name, label, type, required, enum_values, pattern, created_by, created_on, updated_by, updated_on"
+
+// enumValuesJSON is the enum_values column of a definition, NULL when it has
+// none
+func enumValuesJSON(d customfield.Definition) (sql.NullString, error) {
+	if len(d.EnumValues) == 0 {
+		return sql.NullString{}, nil
+	}
+	encoded, err := json.Marshal(d.EnumValues)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return nullString(string(encoded)), nil
+}
+
+func (s *CustomFieldStore) ListFields(ctx context.Context) ([]customfield.Definition, error) {
+	var rows []CustomFieldRow
+	if err := s.DB.SelectContext(ctx, &rows, "SELECT "+customFieldColumns+" FROM custom_fields ORDER BY name"); err != nil {
+		return nil, storeError("failed to list custom fields", err)
+	}
+	fields := make([]customfield.Definition, 0, len(rows))
+	for _, r := range rows {
+		d, err := convertCustomFieldRowToDefinition(r)
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, d)
+	}
+	return fields, nil
+}
+
+func (s *CustomFieldStore) GetField(ctx context.Context, name string) (customfield.Definition, error) {
+	var row CustomFieldRow
+	err := s.DB.GetContext(ctx, &row, "SELECT "+customFieldColumns+" FROM custom_fields WHERE name = ?", name)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return customfield.Definition{}, fmt.Errorf("custom field %s not found: %w", name, customfield.ErrFieldNotFound)
+		}
+		return customfield.Definition{}, storeError("failed to fetch custom field", err)
+	}
+	return convertCustomFieldRowToDefinition(row)
+}
+
+func (s *CustomFieldStore) CreateField(ctx context.Context, d customfield.Definition) (customfield.Definition, error) {
+	enumValues, err := enumValuesJSON(d)
+	if err != nil {
+		return customfield.Definition{}, err
+	}
+	d.CreatedOn = time.Now()
+	d.UpdatedOn = d.CreatedOn
+	_, err = s.DB.ExecContext(ctx, "INSERT INTO custom_fields ("+customFieldColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		d.Name, d.Label, d.Type, d.Required, enumValues, nullString(d.Pattern), d.CreatedBy, d.CreatedOn, d.UpdatedBy, d.UpdatedOn)
+	if err != nil {
+		if isDuplicateKey(err) {
+			return customfield.Definition{}, fmt.Errorf("custom field %s: %w", d.Name, customfield.ErrDuplicateField)
+		}
+		return customfield.Definition{}, storeError("failed to insert custom field", err)
+	}
+	return d, nil
+}
+
+func (s *CustomFieldStore) UpdateField(ctx context.Context, d customfield.Definition) (customfield.Definition, error) {
+	enumValues, err := enumValuesJSON(d)
+	if err != nil {
+		return customfield.Definition{}, err
+	}
+	d.UpdatedOn = time.Now()
+	result, err := s.DB.ExecContext(ctx,
+		"UPDATE custom_fields SET label = ?, required = ?, enum_values = ?, pattern = ?, updated_by = ?, updated_on = ? WHERE name = ?",
+		d.Label, d.Required, enumValues, nullString(d.Pattern), d.UpdatedBy, d.UpdatedOn, d.Name)
+	if err != nil {
+		return customfield.Definition{}, storeError("failed to update custom field", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return customfield.Definition{}, fmt.Errorf("could not determine rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return customfield.Definition{}, fmt.Errorf("no rows were updated, custom field %s might not exist: %w", d.Name, customfield.ErrFieldNotFound)
+	}
+	return d, nil
+}
+
+// DeleteField removes the definition and the values of the field in the same
+// transaction. The students losing a value move to a new version, so a write
+// based on the value they had fails.
+func (s *CustomFieldStore) DeleteField(ctx context.Context, name string) error {
+	tx, err := s.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return storeError("failed to begin the custom field transaction", err)
+	}
+	// a no-op once committed
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, "DELETE FROM custom_fields WHERE name = ?", name)
+	if err != nil {
+		return storeError("failed to delete custom field", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("could not determine rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("custom field %s not found: %w", name, customfield.ErrFieldNotFound)
+	}
+
+	path := `$."` + name + `"`
+	_, err = tx.ExecContext(ctx,
+		`UPDATE students SET custom_fields = NULLIF(JSON_REMOVE(custom_fields, ?), JSON_OBJECT()), version = version + 1
+        WHERE JSON_CONTAINS_PATH(custom_fields, 'one', ?)`,
+		path, path)
+	if err != nil {
+		return storeError("failed to remove the values of custom field", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return storeError("failed to commit the custom field transaction", err)
+	}
+	return nil
+}
@@ -2,10 +2,16 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
 	"fmt"
 	"golang-assignment/config"
+	"golang-assignment/internal/student"
+	"net"
+	"strings"
 
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
 	"github.com/jmoiron/sqlx"
 )
 
@@ -25,11 +31,20 @@ func InitDatabase(cfg *config.Config) (*sqlx.DB, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
+
+	if cfg.AutoMigrate {
+		if _, err := MigrateUp(context.Background(), db); err != nil {
+			return nil, fmt.Errorf("failed to migrate database: %w", err)
+		}
+	}
 	return db, nil
 }
 
 func (store *StudentStore) Ping(ctx context.Context) error {
-	return store.DB.PingContext(ctx)
+	if err := store.DB.PingContext(ctx); err != nil {
+		return storeError("failed to ping database", err)
+	}
+	return nil
 }
 
 type StudentStore struct {
@@ -39,3 +54,65 @@ type StudentStore struct {
 func NewStudentStore(db *sqlx.DB) *StudentStore {
 	return &StudentStore{DB: db}
 }
+
+// MySQL error number of a duplicate entry for a unique key
+const mysqlErrDuplicateEntry = 1062
+
+func isDuplicateKey(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlErrDuplicateEntry
+}
+
+// MySQL error number of a delete or update refused by a foreign key
+const mysqlErrRowIsReferenced = 1451
+
+func isReferenced(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlErrRowIsReferenced
+}
+
+// MySQL error number of an insert or update refused by a foreign key
+const mysqlErrNoReferencedRow = 1452
+
+func isMissingReference(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlErrNoReferencedRow
+}
+
+// duplicateKeyName returns the name of the unique key a duplicate entry error
+// is about, MySQL reports it as "Duplicate entry '...' for key 'table.key'"
+func duplicateKeyName(err error) string {
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) || mysqlErr.Number != mysqlErrDuplicateEntry {
+		return ""
+	}
+	i := strings.LastIndex(mysqlErr.Message, " for key ")
+	if i < 0 {
+		return ""
+	}
+	key := strings.Trim(mysqlErr.Message[i+len(" for key "):], "'")
+	if j := strings.LastIndex(key, "."); j >= 0 {
+		key = key[j+1:]
+	}
+	return key
+}
+
+// isUnavailable reports whether err means the database could not be reached
+// rather than that the query failed
+func isUnavailable(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, mysql.ErrInvalidConn) ||
+		errors.Is(err, sql.ErrConnDone) ||
+		errors.Is(err, context.DeadlineExceeded) ||
+		errors.As(err, &netErr)
+}
+
+// storeError wraps err with msg, and with student.ErrStoreUnavailable when
+// the database could not be reached so callers can answer with a retry
+func storeError(msg string, err error) error {
+	if isUnavailable(err) {
+		return fmt.Errorf("%s: %w: %w", msg, student.ErrStoreUnavailable, err)
+	}
+	return fmt.Errorf("%s: %w", msg, err)
+}
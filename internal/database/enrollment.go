@@ -0,0 +1,293 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang-assignment/internal/course"
+	"golang-assignment/internal/enrollment"
+	"golang-assignment/internal/student"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type EnrollmentStore struct {
+	DB *sqlx.DB
+}
+
+func NewEnrollmentStore(db *sqlx.DB) *EnrollmentStore {
+	return &EnrollmentStore{DB: db}
+}
+
+type EnrollmentRow struct {
+	ID         int64          `db:"id"`
+	StudentID  string         `db:"student_id"`
+	Course     string         `db:"course"`
+	Term       string         `db:"term"`
+	Status     string         `db:"status"`
+	EnrolledBy sql.NullString `db:"enrolled_by"`
+	EnrolledOn time.Time      `db:"enrolled_on"`
+	UpdatedBy  sql.NullString `db:"updated_by"`
+	UpdatedOn  time.Time      `db:"updated_on"`
+}
+
+func convertEnrollmentRowToEnrollment(r EnrollmentRow) enrollment.Enrollment {
+	return enrollment.Enrollment{
+		ID:         r.ID,
+		StudentID:  r.StudentID,
+		Course:     r.Course,
+		Term:       r.Term,
+		Status:     enrollment.Status(r.Status),
+		EnrolledBy: r.EnrolledBy.String,
+		EnrolledOn: r.EnrolledOn,
+		UpdatedBy:  r.UpdatedBy.String,
+		UpdatedOn:  r.UpdatedOn,
+	}
+}
+
+func convertEnrollmentRows(rows []EnrollmentRow) []enrollment.Enrollment {
+	enrollments := make([]enrollment.Enrollment, 0, len(rows))
+	for _, r := range rows {
+		enrollments = append(enrollments, convertEnrollmentRowToEnrollment(r))
+	}
+	return enrollments
+}
+
+const enrollmentColumns = "id, student_id, course, term, status, enrolled_by, enrolled_on, updated_by, updated_on"
+
+func (s *EnrollmentStore) GetEnrollment(ctx context.Context, id int64) (enrollment.Enrollment, error) {
+	var row EnrollmentRow
+	err := s.DB.GetContext(ctx, &row, "SELECT "+enrollmentColumns+" FROM enrollments WHERE id = ?", id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return enrollment.Enrollment{}, fmt.Errorf("enrollment %d not found: %w", id, enrollment.ErrEnrollmentNotFound)
+		}
+		return enrollment.Enrollment{}, storeError("failed to fetch enrollment", err)
+	}
+	return convertEnrollmentRowToEnrollment(row), nil
+}
+
+func (s *EnrollmentStore) ListStudentEnrollments(ctx context.Context, studentID, term string) ([]enrollment.Enrollment, error) {
+	query := "SELECT " + enrollmentColumns + " FROM enrollments WHERE student_id = ?"
+	args := []interface{}{studentID}
+	if term != "" {
+		query += " AND term = ?"
+		args = append(args, term)
+	}
+	var rows []EnrollmentRow
+	if err := s.DB.SelectContext(ctx, &rows, query+" ORDER BY term, course, id", args...); err != nil {
+		return nil, storeError("failed to list enrollments", err)
+	}
+	return convertEnrollmentRows(rows), nil
+}
+
+func (s *EnrollmentStore) ListRoster(ctx context.Context, code string, filter enrollment.RosterFilter) ([]enrollment.Enrollment, error) {
+	query := "SELECT " + enrollmentColumns + " FROM enrollments WHERE course = ?"
+	args := []interface{}{code}
+	if filter.Term != "" {
+		query += " AND term = ?"
+		args = append(args, filter.Term)
+	}
+	if filter.Status != "" {
+		query += " AND status = ?"
+		args = append(args, filter.Status)
+	}
+	var rows []EnrollmentRow
+	if err := s.DB.SelectContext(ctx, &rows, query+" ORDER BY term, enrolled_on, id", args...); err != nil {
+		return nil, storeError("failed to list the roster", err)
+	}
+	return convertEnrollmentRows(rows), nil
+}
+
+// lockCourse locks the catalog row of the course for the rest of the
+// transaction, which serializes every change to its seats, and returns its
+// capacity
+func lockCourse(ctx context.Context, tx *sqlx.Tx, code string) (int, error) {
+	var capacity int
+	err := tx.GetContext(ctx, &capacity, "SELECT capacity FROM courses WHERE code = ? FOR UPDATE", code)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, fmt.Errorf("course %s not found: %w", code, course.ErrCourseNotFound)
+		}
+		return 0, storeError("failed to lock course", err)
+	}
+	return capacity, nil
+}
+
+func takenSeats(ctx context.Context, tx *sqlx.Tx, code, term string) (int, error) {
+	var taken int
+	err := tx.GetContext(ctx, &taken, "SELECT COUNT(*) FROM enrollments WHERE course = ? AND term = ? AND status IN (?, ?)",
+		code, term, enrollment.StatusActive, enrollment.StatusCompleted)
+	if err != nil {
+		return 0, storeError("failed to count the seats", err)
+	}
+	return taken, nil
+}
+
+// fillSeats promotes the oldest waitlisted enrollments of the course and term
+// into its free seats, the caller holds the lock of the course
+func fillSeats(ctx context.Context, tx *sqlx.Tx, code, term string, capacity int, updatedBy string, now time.Time) (int64, error) {
+	query := "UPDATE enrollments SET status = ?, updated_by = ?, updated_on = ? WHERE course = ? AND term = ? AND status = ?"
+	args := []interface{}{enrollment.StatusActive, updatedBy, now, code, term, enrollment.StatusWaitlisted}
+	if capacity > 0 {
+		taken, err := takenSeats(ctx, tx, code, term)
+		if err != nil {
+			return 0, err
+		}
+		if taken >= capacity {
+			return 0, nil
+		}
+		query += " ORDER BY enrolled_on, id LIMIT ?"
+		args = append(args, capacity-taken)
+	}
+	result, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, storeError("failed to promote the waitlist", err)
+	}
+	promoted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("could not determine rows affected: %w", err)
+	}
+	return promoted, nil
+}
+
+func (s *EnrollmentStore) Enroll(ctx context.Context, e enrollment.Enrollment) (enrollment.Enrollment, error) {
+	tx, err := s.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return enrollment.Enrollment{}, storeError("failed to begin the enrollment transaction", err)
+	}
+	// a no-op once committed
+	defer tx.Rollback()
+
+	capacity, err := lockCourse(ctx, tx, e.Course)
+	if err != nil {
+		return enrollment.Enrollment{}, err
+	}
+	e.EnrolledOn = time.Now()
+	e.UpdatedOn = e.EnrolledOn
+	// the waitlist is served before the newcomer
+	if _, err := fillSeats(ctx, tx, e.Course, e.Term, capacity, e.EnrolledBy, e.EnrolledOn); err != nil {
+		return enrollment.Enrollment{}, err
+	}
+
+	e.Status = enrollment.StatusActive
+	if capacity > 0 {
+		taken, err := takenSeats(ctx, tx, e.Course, e.Term)
+		if err != nil {
+			return enrollment.Enrollment{}, err
+		}
+		if taken >= capacity {
+			e.Status = enrollment.StatusWaitlisted
+		}
+	}
+
+	result, err := tx.ExecContext(ctx,
+		`INSERT INTO enrollments (student_id, course, term, status, enrolled_by, enrolled_on, updated_by, updated_on)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		e.StudentID, e.Course, e.Term, e.Status, e.EnrolledBy, e.EnrolledOn, e.UpdatedBy, e.UpdatedOn)
+	if err != nil {
+		switch {
+		case isDuplicateKey(err):
+			return enrollment.Enrollment{}, fmt.Errorf("student %s in %s for %s: %w", e.StudentID, e.Course, e.Term, enrollment.ErrAlreadyEnrolled)
+		case isMissingReference(err):
+			// the student was purged since the service checked it
+			return enrollment.Enrollment{}, fmt.Errorf("student with ID %s not found: %w", e.StudentID, student.ErrNoStudentFound)
+		}
+		return enrollment.Enrollment{}, storeError("failed to insert enrollment", err)
+	}
+	if e.ID, err = result.LastInsertId(); err != nil {
+		return enrollment.Enrollment{}, fmt.Errorf("could not determine the enrollment id: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return enrollment.Enrollment{}, storeError("failed to commit the enrollment transaction", err)
+	}
+	return e, nil
+}
+
+func (s *EnrollmentStore) ChangeStatus(ctx context.Context, id int64, status enrollment.Status, updatedBy string) (enrollment.Enrollment, error) {
+	// the course is locked before the enrollment like Enroll does, so the
+	// course has to be known first
+	e, err := s.GetEnrollment(ctx, id)
+	if err != nil {
+		return enrollment.Enrollment{}, err
+	}
+
+	tx, err := s.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return enrollment.Enrollment{}, storeError("failed to begin the enrollment transaction", err)
+	}
+	// a no-op once committed
+	defer tx.Rollback()
+
+	capacity, err := lockCourse(ctx, tx, e.Course)
+	if err != nil {
+		return enrollment.Enrollment{}, err
+	}
+	var row EnrollmentRow
+	err = tx.GetContext(ctx, &row, "SELECT "+enrollmentColumns+" FROM enrollments WHERE id = ? FOR UPDATE", id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return enrollment.Enrollment{}, fmt.Errorf("enrollment %d not found: %w", id, enrollment.ErrEnrollmentNotFound)
+		}
+		return enrollment.Enrollment{}, storeError("failed to fetch enrollment", err)
+	}
+	e = convertEnrollmentRowToEnrollment(row)
+	if err := e.ChangeStatus(status); err != nil {
+		return enrollment.Enrollment{}, err
+	}
+
+	freed := e.Status.HoldsSeat() && !status.HoldsSeat()
+	e.Status = status
+	e.UpdatedBy = updatedBy
+	e.UpdatedOn = time.Now()
+	_, err = tx.ExecContext(ctx, "UPDATE enrollments SET status = ?, updated_by = ?, updated_on = ? WHERE id = ?",
+		e.Status, e.UpdatedBy, e.UpdatedOn, id)
+	if err != nil {
+		return enrollment.Enrollment{}, storeError("failed to update enrollment", err)
+	}
+	if freed {
+		if _, err := fillSeats(ctx, tx, e.Course, e.Term, capacity, updatedBy, e.UpdatedOn); err != nil {
+			return enrollment.Enrollment{}, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return enrollment.Enrollment{}, storeError("failed to commit the enrollment transaction", err)
+	}
+	return e, nil
+}
+
+func (s *EnrollmentStore) FillSeats(ctx context.Context, code, updatedBy string) (int64, error) {
+	tx, err := s.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, storeError("failed to begin the enrollment transaction", err)
+	}
+	// a no-op once committed
+	defer tx.Rollback()
+
+	capacity, err := lockCourse(ctx, tx, code)
+	if err != nil {
+		return 0, err
+	}
+	var terms []string
+	err = tx.SelectContext(ctx, &terms, "SELECT DISTINCT term FROM enrollments WHERE course = ? AND status = ?",
+		code, enrollment.StatusWaitlisted)
+	if err != nil {
+		return 0, storeError("failed to list the waitlists", err)
+	}
+	now := time.Now()
+	var promoted int64
+	for _, term := range terms {
+		n, err := fillSeats(ctx, tx, code, term, capacity, updatedBy, now)
+		if err != nil {
+			return 0, err
+		}
+		promoted += n
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, storeError("failed to commit the enrollment transaction", err)
+	}
+	return promoted, nil
+}
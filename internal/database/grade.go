@@ -0,0 +1,135 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"golang-assignment/internal/student"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type GradeStore struct {
+	DB *sqlx.DB
+}
+
+func NewGradeStore(db *sqlx.DB) *GradeStore {
+	return &GradeStore{DB: db}
+}
+
+type AssessmentRow struct {
+	ID           int64          `db:"id"`
+	EnrollmentID int64          `db:"enrollment_id"`
+	Name         string         `db:"name"`
+	Score        float64        `db:"score"`
+	MaxScore     float64        `db:"max_score"`
+	Weight       float64        `db:"weight"`
+	RecordedBy   sql.NullString `db:"recorded_by"`
+	RecordedOn   time.Time      `db:"recorded_on"`
+}
+
+func convertAssessmentRowToAssessment(r AssessmentRow) student.Assessment {
+	return student.Assessment{
+		ID:           r.ID,
+		EnrollmentID: r.EnrollmentID,
+		Name:         r.Name,
+		Score:        r.Score,
+		MaxScore:     r.MaxScore,
+		Weight:       r.Weight,
+		RecordedBy:   r.RecordedBy.String,
+		RecordedOn:   r.RecordedOn,
+	}
+}
+
+type FinalGradeRow struct {
+	EnrollmentID int64          `db:"enrollment_id"`
+	Scale        string         `db:"scale"`
+	Letter       string         `db:"letter"`
+	Credits      int            `db:"credits"`
+	GradedBy     sql.NullString `db:"graded_by"`
+	GradedOn     time.Time      `db:"graded_on"`
+}
+
+func convertFinalGradeRowToFinalGrade(r FinalGradeRow) student.FinalGrade {
+	return student.FinalGrade{
+		EnrollmentID: r.EnrollmentID,
+		Scale:        r.Scale,
+		Letter:       r.Letter,
+		Credits:      r.Credits,
+		GradedBy:     r.GradedBy.String,
+		GradedOn:     r.GradedOn,
+	}
+}
+
+func (s *GradeStore) AddAssessment(ctx context.Context, a student.Assessment) (student.Assessment, error) {
+	a.RecordedOn = time.Now()
+	result, err := s.DB.ExecContext(ctx,
+		`INSERT INTO assessments (enrollment_id, name, score, max_score, weight, recorded_by, recorded_on)
+        VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		a.EnrollmentID, a.Name, a.Score, a.MaxScore, a.Weight, a.RecordedBy, a.RecordedOn)
+	if err != nil {
+		if isMissingReference(err) {
+			return student.Assessment{}, fmt.Errorf("enrollment %d not found: %w", a.EnrollmentID, student.ErrEnrollmentNotFound)
+		}
+		return student.Assessment{}, storeError("failed to insert assessment", err)
+	}
+	if a.ID, err = result.LastInsertId(); err != nil {
+		return student.Assessment{}, fmt.Errorf("could not determine the assessment id: %w", err)
+	}
+	return a, nil
+}
+
+func (s *GradeStore) ListAssessments(ctx context.Context, enrollmentID int64) ([]student.Assessment, error) {
+	var rows []AssessmentRow
+	err := s.DB.SelectContext(ctx, &rows,
+		`SELECT id, enrollment_id, name, score, max_score, weight, recorded_by, recorded_on
+        FROM assessments WHERE enrollment_id = ? ORDER BY id`, enrollmentID)
+	if err != nil {
+		return nil, storeError("failed to list assessments", err)
+	}
+	assessments := make([]student.Assessment, 0, len(rows))
+	for _, r := range rows {
+		assessments = append(assessments, convertAssessmentRowToAssessment(r))
+	}
+	return assessments, nil
+}
+
+func (s *GradeStore) SetFinalGrade(ctx context.Context, f student.FinalGrade) (student.FinalGrade, error) {
+	f.GradedOn = time.Now()
+	_, err := s.DB.ExecContext(ctx,
+		`INSERT INTO final_grades (enrollment_id, scale, letter, credits, graded_by, graded_on)
+        VALUES (?, ?, ?, ?, ?, ?)
+        ON DUPLICATE KEY UPDATE scale = VALUES(scale), letter = VALUES(letter), credits = VALUES(credits),
+            graded_by = VALUES(graded_by), graded_on = VALUES(graded_on)`,
+		f.EnrollmentID, f.Scale, f.Letter, f.Credits, f.GradedBy, f.GradedOn)
+	if err != nil {
+		if isMissingReference(err) {
+			return student.FinalGrade{}, fmt.Errorf("enrollment %d not found: %w", f.EnrollmentID, student.ErrEnrollmentNotFound)
+		}
+		return student.FinalGrade{}, storeError("failed to store final grade", err)
+	}
+	return f, nil
+}
+
+func (s *GradeStore) ListFinalGrades(ctx context.Context, enrollmentIDs []int64) ([]student.FinalGrade, error) {
+	if len(enrollmentIDs) == 0 {
+		return nil, nil
+	}
+	query, args, err := sqlx.In(
+		"SELECT enrollment_id, scale, letter, credits, graded_by, graded_on FROM final_grades WHERE enrollment_id IN (?)",
+		enrollmentIDs)
+	if err != nil {
+		return nil, err
+	}
+	var rows []FinalGradeRow
+	if err := s.DB.SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, storeError("failed to list final grades", err)
+	}
+	grades := make([]student.FinalGrade, 0, len(rows))
+	for _, r := range rows {
+		grades = append(grades, convertFinalGradeRowToFinalGrade(r))
+	}
+	return grades, nil
+}
@@ -0,0 +1,107 @@
+package database
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"golang-assignment/internal/student"
+
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+)
+
+// PostStudents inserts the students and their audit entries in one
+// transaction, a failing insert rolls back the ones before it
+func (s *StudentStore) PostStudents(ctx context.Context, studs []student.Student, entries []student.AuditEntry) ([]student.Student, error) {
+	tx, err := s.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, storeError("failed to begin the import transaction", err)
+	}
+	// a no-op once committed
+	defer tx.Rollback()
+
+	now := time.Now()
+	created := make([]student.Student, len(studs))
+	for i, stud := range studs {
+		stud.CreatedOn = now
+		stud.UpdatedOn = now
+		stud.Version = 1
+		params, err := newStudentParams(stud)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := tx.NamedExecContext(ctx, insertStudent, params); err != nil {
+			if isDuplicateKey(err) {
+				// the conflicting student is looked up once the transaction
+				// no longer holds its locks
+				if err := tx.Rollback(); err != nil {
+					log.Errorf("failed to roll back the import transaction: %v", err)
+				}
+				return nil, s.duplicateStudentError(ctx, stud, err)
+			}
+			return nil, storeError("failed to insert student", err)
+		}
+		if err := insertAuditEntry(ctx, tx, entries[i]); err != nil {
+			return nil, err
+		}
+		created[i] = stud
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, storeError("failed to commit the import transaction", err)
+	}
+	return created, nil
+}
+
+func (s *StudentStore) FindConflicts(ctx context.Context, studs []student.Student) ([][]*student.ConflictError, error) {
+	conflicts := make([][]*student.ConflictError, len(studs))
+	var ids, emails []string
+	for _, stud := range studs {
+		if stud.ID != "" {
+			ids = append(ids, stud.ID)
+		}
+		emails = append(emails, strings.ToLower(stud.Email))
+	}
+
+	// IDs stay taken while their student is in the trash
+	taken := map[string]bool{}
+	if len(ids) > 0 {
+		query, args, err := sqlx.In("SELECT id FROM students WHERE id IN (?)", ids)
+		if err != nil {
+			return nil, err
+		}
+		var found []string
+		if err := s.DB.SelectContext(ctx, &found, query, args...); err != nil {
+			return nil, storeError("failed to look up student IDs", err)
+		}
+		for _, id := range found {
+			taken[id] = true
+		}
+	}
+
+	var used []struct {
+		ID    string `db:"id"`
+		Email string `db:"live_email"`
+	}
+	query, args, err := sqlx.In("SELECT id, live_email FROM students WHERE live_email IN (?)", emails)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.DB.SelectContext(ctx, &used, query, args...); err != nil {
+		return nil, storeError("failed to look up student emails", err)
+	}
+	usedBy := make(map[string]string, len(used))
+	for _, u := range used {
+		usedBy[u.Email] = u.ID
+	}
+
+	for i, stud := range studs {
+		if taken[stud.ID] {
+			conflicts[i] = append(conflicts[i], &student.ConflictError{Err: student.ErrDuplicateStudent, Field: "id", ConflictingID: stud.ID})
+		}
+		if id, ok := usedBy[strings.ToLower(stud.Email)]; ok {
+			conflicts[i] = append(conflicts[i], &student.ConflictError{Err: student.ErrDuplicateEmail, Field: "email", ConflictingID: id})
+		}
+	}
+	return conflicts, nil
+}
@@ -0,0 +1,433 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+)
+
+// Migration files are named <version>_<name>.up.sql and <version>_<name>.down.sql
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+const (
+	migrationLockName = "schema_migrations"
+	// seconds another instance waits for a running migration to finish
+	migrationLockTimeout = 60
+)
+
+type Migration struct {
+	Version  int
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+type MigrationStatus struct {
+	Migration
+	Applied   bool
+	AppliedOn time.Time
+	// Halfway is "up" or "down" when the script of that direction failed
+	// after running the first Statements of its statements
+	Halfway    string
+	Statements int
+}
+
+type appliedMigration struct {
+	Version   int       `db:"version"`
+	Name      string    `db:"name"`
+	Checksum  string    `db:"checksum"`
+	AppliedOn time.Time `db:"applied_on"`
+}
+
+// migrationStep is how far the script of a migration got before failing.
+// MySQL commits each DDL statement on its own, so a script cannot be rolled
+// back and is resumed from there instead.
+type migrationStep struct {
+	Version    int    `db:"version"`
+	Direction  string `db:"direction"`
+	Checksum   string `db:"checksum"`
+	Statements int    `db:"statements"`
+}
+
+func scriptChecksum(script string) string {
+	sum := sha256.Sum256([]byte(script))
+	return hex.EncodeToString(sum[:])
+}
+
+// Migrations returns the embedded migrations ordered by version
+func Migrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		file := entry.Name()
+		var direction string
+		switch {
+		case strings.HasSuffix(file, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(file, ".down.sql"):
+			direction = "down"
+		default:
+			return nil, fmt.Errorf("unexpected migration file %s", file)
+		}
+
+		base := strings.TrimSuffix(file, "."+direction+".sql")
+		prefix, name, ok := strings.Cut(base, "_")
+		version, err := strconv.Atoi(prefix)
+		if !ok || err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration file %s does not start with a version", file)
+		}
+
+		content, err := migrationFiles.ReadFile(path.Join("migrations", file))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", file, err)
+		}
+
+		m, exists := byVersion[version]
+		if !exists {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		} else if m.Name != name {
+			return nil, fmt.Errorf("migration version %d is used by %s and %s", version, m.Name, name)
+		}
+		if direction == "up" {
+			m.Up = string(content)
+			m.Checksum = scriptChecksum(m.Up)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up script", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// splitStatements splits a script on the semicolons ending a line, the
+// driver only runs one statement per call
+func splitStatements(script string) []string {
+	var statements []string
+	var current strings.Builder
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		current.WriteString(line)
+		current.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			statements = append(statements, strings.TrimSpace(current.String()))
+			current.Reset()
+		}
+	}
+	if rest := strings.TrimSpace(current.String()); rest != "" {
+		statements = append(statements, rest)
+	}
+	return statements
+}
+
+// withMigrationLock runs fn on a single connection holding a named MySQL lock
+// so that instances starting at the same time apply migrations one by one
+func withMigrationLock(ctx context.Context, db *sqlx.DB, fn func(*sqlx.Conn) error) error {
+	conn, err := db.Connx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get a connection: %w", err)
+	}
+	defer conn.Close()
+
+	var locked sql.NullInt64
+	if err := conn.QueryRowxContext(ctx, "SELECT GET_LOCK(?, ?)", migrationLockName, migrationLockTimeout).Scan(&locked); err != nil {
+		return fmt.Errorf("failed to acquire the migration lock: %w", err)
+	}
+	if !locked.Valid || locked.Int64 != 1 {
+		return errors.New("timed out waiting for the migration lock")
+	}
+	defer func() {
+		var released sql.NullInt64
+		if err := conn.QueryRowxContext(context.Background(), "SELECT RELEASE_LOCK(?)", migrationLockName).Scan(&released); err != nil {
+			log.Errorf("failed to release the migration lock: %v", err)
+		}
+	}()
+
+	if _, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version BIGINT NOT NULL PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		checksum CHAR(64) NOT NULL,
+		applied_on DATETIME NOT NULL
+	)`); err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+	if _, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migration_steps (
+		version BIGINT NOT NULL,
+		direction VARCHAR(4) NOT NULL,
+		checksum CHAR(64) NOT NULL,
+		statements INT NOT NULL,
+		PRIMARY KEY (version, direction)
+	)`); err != nil {
+		return fmt.Errorf("failed to create schema_migration_steps: %w", err)
+	}
+
+	return fn(conn)
+}
+
+func loadApplied(ctx context.Context, conn *sqlx.Conn) (map[int]appliedMigration, error) {
+	var rows []appliedMigration
+	if err := conn.SelectContext(ctx, &rows, "SELECT version, name, checksum, applied_on FROM schema_migrations"); err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	applied := make(map[int]appliedMigration, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+	return applied, nil
+}
+
+// loadSteps returns the scripts that failed halfway keyed by version
+func loadSteps(ctx context.Context, conn *sqlx.Conn) (map[int]migrationStep, error) {
+	var rows []migrationStep
+	if err := conn.SelectContext(ctx, &rows, "SELECT version, direction, checksum, statements FROM schema_migration_steps"); err != nil {
+		return nil, fmt.Errorf("failed to read schema_migration_steps: %w", err)
+	}
+	steps := make(map[int]migrationStep, len(rows))
+	for _, row := range rows {
+		steps[row.Version] = row
+	}
+	return steps, nil
+}
+
+// verifyHalfway refuses to run direction while a script of the other one is
+// still halfway, it has to be finished first
+func verifyHalfway(migrations []Migration, steps map[int]migrationStep, direction string) error {
+	for _, m := range migrations {
+		step, ok := steps[m.Version]
+		if !ok || step.Direction == direction {
+			continue
+		}
+		return fmt.Errorf("migration %d_%s stopped halfway %s after %d statement(s), finish it with migrate %s first",
+			m.Version, m.Name, step.Direction, step.Statements, step.Direction)
+	}
+	return nil
+}
+
+// verifyChecksums refuses to go on when a migration file was edited after
+// being applied, or when the database is ahead of this binary
+func verifyChecksums(migrations []Migration, applied map[int]appliedMigration) error {
+	known := make(map[int]bool, len(migrations))
+	for _, m := range migrations {
+		known[m.Version] = true
+		if a, ok := applied[m.Version]; ok && a.Checksum != m.Checksum {
+			return fmt.Errorf("migration %d_%s was modified after being applied", m.Version, m.Name)
+		}
+	}
+	for version, a := range applied {
+		if !known[version] {
+			return fmt.Errorf("database has migration %d_%s which is unknown to this build", version, a.Name)
+		}
+	}
+	return nil
+}
+
+// runScript runs the statements of the script of m in direction, recording
+// in schema_migration_steps how many of them ran. A script that failed
+// halfway, as given by step, resumes after its last recorded statement.
+func runScript(ctx context.Context, conn *sqlx.Conn, m Migration, direction, script string, step *migrationStep) error {
+	checksum := scriptChecksum(script)
+	start := 0
+	if step != nil {
+		if step.Checksum != checksum {
+			return fmt.Errorf("migration %d_%s was modified after it stopped halfway %s", m.Version, m.Name, direction)
+		}
+		start = step.Statements
+		log.Infof("resuming migration %d_%s %s after statement %d", m.Version, m.Name, direction, start)
+	}
+
+	statements := splitStatements(script)
+	for i := start; i < len(statements); i++ {
+		if _, err := conn.ExecContext(ctx, statements[i]); err != nil {
+			return fmt.Errorf("statement %d: %w", i+1, err)
+		}
+		// a crash before this record runs the statement again on resume
+		if _, err := conn.ExecContext(ctx,
+			`INSERT INTO schema_migration_steps (version, direction, checksum, statements) VALUES (?, ?, ?, ?)
+			ON DUPLICATE KEY UPDATE statements = VALUES(statements)`,
+			m.Version, direction, checksum, i+1); err != nil {
+			return fmt.Errorf("failed to record statement %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// finishScript runs record, which marks m as applied or reverted, and drops
+// the steps of its script in one transaction
+func finishScript(ctx context.Context, conn *sqlx.Conn, m Migration, direction, record string, args ...any) error {
+	tx, err := conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // a no-op once committed
+
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM schema_migration_steps WHERE version = ? AND direction = ?", m.Version, direction); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// MigrateUp applies every pending migration and returns how many were applied
+func MigrateUp(ctx context.Context, db *sqlx.DB) (int, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	err = withMigrationLock(ctx, db, func(conn *sqlx.Conn) error {
+		applied, err := loadApplied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := verifyChecksums(migrations, applied); err != nil {
+			return err
+		}
+		steps, err := loadSteps(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := verifyHalfway(migrations, steps, "up"); err != nil {
+			return err
+		}
+
+		for _, m := range migrations {
+			if _, ok := applied[m.Version]; ok {
+				continue
+			}
+			log.Infof("applying migration %d_%s", m.Version, m.Name)
+			var step *migrationStep
+			if s, ok := steps[m.Version]; ok {
+				step = &s
+			}
+			if err := runScript(ctx, conn, m, "up", m.Up, step); err != nil {
+				return fmt.Errorf("failed to apply migration %d_%s: %w", m.Version, m.Name, err)
+			}
+			if err := finishScript(ctx, conn, m, "up",
+				"INSERT INTO schema_migrations (version, name, checksum, applied_on) VALUES (?, ?, ?, ?)",
+				m.Version, m.Name, m.Checksum, time.Now()); err != nil {
+				return fmt.Errorf("failed to record migration %d_%s: %w", m.Version, m.Name, err)
+			}
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// MigrateDown reverts the last steps applied migrations
+func MigrateDown(ctx context.Context, db *sqlx.DB, steps int) (int, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	err = withMigrationLock(ctx, db, func(conn *sqlx.Conn) error {
+		applied, err := loadApplied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := verifyChecksums(migrations, applied); err != nil {
+			return err
+		}
+		halfway, err := loadSteps(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := verifyHalfway(migrations, halfway, "down"); err != nil {
+			return err
+		}
+
+		for i := len(migrations) - 1; i >= 0 && count < steps; i-- {
+			m := migrations[i]
+			if _, ok := applied[m.Version]; !ok {
+				continue
+			}
+			if m.Down == "" {
+				return fmt.Errorf("migration %d_%s cannot be reverted", m.Version, m.Name)
+			}
+			log.Infof("reverting migration %d_%s", m.Version, m.Name)
+			var step *migrationStep
+			if s, ok := halfway[m.Version]; ok {
+				step = &s
+			}
+			if err := runScript(ctx, conn, m, "down", m.Down, step); err != nil {
+				return fmt.Errorf("failed to revert migration %d_%s: %w", m.Version, m.Name, err)
+			}
+			if err := finishScript(ctx, conn, m, "down", "DELETE FROM schema_migrations WHERE version = ?", m.Version); err != nil {
+				return fmt.Errorf("failed to unrecord migration %d_%s: %w", m.Version, m.Name, err)
+			}
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// MigrationStatuses lists every known migration and whether it is applied
+func MigrationStatuses(ctx context.Context, db *sqlx.DB) ([]MigrationStatus, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []MigrationStatus
+	err = withMigrationLock(ctx, db, func(conn *sqlx.Conn) error {
+		applied, err := loadApplied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		steps, err := loadSteps(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, m := range migrations {
+			status := MigrationStatus{Migration: m}
+			if a, ok := applied[m.Version]; ok {
+				status.Applied = true
+				status.AppliedOn = a.AppliedOn
+			}
+			if step, ok := steps[m.Version]; ok {
+				status.Halfway = step.Direction
+				status.Statements = step.Statements
+			}
+			statuses = append(statuses, status)
+		}
+		return verifyChecksums(migrations, applied)
+	})
+	return statuses, err
+}
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS students (
+    id VARCHAR(64) NOT NULL,
+    created_by VARCHAR(255) NULL,
+    created_on DATETIME NULL,
+    updated_by VARCHAR(255) NULL,
+    updated_on DATETIME NULL,
+    name VARCHAR(255) NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    age INT NOT NULL,
+    course VARCHAR(255) NOT NULL,
+    PRIMARY KEY (id),
+    KEY idx_students_created_on_id (created_on, id),
+    KEY idx_students_course (course),
+    KEY idx_students_created_by (created_by)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS users (
+    id VARCHAR(64) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    password_hash VARCHAR(255) NOT NULL,
+    disabled BOOLEAN NOT NULL DEFAULT FALSE,
+    created_by VARCHAR(64) NULL,
+    created_on DATETIME NOT NULL,
+    updated_by VARCHAR(64) NULL,
+    updated_on DATETIME NOT NULL,
+    PRIMARY KEY (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
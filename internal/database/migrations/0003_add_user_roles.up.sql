@@ -0,0 +1,3 @@
+ALTER TABLE users ADD COLUMN role VARCHAR(32) NOT NULL DEFAULT 'viewer' AFTER name;
+-- accounts created before roles existed could do everything, keep it that way
+UPDATE users SET role = 'admin';
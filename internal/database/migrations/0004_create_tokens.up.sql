@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    hash CHAR(64) NOT NULL,
+    family_id VARCHAR(64) NOT NULL,
+    user_id VARCHAR(64) NOT NULL,
+    created_on DATETIME NOT NULL,
+    expires_on DATETIME NOT NULL,
+    used_on DATETIME NULL,
+    revoked_on DATETIME NULL,
+    PRIMARY KEY (hash),
+    KEY idx_refresh_tokens_family_id (family_id),
+    KEY idx_refresh_tokens_user_id (user_id),
+    KEY idx_refresh_tokens_expires_on (expires_on)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+    jti VARCHAR(64) NOT NULL,
+    expires_on DATETIME NOT NULL,
+    PRIMARY KEY (jti),
+    KEY idx_revoked_tokens_expires_on (expires_on)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS signing_keys (
+    id VARCHAR(64) NOT NULL,
+    algorithm VARCHAR(16) NOT NULL,
+    material TEXT NOT NULL,
+    created_on DATETIME(6) NOT NULL,
+    PRIMARY KEY (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
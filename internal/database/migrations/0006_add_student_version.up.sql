@@ -0,0 +1 @@
+ALTER TABLE students ADD COLUMN version BIGINT NOT NULL DEFAULT 1 AFTER course;
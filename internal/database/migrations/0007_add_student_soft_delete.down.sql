@@ -0,0 +1,6 @@
+-- students still in the trash would come back to life, drop them
+DELETE FROM students WHERE deleted_on IS NOT NULL;
+ALTER TABLE students
+    DROP KEY idx_students_deleted_on,
+    DROP COLUMN deleted_on,
+    DROP COLUMN deleted_by;
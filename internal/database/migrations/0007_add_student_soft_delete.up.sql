@@ -0,0 +1,4 @@
+ALTER TABLE students
+    ADD COLUMN deleted_by VARCHAR(255) NULL AFTER version,
+    ADD COLUMN deleted_on DATETIME NULL AFTER deleted_by,
+    ADD KEY idx_students_deleted_on (deleted_on);
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGINT NOT NULL AUTO_INCREMENT,
+    student_id VARCHAR(64) NOT NULL,
+    action VARCHAR(16) NOT NULL,
+    actor VARCHAR(255) NOT NULL,
+    request_id VARCHAR(64) NULL,
+    occurred_on DATETIME(6) NOT NULL,
+    changes JSON NOT NULL,
+    PRIMARY KEY (id),
+    KEY idx_audit_log_student_id (student_id, id),
+    KEY idx_audit_log_actor (actor, id),
+    KEY idx_audit_log_occurred_on (occurred_on)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,3 @@
+ALTER TABLE students
+    DROP KEY uq_students_live_email,
+    DROP COLUMN live_email;
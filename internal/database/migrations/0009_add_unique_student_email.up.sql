@@ -0,0 +1,6 @@
+-- only students out of the trash need a unique email, the generated column is
+-- NULL for the others and a unique key allows any number of NULLs.
+-- Students already sharing an email make this migration fail, fix them first.
+ALTER TABLE students
+    ADD COLUMN live_email VARCHAR(255) GENERATED ALWAYS AS (IF(deleted_on IS NULL, LOWER(email), NULL)) VIRTUAL,
+    ADD UNIQUE KEY uq_students_live_email (live_email);
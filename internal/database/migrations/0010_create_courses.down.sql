@@ -0,0 +1,2 @@
+ALTER TABLE students DROP FOREIGN KEY fk_students_course;
+DROP TABLE IF EXISTS courses;
@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS courses (
+    code VARCHAR(255) NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    credits INT NOT NULL DEFAULT 0,
+    capacity INT NOT NULL DEFAULT 0,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by VARCHAR(255) NULL,
+    created_on DATETIME NOT NULL,
+    updated_by VARCHAR(255) NULL,
+    updated_on DATETIME NOT NULL,
+    PRIMARY KEY (code)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- every free-text course the students already have becomes an inactive
+-- course of the catalog, so the foreign key holds. `courses legacy` lists
+-- them and `courses map` moves their students to the proper catalog code.
+INSERT INTO courses (code, title, active, created_by, created_on, updated_by, updated_on)
+    SELECT course, MIN(course), FALSE, 'migration', NOW(), 'migration', NOW()
+    FROM students GROUP BY course;
+
+ALTER TABLE students
+    ADD CONSTRAINT fk_students_course FOREIGN KEY (course) REFERENCES courses (code) ON UPDATE CASCADE;
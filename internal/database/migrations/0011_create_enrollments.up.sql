@@ -0,0 +1,20 @@
+-- a student has at most one enrollment still in a course per term, the
+-- generated column is NULL for withdrawn enrollments and a unique key allows
+-- any number of NULLs, so a student who withdrew can enroll again.
+CREATE TABLE IF NOT EXISTS enrollments (
+    id BIGINT NOT NULL AUTO_INCREMENT,
+    student_id VARCHAR(64) NOT NULL,
+    course VARCHAR(255) NOT NULL,
+    term VARCHAR(32) NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    enrolled_by VARCHAR(255) NULL,
+    enrolled_on DATETIME(6) NOT NULL,
+    updated_by VARCHAR(255) NULL,
+    updated_on DATETIME(6) NOT NULL,
+    live TINYINT GENERATED ALWAYS AS (IF(status = 'withdrawn', NULL, 1)) VIRTUAL,
+    PRIMARY KEY (id),
+    UNIQUE KEY uq_enrollments_live (student_id, course, term, live),
+    KEY idx_enrollments_roster (course, term, status, enrolled_on, id),
+    CONSTRAINT fk_enrollments_student FOREIGN KEY (student_id) REFERENCES students (id) ON DELETE CASCADE,
+    CONSTRAINT fk_enrollments_course FOREIGN KEY (course) REFERENCES courses (code) ON UPDATE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS assessments (
+    id BIGINT NOT NULL AUTO_INCREMENT,
+    enrollment_id BIGINT NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    score DECIMAL(10,2) NOT NULL,
+    max_score DECIMAL(10,2) NOT NULL,
+    weight DECIMAL(10,2) NOT NULL,
+    recorded_by VARCHAR(255) NULL,
+    recorded_on DATETIME(6) NOT NULL,
+    PRIMARY KEY (id),
+    KEY idx_assessments_enrollment (enrollment_id, id),
+    CONSTRAINT fk_assessments_enrollment FOREIGN KEY (enrollment_id) REFERENCES enrollments (id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- the points of a grade come from its scale, the credits are those of the
+-- course when it was graded
+CREATE TABLE IF NOT EXISTS final_grades (
+    enrollment_id BIGINT NOT NULL,
+    scale VARCHAR(32) NOT NULL,
+    letter VARCHAR(8) NOT NULL,
+    credits INT NOT NULL,
+    graded_by VARCHAR(255) NULL,
+    graded_on DATETIME(6) NOT NULL,
+    PRIMARY KEY (enrollment_id),
+    CONSTRAINT fk_final_grades_enrollment FOREIGN KEY (enrollment_id) REFERENCES enrollments (id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
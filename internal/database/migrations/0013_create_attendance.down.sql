@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS attendance_alerts;
+DROP TABLE IF EXISTS attendance;
@@ -0,0 +1,34 @@
+CREATE TABLE IF NOT EXISTS attendance (
+    student_id VARCHAR(64) NOT NULL,
+    course VARCHAR(255) NOT NULL,
+    session_date DATE NOT NULL,
+    term VARCHAR(32) NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    note VARCHAR(255) NULL,
+    recorded_by VARCHAR(255) NULL,
+    recorded_on DATETIME(6) NOT NULL,
+    PRIMARY KEY (student_id, course, session_date),
+    KEY idx_attendance_session (course, session_date),
+    CONSTRAINT fk_attendance_student FOREIGN KEY (student_id) REFERENCES students (id) ON DELETE CASCADE,
+    CONSTRAINT fk_attendance_course FOREIGN KEY (course) REFERENCES courses (code) ON UPDATE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- a student has at most one open alert per course and term, the generated
+-- column is NULL once the alert is resolved and a unique key allows any
+-- number of NULLs.
+CREATE TABLE IF NOT EXISTS attendance_alerts (
+    id BIGINT NOT NULL AUTO_INCREMENT,
+    student_id VARCHAR(64) NOT NULL,
+    course VARCHAR(255) NOT NULL,
+    term VARCHAR(32) NOT NULL,
+    percentage DECIMAL(5,2) NOT NULL,
+    threshold DECIMAL(5,2) NOT NULL,
+    raised_on DATETIME(6) NOT NULL,
+    resolved_on DATETIME(6) NULL,
+    open TINYINT GENERATED ALWAYS AS (IF(resolved_on IS NULL, 1, NULL)) VIRTUAL,
+    PRIMARY KEY (id),
+    UNIQUE KEY uq_attendance_alerts_open (student_id, course, term, open),
+    KEY idx_attendance_alerts_course (course, id),
+    CONSTRAINT fk_attendance_alerts_student FOREIGN KEY (student_id) REFERENCES students (id) ON DELETE CASCADE,
+    CONSTRAINT fk_attendance_alerts_course FOREIGN KEY (course) REFERENCES courses (code) ON UPDATE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,24 @@
+-- a student has at most one primary contact, the generated column is NULL
+-- for the other contacts and a unique key allows any number of NULLs.
+CREATE TABLE IF NOT EXISTS contacts (
+    id BIGINT NOT NULL AUTO_INCREMENT,
+    student_id VARCHAR(64) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    relationship VARCHAR(16) NOT NULL,
+    phone VARCHAR(16) NULL,
+    email VARCHAR(255) NULL,
+    address VARCHAR(500) NULL,
+    is_primary TINYINT(1) NOT NULL DEFAULT 0,
+    emergency TINYINT(1) NOT NULL DEFAULT 0,
+    consent_pickup TINYINT(1) NOT NULL DEFAULT 0,
+    consent_medical TINYINT(1) NOT NULL DEFAULT 0,
+    consent_records TINYINT(1) NOT NULL DEFAULT 0,
+    created_by VARCHAR(255) NULL,
+    created_on DATETIME(6) NOT NULL,
+    updated_by VARCHAR(255) NULL,
+    updated_on DATETIME(6) NOT NULL,
+    primary_contact TINYINT GENERATED ALWAYS AS (IF(is_primary, 1, NULL)) VIRTUAL,
+    PRIMARY KEY (id),
+    UNIQUE KEY uq_contacts_primary (student_id, primary_contact),
+    CONSTRAINT fk_contacts_student FOREIGN KEY (student_id) REFERENCES students (id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,17 @@
+-- attachments have no foreign key to students: the blobs of a purged student
+-- live outside the database, so their rows stay until the blobs are swept.
+CREATE TABLE IF NOT EXISTS attachments (
+    id BIGINT NOT NULL AUTO_INCREMENT,
+    student_id VARCHAR(64) NOT NULL,
+    kind VARCHAR(16) NOT NULL,
+    file_name VARCHAR(255) NOT NULL,
+    content_type VARCHAR(64) NOT NULL,
+    size BIGINT NOT NULL,
+    sha256 CHAR(64) NOT NULL,
+    blob_key VARCHAR(255) NOT NULL,
+    uploaded_by VARCHAR(255) NULL,
+    uploaded_on DATETIME(6) NOT NULL,
+    PRIMARY KEY (id),
+    UNIQUE KEY uq_attachments_blob (blob_key),
+    KEY idx_attachments_student (student_id, id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS custom_fields (
+    name VARCHAR(64) NOT NULL,
+    label VARCHAR(255) NOT NULL,
+    type VARCHAR(16) NOT NULL,
+    required TINYINT(1) NOT NULL DEFAULT 0,
+    enum_values JSON NULL,
+    pattern VARCHAR(500) NULL,
+    created_by VARCHAR(255) NULL,
+    created_on DATETIME(6) NOT NULL,
+    updated_by VARCHAR(255) NULL,
+    updated_on DATETIME(6) NOT NULL,
+    PRIMARY KEY (name)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- the values of a student, keyed by field name, checked against the
+-- definitions by the service
+ALTER TABLE students ADD COLUMN custom_fields JSON NULL;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS cohorts;
+DROP TABLE IF EXISTS student_tags;
+DROP TABLE IF EXISTS tags;
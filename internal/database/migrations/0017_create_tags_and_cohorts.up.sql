@@ -0,0 +1,35 @@
+CREATE TABLE IF NOT EXISTS tags (
+    name VARCHAR(64) NOT NULL,
+    description VARCHAR(500) NOT NULL DEFAULT '',
+    created_by VARCHAR(255) NULL,
+    created_on DATETIME(6) NOT NULL,
+    updated_by VARCHAR(255) NULL,
+    updated_on DATETIME(6) NOT NULL,
+    PRIMARY KEY (name)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS student_tags (
+    student_id VARCHAR(64) NOT NULL,
+    tag_name VARCHAR(64) NOT NULL,
+    tagged_by VARCHAR(255) NULL,
+    tagged_on DATETIME(6) NOT NULL,
+    PRIMARY KEY (student_id, tag_name),
+    KEY idx_student_tags_tag (tag_name, student_id),
+    CONSTRAINT fk_student_tags_student FOREIGN KEY (student_id) REFERENCES students (id) ON DELETE CASCADE,
+    CONSTRAINT fk_student_tags_tag FOREIGN KEY (tag_name) REFERENCES tags (name) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- a cohort stores the search parameters of GET /students, its students are
+-- found again each time it is used
+CREATE TABLE IF NOT EXISTS cohorts (
+    id BIGINT NOT NULL AUTO_INCREMENT,
+    name VARCHAR(255) NOT NULL,
+    description VARCHAR(500) NOT NULL DEFAULT '',
+    query VARCHAR(2000) NOT NULL,
+    created_by VARCHAR(255) NULL,
+    created_on DATETIME(6) NOT NULL,
+    updated_by VARCHAR(255) NULL,
+    updated_on DATETIME(6) NOT NULL,
+    PRIMARY KEY (id),
+    UNIQUE KEY uq_cohorts_name (name)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
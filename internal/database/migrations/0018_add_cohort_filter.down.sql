@@ -0,0 +1,5 @@
+-- the cohorts saved since have no query to go back to
+DELETE FROM cohorts WHERE query IS NULL;
+ALTER TABLE cohorts
+    DROP COLUMN filter,
+    MODIFY query VARCHAR(2000) NOT NULL;
@@ -0,0 +1,6 @@
+-- a cohort stores its search filter as JSON. The query of the cohorts saved
+-- before is kept and read into the filter until the cohort is saved again,
+-- SQL cannot parse the search parameters.
+ALTER TABLE cohorts
+    ADD COLUMN filter JSON NULL AFTER description,
+    MODIFY query VARCHAR(2000) NULL;
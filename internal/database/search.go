@@ -0,0 +1,171 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"golang-assignment/internal/student"
+)
+
+// facetColumns are the columns counted per distinct value for each search
+var facetColumns = []string{"course", "created_by"}
+
+// escapeLike makes the user input match literally inside a LIKE pattern
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+func buildSearchWhere(f student.SearchFilter) (string, []interface{}, error) {
+	conds := []string{notDeleted}
+	var args []interface{}
+
+	if f.Query != "" {
+		pattern := "%" + escapeLike(strings.ToLower(f.Query)) + "%"
+		conds = append(conds, "(LOWER(name) LIKE ? OR LOWER(email) LIKE ?)")
+		args = append(args, pattern, pattern)
+	}
+	for _, tag := range f.Tags {
+		conds = append(conds, "EXISTS (SELECT 1 FROM student_tags st WHERE st.student_id = students.id AND st.tag_name = ?)")
+		args = append(args, tag)
+	}
+	if f.Course != "" {
+		conds = append(conds, "course = ?")
+		args = append(args, f.Course)
+	}
+	if f.CreatedBy != "" {
+		conds = append(conds, "created_by = ?")
+		args = append(args, f.CreatedBy)
+	}
+	if f.MinAge > 0 {
+		conds = append(conds, "age >= ?")
+		args = append(args, f.MinAge)
+	}
+	if f.MaxAge > 0 {
+		conds = append(conds, "age <= ?")
+		args = append(args, f.MaxAge)
+	}
+	if !f.CreatedFrom.IsZero() {
+		conds = append(conds, "created_on >= ?")
+		args = append(args, f.CreatedFrom)
+	}
+	if !f.CreatedTo.IsZero() {
+		conds = append(conds, "created_on <= ?")
+		args = append(args, f.CreatedTo)
+	}
+	if !f.UpdatedFrom.IsZero() {
+		conds = append(conds, "updated_on >= ?")
+		args = append(args, f.UpdatedFrom)
+	}
+	if !f.UpdatedTo.IsZero() {
+		conds = append(conds, "updated_on <= ?")
+		args = append(args, f.UpdatedTo)
+	}
+	for _, cf := range f.CustomFields {
+		// JSON values compare by type, 3 matches 3.0 but not "3"
+		value, err := json.Marshal(cf.Value)
+		if err != nil {
+			return "", nil, fmt.Errorf("cannot filter on custom field %q: %w", cf.Name, err)
+		}
+		conds = append(conds, "JSON_EXTRACT(custom_fields, ?) = CAST(? AS JSON)")
+		args = append(args, `$."`+cf.Name+`"`, string(value))
+	}
+
+	return " WHERE " + strings.Join(conds, " AND "), args, nil
+}
+
+// buildSearchOrder returns the ORDER BY clause of the filter, ties are
+// broken by id
+func buildSearchOrder(f student.SearchFilter) (string, error) {
+	// the sort column is interpolated so it must come from the allow list
+	sortBy := "created_on"
+	if f.SortBy != "" {
+		if !student.SortableColumns[f.SortBy] {
+			return "", fmt.Errorf("cannot sort on %q", f.SortBy)
+		}
+		sortBy = f.SortBy
+	}
+	direction := "ASC"
+	if f.SortDesc {
+		direction = "DESC"
+	}
+	return fmt.Sprintf(" ORDER BY %s %s, id %s", sortBy, direction, direction), nil
+}
+
+func (s *StudentStore) SearchStudents(ctx context.Context, f student.SearchFilter) (student.SearchResult, error) {
+	order, err := buildSearchOrder(f)
+	if err != nil {
+		return student.SearchResult{}, err
+	}
+	where, args, err := buildSearchWhere(f)
+	if err != nil {
+		return student.SearchResult{}, err
+	}
+
+	var result student.SearchResult
+	if err := s.DB.GetContext(ctx, &result.Total, "SELECT COUNT(*) FROM students"+where, args...); err != nil {
+		return student.SearchResult{}, storeError("failed to count students", err)
+	}
+
+	query := "SELECT " + studentColumns + " FROM students" + where + order + " LIMIT ? OFFSET ?"
+	var studentRows []StudentRow
+	if err := s.DB.SelectContext(ctx, &studentRows, query, append(args, f.Limit, f.Offset)...); err != nil {
+		return student.SearchResult{}, storeError("failed to search students", err)
+	}
+	result.Students = make([]student.Student, 0, len(studentRows))
+	for _, r := range studentRows {
+		result.Students = append(result.Students, convertStudentRowToStudent(r))
+	}
+
+	result.Facets = make(map[string]map[string]int, len(facetColumns))
+	for _, column := range facetColumns {
+		var counts []struct {
+			Value sql.NullString `db:"value"`
+			Count int            `db:"count"`
+		}
+		facetQuery := fmt.Sprintf("SELECT %s AS value, COUNT(*) AS count FROM students%s GROUP BY %s", column, where, column)
+		if err := s.DB.SelectContext(ctx, &counts, facetQuery, args...); err != nil {
+			return student.SearchResult{}, storeError("failed to count students by "+column, err)
+		}
+		result.Facets[column] = make(map[string]int, len(counts))
+		for _, c := range counts {
+			result.Facets[column][c.Value.String] += c.Count
+		}
+	}
+
+	return result, nil
+}
+
+// ExportStudents reads the matching students row by row as the driver
+// receives them, so the result set is never held in memory as a whole
+func (s *StudentStore) ExportStudents(ctx context.Context, f student.SearchFilter, emit func(student.Student) error) error {
+	order, err := buildSearchOrder(f)
+	if err != nil {
+		return err
+	}
+	where, args, err := buildSearchWhere(f)
+	if err != nil {
+		return err
+	}
+
+	rows, err := s.DB.QueryxContext(ctx, "SELECT "+studentColumns+" FROM students"+where+order, args...)
+	if err != nil {
+		return storeError("failed to export students", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var r StudentRow
+		if err := rows.StructScan(&r); err != nil {
+			return storeError("failed to read exported student", err)
+		}
+		if err := emit(convertStudentRowToStudent(r)); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return storeError("failed to export students", err)
+	}
+	return nil
+}
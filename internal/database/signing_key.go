@@ -0,0 +1,54 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"golang-assignment/internal/auth"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type SigningKeyStore struct {
+	DB *sqlx.DB
+}
+
+func NewSigningKeyStore(db *sqlx.DB) *SigningKeyStore {
+	return &SigningKeyStore{DB: db}
+}
+
+type SigningKeyRow struct {
+	ID        string    `db:"id"`
+	Algorithm string    `db:"algorithm"`
+	Material  string    `db:"material"`
+	CreatedOn time.Time `db:"created_on"`
+}
+
+func (s *SigningKeyStore) ListSigningKeys(ctx context.Context) ([]auth.Key, error) {
+	var rows []SigningKeyRow
+	if err := s.DB.SelectContext(ctx, &rows, "SELECT id, algorithm, material, created_on FROM signing_keys ORDER BY created_on"); err != nil {
+		return nil, fmt.Errorf("failed to list signing keys: %w", err)
+	}
+	keys := make([]auth.Key, 0, len(rows))
+	for _, r := range rows {
+		keys = append(keys, auth.Key{ID: r.ID, Algorithm: r.Algorithm, Material: r.Material, CreatedOn: r.CreatedOn})
+	}
+	return keys, nil
+}
+
+func (s *SigningKeyStore) CreateSigningKey(ctx context.Context, key auth.Key) error {
+	_, err := s.DB.ExecContext(ctx, "INSERT INTO signing_keys (id, algorithm, material, created_on) VALUES (?, ?, ?, ?)",
+		key.ID, key.Algorithm, key.Material, key.CreatedOn)
+	if err != nil {
+		return fmt.Errorf("failed to insert signing key: %w", err)
+	}
+	return nil
+}
+
+func (s *SigningKeyStore) DeleteSigningKey(ctx context.Context, id string) error {
+	if _, err := s.DB.ExecContext(ctx, "DELETE FROM signing_keys WHERE id = ?", id); err != nil {
+		return fmt.Errorf("failed to delete signing key: %w", err)
+	}
+	return nil
+}
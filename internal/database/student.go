@@ -3,11 +3,14 @@ package database
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"golang-assignment/internal/student"
 
+	"github.com/jmoiron/sqlx"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -21,10 +24,15 @@ type StudentRow struct {
 	Email     string         `db:"email"`
 	Age       int            `db:"age"`
 	Course    string         `db:"course"`
+	Version   int64          `db:"version"`
+	DeletedBy sql.NullString `db:"deleted_by"`
+	DeletedOn sql.NullTime   `db:"deleted_on"`
+	// CustomFields is the JSON document of the custom field values
+	CustomFields []byte `db:"custom_fields"`
 }
 
 func convertStudentRowToStudent(r StudentRow) student.Student {
-	return student.Student{
+	s := student.Student{
 		ID:        r.ID,
 		CreatedBy: r.CreatedBy.String,
 		CreatedOn: r.CreatedOn.Time,
@@ -34,42 +42,162 @@ func convertStudentRowToStudent(r StudentRow) student.Student {
 		Email:     r.Email,
 		Age:       r.Age,
 		Course:    r.Course,
+		Version:   r.Version,
 	}
+	if r.DeletedOn.Valid {
+		s.DeletedBy = r.DeletedBy.String
+		s.DeletedOn = &r.DeletedOn.Time
+	}
+	if len(r.CustomFields) > 0 {
+		if err := json.Unmarshal(r.CustomFields, &s.CustomFields); err != nil {
+			log.Errorf("failed to read the custom fields of student %s: %v", r.ID, err)
+		}
+	}
+	return s
+}
+
+const studentColumns = "id, created_by, created_on, updated_by, updated_on, name, email, age, course, version, deleted_by, deleted_on, custom_fields"
+
+// studentParams are the named parameters of the writes of a student, its
+// custom fields go in as a JSON document, NULL when it has none
+type studentParams struct {
+	student.Student
+	CustomFieldsJSON sql.NullString `db:"custom_fields_json"`
+}
+
+func newStudentParams(stud student.Student) (studentParams, error) {
+	params := studentParams{Student: stud}
+	if len(stud.CustomFields) > 0 {
+		encoded, err := json.Marshal(stud.CustomFields)
+		if err != nil {
+			return studentParams{}, fmt.Errorf("could not encode the custom fields: %w", err)
+		}
+		params.CustomFieldsJSON = nullString(string(encoded))
+	}
+	return params, nil
+}
+
+// notDeleted keeps the students out of the trash, every read and write but
+// the trash ones is limited to them
+const notDeleted = "deleted_on IS NULL"
+
+// writeAudited runs write and records the audit entry in one transaction.
+// write returns the number of students it changed, the entry is only
+// recorded when that is not 0. Errors are explained by the caller, once the
+// transaction no longer holds its locks.
+func (s *StudentStore) writeAudited(ctx context.Context, entry student.AuditEntry, write func(tx *sqlx.Tx) (int64, error)) (int64, error) {
+	tx, err := s.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, storeError("failed to begin the transaction", err)
+	}
+	// a no-op once committed
+	defer tx.Rollback()
+
+	changed, err := write(tx)
+	if err != nil || changed == 0 {
+		return 0, err
+	}
+	if err := insertAuditEntry(ctx, tx, entry); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, storeError("failed to commit the transaction", err)
+	}
+	return changed, nil
+}
+
+func rowsAffected(result sql.Result) (int64, error) {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("could not determine rows affected: %w", err)
+	}
+	return n, nil
+}
+
+// versionMismatchError explains why a compare-and-swap on the student
+// changed no row, either it is gone or its version moved on
+func (s *StudentStore) versionMismatchError(ctx context.Context, id string, version int64) error {
+	var current int64
+	err := s.DB.GetContext(ctx, &current, "SELECT version FROM students WHERE id = ? AND "+notDeleted, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("student with ID %s not found: %w", id, student.ErrNoStudentFound)
+		}
+		return storeError("an error occurred fetching the student version", err)
+	}
+	return fmt.Errorf("student with ID %s is at version %d, not %d: %w", id, current, version, student.ErrVersionMismatch)
+}
+
+// duplicateStudentError tells a clash on the email apart from a clash on the
+// ID, and finds the student clashed with
+func (s *StudentStore) duplicateStudentError(ctx context.Context, stud student.Student, err error) error {
+	if !strings.Contains(duplicateKeyName(err), "email") {
+		return &student.ConflictError{Err: student.ErrDuplicateStudent, Field: "id", ConflictingID: stud.ID}
+	}
+
+	conflict := &student.ConflictError{Err: student.ErrDuplicateEmail, Field: "email"}
+	if stud.Email == "" {
+		// a restored student, its email is the stored one
+		if err := s.DB.GetContext(ctx, &stud.Email, "SELECT email FROM students WHERE id = ?", stud.ID); err != nil {
+			log.Errorf("failed to fetch the email of student %s: %v", stud.ID, err)
+			return conflict
+		}
+	}
+	err = s.DB.GetContext(ctx, &conflict.ConflictingID,
+		"SELECT id FROM students WHERE live_email = LOWER(?) AND id <> ?", stud.Email, stud.ID)
+	if err != nil {
+		log.Errorf("failed to find the student using the email %s: %v", stud.Email, err)
+	}
+	return conflict
 }
 
 func (s *StudentStore) GetStudent(ctx context.Context, id string) (student.Student, error) {
 	var studentRow StudentRow
-	query := "SELECT id, created_by, created_on, updated_by, updated_on, name, email, age, course FROM students WHERE id = ?"
+	query := "SELECT " + studentColumns + " FROM students WHERE id = ? AND " + notDeleted
 	err := s.DB.GetContext(ctx, &studentRow, query, id)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return student.Student{}, fmt.Errorf("student with ID %s not found", id)
+			return student.Student{}, fmt.Errorf("student with ID %s not found: %w", id, student.ErrNoStudentFound)
 		}
-		return student.Student{}, fmt.Errorf("an error occurred fetching the student: %w", err)
+		return student.Student{}, storeError("an error occurred fetching the student", err)
 	}
 
 	return convertStudentRowToStudent(studentRow), nil
 }
 
-func (d *StudentStore) PostStudent(ctx context.Context, stud student.Student) (student.Student, error) {
+const insertStudent = `INSERT INTO students (id, created_by, created_on, updated_by, updated_on, name, email, age, course, version, custom_fields)
+        VALUES (:id, :created_by, :created_on, :updated_by, :updated_on, :name, :email, :age, :course, :version, :custom_fields_json)`
+
+func (d *StudentStore) PostStudent(ctx context.Context, stud student.Student, entry student.AuditEntry) (student.Student, error) {
 
 	log.Printf("Creating student: CreatedBy=%s, UpdatedBy=%s", stud.CreatedBy, stud.UpdatedBy)
 
 	stud.CreatedOn = time.Now()
 	stud.UpdatedOn = stud.CreatedOn
-	_, err := d.DB.NamedExecContext(ctx, `INSERT INTO students (id, created_by, created_on, updated_by, updated_on, name, email, age, course)
-        VALUES (:id, :created_by, :created_on, :updated_by, :updated_on, :name, :email, :age, :course)`,
-		stud)
+	stud.Version = 1
+	params, err := newStudentParams(stud)
 	if err != nil {
-		return student.Student{}, fmt.Errorf("failed to insert student: %w", err)
+		return student.Student{}, err
+	}
+	_, err = d.writeAudited(ctx, entry, func(tx *sqlx.Tx) (int64, error) {
+		if _, err := tx.NamedExecContext(ctx, insertStudent, params); err != nil {
+			return 0, storeError("failed to insert student", err)
+		}
+		return 1, nil
+	})
+	if err != nil {
+		if isDuplicateKey(err) {
+			return student.Student{}, d.duplicateStudentError(ctx, stud, err)
+		}
+		return student.Student{}, err
 	}
 	return stud, nil
 }
 
-func (d *StudentStore) UpdateStudent(ctx context.Context, id string, stud student.Student) (student.Student, error) {
+func (d *StudentStore) UpdateStudent(ctx context.Context, id string, stud student.Student, entry student.AuditEntry) (student.Student, error) {
 
 	if id != stud.ID {
-		return student.Student{}, fmt.Errorf("mismatching student ID")
+		return student.Student{}, fmt.Errorf("%w: mismatching student ID", student.ErrInvalidStudent)
 	}
 
 	query := `UPDATE students SET
@@ -79,29 +207,206 @@ func (d *StudentStore) UpdateStudent(ctx context.Context, id string, stud studen
         name = :name,
         email = :email,
         age = :age,
-        course = :course
-        WHERE id = :id`
+        course = :course,
+        custom_fields = :custom_fields_json,
+        version = version + 1
+        WHERE id = :id AND version = :version AND ` + notDeleted
 
-	result, err := d.DB.NamedExecContext(ctx, query, stud)
+	params, err := newStudentParams(stud)
 	if err != nil {
-		return student.Student{}, fmt.Errorf("failed to update student: %w", err)
+		return student.Student{}, err
 	}
-
-	rowsAffected, err := result.RowsAffected()
+	rowsAffected, err := d.writeAudited(ctx, entry, func(tx *sqlx.Tx) (int64, error) {
+		result, err := tx.NamedExecContext(ctx, query, params)
+		if err != nil {
+			return 0, storeError("failed to update student", err)
+		}
+		return rowsAffected(result)
+	})
 	if err != nil {
-		return student.Student{}, fmt.Errorf("could not determine rows affected: %w", err)
+		if isDuplicateKey(err) {
+			return student.Student{}, d.duplicateStudentError(ctx, stud, err)
+		}
+		return student.Student{}, err
 	}
 	if rowsAffected == 0 {
-		return student.Student{}, fmt.Errorf("no rows were updated, student with ID %s might not exist", id)
+		return student.Student{}, d.versionMismatchError(ctx, id, stud.Version)
 	}
 
+	stud.Version++
 	return stud, nil
 }
 
-func (s *StudentStore) DeleteStudent(ctx context.Context, id string) error {
-	_, err := s.DB.ExecContext(ctx, "DELETE FROM students WHERE id = ?", id)
+func (s *StudentStore) PatchStudent(ctx context.Context, id string, version int64, patch student.StudentPatch, entry student.AuditEntry) error {
+	sets := []string{"updated_by = ?", "updated_on = ?", "version = version + 1"}
+	args := []interface{}{patch.UpdatedBy, patch.UpdatedOn}
+	if patch.Name != nil {
+		sets = append(sets, "name = ?")
+		args = append(args, *patch.Name)
+	}
+	if patch.Email != nil {
+		sets = append(sets, "email = ?")
+		args = append(args, *patch.Email)
+	}
+	if patch.Age != nil {
+		sets = append(sets, "age = ?")
+		args = append(args, *patch.Age)
+	}
+	if patch.Course != nil {
+		sets = append(sets, "course = ?")
+		args = append(args, *patch.Course)
+	}
+	if patch.CustomFields != nil {
+		params, err := newStudentParams(student.Student{CustomFields: *patch.CustomFields})
+		if err != nil {
+			return err
+		}
+		sets = append(sets, "custom_fields = ?")
+		args = append(args, params.CustomFieldsJSON)
+	}
+	args = append(args, id, version)
+
+	rowsAffected, err := s.writeAudited(ctx, entry, func(tx *sqlx.Tx) (int64, error) {
+		result, err := tx.ExecContext(ctx, "UPDATE students SET "+strings.Join(sets, ", ")+" WHERE id = ? AND version = ? AND "+notDeleted, args...)
+		if err != nil {
+			return 0, storeError("failed to patch student", err)
+		}
+		return rowsAffected(result)
+	})
+	if err != nil {
+		if isDuplicateKey(err) {
+			stud := student.Student{ID: id}
+			if patch.Email != nil {
+				stud.Email = *patch.Email
+			}
+			return s.duplicateStudentError(ctx, stud, err)
+		}
+		return err
+	}
+	if rowsAffected == 0 {
+		return s.versionMismatchError(ctx, id, version)
+	}
+	return nil
+}
+
+// DeleteStudent moves the student to the trash
+func (s *StudentStore) DeleteStudent(ctx context.Context, id string, version int64, deletedBy string, entry student.AuditEntry) error {
+	rowsAffected, err := s.writeAudited(ctx, entry, func(tx *sqlx.Tx) (int64, error) {
+		result, err := tx.ExecContext(ctx,
+			"UPDATE students SET deleted_by = ?, deleted_on = ?, version = version + 1 WHERE id = ? AND version = ? AND "+notDeleted,
+			deletedBy, time.Now(), id, version)
+		if err != nil {
+			return 0, storeError("failed to delete student", err)
+		}
+		return rowsAffected(result)
+	})
 	if err != nil {
-		return fmt.Errorf("failed to delete student: %w", err)
+		return err
+	}
+	if rowsAffected == 0 {
+		return s.versionMismatchError(ctx, id, version)
 	}
 	return nil
 }
+
+func (s *StudentStore) ListStudents(ctx context.Context, after student.ListCursor, limit int) ([]student.Student, error) {
+	return s.listStudents(ctx, notDeleted, after, limit)
+}
+
+func (s *StudentStore) ListDeletedStudents(ctx context.Context, after student.ListCursor, limit int) ([]student.Student, error) {
+	return s.listStudents(ctx, "deleted_on IS NOT NULL", after, limit)
+}
+
+// listStudents lists the students matching where after the cursor
+func (s *StudentStore) listStudents(ctx context.Context, where string, after student.ListCursor, limit int) ([]student.Student, error) {
+	query := "SELECT " + studentColumns + " FROM students WHERE " + where
+	var args []interface{}
+	if after.ID != "" {
+		query += " AND (created_on > ? OR (created_on = ? AND id > ?))"
+		args = append(args, after.CreatedOn, after.CreatedOn, after.ID)
+	}
+	query += " ORDER BY created_on, id LIMIT ?"
+	args = append(args, limit)
+
+	var studentRows []StudentRow
+	if err := s.DB.SelectContext(ctx, &studentRows, query, args...); err != nil {
+		return nil, storeError("failed to list students", err)
+	}
+
+	students := make([]student.Student, 0, len(studentRows))
+	for _, r := range studentRows {
+		students = append(students, convertStudentRowToStudent(r))
+	}
+	return students, nil
+}
+
+// RestoreStudent takes the student out of the trash, restoring counts as an
+// update so the version moves on
+func (s *StudentStore) RestoreStudent(ctx context.Context, id, restoredBy string, entry student.AuditEntry) (student.Student, error) {
+	rowsAffected, err := s.writeAudited(ctx, entry, func(tx *sqlx.Tx) (int64, error) {
+		result, err := tx.ExecContext(ctx,
+			`UPDATE students SET deleted_by = NULL, deleted_on = NULL, updated_by = ?, updated_on = ?, version = version + 1
+            WHERE id = ? AND deleted_on IS NOT NULL`,
+			restoredBy, time.Now(), id)
+		if err != nil {
+			return 0, storeError("failed to restore student", err)
+		}
+		return rowsAffected(result)
+	})
+	if err != nil {
+		if isDuplicateKey(err) {
+			return student.Student{}, s.duplicateStudentError(ctx, student.Student{ID: id}, err)
+		}
+		return student.Student{}, err
+	}
+	if rowsAffected == 0 {
+		return student.Student{}, fmt.Errorf("no deleted student with ID %s: %w", id, student.ErrNoStudentFound)
+	}
+	return s.GetStudent(ctx, id)
+}
+
+// PurgeDeletedStudents removes for good the students deleted before the
+// given time, recording entry for each of them in the same transaction
+func (s *StudentStore) PurgeDeletedStudents(ctx context.Context, deletedBefore time.Time, entry student.AuditEntry) (int64, error) {
+	changes, err := json.Marshal(entry.Changes)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode audit changes: %w", err)
+	}
+
+	tx, err := s.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, storeError("failed to begin the purge transaction", err)
+	}
+	// a no-op once committed
+	defer tx.Rollback()
+
+	const purgeable = "deleted_on IS NOT NULL AND deleted_on < ?"
+	// locking the students keeps a restore from slipping in between the
+	// audit and the delete
+	var ids []string
+	if err := tx.SelectContext(ctx, &ids, "SELECT id FROM students WHERE "+purgeable+" FOR UPDATE", deletedBefore); err != nil {
+		return 0, storeError("failed to find the students to purge", err)
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO audit_log (student_id, action, actor, request_id, occurred_on, changes)
+        SELECT id, ?, ?, ?, ?, ? FROM students WHERE `+purgeable,
+		string(entry.Action), entry.Actor, nullString(entry.RequestID), entry.OccurredOn, changes, deletedBefore)
+	if err != nil {
+		return 0, storeError("failed to audit the purged students", err)
+	}
+	result, err := tx.ExecContext(ctx, "DELETE FROM students WHERE "+purgeable, deletedBefore)
+	if err != nil {
+		return 0, storeError("failed to purge deleted students", err)
+	}
+	purged, err := rowsAffected(result)
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, storeError("failed to commit the purge transaction", err)
+	}
+	return purged, nil
+}
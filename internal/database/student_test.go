@@ -0,0 +1,60 @@
+package database
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"golang-assignment/internal/student"
+	"golang-assignment/internal/student/storetest"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+)
+
+// testDSNEnv names the DSN of a scratch MySQL database the integration tests
+// migrate and empty, for example root:secret@tcp(localhost:3306)/student_test.
+// The tests are skipped when it is not set.
+const testDSNEnv = "TEST_MYSQL_DSN"
+
+// openTestDB connects to the scratch database and migrates it
+func openTestDB(t *testing.T) *sqlx.DB {
+	t.Helper()
+	dsn := os.Getenv(testDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", testDSNEnv)
+	}
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		t.Fatalf("invalid %s: %v", testDSNEnv, err)
+	}
+	cfg.ParseTime = true
+	db, err := sqlx.Connect("mysql", cfg.FormatDSN())
+	if err != nil {
+		t.Fatalf("failed to connect to database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := MigrateUp(context.Background(), db); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+	return db
+}
+
+func TestStudentStore(t *testing.T) {
+	db := openTestDB(t)
+	storetest.TestStudentStore(t, func(t *testing.T) student.StudentStore {
+		ctx := context.Background()
+		// deleting the students cascades to the rows hanging off them
+		for _, query := range []string{
+			"DELETE FROM students",
+			"DELETE FROM audit_log",
+			`INSERT IGNORE INTO courses (code, title, created_by, created_on, updated_by, updated_on)
+				VALUES ('CS101', 'CS101', 'tester', NOW(), 'tester', NOW())`,
+		} {
+			if _, err := db.ExecContext(ctx, query); err != nil {
+				t.Fatalf("failed to empty the store: %v", err)
+			}
+		}
+		return NewStudentStore(db)
+	})
+}
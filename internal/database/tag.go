@@ -0,0 +1,227 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang-assignment/internal/tag"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type TagStore struct {
+	DB *sqlx.DB
+}
+
+func NewTagStore(db *sqlx.DB) *TagStore {
+	return &TagStore{DB: db}
+}
+
+type TagRow struct {
+	Name        string         `db:"name"`
+	Description string         `db:"description"`
+	Students    int            `db:"students"`
+	CreatedBy   sql.NullString `db:"created_by"`
+	CreatedOn   time.Time      `db:"created_on"`
+	UpdatedBy   sql.NullString `db:"updated_by"`
+	UpdatedOn   time.Time      `db:"updated_on"`
+}
+
+func convertTagRowToTag(r TagRow) tag.Tag {
+	return tag.Tag{
+		Name:        r.Name,
+		Description: r.Description,
+		Students:    r.Students,
+		CreatedBy:   r.CreatedBy.String,
+		CreatedOn:   r.CreatedOn,
+		UpdatedBy:   r.UpdatedBy.String,
+		UpdatedOn:   r.UpdatedOn,
+	}
+}
+
+// tagColumns count the students of each tag, the ones in the trash left out
+const tagColumns = `t.name, t.description, t.created_by, t.created_on, t.updated_by, t.updated_on,
+    (SELECT COUNT(*) FROM student_tags st JOIN students s ON s.id = st.student_id
+        WHERE st.tag_name = t.name AND s.deleted_on IS NULL) AS students`
+
+func (s *TagStore) ListTags(ctx context.Context) ([]tag.Tag, error) {
+	var rows []TagRow
+	if err := s.DB.SelectContext(ctx, &rows, "SELECT "+tagColumns+" FROM tags t ORDER BY t.name"); err != nil {
+		return nil, storeError("failed to list tags", err)
+	}
+	tags := make([]tag.Tag, 0, len(rows))
+	for _, r := range rows {
+		tags = append(tags, convertTagRowToTag(r))
+	}
+	return tags, nil
+}
+
+func (s *TagStore) GetTag(ctx context.Context, name string) (tag.Tag, error) {
+	var row TagRow
+	err := s.DB.GetContext(ctx, &row, "SELECT "+tagColumns+" FROM tags t WHERE t.name = ?", name)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return tag.Tag{}, fmt.Errorf("tag %s not found: %w", name, tag.ErrTagNotFound)
+		}
+		return tag.Tag{}, storeError("failed to fetch tag", err)
+	}
+	return convertTagRowToTag(row), nil
+}
+
+func (s *TagStore) CreateTag(ctx context.Context, t tag.Tag) (tag.Tag, error) {
+	t.CreatedOn = time.Now()
+	t.UpdatedOn = t.CreatedOn
+	_, err := s.DB.ExecContext(ctx,
+		"INSERT INTO tags (name, description, created_by, created_on, updated_by, updated_on) VALUES (?, ?, ?, ?, ?, ?)",
+		t.Name, t.Description, t.CreatedBy, t.CreatedOn, t.UpdatedBy, t.UpdatedOn)
+	if err != nil {
+		if isDuplicateKey(err) {
+			return tag.Tag{}, fmt.Errorf("tag %s: %w", t.Name, tag.ErrDuplicateTag)
+		}
+		return tag.Tag{}, storeError("failed to insert tag", err)
+	}
+	return t, nil
+}
+
+func (s *TagStore) UpdateTag(ctx context.Context, t tag.Tag) (tag.Tag, error) {
+	t.UpdatedOn = time.Now()
+	result, err := s.DB.ExecContext(ctx, "UPDATE tags SET description = ?, updated_by = ?, updated_on = ? WHERE name = ?",
+		t.Description, t.UpdatedBy, t.UpdatedOn, t.Name)
+	if err != nil {
+		return tag.Tag{}, storeError("failed to update tag", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return tag.Tag{}, fmt.Errorf("could not determine rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return tag.Tag{}, fmt.Errorf("no rows were updated, tag %s might not exist: %w", t.Name, tag.ErrTagNotFound)
+	}
+	return t, nil
+}
+
+// DeleteTag removes the tag, the foreign key removes it from the students
+func (s *TagStore) DeleteTag(ctx context.Context, name string) error {
+	result, err := s.DB.ExecContext(ctx, "DELETE FROM tags WHERE name = ?", name)
+	if err != nil {
+		return storeError("failed to delete tag", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("could not determine rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("tag %s not found: %w", name, tag.ErrTagNotFound)
+	}
+	return nil
+}
+
+// liveStudents returns which of ids belong to students out of the trash,
+// along with the others
+func liveStudents(ctx context.Context, tx *sqlx.Tx, ids []string) (found, notFound []string, err error) {
+	query, args, err := sqlx.In("SELECT id FROM students WHERE id IN (?) AND "+notDeleted, ids)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := tx.SelectContext(ctx, &found, query, args...); err != nil {
+		return nil, nil, storeError("failed to look up student IDs", err)
+	}
+	live := make(map[string]bool, len(found))
+	for _, id := range found {
+		live[id] = true
+	}
+	notFound = []string{}
+	for _, id := range ids {
+		if !live[id] {
+			notFound = append(notFound, id)
+		}
+	}
+	return found, notFound, nil
+}
+
+// bulkTag runs write on the live students of ids while the tag row is locked,
+// so a tag deleted meanwhile is reported rather than written to
+func (s *TagStore) bulkTag(ctx context.Context, name string, ids []string, write func(tx *sqlx.Tx, found []string) (sql.Result, error)) (tag.BulkResult, error) {
+	tx, err := s.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return tag.BulkResult{}, storeError("failed to begin the tag transaction", err)
+	}
+	// a no-op once committed
+	defer tx.Rollback()
+
+	var locked string
+	if err := tx.GetContext(ctx, &locked, "SELECT name FROM tags WHERE name = ? FOR UPDATE", name); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return tag.BulkResult{}, fmt.Errorf("tag %s not found: %w", name, tag.ErrTagNotFound)
+		}
+		return tag.BulkResult{}, storeError("failed to lock tag", err)
+	}
+
+	found, notFound, err := liveStudents(ctx, tx, ids)
+	if err != nil {
+		return tag.BulkResult{}, err
+	}
+	result := tag.BulkResult{Matched: len(found), NotFound: notFound}
+	if len(found) > 0 {
+		written, err := write(tx, found)
+		if err != nil {
+			return tag.BulkResult{}, storeError("failed to write student tags", err)
+		}
+		rowsAffected, err := written.RowsAffected()
+		if err != nil {
+			return tag.BulkResult{}, fmt.Errorf("could not determine rows affected: %w", err)
+		}
+		result.Changed = int(rowsAffected)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return tag.BulkResult{}, storeError("failed to commit the tag transaction", err)
+	}
+	return result, nil
+}
+
+// TagStudents inserts the missing memberships, the existing ones keep who
+// tagged them and when
+func (s *TagStore) TagStudents(ctx context.Context, name string, ids []string, taggedBy string) (tag.BulkResult, error) {
+	return s.bulkTag(ctx, name, ids, func(tx *sqlx.Tx, found []string) (sql.Result, error) {
+		// a row left as it is counts as no row affected
+		query, args, err := sqlx.In(`INSERT INTO student_tags (student_id, tag_name, tagged_by, tagged_on)
+            SELECT id, ?, ?, ? FROM students WHERE id IN (?)
+            ON DUPLICATE KEY UPDATE student_id = student_id`, name, taggedBy, time.Now(), found)
+		if err != nil {
+			return nil, err
+		}
+		return tx.ExecContext(ctx, query, args...)
+	})
+}
+
+func (s *TagStore) UntagStudents(ctx context.Context, name string, ids []string) (tag.BulkResult, error) {
+	return s.bulkTag(ctx, name, ids, func(tx *sqlx.Tx, found []string) (sql.Result, error) {
+		query, args, err := sqlx.In("DELETE FROM student_tags WHERE tag_name = ? AND student_id IN (?)", name, found)
+		if err != nil {
+			return nil, err
+		}
+		return tx.ExecContext(ctx, query, args...)
+	})
+}
+
+func (s *TagStore) ListStudentTags(ctx context.Context, studentID string) ([]tag.StudentTag, error) {
+	var rows []struct {
+		Name     string         `db:"tag_name"`
+		TaggedBy sql.NullString `db:"tagged_by"`
+		TaggedOn time.Time      `db:"tagged_on"`
+	}
+	err := s.DB.SelectContext(ctx, &rows,
+		"SELECT tag_name, tagged_by, tagged_on FROM student_tags WHERE student_id = ? ORDER BY tag_name", studentID)
+	if err != nil {
+		return nil, storeError("failed to list student tags", err)
+	}
+	tags := make([]tag.StudentTag, 0, len(rows))
+	for _, r := range rows {
+		tags = append(tags, tag.StudentTag{Name: r.Name, TaggedBy: r.TaggedBy.String, TaggedOn: r.TaggedOn})
+	}
+	return tags, nil
+}
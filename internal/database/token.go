@@ -0,0 +1,120 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"golang-assignment/internal/student"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type TokenStore struct {
+	DB *sqlx.DB
+}
+
+func NewTokenStore(db *sqlx.DB) *TokenStore {
+	return &TokenStore{DB: db}
+}
+
+type RefreshTokenRow struct {
+	Hash      string       `db:"hash"`
+	FamilyID  string       `db:"family_id"`
+	UserID    string       `db:"user_id"`
+	CreatedOn time.Time    `db:"created_on"`
+	ExpiresOn time.Time    `db:"expires_on"`
+	UsedOn    sql.NullTime `db:"used_on"`
+	RevokedOn sql.NullTime `db:"revoked_on"`
+}
+
+func (s *TokenStore) CreateRefreshToken(ctx context.Context, t student.RefreshToken) error {
+	_, err := s.DB.ExecContext(ctx,
+		"INSERT INTO refresh_tokens (hash, family_id, user_id, created_on, expires_on) VALUES (?, ?, ?, ?, ?)",
+		t.Hash, t.FamilyID, t.UserID, t.CreatedOn, t.ExpiresOn)
+	if err != nil {
+		return fmt.Errorf("failed to insert refresh token: %w", err)
+	}
+	return nil
+}
+
+func (s *TokenStore) GetRefreshToken(ctx context.Context, hash string) (student.RefreshToken, error) {
+	var row RefreshTokenRow
+	err := s.DB.GetContext(ctx, &row,
+		"SELECT hash, family_id, user_id, created_on, expires_on, used_on, revoked_on FROM refresh_tokens WHERE hash = ?", hash)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return student.RefreshToken{}, student.ErrTokenNotFound
+		}
+		return student.RefreshToken{}, fmt.Errorf("an error occurred fetching the refresh token: %w", err)
+	}
+	return student.RefreshToken{
+		Hash:      row.Hash,
+		FamilyID:  row.FamilyID,
+		UserID:    row.UserID,
+		CreatedOn: row.CreatedOn,
+		ExpiresOn: row.ExpiresOn,
+		UsedOn:    row.UsedOn.Time,
+		RevokedOn: row.RevokedOn.Time,
+	}, nil
+}
+
+func (s *TokenStore) UseRefreshToken(ctx context.Context, hash string, usedOn time.Time) (bool, error) {
+	result, err := s.DB.ExecContext(ctx,
+		"UPDATE refresh_tokens SET used_on = ? WHERE hash = ? AND used_on IS NULL AND revoked_on IS NULL", usedOn, hash)
+	if err != nil {
+		return false, fmt.Errorf("failed to use refresh token: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("could not determine rows affected: %w", err)
+	}
+	return rowsAffected == 1, nil
+}
+
+func (s *TokenStore) RevokeTokenFamily(ctx context.Context, familyID string, at time.Time) error {
+	_, err := s.DB.ExecContext(ctx,
+		"UPDATE refresh_tokens SET revoked_on = ? WHERE family_id = ? AND revoked_on IS NULL", at, familyID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke token family: %w", err)
+	}
+	return nil
+}
+
+func (s *TokenStore) RevokeUserTokens(ctx context.Context, userID string, at time.Time) error {
+	_, err := s.DB.ExecContext(ctx,
+		"UPDATE refresh_tokens SET revoked_on = ? WHERE user_id = ? AND revoked_on IS NULL", at, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke user tokens: %w", err)
+	}
+	return nil
+}
+
+func (s *TokenStore) RevokeAccessToken(ctx context.Context, jti string, expiresOn time.Time) error {
+	_, err := s.DB.ExecContext(ctx,
+		"INSERT INTO revoked_tokens (jti, expires_on) VALUES (?, ?) ON DUPLICATE KEY UPDATE expires_on = VALUES(expires_on)",
+		jti, expiresOn)
+	if err != nil {
+		return fmt.Errorf("failed to revoke access token: %w", err)
+	}
+	return nil
+}
+
+func (s *TokenStore) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	var count int
+	if err := s.DB.GetContext(ctx, &count, "SELECT COUNT(*) FROM revoked_tokens WHERE jti = ?", jti); err != nil {
+		return false, fmt.Errorf("failed to check revoked tokens: %w", err)
+	}
+	return count > 0, nil
+}
+
+func (s *TokenStore) PurgeExpiredTokens(ctx context.Context, now time.Time) error {
+	if _, err := s.DB.ExecContext(ctx, "DELETE FROM refresh_tokens WHERE expires_on < ?", now); err != nil {
+		return fmt.Errorf("failed to purge refresh tokens: %w", err)
+	}
+	if _, err := s.DB.ExecContext(ctx, "DELETE FROM revoked_tokens WHERE expires_on < ?", now); err != nil {
+		return fmt.Errorf("failed to purge revoked tokens: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"golang-assignment/internal/student"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type UserStore struct {
+	DB *sqlx.DB
+}
+
+func NewUserStore(db *sqlx.DB) *UserStore {
+	return &UserStore{DB: db}
+}
+
+type UserRow struct {
+	ID           string         `db:"id"`
+	Name         string         `db:"name"`
+	Role         string         `db:"role"`
+	PasswordHash string         `db:"password_hash"`
+	Disabled     bool           `db:"disabled"`
+	CreatedBy    sql.NullString `db:"created_by"`
+	CreatedOn    time.Time      `db:"created_on"`
+	UpdatedBy    sql.NullString `db:"updated_by"`
+	UpdatedOn    time.Time      `db:"updated_on"`
+}
+
+func convertUserRowToUser(r UserRow) student.User {
+	return student.User{
+		ID:           r.ID,
+		Name:         r.Name,
+		Role:         student.Role(r.Role),
+		PasswordHash: r.PasswordHash,
+		Disabled:     r.Disabled,
+		CreatedBy:    r.CreatedBy.String,
+		CreatedOn:    r.CreatedOn,
+		UpdatedBy:    r.UpdatedBy.String,
+		UpdatedOn:    r.UpdatedOn,
+	}
+}
+
+const userColumns = "id, name, role, password_hash, disabled, created_by, created_on, updated_by, updated_on"
+
+func (s *UserStore) GetUser(ctx context.Context, id string) (student.User, error) {
+	var row UserRow
+	err := s.DB.GetContext(ctx, &row, "SELECT "+userColumns+" FROM users WHERE id = ?", id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return student.User{}, fmt.Errorf("user with ID %s not found: %w", id, student.ErrUserNotFound)
+		}
+		return student.User{}, storeError("an error occurred fetching the user", err)
+	}
+	return convertUserRowToUser(row), nil
+}
+
+func (s *UserStore) ListUsers(ctx context.Context) ([]student.User, error) {
+	var rows []UserRow
+	if err := s.DB.SelectContext(ctx, &rows, "SELECT "+userColumns+" FROM users ORDER BY id"); err != nil {
+		return nil, storeError("failed to list users", err)
+	}
+	users := make([]student.User, 0, len(rows))
+	for _, r := range rows {
+		users = append(users, convertUserRowToUser(r))
+	}
+	return users, nil
+}
+
+func (s *UserStore) CreateUser(ctx context.Context, user student.User) (student.User, error) {
+	user.CreatedOn = time.Now()
+	user.UpdatedOn = user.CreatedOn
+	_, err := s.DB.ExecContext(ctx, "INSERT INTO users ("+userColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		user.ID, user.Name, user.Role, user.PasswordHash, user.Disabled, user.CreatedBy, user.CreatedOn, user.UpdatedBy, user.UpdatedOn)
+	if err != nil {
+		if isDuplicateKey(err) {
+			return student.User{}, fmt.Errorf("user with ID %s: %w", user.ID, student.ErrDuplicateUser)
+		}
+		return student.User{}, storeError("failed to insert user", err)
+	}
+	return user, nil
+}
+
+func (s *UserStore) UpdateUser(ctx context.Context, user student.User) (student.User, error) {
+	user.UpdatedOn = time.Now()
+	result, err := s.DB.ExecContext(ctx,
+		"UPDATE users SET name = ?, role = ?, password_hash = ?, disabled = ?, updated_by = ?, updated_on = ? WHERE id = ?",
+		user.Name, user.Role, user.PasswordHash, user.Disabled, user.UpdatedBy, user.UpdatedOn, user.ID)
+	if err != nil {
+		return student.User{}, storeError("failed to update user", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return student.User{}, fmt.Errorf("could not determine rows affected: %w", err)
+	}
+	// updated_on has whole seconds, so an update changing nothing in the
+	// second of the previous one affects no row although the user exists
+	if rowsAffected == 0 {
+		if _, err := s.GetUser(ctx, user.ID); err != nil {
+			return student.User{}, err
+		}
+	}
+	return user, nil
+}
+
+func (s *UserStore) CountUsers(ctx context.Context) (int, error) {
+	var count int
+	if err := s.DB.GetContext(ctx, &count, "SELECT COUNT(*) FROM users"); err != nil {
+		return 0, storeError("failed to count users", err)
+	}
+	return count, nil
+}
@@ -0,0 +1,340 @@
+// Package enrollment keeps the enrollments of students in the courses of the
+// catalog, term by term. A course with a capacity has as many active or
+// completed enrollments per term as it has seats, the next students wait in
+// line and are promoted in the order they enrolled as seats free up.
+package enrollment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"golang-assignment/internal/course"
+	"golang-assignment/internal/student"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	// ErrEnrollmentNotFound is the error of the student domain, which grades
+	// enrollments
+	ErrEnrollmentNotFound  = student.ErrEnrollmentNotFound
+	ErrAlreadyEnrolled     = errors.New("student is already enrolled in the course for the term")
+	ErrInvalidEnrollment   = errors.New("invalid enrollment")
+	ErrManagingEnrollments = errors.New("could not manage enrollments")
+)
+
+// domainErrors are passed through by the service, any other store error is
+// hidden behind ErrManagingEnrollments
+var domainErrors = []error{
+	ErrEnrollmentNotFound,
+	ErrAlreadyEnrolled,
+	ErrInvalidEnrollment,
+	student.ErrNoStudentFound,
+	course.ErrCourseNotFound,
+	student.ErrStoreUnavailable,
+}
+
+func serviceError(err error) error {
+	return student.PassThrough(err, ErrManagingEnrollments, domainErrors...)
+}
+
+// Status is where an enrollment stands. Waitlisted enrollments become active
+// when a seat frees up, withdrawn and completed ones never change again.
+type Status string
+
+const (
+	StatusActive     Status = "active"
+	StatusWaitlisted Status = "waitlisted"
+	StatusWithdrawn  Status = "withdrawn"
+	StatusCompleted  Status = "completed"
+)
+
+// Valid reports whether the status is one of the known ones
+func (st Status) Valid() bool {
+	switch st {
+	case StatusActive, StatusWaitlisted, StatusWithdrawn, StatusCompleted:
+		return true
+	}
+	return false
+}
+
+// HoldsSeat reports whether an enrollment with the status takes one of the
+// seats of the course
+func (st Status) HoldsSeat() bool {
+	return st == StatusActive || st == StatusCompleted
+}
+
+// MaxTermLength is the longest term an enrollment may have
+const MaxTermLength = 32
+
+var termPattern = regexp.MustCompile(`^[A-Z0-9][A-Z0-9_-]*$`)
+
+type Enrollment struct {
+	ID         int64     `json:"id"`
+	StudentID  string    `json:"student_id"`
+	Course     string    `json:"course"`
+	Term       string    `json:"term"`
+	Status     Status    `json:"status"`
+	EnrolledBy string    `json:"enrolled_by"`
+	EnrolledOn time.Time `json:"enrolled_on"`
+	UpdatedBy  string    `json:"updated_by"`
+	UpdatedOn  time.Time `json:"updated_on"`
+}
+
+// ChangeStatus checks that the enrollment may move to status. Only a student
+// holding a seat can complete the course, and anybody still in can withdraw.
+func (e Enrollment) ChangeStatus(status Status) error {
+	switch {
+	case e.Status == StatusWithdrawn || e.Status == StatusCompleted:
+		return fmt.Errorf("%w: the enrollment is already %s", ErrInvalidEnrollment, e.Status)
+	case status == StatusWithdrawn:
+		return nil
+	case status == StatusCompleted && e.Status == StatusActive:
+		return nil
+	case status == StatusCompleted:
+		return fmt.Errorf("%w: a waitlisted enrollment cannot be completed", ErrInvalidEnrollment)
+	}
+	return fmt.Errorf("%w: status must be withdrawn or completed", ErrInvalidEnrollment)
+}
+
+// RosterFilter narrows the roster of a course, empty fields match everything
+type RosterFilter struct {
+	Term   string
+	Status Status
+}
+
+// EnrollmentStore keeps the enrollments. Enroll and ChangeStatus decide on
+// the seats atomically: concurrent enrollments in a course never exceed its
+// capacity, and every seat freed up or added goes to the waitlist first.
+type EnrollmentStore interface {
+	GetEnrollment(ctx context.Context, id int64) (Enrollment, error)
+	ListStudentEnrollments(ctx context.Context, studentID, term string) ([]Enrollment, error)
+	ListRoster(ctx context.Context, code string, filter RosterFilter) ([]Enrollment, error)
+	// Enroll inserts e as active when the course has a free seat and nobody
+	// waits for one, as waitlisted otherwise
+	Enroll(ctx context.Context, e Enrollment) (Enrollment, error)
+	// ChangeStatus moves the enrollment to status, promoting the waitlist
+	// when it gave up a seat
+	ChangeStatus(ctx context.Context, id int64, status Status, updatedBy string) (Enrollment, error)
+	// FillSeats promotes the waitlists of every term of the course into the
+	// seats it has free, it returns the number of promoted enrollments
+	FillSeats(ctx context.Context, code, updatedBy string) (int64, error)
+}
+
+// StudentGetter finds the live student an enrollment is for
+type StudentGetter interface {
+	GetStudent(ctx context.Context, id string) (student.Student, error)
+}
+
+// CourseGetter finds the catalog course an enrollment is in
+type CourseGetter interface {
+	GetCourse(ctx context.Context, code string) (course.Course, error)
+}
+
+type Service struct {
+	Store    EnrollmentStore
+	Students StudentGetter
+	Courses  CourseGetter
+}
+
+func NewService(store EnrollmentStore, students StudentGetter, courses CourseGetter) *Service {
+	return &Service{Store: store, Students: students, Courses: courses}
+}
+
+// NormalizeTerm returns the term as it is stored, terms are upper case
+func NormalizeTerm(term string) string {
+	return strings.ToUpper(strings.TrimSpace(term))
+}
+
+func validateTerm(term string) error {
+	if len(term) > MaxTermLength || !termPattern.MatchString(term) {
+		return fmt.Errorf("%w: term must be at most %d letters, digits, - or _", ErrInvalidEnrollment, MaxTermLength)
+	}
+	return nil
+}
+
+func (s *Service) GetEnrollment(ctx context.Context, id int64) (Enrollment, error) {
+	e, err := s.Store.GetEnrollment(ctx, id)
+	if err != nil {
+		if !errors.Is(err, ErrEnrollmentNotFound) {
+			log.Errorf("an error occurred fetching the enrollment: %s", err.Error())
+		}
+		return Enrollment{}, serviceError(err)
+	}
+	return e, nil
+}
+
+// Enroll enrolls a live student in an active course for the term. The
+// enrollment is waitlisted when the course is full.
+func (s *Service) Enroll(ctx context.Context, studentID, code, term, enrolledBy string) (Enrollment, error) {
+	term = NormalizeTerm(term)
+	if err := validateTerm(term); err != nil {
+		return Enrollment{}, err
+	}
+	if _, err := s.Students.GetStudent(ctx, studentID); err != nil {
+		return Enrollment{}, err
+	}
+	c, err := s.Courses.GetCourse(ctx, course.NormalizeCode(code))
+	if err != nil {
+		return Enrollment{}, err
+	}
+	if !c.Active {
+		return Enrollment{}, fmt.Errorf("%w: course %s is not active", ErrInvalidEnrollment, c.Code)
+	}
+
+	e, err := s.Store.Enroll(ctx, Enrollment{
+		StudentID:  studentID,
+		Course:     c.Code,
+		Term:       term,
+		EnrolledBy: enrolledBy,
+		UpdatedBy:  enrolledBy,
+	})
+	if err != nil {
+		if !errors.Is(err, ErrAlreadyEnrolled) {
+			log.Errorf("an error occurred enrolling the student: %s", err.Error())
+		}
+		return Enrollment{}, serviceError(err)
+	}
+	return e, nil
+}
+
+// ChangeStatus withdraws or completes an enrollment
+func (s *Service) ChangeStatus(ctx context.Context, id int64, status Status, updatedBy string) (Enrollment, error) {
+	if status != StatusWithdrawn && status != StatusCompleted {
+		return Enrollment{}, fmt.Errorf("%w: status must be withdrawn or completed", ErrInvalidEnrollment)
+	}
+	e, err := s.Store.ChangeStatus(ctx, id, status, updatedBy)
+	if err != nil {
+		if !errors.Is(err, ErrEnrollmentNotFound) && !errors.Is(err, ErrInvalidEnrollment) {
+			log.Errorf("an error occurred changing the enrollment: %s", err.Error())
+		}
+		return Enrollment{}, serviceError(err)
+	}
+	return e, nil
+}
+
+// ListStudentEnrollments returns every enrollment of a live student ordered by
+// term and course, only those of term when it is set
+func (s *Service) ListStudentEnrollments(ctx context.Context, studentID, term string) ([]Enrollment, error) {
+	if _, err := s.Students.GetStudent(ctx, studentID); err != nil {
+		return nil, err
+	}
+	enrollments, err := s.Store.ListStudentEnrollments(ctx, studentID, NormalizeTerm(term))
+	if err != nil {
+		log.Errorf("an error occurred listing the enrollments: %s", err.Error())
+		return nil, serviceError(err)
+	}
+	return enrollments, nil
+}
+
+// ListRoster returns the enrollments of a course in the order the students
+// enrolled, which is the order of the waitlist
+func (s *Service) ListRoster(ctx context.Context, code string, filter RosterFilter) ([]Enrollment, error) {
+	if filter.Status != "" && !filter.Status.Valid() {
+		return nil, fmt.Errorf("%w: unknown status %s", ErrInvalidEnrollment, filter.Status)
+	}
+	c, err := s.Courses.GetCourse(ctx, course.NormalizeCode(code))
+	if err != nil {
+		return nil, err
+	}
+	filter.Term = NormalizeTerm(filter.Term)
+	roster, err := s.Store.ListRoster(ctx, c.Code, filter)
+	if err != nil {
+		log.Errorf("an error occurred listing the roster: %s", err.Error())
+		return nil, serviceError(err)
+	}
+	return roster, nil
+}
+
+// FillSeats implements course.SeatFiller, the waitlists of a course move up
+// as soon as its capacity grows
+func (s *Service) FillSeats(ctx context.Context, code, updatedBy string) error {
+	promoted, err := s.Store.FillSeats(ctx, code, updatedBy)
+	if err != nil {
+		log.Errorf("an error occurred promoting the waitlist of %s: %s", code, err.Error())
+		return serviceError(err)
+	}
+	if promoted > 0 {
+		log.Infof("promoted %d waitlisted enrollment(s) of %s", promoted, code)
+	}
+	return nil
+}
+
+// WithdrawStudent implements student.SeatReleaser, each enrollment is
+// withdrawn like ChangeStatus does so the waitlist behind it moves up
+func (s *Service) WithdrawStudent(ctx context.Context, studentID, updatedBy string) error {
+	enrollments, err := s.Store.ListStudentEnrollments(ctx, studentID, "")
+	if err != nil {
+		log.Errorf("an error occurred listing the enrollments: %s", err.Error())
+		return serviceError(err)
+	}
+	for _, e := range enrollments {
+		if e.Status != StatusActive && e.Status != StatusWaitlisted {
+			continue
+		}
+		if _, err := s.Store.ChangeStatus(ctx, e.ID, StatusWithdrawn, updatedBy); err != nil {
+			// withdrawn or completed since it was listed
+			if errors.Is(err, ErrInvalidEnrollment) {
+				continue
+			}
+			log.Errorf("an error occurred withdrawing enrollment %d: %s", e.ID, err.Error())
+			return serviceError(err)
+		}
+	}
+	return nil
+}
+
+// enrolledCourse joins the enrollment with the title and credits of its
+// course, courses caches the catalog lookups of a listing
+func (s *Service) enrolledCourse(ctx context.Context, e Enrollment, courses map[string]course.Course) (student.EnrolledCourse, error) {
+	c, ok := courses[e.Course]
+	if !ok {
+		var err error
+		if c, err = s.Courses.GetCourse(ctx, e.Course); err != nil {
+			return student.EnrolledCourse{}, err
+		}
+		courses[e.Course] = c
+	}
+	return student.EnrolledCourse{
+		EnrollmentID: e.ID,
+		StudentID:    e.StudentID,
+		Course:       e.Course,
+		Title:        c.Title,
+		Credits:      c.Credits,
+		Term:         e.Term,
+		Status:       string(e.Status),
+		EnrolledOn:   e.EnrolledOn,
+	}, nil
+}
+
+// GetEnrolledCourse implements student.EnrollmentLookup
+func (s *Service) GetEnrolledCourse(ctx context.Context, enrollmentID int64) (student.EnrolledCourse, error) {
+	e, err := s.GetEnrollment(ctx, enrollmentID)
+	if err != nil {
+		return student.EnrolledCourse{}, err
+	}
+	return s.enrolledCourse(ctx, e, map[string]course.Course{})
+}
+
+// ListEnrolledCourses implements student.EnrollmentLookup
+func (s *Service) ListEnrolledCourses(ctx context.Context, studentID string) ([]student.EnrolledCourse, error) {
+	enrollments, err := s.Store.ListStudentEnrollments(ctx, studentID, "")
+	if err != nil {
+		log.Errorf("an error occurred listing the enrollments: %s", err.Error())
+		return nil, serviceError(err)
+	}
+	courses := map[string]course.Course{}
+	enrolled := make([]student.EnrolledCourse, 0, len(enrollments))
+	for _, e := range enrollments {
+		c, err := s.enrolledCourse(ctx, e, courses)
+		if err != nil {
+			return nil, err
+		}
+		enrolled = append(enrolled, c)
+	}
+	return enrolled, nil
+}
@@ -0,0 +1,152 @@
+// Package export writes students out as CSV, JSON Lines or XLSX files one
+// student at a time, so an export never holds the whole roster in memory.
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang-assignment/internal/student"
+)
+
+const (
+	FormatCSV   = "csv"
+	FormatJSONL = "jsonl"
+	FormatXLSX  = "xlsx"
+)
+
+var ErrUnsupportedFormat = errors.New("export format must be one of csv, jsonl or xlsx")
+
+// contentTypes are the media types of the supported formats
+var contentTypes = map[string]string{
+	FormatCSV:   "text/csv; charset=utf-8",
+	FormatJSONL: "application/x-ndjson",
+	FormatXLSX:  "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+// ContentType returns the media type of the format, false when the format is
+// not supported
+func ContentType(format string) (string, bool) {
+	contentType, ok := contentTypes[format]
+	return contentType, ok
+}
+
+// Writer writes students to a file of one format. Close completes the file
+// but leaves the underlying writer open.
+type Writer interface {
+	Write(student.Student) error
+	Close() error
+}
+
+// NewWriter returns the writer of the format, the header of the file is
+// written right away. The CSV and XLSX files have a column for each of the
+// custom fields named.
+func NewWriter(format string, w io.Writer, fields []string) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w, fields)
+	case FormatJSONL:
+		return &jsonlWriter{enc: json.NewEncoder(w)}, nil
+	case FormatXLSX:
+		return newXLSXWriter(w, fields)
+	}
+	return nil, ErrUnsupportedFormat
+}
+
+// columns are the exported fields in the order of the CSV and XLSX columns,
+// the custom fields come after them
+var columns = []string{"id", "name", "email", "age", "course", "created_by", "created_on", "updated_by", "updated_on", "version"}
+
+// customFieldPrefix names the column of a custom field like the import
+// column holding one
+const customFieldPrefix = "cf."
+
+func header(fields []string) []string {
+	cells := append([]string{}, columns...)
+	for _, name := range fields {
+		cells = append(cells, customFieldPrefix+name)
+	}
+	return cells
+}
+
+func row(s student.Student, fields []string) []string {
+	cells := []string{
+		s.ID, s.Name, s.Email, strconv.Itoa(s.Age), s.Course,
+		s.CreatedBy, s.CreatedOn.UTC().Format(time.RFC3339),
+		s.UpdatedBy, s.UpdatedOn.UTC().Format(time.RFC3339),
+		strconv.FormatInt(s.Version, 10),
+	}
+	for _, name := range fields {
+		cells = append(cells, customFieldCell(s.CustomFields[name]))
+	}
+	return cells
+}
+
+// customFieldCell writes a value the way an import reads it, a missing
+// value is an empty cell
+func customFieldCell(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return fmt.Sprint(value)
+}
+
+type csvWriter struct {
+	w      *csv.Writer
+	fields []string
+}
+
+func newCSVWriter(w io.Writer, fields []string) (*csvWriter, error) {
+	c := &csvWriter{w: csv.NewWriter(w), fields: fields}
+	if err := c.w.Write(header(fields)); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// EscapeCell keeps a spreadsheet opening a CSV file from running a cell as a
+// formula, every CSV file the API writes escapes its cells with it
+func EscapeCell(cell string) string {
+	if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+		return "'" + cell
+	}
+	return cell
+}
+
+func (c *csvWriter) Write(s student.Student) error {
+	cells := row(s, c.fields)
+	for i := range cells {
+		cells[i] = EscapeCell(cells[i])
+	}
+	return c.w.Write(cells)
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+type jsonlWriter struct {
+	enc *json.Encoder
+}
+
+func (j *jsonlWriter) Write(s student.Student) error {
+	return j.enc.Encode(s)
+}
+
+func (j *jsonlWriter) Close() error {
+	return nil
+}
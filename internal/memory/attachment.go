@@ -0,0 +1,107 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"golang-assignment/internal/attachment"
+)
+
+// AttachmentStore keeps the attachments in a map keyed by id. The
+// attachments of purged students move to orphans until they are swept.
+type AttachmentStore struct {
+	mu          sync.RWMutex
+	attachments map[int64]attachment.Attachment
+	orphans     map[int64]attachment.Attachment
+	lastID      int64
+}
+
+// NewAttachmentStore returns an empty store, registered with the students
+// whose purge orphans their attachments
+func NewAttachmentStore(students *StudentStore) *AttachmentStore {
+	s := &AttachmentStore{attachments: map[int64]attachment.Attachment{}, orphans: map[int64]attachment.Attachment{}}
+	students.onPurge(s.orphanStudents)
+	return s
+}
+
+func (s *AttachmentStore) CreateAttachment(ctx context.Context, a attachment.Attachment) (attachment.Attachment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastID++
+	a.ID = s.lastID
+	a.UploadedOn = time.Now()
+	s.attachments[a.ID] = a
+	return a, nil
+}
+
+func (s *AttachmentStore) GetAttachment(ctx context.Context, studentID string, id int64) (attachment.Attachment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	a, ok := s.attachments[id]
+	if !ok || a.StudentID != studentID {
+		return attachment.Attachment{}, fmt.Errorf("attachment %d of student %s not found: %w", id, studentID, attachment.ErrAttachmentNotFound)
+	}
+	return a, nil
+}
+
+// sortedAttachments returns the attachments ordered by id
+func sortedAttachments(attachments map[int64]attachment.Attachment, keep func(attachment.Attachment) bool) []attachment.Attachment {
+	list := []attachment.Attachment{}
+	for _, a := range attachments {
+		if keep(a) {
+			list = append(list, a)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
+
+func (s *AttachmentStore) ListAttachments(ctx context.Context, studentID string) ([]attachment.Attachment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return sortedAttachments(s.attachments, func(a attachment.Attachment) bool { return a.StudentID == studentID }), nil
+}
+
+// DeleteAttachment removes an attachment, orphaned or not
+func (s *AttachmentStore) DeleteAttachment(ctx context.Context, studentID string, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, attachments := range []map[int64]attachment.Attachment{s.attachments, s.orphans} {
+		if a, ok := attachments[id]; ok && a.StudentID == studentID {
+			delete(attachments, id)
+			return nil
+		}
+	}
+	return fmt.Errorf("attachment %d of student %s not found: %w", id, studentID, attachment.ErrAttachmentNotFound)
+}
+
+func (s *AttachmentStore) ListOrphanedAttachments(ctx context.Context) ([]attachment.Attachment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return sortedAttachments(s.orphans, func(attachment.Attachment) bool { return true }), nil
+}
+
+// orphanStudents moves the attachments of purged students to the orphans
+func (s *AttachmentStore) orphanStudents(ids []string) {
+	purged := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		purged[id] = true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, a := range s.attachments {
+		if purged[a.StudentID] {
+			delete(s.attachments, id)
+			s.orphans[id] = a
+		}
+	}
+}
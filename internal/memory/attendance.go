@@ -0,0 +1,178 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"golang-assignment/internal/attendance"
+)
+
+// AttendanceStore keeps the records in a map keyed by student, course and
+// session date, and the alerts in the order they were raised
+type AttendanceStore struct {
+	mu      sync.RWMutex
+	records map[string]attendance.Record
+	alerts  []attendance.Alert
+	lastID  int64
+}
+
+// NewAttendanceStore returns an empty store, registered with the students
+// whose purge drops their attendance
+func NewAttendanceStore(students *StudentStore) *AttendanceStore {
+	s := &AttendanceStore{records: map[string]attendance.Record{}}
+	students.onPurge(s.removeStudents)
+	return s
+}
+
+func recordKey(r attendance.Record) string {
+	return r.StudentID + "|" + r.Course + "|" + r.Date
+}
+
+func (s *AttendanceStore) MarkAttendance(ctx context.Context, records []attendance.Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, r := range records {
+		s.records[recordKey(r)] = r
+	}
+	return nil
+}
+
+// list returns the records kept by keep ordered by date, course and student
+func (s *AttendanceStore) list(keep func(attendance.Record) bool) []attendance.Record {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	records := []attendance.Record{}
+	for _, r := range s.records {
+		if keep(r) {
+			records = append(records, r)
+		}
+	}
+	sort.Slice(records, func(i, j int) bool {
+		a, b := records[i], records[j]
+		if a.Date != b.Date {
+			return a.Date < b.Date
+		}
+		if a.Course != b.Course {
+			return a.Course < b.Course
+		}
+		return a.StudentID < b.StudentID
+	})
+	return records
+}
+
+func (s *AttendanceStore) ListStudentAttendance(ctx context.Context, studentID string, filter attendance.RecordFilter) ([]attendance.Record, error) {
+	return s.list(func(r attendance.Record) bool {
+		return r.StudentID == studentID &&
+			(filter.Course == "" || r.Course == filter.Course) &&
+			(filter.From == "" || r.Date >= filter.From) &&
+			(filter.To == "" || r.Date <= filter.To)
+	}), nil
+}
+
+func (s *AttendanceStore) ListSessionAttendance(ctx context.Context, code, date string) ([]attendance.Record, error) {
+	return s.list(func(r attendance.Record) bool {
+		return r.Course == code && r.Date == date
+	}), nil
+}
+
+func (s *AttendanceStore) Summarize(ctx context.Context, code, term string, studentIDs []string) (map[string]attendance.Summary, error) {
+	wanted := make(map[string]bool, len(studentIDs))
+	for _, id := range studentIDs {
+		wanted[id] = true
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	counts := map[string]map[attendance.Status]int{}
+	for _, r := range s.records {
+		if r.Course != code || r.Term != term || !wanted[r.StudentID] {
+			continue
+		}
+		if counts[r.StudentID] == nil {
+			counts[r.StudentID] = map[attendance.Status]int{}
+		}
+		counts[r.StudentID][r.Status]++
+	}
+	summaries := make(map[string]attendance.Summary, len(counts))
+	for id, studentCounts := range counts {
+		summaries[id] = attendance.NewSummary(studentCounts)
+	}
+	return summaries, nil
+}
+
+func (s *AttendanceStore) RaiseAlert(ctx context.Context, a attendance.Alert) (attendance.Alert, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, open := range s.alerts {
+		if open.ResolvedOn == nil && open.StudentID == a.StudentID && open.Course == a.Course && open.Term == a.Term {
+			return open, false, nil
+		}
+	}
+	s.lastID++
+	a.ID = s.lastID
+	s.alerts = append(s.alerts, a)
+	return a, true, nil
+}
+
+func (s *AttendanceStore) ResolveAlerts(ctx context.Context, code, term string, studentIDs []string, resolvedOn time.Time) error {
+	recovered := make(map[string]bool, len(studentIDs))
+	for _, id := range studentIDs {
+		recovered[id] = true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, a := range s.alerts {
+		if a.ResolvedOn == nil && a.Course == code && a.Term == term && recovered[a.StudentID] {
+			resolved := resolvedOn
+			s.alerts[i].ResolvedOn = &resolved
+		}
+	}
+	return nil
+}
+
+func (s *AttendanceStore) ListAlerts(ctx context.Context, filter attendance.AlertFilter) ([]attendance.Alert, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	alerts := []attendance.Alert{}
+	for i := len(s.alerts) - 1; i >= 0; i-- {
+		a := s.alerts[i]
+		if (filter.StudentID == "" || a.StudentID == filter.StudentID) &&
+			(filter.Course == "" || a.Course == filter.Course) &&
+			(!filter.OpenOnly || a.ResolvedOn == nil) {
+			alerts = append(alerts, a)
+		}
+	}
+	return alerts, nil
+}
+
+// removeStudents drops the attendance and alerts of purged students
+func (s *AttendanceStore) removeStudents(ids []string) {
+	purged := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		purged[id] = true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, r := range s.records {
+		if purged[r.StudentID] {
+			delete(s.records, key)
+		}
+	}
+	alerts := s.alerts[:0]
+	for _, a := range s.alerts {
+		if !purged[a.StudentID] {
+			alerts = append(alerts, a)
+		}
+	}
+	s.alerts = alerts
+}
@@ -0,0 +1,50 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"golang-assignment/internal/student"
+)
+
+// AuditStore keeps the audit entries in insertion order
+type AuditStore struct {
+	mu      sync.RWMutex
+	entries []student.AuditEntry
+}
+
+func NewAuditStore() *AuditStore {
+	return &AuditStore{}
+}
+
+// add appends the entries, it is called by the StudentStore while it holds
+// the lock of the students so a change and its entry are seen together
+func (s *AuditStore) add(entries ...student.AuditEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, entry := range entries {
+		entry.ID = int64(len(s.entries) + 1)
+		s.entries = append(s.entries, entry)
+	}
+}
+
+func (s *AuditStore) ListAuditEntries(ctx context.Context, f student.AuditFilter) ([]student.AuditEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var entries []student.AuditEntry
+	for i := len(s.entries) - 1; i >= 0 && len(entries) < f.Limit; i-- {
+		e := s.entries[i]
+		switch {
+		case f.BeforeID > 0 && e.ID >= f.BeforeID,
+			f.StudentID != "" && e.StudentID != f.StudentID,
+			f.Actor != "" && e.Actor != f.Actor,
+			!f.From.IsZero() && e.OccurredOn.Before(f.From),
+			!f.To.IsZero() && e.OccurredOn.After(f.To):
+			continue
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
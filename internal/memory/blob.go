@@ -0,0 +1,61 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	"golang-assignment/internal/attachment"
+)
+
+// BlobStore keeps the content of attachments in a map keyed by blob key, it
+// stands in for the file or object storage of a real deployment
+type BlobStore struct {
+	mu    sync.RWMutex
+	blobs map[string][]byte
+}
+
+func NewBlobStore() *BlobStore {
+	return &BlobStore{blobs: map[string][]byte{}}
+}
+
+func (s *BlobStore) Put(ctx context.Context, key string, r io.Reader) (int64, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.blobs[key] = content
+	return int64(len(content)), nil
+}
+
+// blob reads a stored content, which is never changed in place
+type blob struct {
+	*bytes.Reader
+}
+
+func (blob) Close() error {
+	return nil
+}
+
+func (s *BlobStore) Open(ctx context.Context, key string) (attachment.Blob, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	content, ok := s.blobs[key]
+	if !ok {
+		return nil, fmt.Errorf("blob %s: %w", key, attachment.ErrBlobNotFound)
+	}
+	return blob{bytes.NewReader(content)}, nil
+}
+
+func (s *BlobStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.blobs, key)
+	return nil
+}
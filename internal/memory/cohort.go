@@ -0,0 +1,98 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang-assignment/internal/cohort"
+)
+
+// CohortStore keeps the cohorts in a map keyed by id
+type CohortStore struct {
+	mu      sync.RWMutex
+	cohorts map[int64]cohort.Cohort
+	lastID  int64
+}
+
+func NewCohortStore() *CohortStore {
+	return &CohortStore{cohorts: map[int64]cohort.Cohort{}}
+}
+
+func (s *CohortStore) ListCohorts(ctx context.Context) ([]cohort.Cohort, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	cohorts := make([]cohort.Cohort, 0, len(s.cohorts))
+	for _, c := range s.cohorts {
+		cohorts = append(cohorts, c)
+	}
+	sort.Slice(cohorts, func(i, j int) bool { return cohorts[i].Name < cohorts[j].Name })
+	return cohorts, nil
+}
+
+func (s *CohortStore) GetCohort(ctx context.Context, id int64) (cohort.Cohort, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	c, ok := s.cohorts[id]
+	if !ok {
+		return cohort.Cohort{}, fmt.Errorf("cohort %d not found: %w", id, cohort.ErrCohortNotFound)
+	}
+	return c, nil
+}
+
+// nameTaken reports whether a cohort other than id has the name, ignoring
+// case like the unique key of the database. The caller holds the lock.
+func (s *CohortStore) nameTaken(id int64, name string) bool {
+	for _, c := range s.cohorts {
+		if c.ID != id && strings.EqualFold(c.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *CohortStore) CreateCohort(ctx context.Context, c cohort.Cohort) (cohort.Cohort, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.nameTaken(0, c.Name) {
+		return cohort.Cohort{}, fmt.Errorf("cohort %s: %w", c.Name, cohort.ErrDuplicateCohort)
+	}
+	s.lastID++
+	c.ID = s.lastID
+	c.CreatedOn = time.Now()
+	c.UpdatedOn = c.CreatedOn
+	s.cohorts[c.ID] = c
+	return c, nil
+}
+
+func (s *CohortStore) UpdateCohort(ctx context.Context, c cohort.Cohort) (cohort.Cohort, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.cohorts[c.ID]; !ok {
+		return cohort.Cohort{}, fmt.Errorf("no rows were updated, cohort %d might not exist: %w", c.ID, cohort.ErrCohortNotFound)
+	}
+	if s.nameTaken(c.ID, c.Name) {
+		return cohort.Cohort{}, fmt.Errorf("cohort %s: %w", c.Name, cohort.ErrDuplicateCohort)
+	}
+	c.UpdatedOn = time.Now()
+	s.cohorts[c.ID] = c
+	return c, nil
+}
+
+func (s *CohortStore) DeleteCohort(ctx context.Context, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.cohorts[id]; !ok {
+		return fmt.Errorf("cohort %d not found: %w", id, cohort.ErrCohortNotFound)
+	}
+	delete(s.cohorts, id)
+	return nil
+}
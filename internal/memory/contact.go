@@ -0,0 +1,128 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"golang-assignment/internal/contact"
+)
+
+// ContactStore keeps the contacts in a map keyed by id
+type ContactStore struct {
+	mu       sync.RWMutex
+	contacts map[int64]contact.Contact
+	lastID   int64
+}
+
+// NewContactStore returns an empty store, registered with the students whose
+// purge drops their contacts
+func NewContactStore(students *StudentStore) *ContactStore {
+	s := &ContactStore{contacts: map[int64]contact.Contact{}}
+	students.onPurge(s.removeStudents)
+	return s
+}
+
+func (s *ContactStore) ListContacts(ctx context.Context, studentID string) ([]contact.Contact, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	contacts := []contact.Contact{}
+	for _, c := range s.contacts {
+		if c.StudentID == studentID {
+			contacts = append(contacts, c)
+		}
+	}
+	sort.Slice(contacts, func(i, j int) bool {
+		if contacts[i].Primary != contacts[j].Primary {
+			return contacts[i].Primary
+		}
+		return contacts[i].ID < contacts[j].ID
+	})
+	return contacts, nil
+}
+
+func (s *ContactStore) GetContact(ctx context.Context, studentID string, id int64) (contact.Contact, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	c, ok := s.contacts[id]
+	if !ok || c.StudentID != studentID {
+		return contact.Contact{}, fmt.Errorf("contact %d of student %s not found: %w", id, studentID, contact.ErrContactNotFound)
+	}
+	return c, nil
+}
+
+// clearPrimary makes every contact of the student but id secondary, the
+// caller holds the lock
+func (s *ContactStore) clearPrimary(studentID string, id int64) {
+	for other, c := range s.contacts {
+		if c.StudentID == studentID && other != id && c.Primary {
+			c.Primary = false
+			s.contacts[other] = c
+		}
+	}
+}
+
+func (s *ContactStore) CreateContact(ctx context.Context, c contact.Contact) (contact.Contact, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastID++
+	c.ID = s.lastID
+	c.CreatedOn = time.Now()
+	c.UpdatedOn = c.CreatedOn
+	if c.Primary {
+		s.clearPrimary(c.StudentID, c.ID)
+	}
+	s.contacts[c.ID] = c
+	return c, nil
+}
+
+func (s *ContactStore) UpdateContact(ctx context.Context, c contact.Contact) (contact.Contact, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.contacts[c.ID]
+	if !ok || stored.StudentID != c.StudentID {
+		return contact.Contact{}, fmt.Errorf("contact %d of student %s not found: %w", c.ID, c.StudentID, contact.ErrContactNotFound)
+	}
+	c.CreatedBy = stored.CreatedBy
+	c.CreatedOn = stored.CreatedOn
+	c.UpdatedOn = time.Now()
+	if c.Primary {
+		s.clearPrimary(c.StudentID, c.ID)
+	}
+	s.contacts[c.ID] = c
+	return c, nil
+}
+
+func (s *ContactStore) DeleteContact(ctx context.Context, studentID string, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.contacts[id]
+	if !ok || c.StudentID != studentID {
+		return fmt.Errorf("contact %d of student %s not found: %w", id, studentID, contact.ErrContactNotFound)
+	}
+	delete(s.contacts, id)
+	return nil
+}
+
+// removeStudents drops the contacts of purged students
+func (s *ContactStore) removeStudents(ids []string) {
+	purged := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		purged[id] = true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, c := range s.contacts {
+		if purged[c.StudentID] {
+			delete(s.contacts, id)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang-assignment/internal/course"
+)
+
+// CourseStore keeps the catalog in a map keyed by the upper case code, so
+// lookups ignore case like the MySQL collation does
+type CourseStore struct {
+	mu          sync.RWMutex
+	courses     map[string]course.Course
+	students    *StudentStore
+	enrollments *EnrollmentStore
+}
+
+// NewCourseStore returns an empty catalog, students is checked before a
+// course is deleted
+func NewCourseStore(students *StudentStore) *CourseStore {
+	return &CourseStore{courses: map[string]course.Course{}, students: students}
+}
+
+func (s *CourseStore) GetCourse(ctx context.Context, code string) (course.Course, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	c, ok := s.courses[strings.ToUpper(code)]
+	if !ok {
+		return course.Course{}, fmt.Errorf("course %s not found: %w", code, course.ErrCourseNotFound)
+	}
+	return c, nil
+}
+
+func (s *CourseStore) ListCourses(ctx context.Context, includeInactive bool) ([]course.Course, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	courses := make([]course.Course, 0, len(s.courses))
+	for _, c := range s.courses {
+		if c.Active || includeInactive {
+			courses = append(courses, c)
+		}
+	}
+	sort.Slice(courses, func(i, j int) bool { return courses[i].Code < courses[j].Code })
+	return courses, nil
+}
+
+func (s *CourseStore) CreateCourse(ctx context.Context, c course.Course) (course.Course, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := strings.ToUpper(c.Code)
+	if _, exists := s.courses[key]; exists {
+		return course.Course{}, fmt.Errorf("course %s: %w", c.Code, course.ErrDuplicateCourse)
+	}
+	c.CreatedOn = time.Now()
+	c.UpdatedOn = c.CreatedOn
+	s.courses[key] = c
+	return c, nil
+}
+
+func (s *CourseStore) UpdateCourse(ctx context.Context, c course.Course) (course.Course, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := strings.ToUpper(c.Code)
+	existing, ok := s.courses[key]
+	if !ok {
+		return course.Course{}, fmt.Errorf("no rows were updated, course %s might not exist: %w", c.Code, course.ErrCourseNotFound)
+	}
+	// the code and creation are never part of an update
+	c.Code = existing.Code
+	c.CreatedBy = existing.CreatedBy
+	c.CreatedOn = existing.CreatedOn
+	c.UpdatedOn = time.Now()
+	s.courses[key] = c
+	return c, nil
+}
+
+func (s *CourseStore) DeleteCourse(ctx context.Context, code string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := strings.ToUpper(code)
+	if _, ok := s.courses[key]; !ok {
+		return fmt.Errorf("course %s not found: %w", code, course.ErrCourseNotFound)
+	}
+	if s.students.hasCourse(code) || (s.enrollments != nil && s.enrollments.hasCourse(code)) {
+		return fmt.Errorf("course %s: %w", code, course.ErrCourseInUse)
+	}
+	delete(s.courses, key)
+	return nil
+}
@@ -0,0 +1,92 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"golang-assignment/internal/customfield"
+)
+
+// CustomFieldStore keeps the definitions in a map keyed by name
+type CustomFieldStore struct {
+	mu       sync.RWMutex
+	fields   map[string]customfield.Definition
+	students *StudentStore
+}
+
+// NewCustomFieldStore returns a store without definitions, the values of a
+// deleted field are removed from students
+func NewCustomFieldStore(students *StudentStore) *CustomFieldStore {
+	return &CustomFieldStore{fields: map[string]customfield.Definition{}, students: students}
+}
+
+func (s *CustomFieldStore) ListFields(ctx context.Context) ([]customfield.Definition, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	fields := make([]customfield.Definition, 0, len(s.fields))
+	for _, d := range s.fields {
+		fields = append(fields, d)
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Name < fields[j].Name })
+	return fields, nil
+}
+
+func (s *CustomFieldStore) GetField(ctx context.Context, name string) (customfield.Definition, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	d, ok := s.fields[name]
+	if !ok {
+		return customfield.Definition{}, fmt.Errorf("custom field %s not found: %w", name, customfield.ErrFieldNotFound)
+	}
+	return d, nil
+}
+
+func (s *CustomFieldStore) CreateField(ctx context.Context, d customfield.Definition) (customfield.Definition, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.fields[d.Name]; exists {
+		return customfield.Definition{}, fmt.Errorf("custom field %s: %w", d.Name, customfield.ErrDuplicateField)
+	}
+	d.CreatedOn = time.Now()
+	d.UpdatedOn = d.CreatedOn
+	s.fields[d.Name] = d
+	return d, nil
+}
+
+func (s *CustomFieldStore) UpdateField(ctx context.Context, d customfield.Definition) (customfield.Definition, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.fields[d.Name]
+	if !ok {
+		return customfield.Definition{}, fmt.Errorf("no rows were updated, custom field %s might not exist: %w", d.Name, customfield.ErrFieldNotFound)
+	}
+	existing.Label = d.Label
+	existing.Required = d.Required
+	existing.EnumValues = d.EnumValues
+	existing.Pattern = d.Pattern
+	existing.UpdatedBy = d.UpdatedBy
+	existing.UpdatedOn = time.Now()
+	s.fields[d.Name] = existing
+	return existing, nil
+}
+
+// DeleteField holds the lock of the definitions while the values are
+// removed, the students are always locked after it
+func (s *CustomFieldStore) DeleteField(ctx context.Context, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.fields[name]; !ok {
+		return fmt.Errorf("custom field %s not found: %w", name, customfield.ErrFieldNotFound)
+	}
+	delete(s.fields, name)
+	s.students.dropCustomField(name)
+	return nil
+}
@@ -0,0 +1,264 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang-assignment/internal/course"
+	"golang-assignment/internal/enrollment"
+)
+
+// EnrollmentStore keeps enrollments in a map keyed by id. Changes to the
+// seats hold the read lock of the catalog, which is always taken before the
+// lock of the enrollments.
+type EnrollmentStore struct {
+	mu          sync.RWMutex
+	enrollments map[int64]enrollment.Enrollment
+	lastID      int64
+	students    *StudentStore
+	courses     *CourseStore
+}
+
+// NewEnrollmentStore returns an empty store. It registers itself with the
+// students, whose purge drops their enrollments, and with the courses, which
+// are in use while they have an enrollment.
+func NewEnrollmentStore(students *StudentStore, courses *CourseStore) *EnrollmentStore {
+	e := &EnrollmentStore{enrollments: map[int64]enrollment.Enrollment{}, students: students, courses: courses}
+	students.onPurge(e.removeStudents)
+	courses.enrollments = e
+	return e
+}
+
+func (s *EnrollmentStore) GetEnrollment(ctx context.Context, id int64) (enrollment.Enrollment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	e, ok := s.enrollments[id]
+	if !ok {
+		return enrollment.Enrollment{}, fmt.Errorf("enrollment %d not found: %w", id, enrollment.ErrEnrollmentNotFound)
+	}
+	return e, nil
+}
+
+// list returns the enrollments kept by keep ordered by less
+func (s *EnrollmentStore) list(keep func(enrollment.Enrollment) bool, less func(a, b enrollment.Enrollment) bool) []enrollment.Enrollment {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	enrollments := []enrollment.Enrollment{}
+	for _, e := range s.enrollments {
+		if keep(e) {
+			enrollments = append(enrollments, e)
+		}
+	}
+	sort.Slice(enrollments, func(i, j int) bool { return less(enrollments[i], enrollments[j]) })
+	return enrollments
+}
+
+// enrolledBefore orders enrollments the way the waitlist is served
+func enrolledBefore(a, b enrollment.Enrollment) bool {
+	if !a.EnrolledOn.Equal(b.EnrolledOn) {
+		return a.EnrolledOn.Before(b.EnrolledOn)
+	}
+	return a.ID < b.ID
+}
+
+func (s *EnrollmentStore) ListStudentEnrollments(ctx context.Context, studentID, term string) ([]enrollment.Enrollment, error) {
+	return s.list(func(e enrollment.Enrollment) bool {
+		return e.StudentID == studentID && (term == "" || e.Term == term)
+	}, func(a, b enrollment.Enrollment) bool {
+		if a.Term != b.Term {
+			return a.Term < b.Term
+		}
+		if a.Course != b.Course {
+			return a.Course < b.Course
+		}
+		return a.ID < b.ID
+	}), nil
+}
+
+func (s *EnrollmentStore) ListRoster(ctx context.Context, code string, filter enrollment.RosterFilter) ([]enrollment.Enrollment, error) {
+	return s.list(func(e enrollment.Enrollment) bool {
+		return strings.EqualFold(e.Course, code) &&
+			(filter.Term == "" || e.Term == filter.Term) &&
+			(filter.Status == "" || e.Status == filter.Status)
+	}, func(a, b enrollment.Enrollment) bool {
+		if a.Term != b.Term {
+			return a.Term < b.Term
+		}
+		return enrolledBefore(a, b)
+	}), nil
+}
+
+// capacity returns the capacity of the course, the caller holds the read
+// lock of the catalog
+func (s *EnrollmentStore) capacity(code string) (int, error) {
+	c, ok := s.courses.courses[strings.ToUpper(code)]
+	if !ok {
+		return 0, fmt.Errorf("course %s not found: %w", code, course.ErrCourseNotFound)
+	}
+	return c.Capacity, nil
+}
+
+// takenSeats counts the enrollments of the course and term holding a seat,
+// the caller holds the lock
+func (s *EnrollmentStore) takenSeats(code, term string) int {
+	taken := 0
+	for _, e := range s.enrollments {
+		if strings.EqualFold(e.Course, code) && e.Term == term && e.Status.HoldsSeat() {
+			taken++
+		}
+	}
+	return taken
+}
+
+// fillSeats promotes the oldest waitlisted enrollments of the course and term
+// into its free seats, the caller holds the lock
+func (s *EnrollmentStore) fillSeats(code, term string, capacity int, updatedBy string, now time.Time) int64 {
+	var waitlist []enrollment.Enrollment
+	for _, e := range s.enrollments {
+		if strings.EqualFold(e.Course, code) && e.Term == term && e.Status == enrollment.StatusWaitlisted {
+			waitlist = append(waitlist, e)
+		}
+	}
+	sort.Slice(waitlist, func(i, j int) bool { return enrolledBefore(waitlist[i], waitlist[j]) })
+	if capacity > 0 {
+		free := capacity - s.takenSeats(code, term)
+		if free <= 0 {
+			return 0
+		}
+		if free < len(waitlist) {
+			waitlist = waitlist[:free]
+		}
+	}
+	for _, e := range waitlist {
+		e.Status = enrollment.StatusActive
+		e.UpdatedBy = updatedBy
+		e.UpdatedOn = now
+		s.enrollments[e.ID] = e
+	}
+	return int64(len(waitlist))
+}
+
+func (s *EnrollmentStore) Enroll(ctx context.Context, e enrollment.Enrollment) (enrollment.Enrollment, error) {
+	s.courses.mu.RLock()
+	defer s.courses.mu.RUnlock()
+	capacity, err := s.capacity(e.Course)
+	if err != nil {
+		return enrollment.Enrollment{}, err
+	}
+	if _, err := s.students.GetStudent(ctx, e.StudentID); err != nil {
+		return enrollment.Enrollment{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.enrollments {
+		if existing.StudentID == e.StudentID && strings.EqualFold(existing.Course, e.Course) &&
+			existing.Term == e.Term && existing.Status != enrollment.StatusWithdrawn {
+			return enrollment.Enrollment{}, fmt.Errorf("student %s in %s for %s: %w", e.StudentID, e.Course, e.Term, enrollment.ErrAlreadyEnrolled)
+		}
+	}
+	e.EnrolledOn = time.Now()
+	e.UpdatedOn = e.EnrolledOn
+	// the waitlist is served before the newcomer
+	s.fillSeats(e.Course, e.Term, capacity, e.EnrolledBy, e.EnrolledOn)
+	e.Status = enrollment.StatusActive
+	if capacity > 0 && s.takenSeats(e.Course, e.Term) >= capacity {
+		e.Status = enrollment.StatusWaitlisted
+	}
+	s.lastID++
+	e.ID = s.lastID
+	s.enrollments[e.ID] = e
+	return e, nil
+}
+
+func (s *EnrollmentStore) ChangeStatus(ctx context.Context, id int64, status enrollment.Status, updatedBy string) (enrollment.Enrollment, error) {
+	s.courses.mu.RLock()
+	defer s.courses.mu.RUnlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.enrollments[id]
+	if !ok {
+		return enrollment.Enrollment{}, fmt.Errorf("enrollment %d not found: %w", id, enrollment.ErrEnrollmentNotFound)
+	}
+	if err := e.ChangeStatus(status); err != nil {
+		return enrollment.Enrollment{}, err
+	}
+	capacity, err := s.capacity(e.Course)
+	if err != nil {
+		return enrollment.Enrollment{}, err
+	}
+
+	freed := e.Status.HoldsSeat() && !status.HoldsSeat()
+	e.Status = status
+	e.UpdatedBy = updatedBy
+	e.UpdatedOn = time.Now()
+	s.enrollments[id] = e
+	if freed {
+		s.fillSeats(e.Course, e.Term, capacity, updatedBy, e.UpdatedOn)
+	}
+	return e, nil
+}
+
+func (s *EnrollmentStore) FillSeats(ctx context.Context, code, updatedBy string) (int64, error) {
+	s.courses.mu.RLock()
+	defer s.courses.mu.RUnlock()
+	capacity, err := s.capacity(code)
+	if err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	terms := map[string]bool{}
+	for _, e := range s.enrollments {
+		if strings.EqualFold(e.Course, code) && e.Status == enrollment.StatusWaitlisted {
+			terms[e.Term] = true
+		}
+	}
+	now := time.Now()
+	var promoted int64
+	for term := range terms {
+		promoted += s.fillSeats(code, term, capacity, updatedBy, now)
+	}
+	return promoted, nil
+}
+
+// hasCourse reports whether an enrollment, whatever its status, is in the
+// course
+func (s *EnrollmentStore) hasCourse(code string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, e := range s.enrollments {
+		if strings.EqualFold(e.Course, code) {
+			return true
+		}
+	}
+	return false
+}
+
+// removeStudents drops the enrollments of purged students, like the cascade
+// of the database
+func (s *EnrollmentStore) removeStudents(ids []string) {
+	purged := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		purged[id] = true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, e := range s.enrollments {
+		if purged[e.StudentID] {
+			delete(s.enrollments, id)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"golang-assignment/internal/student"
+)
+
+// GradeStore keeps the assessments and final grades by enrollment
+type GradeStore struct {
+	mu          sync.RWMutex
+	assessments map[int64][]student.Assessment
+	finals      map[int64]student.FinalGrade
+	lastID      int64
+}
+
+func NewGradeStore() *GradeStore {
+	return &GradeStore{assessments: map[int64][]student.Assessment{}, finals: map[int64]student.FinalGrade{}}
+}
+
+func (s *GradeStore) AddAssessment(ctx context.Context, a student.Assessment) (student.Assessment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastID++
+	a.ID = s.lastID
+	a.RecordedOn = time.Now()
+	s.assessments[a.EnrollmentID] = append(s.assessments[a.EnrollmentID], a)
+	return a, nil
+}
+
+func (s *GradeStore) ListAssessments(ctx context.Context, enrollmentID int64) ([]student.Assessment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return append([]student.Assessment{}, s.assessments[enrollmentID]...), nil
+}
+
+func (s *GradeStore) SetFinalGrade(ctx context.Context, f student.FinalGrade) (student.FinalGrade, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f.GradedOn = time.Now()
+	s.finals[f.EnrollmentID] = f
+	return f, nil
+}
+
+func (s *GradeStore) ListFinalGrades(ctx context.Context, enrollmentIDs []int64) ([]student.FinalGrade, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var grades []student.FinalGrade
+	for _, id := range enrollmentIDs {
+		if f, ok := s.finals[id]; ok {
+			grades = append(grades, f)
+		}
+	}
+	sort.Slice(grades, func(i, j int) bool { return grades[i].EnrollmentID < grades[j].EnrollmentID })
+	return grades, nil
+}
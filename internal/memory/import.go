@@ -0,0 +1,59 @@
+package memory
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"golang-assignment/internal/student"
+)
+
+// PostStudents checks every student before storing any, so a conflict leaves
+// the store as it was
+func (s *StudentStore) PostStudents(ctx context.Context, studs []student.Student, entries []student.AuditEntry) ([]student.Student, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	emails := map[string]string{}
+	for _, stud := range studs {
+		if _, exists := s.students[stud.ID]; exists {
+			return nil, &student.ConflictError{Err: student.ErrDuplicateStudent, Field: "id", ConflictingID: stud.ID}
+		}
+		if err := s.emailConflict(stud.ID, stud.Email); err != nil {
+			return nil, err
+		}
+		if id, ok := emails[strings.ToLower(stud.Email)]; ok {
+			return nil, &student.ConflictError{Err: student.ErrDuplicateEmail, Field: "email", ConflictingID: id}
+		}
+		emails[strings.ToLower(stud.Email)] = stud.ID
+	}
+
+	now := time.Now()
+	created := make([]student.Student, len(studs))
+	for i, stud := range studs {
+		stud.CreatedOn = now
+		stud.UpdatedOn = now
+		stud.Version = 1
+		s.students[stud.ID] = stud
+		created[i] = stud
+	}
+	s.audit.add(entries...)
+	return created, nil
+}
+
+func (s *StudentStore) FindConflicts(ctx context.Context, studs []student.Student) ([][]*student.ConflictError, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	conflicts := make([][]*student.ConflictError, len(studs))
+	for i, stud := range studs {
+		// IDs stay taken while their student is in the trash
+		if _, exists := s.students[stud.ID]; exists && stud.ID != "" {
+			conflicts[i] = append(conflicts[i], &student.ConflictError{Err: student.ErrDuplicateStudent, Field: "id", ConflictingID: stud.ID})
+		}
+		if err := s.emailConflict(stud.ID, stud.Email); err != nil {
+			conflicts[i] = append(conflicts[i], err.(*student.ConflictError))
+		}
+	}
+	return conflicts, nil
+}
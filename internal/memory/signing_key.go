@@ -0,0 +1,43 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"golang-assignment/internal/auth"
+)
+
+type SigningKeyStore struct {
+	mu   sync.Mutex
+	keys []auth.Key
+}
+
+func NewSigningKeyStore() *SigningKeyStore {
+	return &SigningKeyStore{}
+}
+
+func (s *SigningKeyStore) ListSigningKeys(ctx context.Context) ([]auth.Key, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]auth.Key(nil), s.keys...), nil
+}
+
+func (s *SigningKeyStore) CreateSigningKey(ctx context.Context, key auth.Key) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = append(s.keys, key)
+	return nil
+}
+
+func (s *SigningKeyStore) DeleteSigningKey(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, key := range s.keys {
+		if key.ID == id {
+			s.keys = append(s.keys[:i], s.keys[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
@@ -0,0 +1,524 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang-assignment/internal/student"
+	"golang-assignment/internal/tag"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// StudentStore keeps students in a map. It behaves like
+// database.StudentStore so the API can run without MySQL.
+type StudentStore struct {
+	mu       sync.RWMutex
+	students map[string]student.Student
+	// purgeHooks drop what the other stores keep about purged students,
+	// like the cascades of the database
+	purgeHooks []func(ids []string)
+	// tags are the tags of each student keyed by ID and then by tag name,
+	// they are written by the TagStore
+	tags map[string]map[string]tag.StudentTag
+	// audit records the entry of each write
+	audit *AuditStore
+}
+
+func NewStudentStore(audit *AuditStore) *StudentStore {
+	return &StudentStore{students: map[string]student.Student{}, tags: map[string]map[string]tag.StudentTag{}, audit: audit}
+}
+
+func (s *StudentStore) Ping(ctx context.Context) error {
+	return ctx.Err()
+}
+
+func (s *StudentStore) GetStudent(ctx context.Context, id string) (student.Student, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stud, ok := s.live(id)
+	if !ok {
+		return student.Student{}, fmt.Errorf("student with ID %s not found: %w", id, student.ErrNoStudentFound)
+	}
+	return stud, nil
+}
+
+// live returns the student unless it is missing or in the trash, the caller
+// holds the lock
+func (s *StudentStore) live(id string) (student.Student, bool) {
+	stud, ok := s.students[id]
+	return stud, ok && stud.DeletedOn == nil
+}
+
+func (s *StudentStore) PostStudent(ctx context.Context, stud student.Student, entry student.AuditEntry) (student.Student, error) {
+	log.Printf("Creating student: CreatedBy=%s, UpdatedBy=%s", stud.CreatedBy, stud.UpdatedBy)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.students[stud.ID]; exists {
+		return student.Student{}, &student.ConflictError{Err: student.ErrDuplicateStudent, Field: "id", ConflictingID: stud.ID}
+	}
+	if err := s.emailConflict(stud.ID, stud.Email); err != nil {
+		return student.Student{}, err
+	}
+	stud.CreatedOn = time.Now()
+	stud.UpdatedOn = stud.CreatedOn
+	stud.Version = 1
+	s.students[stud.ID] = stud
+	s.audit.add(entry)
+	return stud, nil
+}
+
+func (s *StudentStore) UpdateStudent(ctx context.Context, id string, stud student.Student, entry student.AuditEntry) (student.Student, error) {
+	if id != stud.ID {
+		return student.Student{}, fmt.Errorf("%w: mismatching student ID", student.ErrInvalidStudent)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.live(id)
+	if !ok {
+		return student.Student{}, fmt.Errorf("no rows were updated, student with ID %s might not exist: %w", id, student.ErrNoStudentFound)
+	}
+	if existing.Version != stud.Version {
+		return student.Student{}, versionMismatchError(existing, stud.Version)
+	}
+	if err := s.emailConflict(id, stud.Email); err != nil {
+		return student.Student{}, err
+	}
+	stud.Version++
+	// created_on is never part of an update
+	stud.CreatedOn = existing.CreatedOn
+	s.students[id] = stud
+	s.audit.add(entry)
+	return stud, nil
+}
+
+func (s *StudentStore) PatchStudent(ctx context.Context, id string, version int64, patch student.StudentPatch, entry student.AuditEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.live(id)
+	if !ok {
+		return fmt.Errorf("student with ID %s not found: %w", id, student.ErrNoStudentFound)
+	}
+	if existing.Version != version {
+		return versionMismatchError(existing, version)
+	}
+	if patch.Email != nil {
+		if err := s.emailConflict(id, *patch.Email); err != nil {
+			return err
+		}
+	}
+	patched := patch.Apply(existing)
+	patched.Version++
+	s.students[id] = patched
+	s.audit.add(entry)
+	return nil
+}
+
+// DeleteStudent moves the student to the trash
+func (s *StudentStore) DeleteStudent(ctx context.Context, id string, version int64, deletedBy string, entry student.AuditEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.live(id)
+	if !ok {
+		return fmt.Errorf("student with ID %s not found: %w", id, student.ErrNoStudentFound)
+	}
+	if existing.Version != version {
+		return versionMismatchError(existing, version)
+	}
+	now := time.Now()
+	existing.DeletedBy = deletedBy
+	existing.DeletedOn = &now
+	existing.Version++
+	s.students[id] = existing
+	s.audit.add(entry)
+	return nil
+}
+
+func (s *StudentStore) RestoreStudent(ctx context.Context, id, restoredBy string, entry student.AuditEntry) (student.Student, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.students[id]
+	if !ok || existing.DeletedOn == nil {
+		return student.Student{}, fmt.Errorf("no deleted student with ID %s: %w", id, student.ErrNoStudentFound)
+	}
+	if err := s.emailConflict(id, existing.Email); err != nil {
+		return student.Student{}, err
+	}
+	existing.DeletedBy = ""
+	existing.DeletedOn = nil
+	existing.UpdatedBy = restoredBy
+	existing.UpdatedOn = time.Now()
+	existing.Version++
+	s.students[id] = existing
+	s.audit.add(entry)
+	return existing, nil
+}
+
+func (s *StudentStore) PurgeDeletedStudents(ctx context.Context, deletedBefore time.Time, entry student.AuditEntry) (int64, error) {
+	s.mu.Lock()
+	var purged []string
+	var entries []student.AuditEntry
+	for id, stud := range s.students {
+		if stud.DeletedOn != nil && stud.DeletedOn.Before(deletedBefore) {
+			delete(s.students, id)
+			delete(s.tags, id)
+			purged = append(purged, id)
+			entry.StudentID = id
+			entries = append(entries, entry)
+		}
+	}
+	s.audit.add(entries...)
+	// the other stores are locked after the students are released, their
+	// locks are never taken before the one of the students
+	hooks := s.purgeHooks
+	s.mu.Unlock()
+
+	if len(purged) > 0 {
+		for _, hook := range hooks {
+			hook(purged)
+		}
+	}
+	return int64(len(purged)), nil
+}
+
+// onPurge registers a store dropping the data of purged students
+func (s *StudentStore) onPurge(hook func(ids []string)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.purgeHooks = append(s.purgeHooks, hook)
+}
+
+// dropCustomField removes the value of the field from every student, even
+// one in the trash, moving it to a new version
+func (s *StudentStore) dropCustomField(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, stud := range s.students {
+		if _, ok := stud.CustomFields[name]; !ok {
+			continue
+		}
+		// the map may be shared with a student already returned
+		fields := make(student.CustomFields, len(stud.CustomFields)-1)
+		for k, v := range stud.CustomFields {
+			if k != name {
+				fields[k] = v
+			}
+		}
+		if len(fields) == 0 {
+			fields = nil
+		}
+		stud.CustomFields = fields
+		stud.Version++
+		s.students[id] = stud
+	}
+}
+
+// liveIDs splits ids into the students out of the trash and the others. The
+// caller holds the lock.
+func (s *StudentStore) liveIDs(ids []string) (found, notFound []string) {
+	notFound = []string{}
+	for _, id := range ids {
+		if _, ok := s.live(id); ok {
+			found = append(found, id)
+		} else {
+			notFound = append(notFound, id)
+		}
+	}
+	return found, notFound
+}
+
+// tagStudents gives the tag to the students of ids out of the trash, the ones
+// having it already keep who tagged them and when
+func (s *StudentStore) tagStudents(name string, ids []string, taggedBy string) tag.BulkResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	found, notFound := s.liveIDs(ids)
+	result := tag.BulkResult{Matched: len(found), NotFound: notFound}
+	now := time.Now()
+	for _, id := range found {
+		if _, ok := s.tags[id][name]; ok {
+			continue
+		}
+		if s.tags[id] == nil {
+			s.tags[id] = map[string]tag.StudentTag{}
+		}
+		s.tags[id][name] = tag.StudentTag{Name: name, TaggedBy: taggedBy, TaggedOn: now}
+		result.Changed++
+	}
+	return result
+}
+
+// untagStudents takes the tag away from the students of ids out of the trash
+func (s *StudentStore) untagStudents(name string, ids []string) tag.BulkResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	found, notFound := s.liveIDs(ids)
+	result := tag.BulkResult{Matched: len(found), NotFound: notFound}
+	for _, id := range found {
+		if _, ok := s.tags[id][name]; ok {
+			delete(s.tags[id], name)
+			result.Changed++
+		}
+	}
+	return result
+}
+
+// dropTag takes the tag away from every student, even one in the trash
+func (s *StudentStore) dropTag(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, tags := range s.tags {
+		delete(tags, name)
+	}
+}
+
+// tagCounts returns the number of students out of the trash having each tag
+func (s *StudentStore) tagCounts() map[string]int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	counts := map[string]int{}
+	for id, tags := range s.tags {
+		if _, ok := s.live(id); !ok {
+			continue
+		}
+		for name := range tags {
+			counts[name]++
+		}
+	}
+	return counts
+}
+
+// studentTags returns the tags of a student ordered by name
+func (s *StudentStore) studentTags(id string) []tag.StudentTag {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tags := make([]tag.StudentTag, 0, len(s.tags[id]))
+	for _, t := range s.tags[id] {
+		tags = append(tags, t)
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Name < tags[j].Name })
+	return tags
+}
+
+// hasCourse reports whether a student, even one in the trash, has the course
+func (s *StudentStore) hasCourse(code string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, stud := range s.students {
+		if strings.EqualFold(stud.Course, code) {
+			return true
+		}
+	}
+	return false
+}
+
+// emailConflict returns a ConflictError when a live student other than id
+// uses the email, ignoring case like the unique key of the database. The
+// caller holds the lock.
+func (s *StudentStore) emailConflict(id, email string) error {
+	for _, stud := range s.students {
+		if stud.ID != id && stud.DeletedOn == nil && strings.EqualFold(stud.Email, email) {
+			return &student.ConflictError{Err: student.ErrDuplicateEmail, Field: "email", ConflictingID: stud.ID}
+		}
+	}
+	return nil
+}
+
+func versionMismatchError(existing student.Student, version int64) error {
+	return fmt.Errorf("student with ID %s is at version %d, not %d: %w", existing.ID, existing.Version, version, student.ErrVersionMismatch)
+}
+
+// sorted returns the students matching keep ordered by column and then by id
+func (s *StudentStore) sorted(column string, desc bool, keep func(student.Student) bool) []student.Student {
+	s.mu.RLock()
+	students := make([]student.Student, 0, len(s.students))
+	for _, stud := range s.students {
+		if keep(stud) {
+			students = append(students, stud)
+		}
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(students, func(i, j int) bool {
+		c := compareColumn(students[i], students[j], column)
+		if c == 0 {
+			c = strings.Compare(students[i].ID, students[j].ID)
+		}
+		if desc {
+			return c > 0
+		}
+		return c < 0
+	})
+	return students
+}
+
+func compareColumn(a, b student.Student, column string) int {
+	switch column {
+	case "id":
+		return strings.Compare(a.ID, b.ID)
+	case "created_by":
+		return strings.Compare(a.CreatedBy, b.CreatedBy)
+	case "created_on":
+		return a.CreatedOn.Compare(b.CreatedOn)
+	case "updated_by":
+		return strings.Compare(a.UpdatedBy, b.UpdatedBy)
+	case "updated_on":
+		return a.UpdatedOn.Compare(b.UpdatedOn)
+	case "name":
+		return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+	case "email":
+		return strings.Compare(strings.ToLower(a.Email), strings.ToLower(b.Email))
+	case "age":
+		return a.Age - b.Age
+	case "course":
+		return strings.Compare(strings.ToLower(a.Course), strings.ToLower(b.Course))
+	}
+	return 0
+}
+
+func (s *StudentStore) ListStudents(ctx context.Context, after student.ListCursor, limit int) ([]student.Student, error) {
+	return s.listStudents(false, after, limit), nil
+}
+
+func (s *StudentStore) ListDeletedStudents(ctx context.Context, after student.ListCursor, limit int) ([]student.Student, error) {
+	return s.listStudents(true, after, limit), nil
+}
+
+// listStudents lists the students in or out of the trash after the cursor
+func (s *StudentStore) listStudents(deleted bool, after student.ListCursor, limit int) []student.Student {
+	students := s.sorted("created_on", false, func(stud student.Student) bool {
+		if (stud.DeletedOn != nil) != deleted {
+			return false
+		}
+		if after.ID == "" {
+			return true
+		}
+		return stud.CreatedOn.After(after.CreatedOn) ||
+			(stud.CreatedOn.Equal(after.CreatedOn) && stud.ID > after.ID)
+	})
+	if len(students) > limit {
+		students = students[:limit]
+	}
+	return students
+}
+
+// matchesFilter reports whether the student matches f. The caller holds the
+// lock.
+func (s *StudentStore) matchesFilter(stud student.Student, f student.SearchFilter) bool {
+	if f.Query != "" {
+		q := strings.ToLower(f.Query)
+		if !strings.Contains(strings.ToLower(stud.Name), q) && !strings.Contains(strings.ToLower(stud.Email), q) {
+			return false
+		}
+	}
+	for _, name := range f.Tags {
+		if _, ok := s.tags[stud.ID][name]; !ok {
+			return false
+		}
+	}
+	if f.Course != "" && !strings.EqualFold(stud.Course, f.Course) {
+		return false
+	}
+	if f.CreatedBy != "" && !strings.EqualFold(stud.CreatedBy, f.CreatedBy) {
+		return false
+	}
+	if f.MinAge > 0 && stud.Age < f.MinAge {
+		return false
+	}
+	if f.MaxAge > 0 && stud.Age > f.MaxAge {
+		return false
+	}
+	if !f.CreatedFrom.IsZero() && stud.CreatedOn.Before(f.CreatedFrom) {
+		return false
+	}
+	if !f.CreatedTo.IsZero() && stud.CreatedOn.After(f.CreatedTo) {
+		return false
+	}
+	if !f.UpdatedFrom.IsZero() && stud.UpdatedOn.Before(f.UpdatedFrom) {
+		return false
+	}
+	if !f.UpdatedTo.IsZero() && stud.UpdatedOn.After(f.UpdatedTo) {
+		return false
+	}
+	for _, cf := range f.CustomFields {
+		// the values are decoded from JSON like the filter ones, so 3
+		// matches 3.0 but not "3"
+		if value, ok := stud.CustomFields[cf.Name]; !ok || value != cf.Value {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *StudentStore) SearchStudents(ctx context.Context, f student.SearchFilter) (student.SearchResult, error) {
+	sortBy := "created_on"
+	if f.SortBy != "" {
+		if !student.SortableColumns[f.SortBy] {
+			return student.SearchResult{}, fmt.Errorf("cannot sort on %q", f.SortBy)
+		}
+		sortBy = f.SortBy
+	}
+
+	matches := s.sorted(sortBy, f.SortDesc, func(stud student.Student) bool {
+		return stud.DeletedOn == nil && s.matchesFilter(stud, f)
+	})
+
+	result := student.SearchResult{
+		Total: len(matches),
+		Facets: map[string]map[string]int{
+			"course":     {},
+			"created_by": {},
+		},
+	}
+	for _, stud := range matches {
+		result.Facets["course"][stud.Course]++
+		result.Facets["created_by"][stud.CreatedBy]++
+	}
+
+	start := min(f.Offset, len(matches))
+	end := min(start+f.Limit, len(matches))
+	result.Students = matches[start:end]
+	return result, nil
+}
+
+func (s *StudentStore) ExportStudents(ctx context.Context, f student.SearchFilter, emit func(student.Student) error) error {
+	sortBy := "created_on"
+	if f.SortBy != "" {
+		if !student.SortableColumns[f.SortBy] {
+			return fmt.Errorf("cannot sort on %q", f.SortBy)
+		}
+		sortBy = f.SortBy
+	}
+
+	matches := s.sorted(sortBy, f.SortDesc, func(stud student.Student) bool {
+		return stud.DeletedOn == nil && s.matchesFilter(stud, f)
+	})
+	for _, stud := range matches {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := emit(stud); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,14 @@
+package memory
+
+import (
+	"testing"
+
+	"golang-assignment/internal/student"
+	"golang-assignment/internal/student/storetest"
+)
+
+func TestStudentStore(t *testing.T) {
+	storetest.TestStudentStore(t, func(t *testing.T) student.StudentStore {
+		return NewStudentStore(NewAuditStore())
+	})
+}
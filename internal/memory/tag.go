@@ -0,0 +1,118 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"golang-assignment/internal/tag"
+)
+
+// TagStore keeps the tags in a map keyed by name, the tags of each student
+// are kept by the StudentStore so a search can filter on them
+type TagStore struct {
+	mu       sync.RWMutex
+	tags     map[string]tag.Tag
+	students *StudentStore
+}
+
+func NewTagStore(students *StudentStore) *TagStore {
+	return &TagStore{tags: map[string]tag.Tag{}, students: students}
+}
+
+func (s *TagStore) ListTags(ctx context.Context) ([]tag.Tag, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	counts := s.students.tagCounts()
+	tags := make([]tag.Tag, 0, len(s.tags))
+	for _, t := range s.tags {
+		t.Students = counts[t.Name]
+		tags = append(tags, t)
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Name < tags[j].Name })
+	return tags, nil
+}
+
+func (s *TagStore) GetTag(ctx context.Context, name string) (tag.Tag, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t, ok := s.tags[name]
+	if !ok {
+		return tag.Tag{}, fmt.Errorf("tag %s not found: %w", name, tag.ErrTagNotFound)
+	}
+	t.Students = s.students.tagCounts()[name]
+	return t, nil
+}
+
+func (s *TagStore) CreateTag(ctx context.Context, t tag.Tag) (tag.Tag, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.tags[t.Name]; exists {
+		return tag.Tag{}, fmt.Errorf("tag %s: %w", t.Name, tag.ErrDuplicateTag)
+	}
+	t.CreatedOn = time.Now()
+	t.UpdatedOn = t.CreatedOn
+	s.tags[t.Name] = t
+	return t, nil
+}
+
+func (s *TagStore) UpdateTag(ctx context.Context, t tag.Tag) (tag.Tag, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.tags[t.Name]
+	if !ok {
+		return tag.Tag{}, fmt.Errorf("no rows were updated, tag %s might not exist: %w", t.Name, tag.ErrTagNotFound)
+	}
+	existing.Description = t.Description
+	existing.UpdatedBy = t.UpdatedBy
+	existing.UpdatedOn = time.Now()
+	s.tags[t.Name] = existing
+	existing.Students = s.students.tagCounts()[t.Name]
+	return existing, nil
+}
+
+// DeleteTag holds the lock of the tags while the students lose the tag, the
+// students are always locked after it
+func (s *TagStore) DeleteTag(ctx context.Context, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.tags[name]; !ok {
+		return fmt.Errorf("tag %s not found: %w", name, tag.ErrTagNotFound)
+	}
+	delete(s.tags, name)
+	s.students.dropTag(name)
+	return nil
+}
+
+// TagStudents holds the lock of the tags so the tag cannot be deleted
+// meanwhile
+func (s *TagStore) TagStudents(ctx context.Context, name string, ids []string, taggedBy string) (tag.BulkResult, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.tags[name]; !ok {
+		return tag.BulkResult{}, fmt.Errorf("tag %s not found: %w", name, tag.ErrTagNotFound)
+	}
+	return s.students.tagStudents(name, ids, taggedBy), nil
+}
+
+func (s *TagStore) UntagStudents(ctx context.Context, name string, ids []string) (tag.BulkResult, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.tags[name]; !ok {
+		return tag.BulkResult{}, fmt.Errorf("tag %s not found: %w", name, tag.ErrTagNotFound)
+	}
+	return s.students.untagStudents(name, ids), nil
+}
+
+func (s *TagStore) ListStudentTags(ctx context.Context, studentID string) ([]tag.StudentTag, error) {
+	return s.students.studentTags(studentID), nil
+}
@@ -0,0 +1,107 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"golang-assignment/internal/student"
+)
+
+type TokenStore struct {
+	mu      sync.Mutex
+	refresh map[string]student.RefreshToken
+	// revoked access tokens by jti, with their expiry
+	revoked map[string]time.Time
+}
+
+func NewTokenStore() *TokenStore {
+	return &TokenStore{
+		refresh: map[string]student.RefreshToken{},
+		revoked: map[string]time.Time{},
+	}
+}
+
+func (s *TokenStore) CreateRefreshToken(ctx context.Context, t student.RefreshToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refresh[t.Hash] = t
+	return nil
+}
+
+func (s *TokenStore) GetRefreshToken(ctx context.Context, hash string) (student.RefreshToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.refresh[hash]
+	if !ok {
+		return student.RefreshToken{}, student.ErrTokenNotFound
+	}
+	return t, nil
+}
+
+func (s *TokenStore) UseRefreshToken(ctx context.Context, hash string, usedOn time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.refresh[hash]
+	if !ok || !t.UsedOn.IsZero() || !t.RevokedOn.IsZero() {
+		return false, nil
+	}
+	t.UsedOn = usedOn
+	s.refresh[hash] = t
+	return true, nil
+}
+
+func (s *TokenStore) revokeWhere(at time.Time, match func(student.RefreshToken) bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for hash, t := range s.refresh {
+		if match(t) && t.RevokedOn.IsZero() {
+			t.RevokedOn = at
+			s.refresh[hash] = t
+		}
+	}
+}
+
+func (s *TokenStore) RevokeTokenFamily(ctx context.Context, familyID string, at time.Time) error {
+	s.revokeWhere(at, func(t student.RefreshToken) bool { return t.FamilyID == familyID })
+	return nil
+}
+
+func (s *TokenStore) RevokeUserTokens(ctx context.Context, userID string, at time.Time) error {
+	s.revokeWhere(at, func(t student.RefreshToken) bool { return t.UserID == userID })
+	return nil
+}
+
+func (s *TokenStore) RevokeAccessToken(ctx context.Context, jti string, expiresOn time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.revoked[jti] = expiresOn
+	return nil
+}
+
+func (s *TokenStore) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.revoked[jti]
+	return ok, nil
+}
+
+func (s *TokenStore) PurgeExpiredTokens(ctx context.Context, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for hash, t := range s.refresh {
+		if t.ExpiresOn.Before(now) {
+			delete(s.refresh, hash)
+		}
+	}
+	for jti, expiresOn := range s.revoked {
+		if expiresOn.Before(now) {
+			delete(s.revoked, jti)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"golang-assignment/internal/student"
+)
+
+type UserStore struct {
+	mu    sync.RWMutex
+	users map[string]student.User
+}
+
+func NewUserStore() *UserStore {
+	return &UserStore{users: map[string]student.User{}}
+}
+
+func (s *UserStore) GetUser(ctx context.Context, id string) (student.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	user, ok := s.users[id]
+	if !ok {
+		return student.User{}, fmt.Errorf("user with ID %s not found: %w", id, student.ErrUserNotFound)
+	}
+	return user, nil
+}
+
+func (s *UserStore) ListUsers(ctx context.Context) ([]student.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	users := make([]student.User, 0, len(s.users))
+	for _, user := range s.users {
+		users = append(users, user)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	return users, nil
+}
+
+func (s *UserStore) CreateUser(ctx context.Context, user student.User) (student.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.users[user.ID]; exists {
+		return student.User{}, fmt.Errorf("user with ID %s: %w", user.ID, student.ErrDuplicateUser)
+	}
+	user.CreatedOn = time.Now()
+	user.UpdatedOn = user.CreatedOn
+	s.users[user.ID] = user
+	return user, nil
+}
+
+func (s *UserStore) UpdateUser(ctx context.Context, user student.User) (student.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.users[user.ID]
+	if !ok {
+		return student.User{}, fmt.Errorf("no rows were updated, user with ID %s might not exist: %w", user.ID, student.ErrUserNotFound)
+	}
+	user.CreatedBy = existing.CreatedBy
+	user.CreatedOn = existing.CreatedOn
+	user.UpdatedOn = time.Now()
+	s.users[user.ID] = user
+	return user, nil
+}
+
+func (s *UserStore) CountUsers(ctx context.Context) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.users), nil
+}
@@ -0,0 +1,194 @@
+package student
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	util "golang-assignment/utils"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	ErrListingAudit = errors.New("could not list audit entries")
+	ErrInvalidAudit = errors.New("invalid audit query")
+)
+
+type AuditAction string
+
+const (
+	AuditCreate  AuditAction = "create"
+	AuditUpdate  AuditAction = "update"
+	AuditDelete  AuditAction = "delete"
+	AuditRestore AuditAction = "restore"
+	AuditPurge   AuditAction = "purge"
+)
+
+// FieldChange is the value of one field before and after a change, Before is
+// nil for a created student
+type FieldChange struct {
+	Field  string      `json:"field"`
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// AuditEntry records one change made to a student. Entries are only ever
+// added, never changed or removed, and outlive the students they are about.
+type AuditEntry struct {
+	ID         int64         `json:"id"`
+	StudentID  string        `json:"student_id"`
+	Action     AuditAction   `json:"action"`
+	Actor      string        `json:"actor"`
+	RequestID  string        `json:"request_id,omitempty"`
+	OccurredOn time.Time     `json:"occurred_on"`
+	Changes    []FieldChange `json:"changes"`
+}
+
+// AuditFilter selects audit entries, the zero value of a field matches
+// everything. Entries come newest first, starting below BeforeID when set.
+type AuditFilter struct {
+	StudentID string
+	Actor     string
+	From      time.Time
+	To        time.Time
+	BeforeID  int64
+	Limit     int
+}
+
+// AuditStore lists the audit entries. They are written by the StudentStore
+// along with the change they record, so neither is stored without the other.
+type AuditStore interface {
+	ListAuditEntries(context.Context, AuditFilter) ([]AuditEntry, error)
+}
+
+type AuditPage struct {
+	Entries       []AuditEntry `json:"entries"`
+	NextPageToken string       `json:"next_page_token,omitempty"`
+}
+
+// diffStudents lists the fields a user can set that differ between before
+// and after
+func diffStudents(before, after Student) []FieldChange {
+	changes := []FieldChange{}
+	if before.Name != after.Name {
+		changes = append(changes, FieldChange{Field: "name", Before: before.Name, After: after.Name})
+	}
+	if before.Email != after.Email {
+		changes = append(changes, FieldChange{Field: "email", Before: before.Email, After: after.Email})
+	}
+	if before.Age != after.Age {
+		changes = append(changes, FieldChange{Field: "age", Before: before.Age, After: after.Age})
+	}
+	if before.Course != after.Course {
+		changes = append(changes, FieldChange{Field: "course", Before: before.Course, After: after.Course})
+	}
+	for _, name := range sortedFieldNames(before.CustomFields, after.CustomFields) {
+		if !reflect.DeepEqual(before.CustomFields[name], after.CustomFields[name]) {
+			changes = append(changes, FieldChange{Field: "custom_fields." + name, Before: before.CustomFields[name], After: after.CustomFields[name]})
+		}
+	}
+	return changes
+}
+
+// createdFields lists every field a user can set of a new student
+func createdFields(s Student) []FieldChange {
+	changes := []FieldChange{
+		{Field: "name", After: s.Name},
+		{Field: "email", After: s.Email},
+		{Field: "age", After: s.Age},
+		{Field: "course", After: s.Course},
+	}
+	for _, name := range sortedFieldNames(s.CustomFields) {
+		changes = append(changes, FieldChange{Field: "custom_fields." + name, After: s.CustomFields[name]})
+	}
+	return changes
+}
+
+// auditEntry returns the entry recording a change made to a student, the
+// store writes it along with the change
+func auditEntry(ctx context.Context, studentID string, action AuditAction, actor string, changes []FieldChange) AuditEntry {
+	if changes == nil {
+		changes = []FieldChange{}
+	}
+	return AuditEntry{
+		StudentID:  studentID,
+		Action:     action,
+		Actor:      actor,
+		RequestID:  util.GetRequestID(ctx),
+		OccurredOn: time.Now(),
+		Changes:    changes,
+	}
+}
+
+func encodeAuditPageToken(beforeID int64) string {
+	b, _ := json.Marshal(struct {
+		B int64 `json:"b"`
+	}{beforeID})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeAuditPageToken(token string) (int64, error) {
+	if token == "" {
+		return 0, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, ErrInvalidPageToken
+	}
+	var t struct {
+		B int64 `json:"b"`
+	}
+	if err := json.Unmarshal(b, &t); err != nil || t.B <= 0 {
+		return 0, ErrInvalidPageToken
+	}
+	return t.B, nil
+}
+
+// ListAuditEntries returns a page of the audit entries matching the filter,
+// newest first
+func (s *Service) ListAuditEntries(ctx context.Context, filter AuditFilter, pageToken string, pageSize int) (AuditPage, error) {
+	if pageSize == 0 {
+		pageSize = DefaultPageSize
+	}
+	if pageSize < 0 || pageSize > MaxPageSize {
+		return AuditPage{}, ErrInvalidPageSize
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && filter.From.After(filter.To) {
+		return AuditPage{}, fmt.Errorf("%w: from is after to", ErrInvalidAudit)
+	}
+
+	beforeID, err := decodeAuditPageToken(pageToken)
+	if err != nil {
+		return AuditPage{}, err
+	}
+	filter.BeforeID = beforeID
+	// one extra entry tells us whether there is a next page
+	filter.Limit = pageSize + 1
+
+	entries, err := s.Audit.ListAuditEntries(ctx, filter)
+	if err != nil {
+		log.Errorf("an error occurred listing the audit entries: %s", err.Error())
+		return AuditPage{}, serviceError(err, ErrListingAudit)
+	}
+
+	page := AuditPage{Entries: entries}
+	if page.Entries == nil {
+		page.Entries = []AuditEntry{}
+	}
+	if len(entries) > pageSize {
+		page.Entries = entries[:pageSize]
+		page.NextPageToken = encodeAuditPageToken(page.Entries[pageSize-1].ID)
+	}
+	return page, nil
+}
+
+// StudentHistory returns a page of the changes made to one student, newest
+// first. The history of a deleted or purged student stays available.
+func (s *Service) StudentHistory(ctx context.Context, ID string, pageToken string, pageSize int) (AuditPage, error) {
+	return s.ListAuditEntries(ctx, AuditFilter{StudentID: ID}, pageToken, pageSize)
+}
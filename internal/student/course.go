@@ -0,0 +1,38 @@
+package student
+
+import (
+	"context"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// CourseCatalog tells which course codes a student may be given
+type CourseCatalog interface {
+	// ResolveCourse returns the catalog code matching code, or an error
+	// wrapping ErrInvalidStudent when no active course has it
+	ResolveCourse(ctx context.Context, code string) (string, error)
+}
+
+// resolveCourse returns the catalog code of the course a student is given.
+// Without a catalog any course is taken as is.
+func (s *Service) resolveCourse(ctx context.Context, code string) (string, error) {
+	if s.Courses == nil {
+		return code, nil
+	}
+	resolved, err := s.Courses.ResolveCourse(ctx, code)
+	if err != nil {
+		log.Warnf("course %q refused: %s", code, err.Error())
+		return "", err
+	}
+	return resolved, nil
+}
+
+// resolveChangedCourse is resolveCourse for a student that already has a
+// course. Keeping it is always allowed, even once the course is inactive.
+func (s *Service) resolveChangedCourse(ctx context.Context, before, code string) (string, error) {
+	if strings.EqualFold(before, strings.TrimSpace(code)) {
+		return before, nil
+	}
+	return s.resolveCourse(ctx, code)
+}
@@ -0,0 +1,103 @@
+package student
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// CustomFields are the values a student has for the custom fields an admin
+// defined, keyed by field name. Numbers are float64 as JSON decodes them.
+type CustomFields map[string]interface{}
+
+// FieldSchema tells which custom fields a student may have, it is
+// implemented by the custom field service
+type FieldSchema interface {
+	// CheckCustomFields returns the values as they are stored, or an error
+	// wrapping ErrInvalidStudent when they break the definitions
+	CheckCustomFields(ctx context.Context, values CustomFields) (CustomFields, error)
+	// ParseCustomFieldFilter returns the value of the field a search
+	// compares with, or an error wrapping ErrInvalidSearch
+	ParseCustomFieldFilter(ctx context.Context, name, text string) (interface{}, error)
+	// ParseCustomFields reads the cells of an import file keyed by field
+	// name, or returns an error wrapping ErrInvalidStudent
+	ParseCustomFields(ctx context.Context, texts map[string]string) (CustomFields, error)
+}
+
+// FieldFilter keeps the students whose custom field equals a value. The
+// caller sets the text, the service parses it into the value.
+type FieldFilter struct {
+	Name  string      `json:"name"`
+	Text  string      `json:"text"`
+	Value interface{} `json:"-"`
+}
+
+// checkCustomFields returns the custom fields of a student as they are
+// stored. Without a schema a student has none.
+func (s *Service) checkCustomFields(ctx context.Context, values CustomFields) (CustomFields, error) {
+	if s.Fields == nil {
+		if len(values) > 0 {
+			return nil, fmt.Errorf("%w: no custom field is defined", ErrInvalidStudent)
+		}
+		return nil, nil
+	}
+	checked, err := s.Fields.CheckCustomFields(ctx, values)
+	if err != nil {
+		if !errors.Is(err, ErrInvalidStudent) {
+			log.Errorf("an error occurred checking the custom fields: %s", err.Error())
+		}
+		return nil, err
+	}
+	return checked, nil
+}
+
+// parseCustomFields reads the custom field cells of an import row, which
+// are checked like any other values afterwards
+func (s *Service) parseCustomFields(ctx context.Context, texts map[string]string) (CustomFields, error) {
+	if s.Fields == nil {
+		return nil, fmt.Errorf("%w: no custom field is defined", ErrInvalidStudent)
+	}
+	values, err := s.Fields.ParseCustomFields(ctx, texts)
+	if err != nil {
+		if !errors.Is(err, ErrInvalidStudent) {
+			log.Errorf("an error occurred reading the custom fields: %s", err.Error())
+		}
+		return nil, err
+	}
+	return values, nil
+}
+
+// parseFieldFilters sets the value of every custom field filter
+func (s *Service) parseFieldFilters(ctx context.Context, filters []FieldFilter) error {
+	for i, f := range filters {
+		if s.Fields == nil {
+			return fmt.Errorf("%w: custom field %q is not defined", ErrInvalidSearch, f.Name)
+		}
+		value, err := s.Fields.ParseCustomFieldFilter(ctx, f.Name, f.Text)
+		if err != nil {
+			return err
+		}
+		filters[i].Value = value
+	}
+	return nil
+}
+
+// sortedFieldNames returns the names of the custom fields set in any of
+// values, in order
+func sortedFieldNames(values ...CustomFields) []string {
+	seen := map[string]bool{}
+	var names []string
+	for _, v := range values {
+		for name := range v {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
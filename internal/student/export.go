@@ -0,0 +1,24 @@
+package student
+
+import (
+	"context"
+	"errors"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var ErrExportingStudents = errors.New("could not export students")
+
+// ExportStudents passes every student matching the filter to emit, one at a
+// time so that an export of the whole roster is never held in memory. The
+// offset and limit of the filter are ignored.
+func (s *Service) ExportStudents(ctx context.Context, filter SearchFilter, emit func(Student) error) error {
+	if err := s.CheckSearchFilter(ctx, &filter); err != nil {
+		return err
+	}
+	if err := s.Store.ExportStudents(ctx, filter, emit); err != nil {
+		log.Errorf("an error occurred exporting the students: %s", err.Error())
+		return serviceError(err, ErrExportingStudents)
+	}
+	return nil
+}
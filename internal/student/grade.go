@@ -0,0 +1,286 @@
+package student
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	ErrEnrollmentNotFound = errors.New("no enrollment found")
+	ErrInvalidGrade       = errors.New("invalid grade")
+	ErrGrading            = errors.New("could not record grades")
+)
+
+// ScaleGrade is one grade of a scale. A grade without Points does not count
+// in the GPA, MinPercent is the lowest weighted assessment score earning it.
+type ScaleGrade struct {
+	Letter     string   `json:"letter"`
+	Points     *float64 `json:"points"`
+	MinPercent float64  `json:"min_percent"`
+	Passing    bool     `json:"passing"`
+}
+
+// GradeScale lists its grades from the best to the worst
+type GradeScale struct {
+	Name   string       `json:"name"`
+	Grades []ScaleGrade `json:"grades"`
+}
+
+func points(p float64) *float64 {
+	return &p
+}
+
+const (
+	ScaleLetter   = "letter"
+	ScalePassFail = "pass_fail"
+)
+
+// GradeScales are the scales final grades can be given on
+var GradeScales = []GradeScale{
+	{Name: ScaleLetter, Grades: []ScaleGrade{
+		{Letter: "A+", Points: points(4.0), MinPercent: 97, Passing: true},
+		{Letter: "A", Points: points(4.0), MinPercent: 93, Passing: true},
+		{Letter: "A-", Points: points(3.7), MinPercent: 90, Passing: true},
+		{Letter: "B+", Points: points(3.3), MinPercent: 87, Passing: true},
+		{Letter: "B", Points: points(3.0), MinPercent: 83, Passing: true},
+		{Letter: "B-", Points: points(2.7), MinPercent: 80, Passing: true},
+		{Letter: "C+", Points: points(2.3), MinPercent: 77, Passing: true},
+		{Letter: "C", Points: points(2.0), MinPercent: 73, Passing: true},
+		{Letter: "C-", Points: points(1.7), MinPercent: 70, Passing: true},
+		{Letter: "D+", Points: points(1.3), MinPercent: 67, Passing: true},
+		{Letter: "D", Points: points(1.0), MinPercent: 63, Passing: true},
+		{Letter: "D-", Points: points(0.7), MinPercent: 60, Passing: true},
+		{Letter: "F", Points: points(0), MinPercent: 0},
+	}},
+	{Name: ScalePassFail, Grades: []ScaleGrade{
+		{Letter: "P", MinPercent: 60, Passing: true},
+		{Letter: "F", MinPercent: 0},
+	}},
+}
+
+// FindGradeScale returns the scale called name
+func FindGradeScale(name string) (GradeScale, bool) {
+	for _, scale := range GradeScales {
+		if scale.Name == name {
+			return scale, true
+		}
+	}
+	return GradeScale{}, false
+}
+
+// Grade returns the grade of the scale with the letter, ignoring case
+func (g GradeScale) Grade(letter string) (ScaleGrade, bool) {
+	for _, grade := range g.Grades {
+		if strings.EqualFold(grade.Letter, letter) {
+			return grade, true
+		}
+	}
+	return ScaleGrade{}, false
+}
+
+// ForPercent returns the best grade of the scale the percentage earns
+func (g GradeScale) ForPercent(percent float64) ScaleGrade {
+	for _, grade := range g.Grades {
+		if percent >= grade.MinPercent {
+			return grade
+		}
+	}
+	return g.Grades[len(g.Grades)-1]
+}
+
+// EnrolledCourse is an enrollment of a student as grading needs it, with the
+// title and credits of its course
+type EnrolledCourse struct {
+	EnrollmentID int64
+	StudentID    string
+	Course       string
+	Title        string
+	Credits      int
+	Term         string
+	Status       string
+	EnrolledOn   time.Time
+}
+
+// graded reports whether the enrollment holds a seat, only those are graded
+func (c EnrolledCourse) graded() bool {
+	return c.Status == "active" || c.Status == "completed"
+}
+
+// EnrollmentLookup finds the enrollments grades are recorded against, it is
+// implemented by the enrollment service
+type EnrollmentLookup interface {
+	// GetEnrolledCourse fails with ErrEnrollmentNotFound for an unknown id
+	GetEnrolledCourse(ctx context.Context, enrollmentID int64) (EnrolledCourse, error)
+	ListEnrolledCourses(ctx context.Context, studentID string) ([]EnrolledCourse, error)
+}
+
+// Assessment is one scored piece of work of an enrollment. Weights are
+// relative to the other assessments of the enrollment.
+type Assessment struct {
+	ID           int64     `json:"id"`
+	EnrollmentID int64     `json:"enrollment_id"`
+	Name         string    `json:"name"`
+	Score        float64   `json:"score"`
+	MaxScore     float64   `json:"max_score"`
+	Weight       float64   `json:"weight"`
+	RecordedBy   string    `json:"recorded_by"`
+	RecordedOn   time.Time `json:"recorded_on"`
+}
+
+// FinalGrade is the grade an enrollment ends with. Credits are those of the
+// course when it was graded, so later catalog changes leave transcripts alone.
+type FinalGrade struct {
+	EnrollmentID int64     `json:"enrollment_id"`
+	Scale        string    `json:"scale"`
+	Letter       string    `json:"letter"`
+	Points       *float64  `json:"points"`
+	Passing      bool      `json:"passing"`
+	Credits      int       `json:"credits"`
+	GradedBy     string    `json:"graded_by"`
+	GradedOn     time.Time `json:"graded_on"`
+}
+
+// withScale fills the points and passing flag of the grade from its scale
+func (f FinalGrade) withScale() FinalGrade {
+	if scale, ok := FindGradeScale(f.Scale); ok {
+		if grade, ok := scale.Grade(f.Letter); ok {
+			f.Points = grade.Points
+			f.Passing = grade.Passing
+		}
+	}
+	return f
+}
+
+// GradeStore keeps the assessments and final grades of enrollments
+type GradeStore interface {
+	AddAssessment(context.Context, Assessment) (Assessment, error)
+	// ListAssessments returns the assessments of the enrollment in the order
+	// they were recorded
+	ListAssessments(ctx context.Context, enrollmentID int64) ([]Assessment, error)
+	// SetFinalGrade stores the grade, replacing the one the enrollment had
+	SetFinalGrade(context.Context, FinalGrade) (FinalGrade, error)
+	// ListFinalGrades returns the final grades of the enrollments having one
+	ListFinalGrades(ctx context.Context, enrollmentIDs []int64) ([]FinalGrade, error)
+}
+
+// gradedEnrollment returns the enrollment a grade is recorded against, which
+// must hold a seat
+func (s *Service) gradedEnrollment(ctx context.Context, enrollmentID int64) (EnrolledCourse, error) {
+	if s.Enrollments == nil || s.Grades == nil {
+		return EnrolledCourse{}, ErrNotImplemented
+	}
+	c, err := s.Enrollments.GetEnrolledCourse(ctx, enrollmentID)
+	if err != nil {
+		if !errors.Is(err, ErrEnrollmentNotFound) {
+			log.Errorf("an error occurred fetching the enrollment: %s", err.Error())
+		}
+		return EnrolledCourse{}, serviceError(err, ErrGrading)
+	}
+	if !c.graded() {
+		return EnrolledCourse{}, fmt.Errorf("%w: a %s enrollment cannot be graded", ErrInvalidGrade, c.Status)
+	}
+	return c, nil
+}
+
+// AddAssessment records a scored assessment of an enrollment
+func (s *Service) AddAssessment(ctx context.Context, a Assessment, recordedBy string) (Assessment, error) {
+	a.Name = strings.TrimSpace(a.Name)
+	switch {
+	case a.Name == "":
+		return Assessment{}, fmt.Errorf("%w: name is required", ErrInvalidGrade)
+	case a.MaxScore <= 0:
+		return Assessment{}, fmt.Errorf("%w: max_score must be greater than 0", ErrInvalidGrade)
+	case a.Score < 0 || a.Score > a.MaxScore:
+		return Assessment{}, fmt.Errorf("%w: score must be between 0 and max_score", ErrInvalidGrade)
+	case a.Weight <= 0:
+		return Assessment{}, fmt.Errorf("%w: weight must be greater than 0", ErrInvalidGrade)
+	}
+	if _, err := s.gradedEnrollment(ctx, a.EnrollmentID); err != nil {
+		return Assessment{}, err
+	}
+
+	a.RecordedBy = recordedBy
+	a, err := s.Grades.AddAssessment(ctx, a)
+	if err != nil {
+		log.Errorf("an error occurred adding the assessment: %s", err.Error())
+		return Assessment{}, serviceError(err, ErrGrading)
+	}
+	return a, nil
+}
+
+func (s *Service) ListAssessments(ctx context.Context, enrollmentID int64) ([]Assessment, error) {
+	if s.Enrollments == nil || s.Grades == nil {
+		return nil, ErrNotImplemented
+	}
+	if _, err := s.Enrollments.GetEnrolledCourse(ctx, enrollmentID); err != nil {
+		return nil, serviceError(err, ErrGrading)
+	}
+	assessments, err := s.Grades.ListAssessments(ctx, enrollmentID)
+	if err != nil {
+		log.Errorf("an error occurred listing the assessments: %s", err.Error())
+		return nil, serviceError(err, ErrGrading)
+	}
+	return assessments, nil
+}
+
+// weightedPercent returns the weighted score of the assessments out of 100
+func weightedPercent(assessments []Assessment) float64 {
+	var score, weight float64
+	for _, a := range assessments {
+		score += a.Weight * a.Score / a.MaxScore
+		weight += a.Weight
+	}
+	return 100 * score / weight
+}
+
+// SetFinalGrade gives an enrollment its final grade on the scale, the default
+// one when scale is empty. Without a letter the grade is the one the weighted
+// assessments of the enrollment earn.
+func (s *Service) SetFinalGrade(ctx context.Context, enrollmentID int64, scale, letter, gradedBy string) (FinalGrade, error) {
+	if scale == "" {
+		scale = s.GradeScale
+	}
+	gradeScale, ok := FindGradeScale(scale)
+	if !ok {
+		return FinalGrade{}, fmt.Errorf("%w: unknown scale %s", ErrInvalidGrade, scale)
+	}
+	c, err := s.gradedEnrollment(ctx, enrollmentID)
+	if err != nil {
+		return FinalGrade{}, err
+	}
+
+	var grade ScaleGrade
+	if letter != "" {
+		if grade, ok = gradeScale.Grade(letter); !ok {
+			return FinalGrade{}, fmt.Errorf("%w: %s is not a grade of the %s scale", ErrInvalidGrade, letter, scale)
+		}
+	} else {
+		assessments, err := s.Grades.ListAssessments(ctx, enrollmentID)
+		if err != nil {
+			log.Errorf("an error occurred listing the assessments: %s", err.Error())
+			return FinalGrade{}, serviceError(err, ErrGrading)
+		}
+		if len(assessments) == 0 {
+			return FinalGrade{}, fmt.Errorf("%w: letter is required when the enrollment has no assessment", ErrInvalidGrade)
+		}
+		grade = gradeScale.ForPercent(weightedPercent(assessments))
+	}
+
+	final, err := s.Grades.SetFinalGrade(ctx, FinalGrade{
+		EnrollmentID: enrollmentID,
+		Scale:        gradeScale.Name,
+		Letter:       grade.Letter,
+		Credits:      c.Credits,
+		GradedBy:     gradedBy,
+	})
+	if err != nil {
+		log.Errorf("an error occurred setting the final grade: %s", err.Error())
+		return FinalGrade{}, serviceError(err, ErrGrading)
+	}
+	return final.withScale(), nil
+}
@@ -0,0 +1,183 @@
+package student
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestForPercent(t *testing.T) {
+	letter, _ := FindGradeScale(ScaleLetter)
+	passFail, _ := FindGradeScale(ScalePassFail)
+	tests := []struct {
+		scale   GradeScale
+		percent float64
+		want    string
+	}{
+		{letter, 100, "A+"},
+		{letter, 97, "A+"},
+		{letter, 96.99, "A"},
+		{letter, 90, "A-"},
+		{letter, 89.5, "B+"},
+		{letter, 73, "C"},
+		{letter, 60, "D-"},
+		{letter, 59.99, "F"},
+		{letter, 0, "F"},
+		{letter, -5, "F"},
+		{passFail, 100, "P"},
+		{passFail, 60, "P"},
+		{passFail, 59.99, "F"},
+		{passFail, 0, "F"},
+	}
+	for _, tt := range tests {
+		if got := tt.scale.ForPercent(tt.percent).Letter; got != tt.want {
+			t.Errorf("%s scale at %v%%: got %s, want %s", tt.scale.Name, tt.percent, got, tt.want)
+		}
+	}
+}
+
+// finalGrade returns the grade of the scale as a transcript reads it
+func finalGrade(scale, letter string, credits int) *FinalGrade {
+	g := FinalGrade{Scale: scale, Letter: letter, Credits: credits}.withScale()
+	return &g
+}
+
+func TestGPASummary(t *testing.T) {
+	tests := []struct {
+		name          string
+		grades        []*FinalGrade
+		attempted     int
+		earned        int
+		qualityPoints float64
+		// gpa is -1 when the summary has none
+		gpa float64
+	}{
+		{"NoGrades", nil, 0, 0, 0, -1},
+		{"Ungraded", []*FinalGrade{nil, nil}, 0, 0, 0, -1},
+		{"CreditWeighted", []*FinalGrade{
+			finalGrade(ScaleLetter, "A", 3),
+			finalGrade(ScaleLetter, "C", 1),
+		}, 4, 4, 14, 3.5},
+		{"Rounded", []*FinalGrade{
+			finalGrade(ScaleLetter, "A-", 3),
+			finalGrade(ScaleLetter, "B+", 4),
+		}, 7, 7, 24.3, 3.47},
+		{"FailAttemptedNotEarned", []*FinalGrade{
+			finalGrade(ScaleLetter, "B", 2),
+			finalGrade(ScaleLetter, "F", 2),
+		}, 4, 2, 6, 1.5},
+		{"PassFailEarnedNotAttempted", []*FinalGrade{
+			finalGrade(ScaleLetter, "B", 3),
+			finalGrade(ScalePassFail, "P", 2),
+			finalGrade(ScalePassFail, "F", 1),
+		}, 3, 5, 9, 3},
+		{"OnlyPassFail", []*FinalGrade{finalGrade(ScalePassFail, "P", 2)}, 0, 2, 0, -1},
+		{"ZeroCredits", []*FinalGrade{
+			finalGrade(ScaleLetter, "A", 0),
+			finalGrade(ScaleLetter, "C", 2),
+		}, 2, 2, 4, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var g GPASummary
+			for _, grade := range tt.grades {
+				g.add(grade)
+			}
+			g.finish()
+			wantGPASummary(t, g, tt.attempted, tt.earned, tt.qualityPoints, tt.gpa)
+		})
+	}
+}
+
+// wantGPASummary fails the test unless the summary has the totals, gpa is -1
+// when it should have none
+func wantGPASummary(t *testing.T, g GPASummary, attempted, earned int, qualityPoints, gpa float64) {
+	t.Helper()
+	if g.AttemptedCredits != attempted || g.EarnedCredits != earned || g.QualityPoints != qualityPoints {
+		t.Fatalf("got %d attempted, %d earned and %v quality points, want %d, %d and %v",
+			g.AttemptedCredits, g.EarnedCredits, g.QualityPoints, attempted, earned, qualityPoints)
+	}
+	switch {
+	case gpa < 0 && g.GPA != nil:
+		t.Fatalf("got GPA %v, want none", *g.GPA)
+	case gpa >= 0 && (g.GPA == nil || *g.GPA != gpa):
+		t.Fatalf("got GPA %v, want %v", g.GPA, gpa)
+	}
+}
+
+// transcriptStudents serves the one student a transcript is built for
+type transcriptStudents struct {
+	StudentStore
+	student Student
+}
+
+func (s transcriptStudents) GetStudent(ctx context.Context, ID string) (Student, error) {
+	if ID != s.student.ID {
+		return Student{}, ErrNoStudentFound
+	}
+	return s.student, nil
+}
+
+// transcriptEnrollments lists fixed enrollments and their final grades
+type transcriptEnrollments struct {
+	GradeStore
+	courses []EnrolledCourse
+	grades  []FinalGrade
+}
+
+func (e transcriptEnrollments) GetEnrolledCourse(ctx context.Context, enrollmentID int64) (EnrolledCourse, error) {
+	for _, c := range e.courses {
+		if c.EnrollmentID == enrollmentID {
+			return c, nil
+		}
+	}
+	return EnrolledCourse{}, ErrEnrollmentNotFound
+}
+
+func (e transcriptEnrollments) ListEnrolledCourses(ctx context.Context, studentID string) ([]EnrolledCourse, error) {
+	return e.courses, nil
+}
+
+func (e transcriptEnrollments) ListFinalGrades(ctx context.Context, enrollmentIDs []int64) ([]FinalGrade, error) {
+	return e.grades, nil
+}
+
+func TestTranscriptExcludesWithdrawn(t *testing.T) {
+	on := time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC)
+	enrollments := transcriptEnrollments{
+		courses: []EnrolledCourse{
+			{EnrollmentID: 1, StudentID: "s1", Course: "CS101", Credits: 3, Term: "2026-spring", Status: "completed", EnrolledOn: on},
+			{EnrollmentID: 2, StudentID: "s1", Course: "MA101", Credits: 4, Term: "2026-spring", Status: "withdrawn", EnrolledOn: on},
+			{EnrollmentID: 3, StudentID: "s1", Course: "PH101", Credits: 2, Term: "2026-spring", Status: "waitlisted", EnrolledOn: on},
+		},
+		grades: []FinalGrade{
+			{EnrollmentID: 1, Scale: ScaleLetter, Letter: "B", Credits: 3},
+			// graded before the withdrawal, it must not count
+			{EnrollmentID: 2, Scale: ScaleLetter, Letter: "F", Credits: 4},
+		},
+	}
+	s := &Service{
+		Store:       transcriptStudents{student: Student{ID: "s1", Name: "Ann", Email: "ann@x.io"}},
+		Enrollments: enrollments,
+		Grades:      enrollments,
+	}
+
+	transcript, err := s.Transcript(context.Background(), "s1")
+	if err != nil {
+		t.Fatalf("Transcript: %v", err)
+	}
+	if len(transcript.Terms) != 1 {
+		t.Fatalf("got %d terms, want 1", len(transcript.Terms))
+	}
+	term := transcript.Terms[0]
+	if len(term.Courses) != 2 {
+		t.Fatalf("got %d courses, want the completed and withdrawn ones: %+v", len(term.Courses), term.Courses)
+	}
+	for _, c := range term.Courses {
+		if c.Course == "MA101" && c.Grade != nil {
+			t.Fatalf("the withdrawn course is listed with grade %+v", *c.Grade)
+		}
+	}
+	wantGPASummary(t, term.GPASummary, 3, 3, 9, 3)
+	wantGPASummary(t, transcript.Cumulative, 3, 3, 9, 3)
+}
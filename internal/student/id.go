@@ -0,0 +1,84 @@
+package student
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	IDFormatUUIDv7 = "uuidv7"
+	IDFormatULID   = "ulid"
+)
+
+var ErrUnsupportedIDFormat = errors.New("student ID format must be one of uuidv7 or ulid")
+
+// IDGenerator returns the ID of a new student. Both formats start with the
+// creation time so IDs sort roughly in creation order.
+type IDGenerator func() (string, error)
+
+// NewIDGenerator returns the generator of the given format
+func NewIDGenerator(format string) (IDGenerator, error) {
+	switch format {
+	case IDFormatUUIDv7:
+		return NewUUIDv7, nil
+	case IDFormatULID:
+		return NewULID, nil
+	}
+	return nil, ErrUnsupportedIDFormat
+}
+
+// timestampedRandom returns 16 bytes starting with the 48 bit unix time in
+// milliseconds followed by random bytes
+func timestampedRandom() ([16]byte, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[6:]); err != nil {
+		return b, fmt.Errorf("failed to generate student ID: %w", err)
+	}
+	var ms [8]byte
+	binary.BigEndian.PutUint64(ms[:], uint64(time.Now().UnixMilli()))
+	copy(b[:6], ms[2:])
+	return b, nil
+}
+
+// NewUUIDv7 returns an RFC 9562 version 7 UUID
+func NewUUIDv7() (string, error) {
+	b, err := timestampedRandom()
+	if err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x70
+	b[8] = b[8]&0x3f | 0x80
+
+	h := hex.EncodeToString(b[:])
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:], nil
+}
+
+const crockfordBase32 = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// NewULID returns a ULID, 26 characters of Crockford's base 32
+func NewULID() (string, error) {
+	b, err := timestampedRandom()
+	if err != nil {
+		return "", err
+	}
+	hi := binary.BigEndian.Uint64(b[:8])
+	lo := binary.BigEndian.Uint64(b[8:])
+
+	// each character holds 5 of the 128 bits, the first one only 3
+	id := make([]byte, 26)
+	for i := range id {
+		shift := uint(125 - 5*i)
+		var v uint64
+		if shift >= 64 {
+			v = hi >> (shift - 64)
+		} else {
+			v = lo>>shift | hi<<(64-shift)
+		}
+		id[i] = crockfordBase32[v&31]
+	}
+	return string(id), nil
+}
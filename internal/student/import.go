@@ -0,0 +1,289 @@
+package student
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	ErrImportingStudents = errors.New("could not import students")
+	ErrInvalidImport     = errors.New("invalid import")
+)
+
+// MaxImportRows is the most rows a single import may have
+const MaxImportRows = 5000
+
+// ImportRow is one student read from an import file. Errors are the problems
+// found reading it, a row having any is rejected without further checks.
+type ImportRow struct {
+	Line    int
+	Student Student
+	// FieldTexts are the custom field cells of a CSV row keyed by field
+	// name, the service reads them into the custom fields of the student
+	FieldTexts map[string]string
+	Errors     []ImportError
+}
+
+// ImportError is one reason a row is rejected, Field is empty when the
+// reason is about the row as a whole
+type ImportError struct {
+	Field         string `json:"field,omitempty"`
+	Message       string `json:"message"`
+	ConflictingID string `json:"conflicting_id,omitempty"`
+}
+
+type RejectedRow struct {
+	Line   int           `json:"line"`
+	ID     string        `json:"id,omitempty"`
+	Email  string        `json:"email,omitempty"`
+	Errors []ImportError `json:"errors"`
+}
+
+// ImportOptions tunes an import. A BatchSize of 0 uses the batch size of the
+// service, which itself inserts every row in one transaction when 0.
+type ImportOptions struct {
+	DryRun     bool
+	BatchSize  int
+	ImportedBy string
+}
+
+// ImportReport tells what became of each row. In a dry run Imported is
+// always 0 and Valid is what a real run would have imported.
+type ImportReport struct {
+	DryRun   bool          `json:"dry_run"`
+	Total    int           `json:"total"`
+	Valid    int           `json:"valid"`
+	Imported int           `json:"imported"`
+	Rejected []RejectedRow `json:"rejected"`
+}
+
+func (r *ImportReport) reject(row ImportRow, errs ...ImportError) {
+	r.Rejected = append(r.Rejected, RejectedRow{
+		Line:   row.Line,
+		ID:     row.Student.ID,
+		Email:  row.Student.Email,
+		Errors: errs,
+	})
+}
+
+// sortRejected orders the rejected rows as they are in the file
+func (r *ImportReport) sortRejected() {
+	sort.SliceStable(r.Rejected, func(i, j int) bool {
+		return r.Rejected[i].Line < r.Rejected[j].Line
+	})
+}
+
+// conflictImportError describes the stored student a row clashes with
+func conflictImportError(conflict *ConflictError) ImportError {
+	return ImportError{
+		Field:         conflict.Field,
+		Message:       fmt.Sprintf("is already used by student %s", conflict.ConflictingID),
+		ConflictingID: conflict.ConflictingID,
+	}
+}
+
+// rolledBackImportError describes the conflict that rolled a batch back. The
+// text of the store error is only logged, it may tell about the schema.
+func rolledBackImportError(err error) ImportError {
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) {
+		return ImportError{Message: "not imported, its batch was rolled back as an ID or email of it was taken meanwhile"}
+	}
+	if conflict.ConflictingID == "" {
+		return ImportError{Message: fmt.Sprintf("not imported, its batch was rolled back as the %s of one of its rows was taken meanwhile", conflict.Field)}
+	}
+	return ImportError{
+		Message:       fmt.Sprintf("not imported, its batch was rolled back as the %s of one of its rows is already used by student %s", conflict.Field, conflict.ConflictingID),
+		ConflictingID: conflict.ConflictingID,
+	}
+}
+
+// ImportStudents creates the valid rows and reports the others. Rows are
+// checked against each other and against the stored students before anything
+// is written, then inserted batch by batch, each batch being all or nothing.
+func (s *Service) ImportStudents(ctx context.Context, rows []ImportRow, opts ImportOptions) (ImportReport, error) {
+	switch {
+	case len(rows) == 0:
+		return ImportReport{}, fmt.Errorf("%w: the file has no rows", ErrInvalidImport)
+	case len(rows) > MaxImportRows:
+		return ImportReport{}, fmt.Errorf("%w: the file has more than %d rows", ErrInvalidImport, MaxImportRows)
+	case opts.BatchSize < 0:
+		return ImportReport{}, fmt.Errorf("%w: batch_size must not be negative", ErrInvalidImport)
+	}
+	batchSize := opts.BatchSize
+	if batchSize == 0 {
+		batchSize = s.ImportBatchSize
+	}
+	if batchSize == 0 {
+		batchSize = len(rows)
+	}
+
+	report := ImportReport{DryRun: opts.DryRun, Total: len(rows), Rejected: []RejectedRow{}}
+	valid, err := s.checkImportRows(ctx, rows, opts.ImportedBy, &report)
+	if err != nil {
+		return ImportReport{}, err
+	}
+	report.Valid = len(valid)
+	if opts.DryRun {
+		report.sortRejected()
+		return report, nil
+	}
+
+	for start := 0; start < len(valid); start += batchSize {
+		batch := valid[start:min(start+batchSize, len(valid))]
+		students := make([]Student, len(batch))
+		entries := make([]AuditEntry, len(batch))
+		for i, row := range batch {
+			students[i] = row.Student
+			if students[i].ID == "" {
+				if students[i].ID, err = s.NewID(); err != nil {
+					log.Errorf("an error occurred generating the student ID: %s", err.Error())
+					return ImportReport{}, ErrImportingStudents
+				}
+			}
+			entries[i] = auditEntry(ctx, students[i].ID, AuditCreate, students[i].CreatedBy, createdFields(students[i]))
+		}
+
+		created, err := s.Store.PostStudents(ctx, students, entries)
+		if err != nil {
+			log.Errorf("an error occurred importing rows %d to %d: %s", batch[0].Line, batch[len(batch)-1].Line, err.Error())
+			if !errors.Is(err, ErrDuplicateStudent) && !errors.Is(err, ErrDuplicateEmail) {
+				if report.Imported == 0 {
+					return ImportReport{}, serviceError(err, ErrImportingStudents)
+				}
+				// earlier batches are stored, so report them rather than fail
+				for _, row := range valid[start:] {
+					report.reject(row, ImportError{Message: "not imported, the import stopped after an error"})
+				}
+				break
+			}
+			// another writer took an ID or email since the rows were checked
+			rolledBack := rolledBackImportError(err)
+			for _, row := range batch {
+				report.reject(row, rolledBack)
+			}
+			continue
+		}
+		report.Imported += len(created)
+	}
+	report.sortRejected()
+	return report, nil
+}
+
+// importCourse is what the catalog answered for a course of an import
+type importCourse struct {
+	code string
+	err  error
+}
+
+// checkImportRows returns the rows that can be inserted and rejects the
+// others into the report
+func (s *Service) checkImportRows(ctx context.Context, rows []ImportRow, importedBy string, report *ImportReport) ([]ImportRow, error) {
+	candidates := make([]ImportRow, 0, len(rows))
+	// the first line using each email and ID, later ones are duplicates
+	emails := map[string]int{}
+	ids := map[string]int{}
+	// the catalog is asked once per course
+	courses := map[string]importCourse{}
+	for _, row := range rows {
+		if len(row.Errors) > 0 {
+			report.reject(row, row.Errors...)
+			continue
+		}
+		if err := validateStudent(row.Student); err != nil {
+			report.reject(row, ImportError{Message: err.Error()})
+			continue
+		}
+		course, ok := courses[strings.ToLower(row.Student.Course)]
+		if !ok {
+			resolved, err := s.resolveCourse(ctx, row.Student.Course)
+			if err != nil && !errors.Is(err, ErrInvalidStudent) {
+				return nil, serviceError(err, ErrImportingStudents)
+			}
+			course = importCourse{code: resolved, err: err}
+			courses[strings.ToLower(row.Student.Course)] = course
+		}
+		if course.err != nil {
+			report.reject(row, ImportError{Field: "course", Message: course.err.Error()})
+			continue
+		}
+		row.Student.Course = course.code
+		if len(row.FieldTexts) > 0 {
+			values, err := s.parseCustomFields(ctx, row.FieldTexts)
+			if err != nil {
+				if !errors.Is(err, ErrInvalidStudent) {
+					return nil, serviceError(err, ErrImportingStudents)
+				}
+				report.reject(row, ImportError{Field: "custom_fields", Message: err.Error()})
+				continue
+			}
+			row.Student.CustomFields = values
+		}
+		fields, err := s.checkCustomFields(ctx, row.Student.CustomFields)
+		if err != nil {
+			if !errors.Is(err, ErrInvalidStudent) {
+				return nil, serviceError(err, ErrImportingStudents)
+			}
+			report.reject(row, ImportError{Field: "custom_fields", Message: err.Error()})
+			continue
+		}
+		row.Student.CustomFields = fields
+		if row.Student.ID != "" && !s.AllowClientIDs {
+			report.reject(row, ImportError{Field: "id", Message: "is assigned by the server and cannot be set"})
+			continue
+		}
+
+		var errs []ImportError
+		email := strings.ToLower(row.Student.Email)
+		if line, ok := emails[email]; ok {
+			errs = append(errs, ImportError{Field: "email", Message: fmt.Sprintf("is already used on line %d", line)})
+		}
+		if line, ok := ids[row.Student.ID]; ok && row.Student.ID != "" {
+			errs = append(errs, ImportError{Field: "id", Message: fmt.Sprintf("is already used on line %d", line)})
+		}
+		if len(errs) > 0 {
+			report.reject(row, errs...)
+			continue
+		}
+		emails[email] = row.Line
+		if row.Student.ID != "" {
+			ids[row.Student.ID] = row.Line
+		}
+
+		row.Student.CreatedBy = importedBy
+		row.Student.UpdatedBy = importedBy
+		candidates = append(candidates, row)
+	}
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	students := make([]Student, len(candidates))
+	for i, row := range candidates {
+		students[i] = row.Student
+	}
+	conflicts, err := s.Store.FindConflicts(ctx, students)
+	if err != nil {
+		log.Errorf("an error occurred checking the imported students: %s", err.Error())
+		return nil, serviceError(err, ErrImportingStudents)
+	}
+
+	valid := candidates[:0]
+	for i, row := range candidates {
+		if len(conflicts[i]) > 0 {
+			errs := make([]ImportError, len(conflicts[i]))
+			for j, conflict := range conflicts[i] {
+				errs[j] = conflictImportError(conflict)
+			}
+			report.reject(row, errs...)
+			continue
+		}
+		valid = append(valid, row)
+	}
+	return valid, nil
+}
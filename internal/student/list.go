@@ -0,0 +1,94 @@
+package student
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+var (
+	ErrInvalidPageToken = errors.New("invalid page token")
+	ErrInvalidPageSize  = errors.New("page size must be between 1 and 100")
+)
+
+// ListCursor is the position of the last student of a page. Students are
+// listed ordered by created_on and then by id, so the pair is unique.
+type ListCursor struct {
+	CreatedOn time.Time `json:"c"`
+	ID        string    `json:"i"`
+}
+
+type StudentPage struct {
+	Students      []Student `json:"students"`
+	NextPageToken string    `json:"next_page_token,omitempty"`
+}
+
+// The page token is opaque to clients, it only has to round trip
+func encodePageToken(c ListCursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodePageToken(token string) (ListCursor, error) {
+	var c ListCursor
+	if token == "" {
+		return c, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return c, ErrInvalidPageToken
+	}
+	if err := json.Unmarshal(b, &c); err != nil || c.ID == "" {
+		return ListCursor{}, ErrInvalidPageToken
+	}
+	return c, nil
+}
+
+func (s *Service) ListStudents(ctx context.Context, pageToken string, pageSize int) (StudentPage, error) {
+	return s.listPage(ctx, s.Store.ListStudents, pageToken, pageSize)
+}
+
+// listPage reads one page with list, which returns the students after the
+// cursor in (created_on, id) order
+func (s *Service) listPage(
+	ctx context.Context, list func(context.Context, ListCursor, int) ([]Student, error), pageToken string, pageSize int,
+) (StudentPage, error) {
+	if pageSize == 0 {
+		pageSize = DefaultPageSize
+	}
+	if pageSize < 0 || pageSize > MaxPageSize {
+		return StudentPage{}, ErrInvalidPageSize
+	}
+
+	cursor, err := decodePageToken(pageToken)
+	if err != nil {
+		return StudentPage{}, err
+	}
+
+	// one extra row tells us whether there is a next page
+	students, err := list(ctx, cursor, pageSize+1)
+	if err != nil {
+		log.Errorf("an error occurred listing the students: %s", err.Error())
+		return StudentPage{}, serviceError(err, ErrListingStudents)
+	}
+
+	page := StudentPage{Students: students}
+	if page.Students == nil {
+		page.Students = []Student{}
+	}
+	if len(students) > pageSize {
+		page.Students = students[:pageSize]
+		last := page.Students[pageSize-1]
+		page.NextPageToken = encodePageToken(ListCursor{CreatedOn: last.CreatedOn, ID: last.ID})
+	}
+	return page, nil
+}
@@ -3,16 +3,51 @@ package student
 import (
 	"context"
 	"errors"
-	utils "golang-assignment/utils"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	MinPasswordLength = 8
+	// MaxPasswordLength is the most bytes bcrypt hashes
+	MaxPasswordLength = 72
+)
+
+var (
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrUserNotFound       = errors.New("no user found")
+	ErrDuplicateUser      = errors.New("user already exists")
+	ErrWeakPassword       = fmt.Errorf("password must be %d to %d bytes long", MinPasswordLength, MaxPasswordLength)
+	ErrManagingUsers      = errors.New("could not manage users")
 )
 
 type Services struct {
 	Store StudentStore
 }
 
+// User is a staff member allowed to log in. Its ID is what ends up in the
+// created_by and updated_by columns of the students it touches.
 type User struct {
-	ID       string
-	Password string
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	Role         Role      `json:"role"`
+	PasswordHash string    `json:"-"`
+	Disabled     bool      `json:"disabled"`
+	CreatedBy    string    `json:"created_by"`
+	CreatedOn    time.Time `json:"created_on"`
+	UpdatedBy    string    `json:"updated_by"`
+	UpdatedOn    time.Time `json:"updated_on"`
+}
+
+type UserStore interface {
+	GetUser(context.Context, string) (User, error)
+	ListUsers(context.Context) ([]User, error)
+	CreateUser(context.Context, User) (User, error)
+	UpdateUser(context.Context, User) (User, error)
+	CountUsers(context.Context) (int, error)
 }
 
 type StudentService interface {
@@ -20,13 +55,177 @@ type StudentService interface {
 	GenerateJWT(user User) (string, error)
 }
 
+// compared against when the user does not exist so that unknown and known
+// user IDs take the same time to be rejected
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("not a real password"), bcrypt.DefaultCost)
+
+func hashPassword(password string) (string, error) {
+	if len(password) < MinPasswordLength || len(password) > MaxPasswordLength {
+		return "", ErrWeakPassword
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	return string(hash), nil
+}
+
 func (s *Service) AuthenticateUser(ctx context.Context, userID, password string) (User, error) {
-	if userID == "user123" && password == "password" {
-		return User{ID: "user123"}, nil
+	user, err := s.Users.GetUser(ctx, userID)
+	if err != nil {
+		if !errors.Is(err, ErrUserNotFound) {
+			log.Errorf("an error occurred fetching the user: %s", err.Error())
+			if errors.Is(err, ErrStoreUnavailable) {
+				return User{}, err
+			}
+		}
+		bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+		return User{}, ErrInvalidCredentials
 	}
-	return User{}, errors.New("invalid credentials")
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return User{}, ErrInvalidCredentials
+	}
+	if user.Disabled {
+		log.Warnf("disabled user %s tried to log in", user.ID)
+		return User{}, ErrInvalidCredentials
+	}
+	return user, nil
 }
 
 func (s *Service) GenerateJWT(user User) (string, error) {
-	return utils.GenerateJWT(user.ID)
+	return s.Signer.Sign(user.ID, string(user.Role), s.AccessTokenTTL)
+}
+
+func (s *Service) GetUser(ctx context.Context, userID string) (User, error) {
+	user, err := s.Users.GetUser(ctx, userID)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return User{}, ErrUserNotFound
+		}
+		log.Errorf("an error occurred fetching the user: %s", err.Error())
+		return User{}, serviceError(err, ErrManagingUsers)
+	}
+	return user, nil
+}
+
+func (s *Service) ListUsers(ctx context.Context) ([]User, error) {
+	users, err := s.Users.ListUsers(ctx)
+	if err != nil {
+		log.Errorf("an error occurred listing the users: %s", err.Error())
+		return nil, serviceError(err, ErrManagingUsers)
+	}
+	return users, nil
+}
+
+// CreateUser adds a user able to log in with the given password, createdBy is
+// the ID of the user doing it
+func (s *Service) CreateUser(ctx context.Context, user User, password, createdBy string) (User, error) {
+	if !user.Role.Valid() {
+		return User{}, ErrInvalidRole
+	}
+	hash, err := hashPassword(password)
+	if err != nil {
+		return User{}, err
+	}
+
+	user.PasswordHash = hash
+	user.CreatedBy = createdBy
+	user.UpdatedBy = createdBy
+	user, err = s.Users.CreateUser(ctx, user)
+	if err != nil {
+		if errors.Is(err, ErrDuplicateUser) {
+			return User{}, ErrDuplicateUser
+		}
+		log.Errorf("an error occurred adding the user: %s", err.Error())
+		return User{}, serviceError(err, ErrManagingUsers)
+	}
+	return user, nil
+}
+
+func (s *Service) updateUser(ctx context.Context, userID, updatedBy string, change func(*User) error) (User, error) {
+	user, err := s.GetUser(ctx, userID)
+	if err != nil {
+		return User{}, err
+	}
+	if err := change(&user); err != nil {
+		return User{}, err
+	}
+
+	user.UpdatedBy = updatedBy
+	user, err = s.Users.UpdateUser(ctx, user)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return User{}, ErrUserNotFound
+		}
+		log.Errorf("an error occurred updating the user: %s", err.Error())
+		return User{}, serviceError(err, ErrManagingUsers)
+	}
+	return user, nil
+}
+
+// SetUserDisabled disables or re-enables a user, a disabled user cannot log in
+// and its refresh tokens are revoked. Its access tokens are refused while it
+// stays disabled, see IsTokenRevoked, and the unexpired ones are accepted again
+// once it is re-enabled.
+func (s *Service) SetUserDisabled(ctx context.Context, userID string, disabled bool, updatedBy string) (User, error) {
+	user, err := s.updateUser(ctx, userID, updatedBy, func(u *User) error {
+		u.Disabled = disabled
+		return nil
+	})
+	if err != nil || !disabled {
+		return user, err
+	}
+
+	if err := s.Tokens.RevokeUserTokens(ctx, userID, time.Now()); err != nil {
+		log.Errorf("an error occurred revoking the tokens of the user: %s", err.Error())
+		return User{}, serviceError(err, ErrManagingUsers)
+	}
+	return user, nil
+}
+
+func (s *Service) SetUserRole(ctx context.Context, userID string, role Role, updatedBy string) (User, error) {
+	if !role.Valid() {
+		return User{}, ErrInvalidRole
+	}
+	return s.updateUser(ctx, userID, updatedBy, func(u *User) error {
+		u.Role = role
+		return nil
+	})
+}
+
+func (s *Service) ResetPassword(ctx context.Context, userID, password, updatedBy string) (User, error) {
+	return s.updateUser(ctx, userID, updatedBy, func(u *User) error {
+		hash, err := hashPassword(password)
+		if err != nil {
+			return err
+		}
+		u.PasswordHash = hash
+		return nil
+	})
+}
+
+// BootstrapUser creates the first user when there is none yet, so that a
+// fresh installation has someone able to log in and create the others
+func (s *Service) BootstrapUser(ctx context.Context, userID, password string) error {
+	count, err := s.Users.CountUsers(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to count users: %w", err)
+	}
+	if count > 0 {
+		return nil
+	}
+	if userID == "" {
+		log.Warn("no user exists and ADMIN_USER_ID is not set, nobody can log in")
+		return nil
+	}
+	// there is no default password, one shipped with the code would let
+	// anyone in
+	if password == "" {
+		return errors.New("ADMIN_PASSWORD is required to create the initial user")
+	}
+
+	log.Infof("creating the initial user %s", userID)
+	_, err = s.CreateUser(ctx, User{ID: userID, Name: userID, Role: RoleAdmin}, password, userID)
+	return err
 }
@@ -0,0 +1,83 @@
+package student
+
+import (
+	"context"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// StudentPatch holds the fields a partial update changes, a nil field is
+// left as stored
+type StudentPatch struct {
+	Name   *string
+	Email  *string
+	Age    *int
+	Course *string
+	// CustomFields replaces all the custom fields of the student
+	CustomFields *CustomFields
+	UpdatedBy    string
+	UpdatedOn    time.Time
+}
+
+// IsEmpty reports whether the patch changes none of the fields
+func (p StudentPatch) IsEmpty() bool {
+	return p.Name == nil && p.Email == nil && p.Age == nil && p.Course == nil && p.CustomFields == nil
+}
+
+// Apply returns the student as it is after the patch
+func (p StudentPatch) Apply(s Student) Student {
+	if p.Name != nil {
+		s.Name = *p.Name
+	}
+	if p.Email != nil {
+		s.Email = *p.Email
+	}
+	if p.Age != nil {
+		s.Age = *p.Age
+	}
+	if p.Course != nil {
+		s.Course = *p.Course
+	}
+	if p.CustomFields != nil {
+		s.CustomFields = *p.CustomFields
+	}
+	s.UpdatedBy = p.UpdatedBy
+	s.UpdatedOn = p.UpdatedOn
+	return s
+}
+
+// PatchStudent writes the changed fields of current, current must be the
+// student as the caller read it so that its version guards the write. A
+// patch changing nothing returns current as is.
+func (s *Service) PatchStudent(ctx context.Context, current Student, patch StudentPatch) (Student, error) {
+	if patch.IsEmpty() {
+		return current, nil
+	}
+
+	if err := validateStudent(patch.Apply(current)); err != nil {
+		return Student{}, err
+	}
+	if patch.Course != nil {
+		course, err := s.resolveChangedCourse(ctx, current.Course, *patch.Course)
+		if err != nil {
+			return Student{}, serviceError(err, ErrUpdatingStudent)
+		}
+		patch.Course = &course
+	}
+	if patch.CustomFields != nil {
+		fields, err := s.checkCustomFields(ctx, *patch.CustomFields)
+		if err != nil {
+			return Student{}, serviceError(err, ErrUpdatingStudent)
+		}
+		patch.CustomFields = &fields
+	}
+	patched := patch.Apply(current)
+	entry := auditEntry(ctx, current.ID, AuditUpdate, patch.UpdatedBy, diffStudents(current, patched))
+	if err := s.Store.PatchStudent(ctx, current.ID, current.Version, patch, entry); err != nil {
+		log.Errorf("an error occurred patching the student: %s", err.Error())
+		return Student{}, serviceError(err, ErrUpdatingStudent)
+	}
+	patched.Version++
+	return patched, nil
+}
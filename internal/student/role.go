@@ -0,0 +1,47 @@
+package student
+
+import "errors"
+
+var ErrInvalidRole = errors.New("role must be one of viewer, registrar or admin")
+
+// Role is carried in the JWT of a user and decides what it may do
+type Role string
+
+const (
+	RoleViewer    Role = "viewer"
+	RoleRegistrar Role = "registrar"
+	RoleAdmin     Role = "admin"
+)
+
+type Permission string
+
+const (
+	PermReadStudents   Permission = "students:read"
+	PermWriteStudents  Permission = "students:write"
+	PermDeleteStudents Permission = "students:delete"
+	PermManageUsers    Permission = "users:manage"
+	PermReadAudit      Permission = "audit:read"
+	PermReadCourses    Permission = "courses:read"
+	PermManageCourses  Permission = "courses:manage"
+	PermManageFields   Permission = "fields:manage"
+)
+
+var rolePermissions = map[Role][]Permission{
+	RoleViewer:    {PermReadStudents, PermReadCourses},
+	RoleRegistrar: {PermReadStudents, PermWriteStudents, PermDeleteStudents, PermReadCourses, PermManageCourses},
+	RoleAdmin:     {PermReadStudents, PermWriteStudents, PermDeleteStudents, PermManageUsers, PermReadAudit, PermReadCourses, PermManageCourses, PermManageFields},
+}
+
+func (r Role) Valid() bool {
+	_, ok := rolePermissions[r]
+	return ok
+}
+
+func (r Role) Can(p Permission) bool {
+	for _, granted := range rolePermissions[r] {
+		if granted == p {
+			return true
+		}
+	}
+	return false
+}
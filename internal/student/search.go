@@ -0,0 +1,165 @@
+package student
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var ErrInvalidSearch = errors.New("invalid search")
+
+// SortableColumns are the students columns a search can be sorted on
+var SortableColumns = map[string]bool{
+	"id":         true,
+	"created_by": true,
+	"created_on": true,
+	"updated_by": true,
+	"updated_on": true,
+	"name":       true,
+	"email":      true,
+	"age":        true,
+	"course":     true,
+}
+
+// SearchFilter narrows down the students returned by SearchStudents.
+// Zero values mean "no filter", time ranges are inclusive. A cohort stores
+// it as JSON, without the page of the search.
+type SearchFilter struct {
+	Query string `json:"q,omitempty"`
+	// Tags keep the students having every one of the tags
+	Tags        []string  `json:"tags,omitempty"`
+	Course      string    `json:"course,omitempty"`
+	CreatedBy   string    `json:"created_by,omitempty"`
+	MinAge      int       `json:"min_age,omitempty"`
+	MaxAge      int       `json:"max_age,omitempty"`
+	CreatedFrom time.Time `json:"created_from"`
+	CreatedTo   time.Time `json:"created_to"`
+	UpdatedFrom time.Time `json:"updated_from"`
+	UpdatedTo   time.Time `json:"updated_to"`
+	// CustomFields keep the students having all the custom field values
+	CustomFields []FieldFilter `json:"custom_fields,omitempty"`
+	SortBy       string        `json:"sort_by,omitempty"`
+	SortDesc     bool          `json:"sort_desc,omitempty"`
+	Offset       int           `json:"-"`
+	Limit        int           `json:"-"`
+}
+
+func (f SearchFilter) IsEmpty() bool {
+	return f.Query == "" && len(f.Tags) == 0 && f.Course == "" && f.CreatedBy == "" &&
+		f.MinAge == 0 && f.MaxAge == 0 &&
+		f.CreatedFrom.IsZero() && f.CreatedTo.IsZero() &&
+		f.UpdatedFrom.IsZero() && f.UpdatedTo.IsZero() &&
+		len(f.CustomFields) == 0 && f.SortBy == ""
+}
+
+func (f SearchFilter) Validate() error {
+	if f.SortBy != "" && !SortableColumns[f.SortBy] {
+		return fmt.Errorf("%w: cannot sort on %q", ErrInvalidSearch, f.SortBy)
+	}
+	if f.MinAge < 0 || f.MaxAge < 0 {
+		return fmt.Errorf("%w: age must not be negative", ErrInvalidSearch)
+	}
+	if f.MaxAge != 0 && f.MinAge > f.MaxAge {
+		return fmt.Errorf("%w: min_age is greater than max_age", ErrInvalidSearch)
+	}
+	if !f.CreatedTo.IsZero() && f.CreatedFrom.After(f.CreatedTo) {
+		return fmt.Errorf("%w: created_from is after created_to", ErrInvalidSearch)
+	}
+	if !f.UpdatedTo.IsZero() && f.UpdatedFrom.After(f.UpdatedTo) {
+		return fmt.Errorf("%w: updated_from is after updated_to", ErrInvalidSearch)
+	}
+	return nil
+}
+
+// CheckSearchFilter validates the filter and parses its custom field values
+// the way a search does
+func (s *Service) CheckSearchFilter(ctx context.Context, filter *SearchFilter) error {
+	if err := filter.Validate(); err != nil {
+		return err
+	}
+	if err := s.parseFieldFilters(ctx, filter.CustomFields); err != nil {
+		return serviceError(err, ErrSearchingStudents)
+	}
+	return nil
+}
+
+// SearchResult is one page of matching students along with the number of
+// students matching the whole filter and per value counts used for facets.
+type SearchResult struct {
+	Students []Student
+	Total    int
+	Facets   map[string]map[string]int
+}
+
+type SearchPage struct {
+	StudentPage
+	Total  int                       `json:"total"`
+	Facets map[string]map[string]int `json:"facets"`
+}
+
+type searchCursor struct {
+	Offset int `json:"o"`
+}
+
+func encodeSearchToken(offset int) string {
+	b, _ := json.Marshal(searchCursor{Offset: offset})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeSearchToken(token string) (int, error) {
+	if token == "" {
+		return 0, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, ErrInvalidPageToken
+	}
+	var c searchCursor
+	if err := json.Unmarshal(b, &c); err != nil || c.Offset <= 0 {
+		return 0, ErrInvalidPageToken
+	}
+	return c.Offset, nil
+}
+
+func (s *Service) SearchStudents(ctx context.Context, filter SearchFilter, pageToken string, pageSize int) (SearchPage, error) {
+	if pageSize == 0 {
+		pageSize = DefaultPageSize
+	}
+	if pageSize < 0 || pageSize > MaxPageSize {
+		return SearchPage{}, ErrInvalidPageSize
+	}
+	if err := s.CheckSearchFilter(ctx, &filter); err != nil {
+		return SearchPage{}, err
+	}
+
+	offset, err := decodeSearchToken(pageToken)
+	if err != nil {
+		return SearchPage{}, err
+	}
+	filter.Offset = offset
+	filter.Limit = pageSize
+
+	result, err := s.Store.SearchStudents(ctx, filter)
+	if err != nil {
+		log.Errorf("an error occurred searching the students: %s", err.Error())
+		return SearchPage{}, serviceError(err, ErrSearchingStudents)
+	}
+
+	page := SearchPage{
+		StudentPage: StudentPage{Students: result.Students},
+		Total:       result.Total,
+		Facets:      result.Facets,
+	}
+	if page.Students == nil {
+		page.Students = []Student{}
+	}
+	if next := offset + len(result.Students); next < result.Total {
+		page.NextPageToken = encodeSearchToken(next)
+	}
+	return page, nil
+}
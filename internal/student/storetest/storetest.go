@@ -0,0 +1,396 @@
+// Package storetest checks that a student.StudentStore behaves the way the
+// service relies on. Every implementation runs the same suite, so the
+// memory store cannot drift from the database one.
+package storetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"golang-assignment/internal/student"
+)
+
+// TestStudentStore runs the suite, newStore returns an empty store for each
+// test
+func TestStudentStore(t *testing.T, newStore func(t *testing.T) student.StudentStore) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, store student.StudentStore)
+	}{
+		{"GetMissingStudent", testGetMissingStudent},
+		{"UpdateMissingStudent", testUpdateMissingStudent},
+		{"DeleteMissingStudent", testDeleteMissingStudent},
+		{"PostDuplicateID", testPostDuplicateID},
+		{"UpdateVersionMismatch", testUpdateVersionMismatch},
+		{"PatchVersionMismatch", testPatchVersionMismatch},
+		{"DeleteVersionMismatch", testDeleteVersionMismatch},
+		{"WritesBumpVersion", testWritesBumpVersion},
+		{"DeleteMovesToTrash", testDeleteMovesToTrash},
+		{"RestoreStudent", testRestoreStudent},
+		{"RestoreLiveStudent", testRestoreLiveStudent},
+		{"PurgeDeletedStudents", testPurgeDeletedStudents},
+		{"PostEmailConflict", testPostEmailConflict},
+		{"EmailConflictIgnoresCase", testEmailConflictIgnoresCase},
+		{"UpdateEmailConflict", testUpdateEmailConflict},
+		{"PatchEmailConflict", testPatchEmailConflict},
+		{"TrashFreesEmail", testTrashFreesEmail},
+		{"RestoreEmailConflict", testRestoreEmailConflict},
+		{"FindConflicts", testFindConflicts},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, newStore(t))
+		})
+	}
+}
+
+func newStudent(id, email string) student.Student {
+	return student.Student{
+		ID:        id,
+		Name:      "Student " + id,
+		Email:     email,
+		Age:       20,
+		Course:    "CS101",
+		CreatedBy: "tester",
+		UpdatedBy: "tester",
+	}
+}
+
+func entry(id string, action student.AuditAction) student.AuditEntry {
+	return student.AuditEntry{StudentID: id, Action: action, Actor: "tester"}
+}
+
+// post stores the student, failing the test when it cannot
+func post(t *testing.T, store student.StudentStore, stud student.Student) student.Student {
+	t.Helper()
+	posted, err := store.PostStudent(context.Background(), stud, entry(stud.ID, student.AuditCreate))
+	if err != nil {
+		t.Fatalf("PostStudent(%s): %v", stud.ID, err)
+	}
+	return posted
+}
+
+// wantErr fails the test unless err wraps target
+func wantErr(t *testing.T, err, target error) {
+	t.Helper()
+	if !errors.Is(err, target) {
+		t.Fatalf("got error %v, want %v", err, target)
+	}
+}
+
+// wantConflict fails the test unless err is a ConflictError on field with
+// the student of conflictingID
+func wantConflict(t *testing.T, err, target error, field, conflictingID string) {
+	t.Helper()
+	wantErr(t, err, target)
+	var conflict *student.ConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("got error %v, want a ConflictError", err)
+	}
+	if conflict.Field != field || conflict.ConflictingID != conflictingID {
+		t.Fatalf("got conflict on %s with %q, want %s with %q", conflict.Field, conflict.ConflictingID, field, conflictingID)
+	}
+}
+
+func testGetMissingStudent(t *testing.T, store student.StudentStore) {
+	_, err := store.GetStudent(context.Background(), "missing")
+	wantErr(t, err, student.ErrNoStudentFound)
+}
+
+func testUpdateMissingStudent(t *testing.T, store student.StudentStore) {
+	stud := newStudent("missing", "missing@example.com")
+	stud.Version = 1
+	_, err := store.UpdateStudent(context.Background(), stud.ID, stud, entry(stud.ID, student.AuditUpdate))
+	wantErr(t, err, student.ErrNoStudentFound)
+}
+
+func testDeleteMissingStudent(t *testing.T, store student.StudentStore) {
+	err := store.DeleteStudent(context.Background(), "missing", 1, "tester", entry("missing", student.AuditDelete))
+	wantErr(t, err, student.ErrNoStudentFound)
+}
+
+func testPostDuplicateID(t *testing.T, store student.StudentStore) {
+	post(t, store, newStudent("s1", "first@example.com"))
+
+	_, err := store.PostStudent(context.Background(), newStudent("s1", "second@example.com"), entry("s1", student.AuditCreate))
+	wantConflict(t, err, student.ErrDuplicateStudent, "id", "s1")
+}
+
+func testUpdateVersionMismatch(t *testing.T, store student.StudentStore) {
+	stud := post(t, store, newStudent("s1", "first@example.com"))
+
+	stale := stud
+	stale.Version--
+	stale.Name = "Stale"
+	_, err := store.UpdateStudent(context.Background(), stud.ID, stale, entry(stud.ID, student.AuditUpdate))
+	wantErr(t, err, student.ErrVersionMismatch)
+
+	got, err := store.GetStudent(context.Background(), stud.ID)
+	if err != nil {
+		t.Fatalf("GetStudent: %v", err)
+	}
+	if got.Name != stud.Name || got.Version != stud.Version {
+		t.Fatalf("a refused update changed the student to %q at version %d", got.Name, got.Version)
+	}
+}
+
+func testPatchVersionMismatch(t *testing.T, store student.StudentStore) {
+	stud := post(t, store, newStudent("s1", "first@example.com"))
+
+	name := "Stale"
+	err := store.PatchStudent(context.Background(), stud.ID, stud.Version+1, student.StudentPatch{Name: &name, UpdatedBy: "tester"}, entry(stud.ID, student.AuditUpdate))
+	wantErr(t, err, student.ErrVersionMismatch)
+}
+
+func testDeleteVersionMismatch(t *testing.T, store student.StudentStore) {
+	stud := post(t, store, newStudent("s1", "first@example.com"))
+
+	err := store.DeleteStudent(context.Background(), stud.ID, stud.Version+1, "tester", entry(stud.ID, student.AuditDelete))
+	wantErr(t, err, student.ErrVersionMismatch)
+	if _, err := store.GetStudent(context.Background(), stud.ID); err != nil {
+		t.Fatalf("a refused delete removed the student: %v", err)
+	}
+}
+
+// testWritesBumpVersion checks that a new student starts at version 1 and
+// every write moves it to the next one
+func testWritesBumpVersion(t *testing.T, store student.StudentStore) {
+	ctx := context.Background()
+	stud := post(t, store, newStudent("s1", "first@example.com"))
+	if stud.Version != 1 {
+		t.Fatalf("a new student is at version %d, want 1", stud.Version)
+	}
+
+	stud.Name = "Updated"
+	updated, err := store.UpdateStudent(ctx, stud.ID, stud, entry(stud.ID, student.AuditUpdate))
+	if err != nil {
+		t.Fatalf("UpdateStudent: %v", err)
+	}
+	if updated.Version != 2 {
+		t.Fatalf("an updated student is at version %d, want 2", updated.Version)
+	}
+
+	age := 30
+	if err := store.PatchStudent(ctx, stud.ID, updated.Version, student.StudentPatch{Age: &age, UpdatedBy: "tester"}, entry(stud.ID, student.AuditUpdate)); err != nil {
+		t.Fatalf("PatchStudent: %v", err)
+	}
+	patched, err := store.GetStudent(ctx, stud.ID)
+	if err != nil {
+		t.Fatalf("GetStudent: %v", err)
+	}
+	if patched.Version != 3 || patched.Age != age || patched.Name != "Updated" {
+		t.Fatalf("got %q aged %d at version %d after the patch, want %q aged %d at version 3", patched.Name, patched.Age, patched.Version, "Updated", age)
+	}
+}
+
+// studentIDs returns the IDs of the students
+func studentIDs(students []student.Student) []string {
+	ids := make([]string, 0, len(students))
+	for _, stud := range students {
+		ids = append(ids, stud.ID)
+	}
+	return ids
+}
+
+// wantIDs fails the test unless the students are the ones of want, in order
+func wantIDs(t *testing.T, what string, students []student.Student, want ...string) {
+	t.Helper()
+	got := studentIDs(students)
+	if len(got) != len(want) {
+		t.Fatalf("%s got %v, want %v", what, got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("%s got %v, want %v", what, got, want)
+		}
+	}
+}
+
+// trash posts the student and moves it to the trash
+func trash(t *testing.T, store student.StudentStore, stud student.Student) {
+	t.Helper()
+	stud = post(t, store, stud)
+	if err := store.DeleteStudent(context.Background(), stud.ID, stud.Version, "tester", entry(stud.ID, student.AuditDelete)); err != nil {
+		t.Fatalf("DeleteStudent(%s): %v", stud.ID, err)
+	}
+}
+
+func testDeleteMovesToTrash(t *testing.T, store student.StudentStore) {
+	ctx := context.Background()
+	post(t, store, newStudent("s1", "first@example.com"))
+	trash(t, store, newStudent("s2", "second@example.com"))
+
+	_, err := store.GetStudent(ctx, "s2")
+	wantErr(t, err, student.ErrNoStudentFound)
+
+	live, err := store.ListStudents(ctx, student.ListCursor{}, 10)
+	if err != nil {
+		t.Fatalf("ListStudents: %v", err)
+	}
+	wantIDs(t, "ListStudents", live, "s1")
+
+	found, err := store.SearchStudents(ctx, student.SearchFilter{Course: "CS101", Limit: 10})
+	if err != nil {
+		t.Fatalf("SearchStudents: %v", err)
+	}
+	wantIDs(t, "SearchStudents", found.Students, "s1")
+
+	deleted, err := store.ListDeletedStudents(ctx, student.ListCursor{}, 10)
+	if err != nil {
+		t.Fatalf("ListDeletedStudents: %v", err)
+	}
+	wantIDs(t, "ListDeletedStudents", deleted, "s2")
+	if deleted[0].DeletedBy != "tester" || deleted[0].DeletedOn == nil {
+		t.Fatalf("a deleted student has deleted_by %q and deleted_on %v", deleted[0].DeletedBy, deleted[0].DeletedOn)
+	}
+}
+
+func testRestoreStudent(t *testing.T, store student.StudentStore) {
+	ctx := context.Background()
+	trash(t, store, newStudent("s1", "first@example.com"))
+
+	restored, err := store.RestoreStudent(ctx, "s1", "restorer", entry("s1", student.AuditRestore))
+	if err != nil {
+		t.Fatalf("RestoreStudent: %v", err)
+	}
+	if restored.DeletedOn != nil || restored.DeletedBy != "" || restored.UpdatedBy != "restorer" {
+		t.Fatalf("a restored student has deleted_by %q, deleted_on %v and updated_by %q", restored.DeletedBy, restored.DeletedOn, restored.UpdatedBy)
+	}
+	// post, delete and restore each move to the next version
+	if restored.Version != 3 {
+		t.Fatalf("a restored student is at version %d, want 3", restored.Version)
+	}
+	if _, err := store.GetStudent(ctx, "s1"); err != nil {
+		t.Fatalf("GetStudent of a restored student: %v", err)
+	}
+}
+
+func testRestoreLiveStudent(t *testing.T, store student.StudentStore) {
+	post(t, store, newStudent("s1", "first@example.com"))
+
+	_, err := store.RestoreStudent(context.Background(), "s1", "restorer", entry("s1", student.AuditRestore))
+	wantErr(t, err, student.ErrNoStudentFound)
+	_, err = store.RestoreStudent(context.Background(), "missing", "restorer", entry("missing", student.AuditRestore))
+	wantErr(t, err, student.ErrNoStudentFound)
+}
+
+func testPurgeDeletedStudents(t *testing.T, store student.StudentStore) {
+	ctx := context.Background()
+	post(t, store, newStudent("s1", "first@example.com"))
+	trash(t, store, newStudent("s2", "second@example.com"))
+
+	// the student was deleted after the cutoff, so it stays in the trash
+	purged, err := store.PurgeDeletedStudents(ctx, time.Now().Add(-time.Hour), entry("", student.AuditPurge))
+	if err != nil {
+		t.Fatalf("PurgeDeletedStudents: %v", err)
+	}
+	if purged != 0 {
+		t.Fatalf("purged %d students deleted after the cutoff", purged)
+	}
+
+	purged, err = store.PurgeDeletedStudents(ctx, time.Now().Add(time.Hour), entry("", student.AuditPurge))
+	if err != nil {
+		t.Fatalf("PurgeDeletedStudents: %v", err)
+	}
+	if purged != 1 {
+		t.Fatalf("purged %d students, want 1", purged)
+	}
+	deleted, err := store.ListDeletedStudents(ctx, student.ListCursor{}, 10)
+	if err != nil {
+		t.Fatalf("ListDeletedStudents: %v", err)
+	}
+	wantIDs(t, "ListDeletedStudents", deleted)
+	_, err = store.RestoreStudent(ctx, "s2", "restorer", entry("s2", student.AuditRestore))
+	wantErr(t, err, student.ErrNoStudentFound)
+	if _, err := store.GetStudent(ctx, "s1"); err != nil {
+		t.Fatalf("the purge removed a live student: %v", err)
+	}
+}
+
+func testPostEmailConflict(t *testing.T, store student.StudentStore) {
+	post(t, store, newStudent("s1", "taken@example.com"))
+
+	_, err := store.PostStudent(context.Background(), newStudent("s2", "taken@example.com"), entry("s2", student.AuditCreate))
+	wantConflict(t, err, student.ErrDuplicateEmail, "email", "s1")
+}
+
+func testEmailConflictIgnoresCase(t *testing.T, store student.StudentStore) {
+	post(t, store, newStudent("s1", "taken@example.com"))
+
+	_, err := store.PostStudent(context.Background(), newStudent("s2", "Taken@Example.COM"), entry("s2", student.AuditCreate))
+	wantConflict(t, err, student.ErrDuplicateEmail, "email", "s1")
+}
+
+func testUpdateEmailConflict(t *testing.T, store student.StudentStore) {
+	post(t, store, newStudent("s1", "taken@example.com"))
+	stud := post(t, store, newStudent("s2", "second@example.com"))
+
+	stud.Email = "taken@example.com"
+	_, err := store.UpdateStudent(context.Background(), stud.ID, stud, entry(stud.ID, student.AuditUpdate))
+	wantConflict(t, err, student.ErrDuplicateEmail, "email", "s1")
+
+	// keeping its own email is no conflict
+	stud.Email = "second@example.com"
+	if _, err := store.UpdateStudent(context.Background(), stud.ID, stud, entry(stud.ID, student.AuditUpdate)); err != nil {
+		t.Fatalf("UpdateStudent keeping the email: %v", err)
+	}
+}
+
+func testPatchEmailConflict(t *testing.T, store student.StudentStore) {
+	post(t, store, newStudent("s1", "taken@example.com"))
+	stud := post(t, store, newStudent("s2", "second@example.com"))
+
+	email := "taken@example.com"
+	err := store.PatchStudent(context.Background(), stud.ID, stud.Version, student.StudentPatch{Email: &email, UpdatedBy: "tester"}, entry(stud.ID, student.AuditUpdate))
+	wantConflict(t, err, student.ErrDuplicateEmail, "email", "s1")
+}
+
+func testTrashFreesEmail(t *testing.T, store student.StudentStore) {
+	trash(t, store, newStudent("s1", "taken@example.com"))
+
+	post(t, store, newStudent("s2", "taken@example.com"))
+}
+
+func testRestoreEmailConflict(t *testing.T, store student.StudentStore) {
+	trash(t, store, newStudent("s1", "taken@example.com"))
+	post(t, store, newStudent("s2", "taken@example.com"))
+
+	_, err := store.RestoreStudent(context.Background(), "s1", "restorer", entry("s1", student.AuditRestore))
+	wantConflict(t, err, student.ErrDuplicateEmail, "email", "s2")
+}
+
+func testFindConflicts(t *testing.T, store student.StudentStore) {
+	post(t, store, newStudent("s1", "first@example.com"))
+	trash(t, store, newStudent("s2", "second@example.com"))
+
+	conflicts, err := store.FindConflicts(context.Background(), []student.Student{
+		newStudent("s1", "new@example.com"),
+		newStudent("", "FIRST@example.com"),
+		// the ID of a student in the trash stays taken, its email does not
+		newStudent("s2", "second@example.com"),
+		newStudent("s3", "third@example.com"),
+	})
+	if err != nil {
+		t.Fatalf("FindConflicts: %v", err)
+	}
+	if len(conflicts) != 4 {
+		t.Fatalf("got conflicts of %d students, want 4", len(conflicts))
+	}
+	want := []struct {
+		field, conflictingID string
+	}{
+		{"id", "s1"},
+		{"email", "s1"},
+		{"id", "s2"},
+	}
+	for i, w := range want {
+		if len(conflicts[i]) != 1 || conflicts[i][0].Field != w.field || conflicts[i][0].ConflictingID != w.conflictingID {
+			t.Fatalf("student %d got conflicts %v, want one on %s with %q", i, conflicts[i], w.field, w.conflictingID)
+		}
+	}
+	if len(conflicts[3]) != 0 {
+		t.Fatalf("a new student got conflicts %v", conflicts[3])
+	}
+}
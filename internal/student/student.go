@@ -3,6 +3,8 @@ package student
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -10,14 +12,69 @@ import (
 
 var (
 	ErrFetchingStudent   = errors.New("could not fetch student by ID")
+	ErrPostingStudent    = errors.New("could not add student")
 	ErrUpdatingStudent   = errors.New("could not update student")
 	ErrNoStudentFound    = errors.New("no student found")
+	ErrDuplicateStudent  = errors.New("student already exists")
+	ErrDuplicateEmail    = errors.New("a student with this email already exists")
+	ErrVersionMismatch   = errors.New("student was modified since it was read")
+	ErrInvalidStudent    = errors.New("invalid student")
+	ErrStoreUnavailable  = errors.New("the store is unavailable")
 	ErrDeletingStudent   = errors.New("could not delete student")
 	ErrListingStudents   = errors.New("could not list students")
 	ErrSearchingStudents = errors.New("could not search students")
 	ErrNotImplemented    = errors.New("not implemented")
 )
 
+// ConflictError is a duplicate error telling which stored student the new
+// one clashes with
+type ConflictError struct {
+	Err           error
+	Field         string
+	ConflictingID string
+}
+
+func (e *ConflictError) Error() string {
+	if e.ConflictingID == "" {
+		return fmt.Sprintf("%s: the %s is already used", e.Err, e.Field)
+	}
+	return fmt.Sprintf("%s: the %s is already used by student %s", e.Err, e.Field, e.ConflictingID)
+}
+
+func (e *ConflictError) Unwrap() error {
+	return e.Err
+}
+
+// domainErrors are the errors the callers of the service can act on, the
+// service passes them through and hides every other store error
+var domainErrors = []error{
+	ErrNoStudentFound,
+	ErrDuplicateStudent,
+	ErrDuplicateEmail,
+	ErrVersionMismatch,
+	ErrInvalidStudent,
+	ErrInvalidSearch,
+	ErrStoreUnavailable,
+	ErrEnrollmentNotFound,
+	ErrInvalidGrade,
+}
+
+// PassThrough returns err when it wraps one of the domain errors, and
+// fallback otherwise. The services hide the store errors their callers
+// cannot act on behind it.
+func PassThrough(err, fallback error, domain ...error) error {
+	for _, domainErr := range domain {
+		if errors.Is(err, domainErr) {
+			return err
+		}
+	}
+	return fallback
+}
+
+func serviceError(err, fallback error) error {
+	return PassThrough(err, fallback, domainErrors...)
+}
+
 type Student struct {
 	ID        string    `json:"id" db:"id"`
 	CreatedBy string    `json:"created_by" db:"created_by"`
@@ -28,39 +85,145 @@ type Student struct {
 	Email     string    `json:"email" db:"email"`
 	Age       int       `json:"age" db:"age"`
 	Course    string    `json:"course" db:"course"`
+	// Version grows with every update, writers send the version they read
+	// and fail with ErrVersionMismatch when someone else wrote in between
+	Version int64 `json:"version" db:"version"`
+	// DeletedOn is set once the student is moved to the trash
+	DeletedBy string     `json:"deleted_by,omitempty" db:"deleted_by"`
+	DeletedOn *time.Time `json:"deleted_on,omitempty" db:"deleted_on"`
+	// CustomFields are stored as a JSON document, see FieldSchema
+	CustomFields CustomFields `json:"custom_fields,omitempty" db:"-"`
 }
 
+// StudentStore keeps the students. Deleted students stay in the trash, where
+// only ListDeletedStudents, RestoreStudent and PurgeDeletedStudents see
+// them, until they are purged.
 type StudentStore interface {
 	GetStudent(context.Context, string) (Student, error)
-	PostStudent(context.Context, Student) (Student, error)
-	UpdateStudent(context.Context, string, Student) (Student, error)
-	DeleteStudent(context.Context, string) error
+	// The writes record the given audit entry in the same transaction as the
+	// change, a change failing to be audited is not stored.
+	PostStudent(context.Context, Student, AuditEntry) (Student, error)
+	// UpdateStudent and DeleteStudent only apply when the stored version is
+	// still the given one
+	UpdateStudent(context.Context, string, Student, AuditEntry) (Student, error)
+	DeleteStudent(ctx context.Context, id string, version int64, deletedBy string, entry AuditEntry) error
+	// PatchStudent writes only the fields set in the patch
+	PatchStudent(context.Context, string, int64, StudentPatch, AuditEntry) error
+	ListStudents(context.Context, ListCursor, int) ([]Student, error)
+	SearchStudents(context.Context, SearchFilter) (SearchResult, error)
+	// ExportStudents passes every student matching the filter to emit, in
+	// the order of the filter, and stops at the first error emit returns
+	ExportStudents(ctx context.Context, filter SearchFilter, emit func(Student) error) error
+	ListDeletedStudents(context.Context, ListCursor, int) ([]Student, error)
+	RestoreStudent(ctx context.Context, id, restoredBy string, entry AuditEntry) (Student, error)
+	// PurgeDeletedStudents records entry once for each purged student, with
+	// the StudentID set to it
+	PurgeDeletedStudents(ctx context.Context, deletedBefore time.Time, entry AuditEntry) (int64, error)
+	// PostStudents inserts all the students or none of them, entries holds
+	// the audit entry of each
+	PostStudents(ctx context.Context, studs []Student, entries []AuditEntry) ([]Student, error)
+	// FindConflicts returns, for each student, the stored students using its
+	// ID or, outside the trash, its email. An empty ID is not checked.
+	FindConflicts(context.Context, []Student) ([][]*ConflictError, error)
 	Ping(context.Context) error
 }
 
 type Service struct {
 	Store StudentStore
+	Audit AuditStore
+	// NewID generates the IDs of new students, client supplied IDs are
+	// refused unless AllowClientIDs is set
+	NewID          IDGenerator
+	AllowClientIDs bool
+	// ImportBatchSize is the number of rows an import inserts per
+	// transaction, 0 inserts them all in one
+	ImportBatchSize int
+	// Courses checks the course of the students written, nil accepts any
+	Courses CourseCatalog
+	// Fields checks the custom fields of the students written, nil allows
+	// none
+	Fields FieldSchema
+	// Enrollments and Grades keep the grades and transcripts, GradeScale is
+	// the scale final grades are given on unless told otherwise
+	Enrollments EnrollmentLookup
+	Grades      GradeStore
+	// Seats gives up the seats of deleted and purged students, nil when no
+	// enrollment is kept
+	Seats           SeatReleaser
+	GradeScale      string
+	Users           UserStore
+	Tokens          TokenStore
+	Signer          AccessTokenSigner
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
 }
 
-func NewService(store StudentStore) *Service {
+func NewService(store StudentStore, audit AuditStore, users UserStore, tokens TokenStore, signer AccessTokenSigner) *Service {
 	return &Service{
-		Store: store,
+		Store:           store,
+		Audit:           audit,
+		NewID:           NewUUIDv7,
+		GradeScale:      ScaleLetter,
+		Users:           users,
+		Tokens:          tokens,
+		Signer:          signer,
+		AccessTokenTTL:  DefaultAccessTokenTTL,
+		RefreshTokenTTL: DefaultRefreshTokenTTL,
 	}
 }
 
+// validateStudent checks the rules every stored student follows whatever
+// the way it came in
+func validateStudent(student Student) error {
+	switch {
+	case strings.TrimSpace(student.Name) == "":
+		return fmt.Errorf("%w: name is required", ErrInvalidStudent)
+	case !strings.Contains(student.Email, "@"):
+		return fmt.Errorf("%w: email must be a valid email address", ErrInvalidStudent)
+	case student.Age <= 0:
+		return fmt.Errorf("%w: age must be greater than 0", ErrInvalidStudent)
+	case strings.TrimSpace(student.Course) == "":
+		return fmt.Errorf("%w: course is required", ErrInvalidStudent)
+	}
+	return nil
+}
+
 func (s *Service) GetStudent(ctx context.Context, ID string) (Student, error) {
 	student, err := s.Store.GetStudent(ctx, ID)
 	if err != nil {
 		log.Errorf("an error occurred fetching the student: %s", err.Error())
-		return Student{}, ErrFetchingStudent
+		return Student{}, serviceError(err, ErrFetchingStudent)
 	}
 	return student, nil
 }
 
 func (s *Service) PostStudent(ctx context.Context, student Student) (Student, error) {
-	student, err := s.Store.PostStudent(ctx, student)
+	if err := validateStudent(student); err != nil {
+		return Student{}, err
+	}
+	if student.ID == "" {
+		id, err := s.NewID()
+		if err != nil {
+			log.Errorf("an error occurred generating the student ID: %s", err.Error())
+			return Student{}, ErrPostingStudent
+		}
+		student.ID = id
+	} else if !s.AllowClientIDs {
+		return Student{}, fmt.Errorf("%w: id is assigned by the server and cannot be set", ErrInvalidStudent)
+	}
+	course, err := s.resolveCourse(ctx, student.Course)
+	if err != nil {
+		return Student{}, serviceError(err, ErrPostingStudent)
+	}
+	student.Course = course
+	if student.CustomFields, err = s.checkCustomFields(ctx, student.CustomFields); err != nil {
+		return Student{}, serviceError(err, ErrPostingStudent)
+	}
+	entry := auditEntry(ctx, student.ID, AuditCreate, student.CreatedBy, createdFields(student))
+	student, err = s.Store.PostStudent(ctx, student, entry)
 	if err != nil {
 		log.Errorf("an error occurred adding the student: %s", err.Error())
+		return Student{}, serviceError(err, ErrPostingStudent)
 	}
 	return student, nil
 }
@@ -68,19 +231,56 @@ func (s *Service) PostStudent(ctx context.Context, student Student) (Student, er
 func (s *Service) UpdateStudent(
 	ctx context.Context, ID string, newStudent Student,
 ) (Student, error) {
-	student, err := s.Store.UpdateStudent(ctx, ID, newStudent)
+	if err := validateStudent(newStudent); err != nil {
+		return Student{}, err
+	}
+	// the version check of the store makes sure nothing changed in between
+	before, err := s.Store.GetStudent(ctx, ID)
+	if err != nil {
+		log.Errorf("an error occurred fetching the student: %s", err.Error())
+		return Student{}, serviceError(err, ErrUpdatingStudent)
+	}
+	if newStudent.Course, err = s.resolveChangedCourse(ctx, before.Course, newStudent.Course); err != nil {
+		return Student{}, serviceError(err, ErrUpdatingStudent)
+	}
+	if newStudent.CustomFields, err = s.checkCustomFields(ctx, newStudent.CustomFields); err != nil {
+		return Student{}, serviceError(err, ErrUpdatingStudent)
+	}
+	entry := auditEntry(ctx, ID, AuditUpdate, newStudent.UpdatedBy, diffStudents(before, newStudent))
+	student, err := s.Store.UpdateStudent(ctx, ID, newStudent, entry)
 	if err != nil {
 		log.Errorf("an error occurred updating the student: %s", err.Error())
+		return Student{}, serviceError(err, ErrUpdatingStudent)
 	}
 	return student, nil
 }
 
-func (s *Service) DeleteStudent(ctx context.Context, ID string) error {
-	err := s.Store.DeleteStudent(ctx, ID)
+// SeatReleaser frees the seats of the students leaving for the students
+// waiting for one, it is implemented by the enrollment service
+type SeatReleaser interface {
+	// WithdrawStudent withdraws every enrollment of the student still
+	// active or waitlisted, promoting the waitlists of their courses
+	WithdrawStudent(ctx context.Context, studentID, updatedBy string) error
+	// FillSeats promotes the waitlists of the course into its free seats
+	FillSeats(ctx context.Context, code, updatedBy string) error
+}
+
+// DeleteStudent moves the student to the trash and withdraws its enrollments,
+// a restored student enrolls again
+func (s *Service) DeleteStudent(ctx context.Context, ID string, version int64, deletedBy string) error {
+	err := s.Store.DeleteStudent(ctx, ID, version, deletedBy, auditEntry(ctx, ID, AuditDelete, deletedBy, nil))
 	if err != nil {
 		log.Errorf("an error occurred deleting the student: %s", err.Error())
+		return serviceError(err, ErrDeletingStudent)
+	}
+	if s.Seats != nil {
+		// the student is in the trash either way, seats still held are
+		// given up when it is purged
+		if err := s.Seats.WithdrawStudent(ctx, ID, deletedBy); err != nil {
+			log.Errorf("an error occurred withdrawing the enrollments of deleted student %s: %s", ID, err.Error())
+		}
 	}
-	return err
+	return nil
 }
 
 func (s *Service) ReadyCheck(ctx context.Context) error {
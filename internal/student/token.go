@@ -0,0 +1,234 @@
+package student
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	DefaultAccessTokenTTL  = 15 * time.Minute
+	DefaultRefreshTokenTTL = 7 * 24 * time.Hour
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token was already used, all tokens of its family are revoked")
+	ErrTokenNotFound       = errors.New("no token found")
+	ErrCheckingToken       = errors.New("could not check token")
+)
+
+// RefreshToken is the server side record of a refresh token. Only the
+// SHA-256 of the token is kept. Every refresh replaces the token by a new
+// one of the same family, presenting a replaced token again revokes the
+// whole family since it means the token leaked.
+type RefreshToken struct {
+	Hash      string
+	FamilyID  string
+	UserID    string
+	CreatedOn time.Time
+	ExpiresOn time.Time
+	UsedOn    time.Time
+	RevokedOn time.Time
+}
+
+type TokenStore interface {
+	CreateRefreshToken(context.Context, RefreshToken) error
+	GetRefreshToken(context.Context, string) (RefreshToken, error)
+	// UseRefreshToken marks an unused and unrevoked token as used and
+	// reports false when it was not in that state anymore
+	UseRefreshToken(context.Context, string, time.Time) (bool, error)
+	RevokeTokenFamily(context.Context, string, time.Time) error
+	RevokeUserTokens(context.Context, string, time.Time) error
+	RevokeAccessToken(context.Context, string, time.Time) error
+	IsAccessTokenRevoked(context.Context, string) (bool, error)
+	PurgeExpiredTokens(context.Context, time.Time) error
+}
+
+// AccessTokenSigner signs the JWT access tokens
+type AccessTokenSigner interface {
+	Sign(userID, role string, ttl time.Duration) (string, error)
+}
+
+type TokenPair struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate random token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// issueTokens creates an access token and a refresh token in the given family
+func (s *Service) issueTokens(ctx context.Context, user User, familyID string) (TokenPair, error) {
+	accessToken, err := s.GenerateJWT(user)
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	refreshToken, err := randomToken(32)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	now := time.Now()
+	err = s.Tokens.CreateRefreshToken(ctx, RefreshToken{
+		Hash:      hashToken(refreshToken),
+		FamilyID:  familyID,
+		UserID:    user.ID,
+		CreatedOn: now,
+		ExpiresOn: now.Add(s.RefreshTokenTTL),
+	})
+	if err != nil {
+		return TokenPair{}, fmt.Errorf("failed to store refresh token: %w", err)
+	}
+
+	return TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(s.AccessTokenTTL.Seconds()),
+	}, nil
+}
+
+// IssueTokens starts a new token family for a user who just logged in
+func (s *Service) IssueTokens(ctx context.Context, user User) (TokenPair, error) {
+	familyID, err := randomToken(16)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	return s.issueTokens(ctx, user, familyID)
+}
+
+// RefreshTokens exchanges a refresh token for a new access token and a new
+// refresh token, the presented one can not be used again
+func (s *Service) RefreshTokens(ctx context.Context, refreshToken string) (TokenPair, error) {
+	hash := hashToken(refreshToken)
+	stored, err := s.Tokens.GetRefreshToken(ctx, hash)
+	if err != nil {
+		if errors.Is(err, ErrTokenNotFound) {
+			return TokenPair{}, ErrInvalidRefreshToken
+		}
+		log.Errorf("an error occurred fetching the refresh token: %s", err.Error())
+		return TokenPair{}, ErrCheckingToken
+	}
+
+	now := time.Now()
+	if !stored.RevokedOn.IsZero() || !stored.UsedOn.IsZero() {
+		return TokenPair{}, s.revokeReusedFamily(ctx, stored, now)
+	}
+	if now.After(stored.ExpiresOn) {
+		return TokenPair{}, ErrInvalidRefreshToken
+	}
+
+	user, err := s.Users.GetUser(ctx, stored.UserID)
+	if err != nil || user.Disabled {
+		return TokenPair{}, ErrInvalidRefreshToken
+	}
+
+	used, err := s.Tokens.UseRefreshToken(ctx, hash, now)
+	if err != nil {
+		log.Errorf("an error occurred using the refresh token: %s", err.Error())
+		return TokenPair{}, ErrCheckingToken
+	}
+	if !used {
+		// someone else refreshed with the same token in the meantime
+		return TokenPair{}, s.revokeReusedFamily(ctx, stored, now)
+	}
+
+	return s.issueTokens(ctx, user, stored.FamilyID)
+}
+
+func (s *Service) revokeReusedFamily(ctx context.Context, stored RefreshToken, now time.Time) error {
+	log.Warnf("refresh token reuse detected for user %s, revoking its token family", stored.UserID)
+	if err := s.Tokens.RevokeTokenFamily(ctx, stored.FamilyID, now); err != nil {
+		log.Errorf("an error occurred revoking the token family: %s", err.Error())
+		return ErrCheckingToken
+	}
+	return ErrRefreshTokenReused
+}
+
+// Logout revokes the access token identified by jti until it expires, and
+// the family of the refresh token when one is given
+func (s *Service) Logout(ctx context.Context, jti string, expiresOn time.Time, refreshToken string) error {
+	now := time.Now()
+	if err := s.Tokens.RevokeAccessToken(ctx, jti, expiresOn); err != nil {
+		log.Errorf("an error occurred revoking the access token: %s", err.Error())
+		return ErrCheckingToken
+	}
+
+	if refreshToken == "" {
+		return nil
+	}
+	stored, err := s.Tokens.GetRefreshToken(ctx, hashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, ErrTokenNotFound) {
+			return nil
+		}
+		log.Errorf("an error occurred fetching the refresh token: %s", err.Error())
+		return ErrCheckingToken
+	}
+	if err := s.Tokens.RevokeTokenFamily(ctx, stored.FamilyID, now); err != nil {
+		log.Errorf("an error occurred revoking the token family: %s", err.Error())
+		return ErrCheckingToken
+	}
+	return nil
+}
+
+// IsTokenRevoked reports whether the access token jti of the user was
+// revoked, either by itself or by disabling the user. The access tokens are
+// not listed per user, so the user is read on every check instead.
+func (s *Service) IsTokenRevoked(ctx context.Context, jti, userID string) (bool, error) {
+	revoked, err := s.Tokens.IsAccessTokenRevoked(ctx, jti)
+	if err != nil {
+		log.Errorf("an error occurred checking the token revocation list: %s", err.Error())
+		return false, ErrCheckingToken
+	}
+	if revoked {
+		return true, nil
+	}
+
+	user, err := s.Users.GetUser(ctx, userID)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return true, nil
+		}
+		log.Errorf("an error occurred fetching the user of the token: %s", err.Error())
+		return false, ErrCheckingToken
+	}
+	return user.Disabled, nil
+}
+
+// PurgeExpiredTokens periodically drops the refresh tokens and revoked access
+// tokens that expired, until ctx is done
+func (s *Service) PurgeExpiredTokens(ctx context.Context, every time.Duration) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Tokens.PurgeExpiredTokens(ctx, time.Now()); err != nil {
+				log.Errorf("an error occurred purging expired tokens: %s", err.Error())
+			}
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package student
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var ErrBuildingTranscript = errors.New("could not build the transcript")
+
+// TranscriptCourse is a line of a transcript, Grade is nil until the
+// enrollment has a final grade
+type TranscriptCourse struct {
+	EnrollmentID int64       `json:"enrollment_id"`
+	Course       string      `json:"course"`
+	Title        string      `json:"title"`
+	Credits      int         `json:"credits"`
+	Status       string      `json:"status"`
+	Grade        *FinalGrade `json:"grade"`
+}
+
+// GPASummary adds up graded courses. Attempted credits are those of every
+// course graded on points, the GPA is their credit-weighted average and is nil
+// when there are none. Earned credits are those of every passing grade.
+type GPASummary struct {
+	AttemptedCredits int      `json:"attempted_credits"`
+	EarnedCredits    int      `json:"earned_credits"`
+	QualityPoints    float64  `json:"quality_points"`
+	GPA              *float64 `json:"gpa"`
+}
+
+func (g *GPASummary) add(grade *FinalGrade) {
+	if grade == nil {
+		return
+	}
+	if grade.Passing {
+		g.EarnedCredits += grade.Credits
+	}
+	if grade.Points != nil {
+		g.AttemptedCredits += grade.Credits
+		g.QualityPoints += *grade.Points * float64(grade.Credits)
+	}
+}
+
+// finish rounds the totals to two decimals and computes the GPA
+func (g *GPASummary) finish() {
+	if g.AttemptedCredits > 0 {
+		g.GPA = points(math.Round(100*g.QualityPoints/float64(g.AttemptedCredits)) / 100)
+	}
+	g.QualityPoints = math.Round(100*g.QualityPoints) / 100
+}
+
+type TranscriptTerm struct {
+	Term    string             `json:"term"`
+	Courses []TranscriptCourse `json:"courses"`
+	GPASummary
+}
+
+// Transcript lists the courses of a student term by term, in the order the
+// student first enrolled in each term, with the GPA of every term and the
+// cumulative one. Waitlisted enrollments are left out, withdrawn ones are
+// listed without a grade.
+type Transcript struct {
+	StudentID   string           `json:"student_id"`
+	Name        string           `json:"name"`
+	Email       string           `json:"email"`
+	Terms       []TranscriptTerm `json:"terms"`
+	Cumulative  GPASummary       `json:"cumulative"`
+	GeneratedOn time.Time        `json:"generated_on"`
+}
+
+func (s *Service) Transcript(ctx context.Context, studentID string) (Transcript, error) {
+	if s.Enrollments == nil || s.Grades == nil {
+		return Transcript{}, ErrNotImplemented
+	}
+	stud, err := s.GetStudent(ctx, studentID)
+	if err != nil {
+		return Transcript{}, err
+	}
+	enrolled, err := s.Enrollments.ListEnrolledCourses(ctx, studentID)
+	if err != nil {
+		log.Errorf("an error occurred listing the enrollments: %s", err.Error())
+		return Transcript{}, serviceError(err, ErrBuildingTranscript)
+	}
+	var courses []EnrolledCourse
+	var ids []int64
+	for _, c := range enrolled {
+		if c.Status != "waitlisted" {
+			courses = append(courses, c)
+			ids = append(ids, c.EnrollmentID)
+		}
+	}
+	grades := map[int64]FinalGrade{}
+	if len(ids) > 0 {
+		finals, err := s.Grades.ListFinalGrades(ctx, ids)
+		if err != nil {
+			log.Errorf("an error occurred listing the final grades: %s", err.Error())
+			return Transcript{}, serviceError(err, ErrBuildingTranscript)
+		}
+		for _, f := range finals {
+			grades[f.EnrollmentID] = f.withScale()
+		}
+	}
+
+	t := Transcript{StudentID: stud.ID, Name: stud.Name, Email: stud.Email, Terms: []TranscriptTerm{}, GeneratedOn: time.Now()}
+	for _, term := range sortedTerms(courses) {
+		tt := TranscriptTerm{Term: term, Courses: []TranscriptCourse{}}
+		for _, c := range courses {
+			if c.Term != term {
+				continue
+			}
+			line := TranscriptCourse{EnrollmentID: c.EnrollmentID, Course: c.Course, Title: c.Title, Credits: c.Credits, Status: c.Status}
+			// a withdrawn course is listed without its grade, it counts
+			// neither toward the credits nor the GPA
+			if grade, ok := grades[c.EnrollmentID]; ok && c.Status != "withdrawn" {
+				line.Grade = &grade
+				// the credits the course was graded with
+				line.Credits = grade.Credits
+			}
+			tt.add(line.Grade)
+			t.Cumulative.add(line.Grade)
+			tt.Courses = append(tt.Courses, line)
+		}
+		sort.Slice(tt.Courses, func(i, j int) bool { return tt.Courses[i].Course < tt.Courses[j].Course })
+		tt.finish()
+		t.Terms = append(t.Terms, tt)
+	}
+	t.Cumulative.finish()
+	return t, nil
+}
+
+// sortedTerms returns the terms of the courses in the order the student
+// first enrolled in them, which is the order of a transcript
+func sortedTerms(courses []EnrolledCourse) []string {
+	first := map[string]time.Time{}
+	for _, c := range courses {
+		if on, ok := first[c.Term]; !ok || c.EnrolledOn.Before(on) {
+			first[c.Term] = c.EnrolledOn
+		}
+	}
+	terms := make([]string, 0, len(first))
+	for term := range first {
+		terms = append(terms, term)
+	}
+	sort.Slice(terms, func(i, j int) bool {
+		if !first[terms[i]].Equal(first[terms[j]]) {
+			return first[terms[i]].Before(first[terms[j]])
+		}
+		return terms[i] < terms[j]
+	})
+	return terms
+}
@@ -0,0 +1,102 @@
+package student
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var ErrRestoringStudent = errors.New("could not restore student")
+
+// ListDeletedStudents lists the students in the trash page by page
+func (s *Service) ListDeletedStudents(ctx context.Context, pageToken string, pageSize int) (StudentPage, error) {
+	return s.listPage(ctx, s.Store.ListDeletedStudents, pageToken, pageSize)
+}
+
+// RestoreStudent takes a student out of the trash
+func (s *Service) RestoreStudent(ctx context.Context, ID, restoredBy string) (Student, error) {
+	student, err := s.Store.RestoreStudent(ctx, ID, restoredBy, auditEntry(ctx, ID, AuditRestore, restoredBy, nil))
+	if err != nil {
+		log.Errorf("an error occurred restoring the student: %s", err.Error())
+		return Student{}, serviceError(err, ErrRestoringStudent)
+	}
+	return student, nil
+}
+
+// PurgeActor is the actor of the audit entries of purged students
+const PurgeActor = "system"
+
+// PurgeDeletedStudents periodically removes for good the students deleted
+// more than retention ago, until ctx is done. Each purge is audited.
+func (s *Service) PurgeDeletedStudents(ctx context.Context, every, retention time.Duration) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.purge(ctx, time.Now().Add(-retention))
+		}
+	}
+}
+
+// purge removes the students deleted before deletedBefore. Their enrollments
+// go with them, so the waitlists of their courses are promoted afterwards.
+func (s *Service) purge(ctx context.Context, deletedBefore time.Time) {
+	courses, err := s.purgedCourses(ctx, deletedBefore)
+	if err != nil {
+		log.Errorf("an error occurred finding the courses of the students to purge: %s", err.Error())
+		return
+	}
+	entry := auditEntry(ctx, "", AuditPurge, PurgeActor, nil)
+	purged, err := s.Store.PurgeDeletedStudents(ctx, deletedBefore, entry)
+	if err != nil {
+		log.Errorf("an error occurred purging deleted students: %s", err.Error())
+		return
+	}
+	if purged == 0 {
+		return
+	}
+	log.Infof("purged %d students deleted before %s", purged, deletedBefore.Format(time.RFC3339))
+	for code := range courses {
+		// a failure is logged by the enrollment service, the next purge
+		// or change of the course promotes the waitlist
+		_ = s.Seats.FillSeats(ctx, code, PurgeActor)
+	}
+}
+
+// purgedCourses returns the courses the students deleted before
+// deletedBefore are enrolled in, none when no enrollment is kept
+func (s *Service) purgedCourses(ctx context.Context, deletedBefore time.Time) (map[string]bool, error) {
+	courses := map[string]bool{}
+	if s.Seats == nil || s.Enrollments == nil {
+		return courses, nil
+	}
+	var after ListCursor
+	for {
+		students, err := s.Store.ListDeletedStudents(ctx, after, MaxPageSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, stud := range students {
+			if stud.DeletedOn == nil || !stud.DeletedOn.Before(deletedBefore) {
+				continue
+			}
+			enrolled, err := s.Enrollments.ListEnrolledCourses(ctx, stud.ID)
+			if err != nil {
+				return nil, err
+			}
+			for _, c := range enrolled {
+				courses[c.Course] = true
+			}
+		}
+		if len(students) < MaxPageSize {
+			return courses, nil
+		}
+		last := students[len(students)-1]
+		after = ListCursor{CreatedOn: last.CreatedOn, ID: last.ID}
+	}
+}
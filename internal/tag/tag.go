@@ -0,0 +1,340 @@
+// Package tag groups students under free-form tags such as "scholarship
+// 2026" or "needs follow-up". A student has any number of tags and a tag any
+// number of students, a search keeps the students having given tags.
+package tag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"golang-assignment/internal/student"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	ErrTagNotFound  = errors.New("no tag found")
+	ErrDuplicateTag = errors.New("tag already exists")
+	ErrInvalidTag   = errors.New("invalid tag")
+	ErrManagingTags = errors.New("could not manage tags")
+)
+
+// domainErrors are passed through by the service, any other store error is
+// hidden behind ErrManagingTags
+var domainErrors = []error{
+	ErrTagNotFound,
+	ErrDuplicateTag,
+	ErrInvalidTag,
+	student.ErrNoStudentFound,
+	student.ErrInvalidSearch,
+	student.ErrStoreUnavailable,
+}
+
+func serviceError(err error) error {
+	return student.PassThrough(err, ErrManagingTags, domainErrors...)
+}
+
+const (
+	// MaxNameLength is the longest name a tag may have
+	MaxNameLength = 64
+	// MaxBulkStudents is the most student IDs a bulk operation may list
+	MaxBulkStudents = 5000
+	// bulkBatchSize is the number of students written per store call, the
+	// students of a filter are tagged a batch at a time as they are found
+	bulkBatchSize = 500
+)
+
+// Tag is a label students are grouped under. Its name is stored lower case
+// and never changes, Students is the number of students out of the trash
+// having it.
+type Tag struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Students    int       `json:"students"`
+	CreatedBy   string    `json:"created_by"`
+	CreatedOn   time.Time `json:"created_on"`
+	UpdatedBy   string    `json:"updated_by"`
+	UpdatedOn   time.Time `json:"updated_on"`
+}
+
+// StudentTag is a tag of a student along with who tagged it
+type StudentTag struct {
+	Name     string    `json:"name"`
+	TaggedBy string    `json:"tagged_by"`
+	TaggedOn time.Time `json:"tagged_on"`
+}
+
+// BulkResult tells what a bulk tag or untag did. Matched counts the students
+// found out of the trash, Changed the ones gaining or losing the tag.
+type BulkResult struct {
+	Matched  int      `json:"matched"`
+	Changed  int      `json:"changed"`
+	NotFound []string `json:"not_found"`
+}
+
+func (r *BulkResult) add(o BulkResult) {
+	r.Matched += o.Matched
+	r.Changed += o.Changed
+	r.NotFound = append(r.NotFound, o.NotFound...)
+}
+
+// TagStore keeps the tags and which students have them. Names are compared
+// ignoring case.
+type TagStore interface {
+	ListTags(context.Context) ([]Tag, error)
+	GetTag(context.Context, string) (Tag, error)
+	CreateTag(context.Context, Tag) (Tag, error)
+	UpdateTag(context.Context, Tag) (Tag, error)
+	// DeleteTag removes the tag from every student as well
+	DeleteTag(context.Context, string) error
+	// TagStudents gives the tag to the students of ids out of the trash,
+	// the others are reported as not found
+	TagStudents(ctx context.Context, name string, ids []string, taggedBy string) (BulkResult, error)
+	// UntagStudents takes the tag away from the students of ids out of
+	// the trash, the others are reported as not found
+	UntagStudents(ctx context.Context, name string, ids []string) (BulkResult, error)
+	ListStudentTags(ctx context.Context, studentID string) ([]StudentTag, error)
+}
+
+// StudentExporter reads the students matching a search, it is implemented
+// by the student service
+type StudentExporter interface {
+	GetStudent(ctx context.Context, ID string) (student.Student, error)
+	ExportStudents(ctx context.Context, filter student.SearchFilter, emit func(student.Student) error) error
+}
+
+type Service struct {
+	Store    TagStore
+	Students StudentExporter
+}
+
+func NewService(store TagStore, students StudentExporter) *Service {
+	return &Service{Store: store, Students: students}
+}
+
+// NormalizeName returns the name as it is stored, lower case with the
+// surrounding spaces trimmed
+func NormalizeName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+func validateName(name string) error {
+	if name == "" || len(name) > MaxNameLength {
+		return fmt.Errorf("%w: name must be 1 to %d characters", ErrInvalidTag, MaxNameLength)
+	}
+	for _, r := range name {
+		// a slash would not fit in the path of the tag
+		if r == '/' || !unicode.IsPrint(r) {
+			return fmt.Errorf("%w: name must be printable characters other than /", ErrInvalidTag)
+		}
+	}
+	return nil
+}
+
+func (s *Service) ListTags(ctx context.Context) ([]Tag, error) {
+	tags, err := s.Store.ListTags(ctx)
+	if err != nil {
+		log.Errorf("an error occurred listing the tags: %s", err.Error())
+		return nil, serviceError(err)
+	}
+	return tags, nil
+}
+
+func (s *Service) GetTag(ctx context.Context, name string) (Tag, error) {
+	t, err := s.Store.GetTag(ctx, NormalizeName(name))
+	if err != nil {
+		if !errors.Is(err, ErrTagNotFound) {
+			log.Errorf("an error occurred fetching the tag: %s", err.Error())
+		}
+		return Tag{}, serviceError(err)
+	}
+	return t, nil
+}
+
+func (s *Service) CreateTag(ctx context.Context, t Tag, createdBy string) (Tag, error) {
+	t.Name = NormalizeName(t.Name)
+	if err := validateName(t.Name); err != nil {
+		return Tag{}, err
+	}
+	t.Students = 0
+	t.CreatedBy = createdBy
+	t.UpdatedBy = createdBy
+	t, err := s.Store.CreateTag(ctx, t)
+	if err != nil {
+		log.Errorf("an error occurred adding the tag: %s", err.Error())
+		return Tag{}, serviceError(err)
+	}
+	return t, nil
+}
+
+// UpdateTag replaces the description of the tag, its name never changes
+func (s *Service) UpdateTag(ctx context.Context, name string, t Tag, updatedBy string) (Tag, error) {
+	existing, err := s.GetTag(ctx, name)
+	if err != nil {
+		return Tag{}, err
+	}
+	existing.Description = t.Description
+	existing.UpdatedBy = updatedBy
+	updated, err := s.Store.UpdateTag(ctx, existing)
+	if err != nil {
+		log.Errorf("an error occurred updating the tag: %s", err.Error())
+		return Tag{}, serviceError(err)
+	}
+	return updated, nil
+}
+
+// DeleteTag removes the tag from every student and then the tag itself
+func (s *Service) DeleteTag(ctx context.Context, name string) error {
+	if err := s.Store.DeleteTag(ctx, NormalizeName(name)); err != nil {
+		if !errors.Is(err, ErrTagNotFound) {
+			log.Errorf("an error occurred deleting the tag: %s", err.Error())
+		}
+		return serviceError(err)
+	}
+	return nil
+}
+
+// Bulk selects the students of a bulk operation, either by ID or by the
+// search of GET /students in Filter
+type Bulk struct {
+	StudentIDs []string
+	Filter     *student.SearchFilter
+}
+
+// TagStudents gives the tag to the students the bulk selects, the ones
+// already having it are left as they are
+func (s *Service) TagStudents(ctx context.Context, name string, bulk Bulk, taggedBy string) (BulkResult, error) {
+	return s.bulk(ctx, name, bulk, func(ids []string) (BulkResult, error) {
+		return s.Store.TagStudents(ctx, NormalizeName(name), ids, taggedBy)
+	})
+}
+
+// UntagStudents takes the tag away from the students the bulk selects
+func (s *Service) UntagStudents(ctx context.Context, name string, bulk Bulk) (BulkResult, error) {
+	return s.bulk(ctx, name, bulk, func(ids []string) (BulkResult, error) {
+		return s.Store.UntagStudents(ctx, NormalizeName(name), ids)
+	})
+}
+
+// bulk runs apply on the IDs the bulk selects, a batch at a time. The batches
+// written before a failing one stay written.
+func (s *Service) bulk(ctx context.Context, name string, bulk Bulk, apply func(ids []string) (BulkResult, error)) (BulkResult, error) {
+	if _, err := s.GetTag(ctx, name); err != nil {
+		return BulkResult{}, err
+	}
+
+	result := BulkResult{NotFound: []string{}}
+	err := s.eachBulkBatch(ctx, bulk, func(ids []string) error {
+		batch, err := apply(ids)
+		if err != nil {
+			log.Errorf("an error occurred tagging the students: %s", err.Error())
+			return serviceError(err)
+		}
+		result.add(batch)
+		return nil
+	})
+	if err != nil {
+		return BulkResult{}, err
+	}
+	return result, nil
+}
+
+// eachBulkBatch calls apply with the distinct IDs the bulk selects, at most
+// bulkBatchSize at a time. The students of a filter are never all held in
+// memory, however many match.
+func (s *Service) eachBulkBatch(ctx context.Context, bulk Bulk, apply func(ids []string) error) error {
+	if (len(bulk.StudentIDs) == 0) == (bulk.Filter == nil) {
+		return fmt.Errorf("%w: either student_ids or query selects the students", ErrInvalidTag)
+	}
+	if bulk.Filter == nil {
+		if len(bulk.StudentIDs) > MaxBulkStudents {
+			return fmt.Errorf("%w: at most %d student_ids", ErrInvalidTag, MaxBulkStudents)
+		}
+		seen := make(map[string]bool, len(bulk.StudentIDs))
+		ids := make([]string, 0, len(bulk.StudentIDs))
+		for _, id := range bulk.StudentIDs {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+		for start := 0; start < len(ids); start += bulkBatchSize {
+			if err := apply(ids[start:min(start+bulkBatchSize, len(ids))]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// tagging the whole roster by mistake is too easy with an empty query
+	if bulk.Filter.IsEmpty() {
+		return fmt.Errorf("%w: the query must filter the students", ErrInvalidTag)
+	}
+	ids := make([]string, 0, bulkBatchSize)
+	// an error of apply is returned as is, unlike one of the export
+	var applyErr error
+	err := s.Students.ExportStudents(ctx, *bulk.Filter, func(stud student.Student) error {
+		ids = append(ids, stud.ID)
+		if len(ids) < bulkBatchSize {
+			return nil
+		}
+		applyErr = apply(ids)
+		ids = make([]string, 0, bulkBatchSize)
+		return applyErr
+	})
+	if applyErr != nil {
+		return applyErr
+	}
+	if err != nil {
+		if errors.Is(err, student.ErrInvalidSearch) {
+			return fmt.Errorf("%w: %v", ErrInvalidTag, err)
+		}
+		return serviceError(err)
+	}
+	if len(ids) > 0 {
+		return apply(ids)
+	}
+	return nil
+}
+
+// AddStudentTag gives one tag to a student
+func (s *Service) AddStudentTag(ctx context.Context, studentID, name, taggedBy string) error {
+	result, err := s.TagStudents(ctx, name, Bulk{StudentIDs: []string{studentID}}, taggedBy)
+	if err != nil {
+		return err
+	}
+	if len(result.NotFound) > 0 {
+		return fmt.Errorf("student with ID %s not found: %w", studentID, student.ErrNoStudentFound)
+	}
+	return nil
+}
+
+// RemoveStudentTag takes one tag away from a student, a student without the
+// tag is left as it is
+func (s *Service) RemoveStudentTag(ctx context.Context, studentID, name string) error {
+	result, err := s.UntagStudents(ctx, name, Bulk{StudentIDs: []string{studentID}})
+	if err != nil {
+		return err
+	}
+	if len(result.NotFound) > 0 {
+		return fmt.Errorf("student with ID %s not found: %w", studentID, student.ErrNoStudentFound)
+	}
+	return nil
+}
+
+// ListStudentTags returns the tags of a student ordered by name
+func (s *Service) ListStudentTags(ctx context.Context, studentID string) ([]StudentTag, error) {
+	if _, err := s.Students.GetStudent(ctx, studentID); err != nil {
+		return nil, err
+	}
+	tags, err := s.Store.ListStudentTags(ctx, studentID)
+	if err != nil {
+		log.Errorf("an error occurred listing the tags of the student: %s", err.Error())
+		return nil, serviceError(err)
+	}
+	return tags, nil
+}
@@ -0,0 +1,182 @@
+package transport
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"golang-assignment/internal/attachment"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	util "golang-assignment/utils"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+type AttachmentService interface {
+	Upload(ctx context.Context, u attachment.Upload, uploadedBy string) (attachment.Attachment, error)
+	ListAttachments(ctx context.Context, studentID string) ([]attachment.Attachment, error)
+	GetAttachment(ctx context.Context, studentID string, id int64) (attachment.Attachment, error)
+	OpenAttachment(ctx context.Context, studentID string, id int64) (attachment.Attachment, attachment.Blob, error)
+	DeleteAttachment(ctx context.Context, studentID string, id int64) error
+}
+
+const (
+	// maxUploadFields bounds the form fields read before the file
+	maxUploadFields = 8
+	// maxUploadFieldBytes is the longest value of a form field
+	maxUploadFieldBytes = 1024
+)
+
+// attachmentID returns the id of the attachment of the route, answering 404
+// when it is not a number
+func attachmentID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(mux.Vars(r)["attachmentID"], 10, 64)
+	if err != nil {
+		writeError(w, r, attachment.ErrAttachmentNotFound, "")
+		return 0, false
+	}
+	return id, true
+}
+
+// UploadAttachment streams the file field of a multipart form to the
+// attachment service. The kind and the optional sha256 fields come before the
+// file, which is read as it arrives and never held whole.
+func (h *Handler) UploadAttachment(w http.ResponseWriter, r *http.Request) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		writeProblem(w, r, http.StatusUnsupportedMediaType, CodeUnsupportedMediaType,
+			"The Content-Type must be multipart/form-data")
+		return
+	}
+	// the read and write timeouts of the server are meant for the other
+	// routes, a large file may take longer
+	rc := http.NewResponseController(w)
+	if err := rc.SetReadDeadline(time.Time{}); err != nil {
+		log.Warnf("failed to lift the read deadline of the upload: %v", err)
+	}
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		log.Warnf("failed to lift the write deadline of the upload: %v", err)
+	}
+
+	fields := map[string]string{}
+	for i := 0; i <= maxUploadFields; i++ {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			writeProblem(w, r, http.StatusBadRequest, CodeInvalidRequestBody, "Invalid multipart form")
+			return
+		}
+		if part.FormName() != "file" {
+			value, err := io.ReadAll(io.LimitReader(part, maxUploadFieldBytes+1))
+			if err != nil || len(value) > maxUploadFieldBytes {
+				writeProblem(w, r, http.StatusBadRequest, CodeInvalidRequestBody, "Invalid form field "+part.FormName())
+				return
+			}
+			fields[part.FormName()] = string(value)
+			continue
+		}
+
+		a, err := h.Attachments.Upload(r.Context(), attachment.Upload{
+			StudentID: mux.Vars(r)["id"],
+			Kind:      attachment.Kind(fields["kind"]),
+			FileName:  part.FileName(),
+			SHA256:    fields["sha256"],
+			Content:   part,
+		}, util.GetCurrentUserID(r.Context()))
+		if err != nil {
+			writeError(w, r, err, "Failed to upload attachment")
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		if err := json.NewEncoder(w).Encode(a); err != nil {
+			log.Errorf("Error encoding response: %v", err)
+		}
+		return
+	}
+	writeProblem(w, r, http.StatusBadRequest, CodeInvalidRequestBody, "The form must have a file field after the kind field")
+}
+
+func (h *Handler) ListAttachments(w http.ResponseWriter, r *http.Request) {
+	attachments, err := h.Attachments.ListAttachments(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, r, err, "Failed to list attachments")
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(attachments); err != nil {
+		log.Errorf("Error encoding response: %v", err)
+	}
+}
+
+func (h *Handler) GetAttachment(w http.ResponseWriter, r *http.Request) {
+	id, ok := attachmentID(w, r)
+	if !ok {
+		return
+	}
+
+	a, err := h.Attachments.GetAttachment(r.Context(), mux.Vars(r)["id"], id)
+	if err != nil {
+		writeError(w, r, err, "Failed to get attachment")
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(a); err != nil {
+		log.Errorf("Error encoding response: %v", err)
+	}
+}
+
+// DownloadAttachment streams the content of an attachment. Range and
+// conditional requests are answered by http.ServeContent, the checksum of
+// the content being its ETag.
+func (h *Handler) DownloadAttachment(w http.ResponseWriter, r *http.Request) {
+	id, ok := attachmentID(w, r)
+	if !ok {
+		return
+	}
+
+	a, blob, err := h.Attachments.OpenAttachment(r.Context(), mux.Vars(r)["id"], id)
+	if err != nil {
+		writeError(w, r, err, "Failed to download attachment")
+		return
+	}
+	defer blob.Close()
+
+	// the write timeout of the server is meant for the other routes
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		log.Warnf("failed to lift the write deadline of the download: %v", err)
+	}
+	w.Header().Set("Content-Type", a.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": a.FileName}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("ETag", `"`+a.SHA256+`"`)
+	if sum, err := hex.DecodeString(a.SHA256); err == nil {
+		w.Header().Set("Repr-Digest", "sha-256=:"+base64.StdEncoding.EncodeToString(sum)+":")
+	}
+	http.ServeContent(w, r, a.FileName, a.UploadedOn, blob)
+}
+
+func (h *Handler) DeleteAttachment(w http.ResponseWriter, r *http.Request) {
+	id, ok := attachmentID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.Attachments.DeleteAttachment(r.Context(), mux.Vars(r)["id"], id); err != nil {
+		writeError(w, r, err, "Failed to delete attachment")
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(Response{Message: "Successfully Deleted"}); err != nil {
+		log.Errorf("Error encoding response: %v", err)
+	}
+}
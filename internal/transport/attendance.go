@@ -0,0 +1,117 @@
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"golang-assignment/internal/attendance"
+	"net/http"
+	"strconv"
+
+	util "golang-assignment/utils"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+type AttendanceService interface {
+	MarkClass(ctx context.Context, m attendance.ClassMarking, recordedBy string) ([]attendance.Record, error)
+	SessionAttendance(ctx context.Context, code, date string) ([]attendance.Record, error)
+	StudentAttendance(ctx context.Context, studentID string, filter attendance.RecordFilter) (attendance.StudentAttendance, error)
+	ListAlerts(ctx context.Context, filter attendance.AlertFilter) ([]attendance.Alert, error)
+}
+
+type AttendanceEntry struct {
+	StudentID string `json:"student_id" validate:"required,max=64"`
+	Status    string `json:"status" validate:"required,oneof=present absent late excused"`
+	Note      string `json:"note" validate:"max=255"`
+}
+
+// MarkAttendanceRequest marks a session of the course, the students of the
+// roster missing from Records get DefaultStatus when it is set
+type MarkAttendanceRequest struct {
+	Term          string            `json:"term" validate:"required,max=32"`
+	Date          string            `json:"date" validate:"required"`
+	DefaultStatus string            `json:"default_status" validate:"omitempty,oneof=present absent late excused"`
+	Records       []AttendanceEntry `json:"records" validate:"dive"`
+}
+
+func (h *Handler) MarkAttendance(w http.ResponseWriter, r *http.Request) {
+	var req MarkAttendanceRequest
+	if !decodeAndValidate(w, r, &req) {
+		return
+	}
+
+	m := attendance.ClassMarking{
+		Course:        mux.Vars(r)["code"],
+		Term:          req.Term,
+		Date:          req.Date,
+		DefaultStatus: attendance.Status(req.DefaultStatus),
+	}
+	for _, entry := range req.Records {
+		m.Records = append(m.Records, attendance.Record{StudentID: entry.StudentID, Status: attendance.Status(entry.Status), Note: entry.Note})
+	}
+	records, err := h.Attendance.MarkClass(r.Context(), m, util.GetCurrentUserID(r.Context()))
+	if err != nil {
+		writeError(w, r, err, "Failed to mark attendance")
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(records); err != nil {
+		log.Errorf("Error encoding response: %v", err)
+	}
+}
+
+func (h *Handler) SessionAttendance(w http.ResponseWriter, r *http.Request) {
+	date := r.URL.Query().Get("date")
+	if date == "" {
+		writeProblem(w, r, http.StatusBadRequest, CodeInvalidQuery, "date is required")
+		return
+	}
+
+	records, err := h.Attendance.SessionAttendance(r.Context(), mux.Vars(r)["code"], date)
+	if err != nil {
+		writeError(w, r, err, "Failed to list attendance")
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(records); err != nil {
+		log.Errorf("Error encoding response: %v", err)
+	}
+}
+
+func (h *Handler) StudentAttendance(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := attendance.RecordFilter{Course: query.Get("course"), From: query.Get("from"), To: query.Get("to")}
+
+	a, err := h.Attendance.StudentAttendance(r.Context(), mux.Vars(r)["id"], filter)
+	if err != nil {
+		writeError(w, r, err, "Failed to get attendance")
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(a); err != nil {
+		log.Errorf("Error encoding response: %v", err)
+	}
+}
+
+func (h *Handler) ListAttendanceAlerts(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := attendance.AlertFilter{StudentID: query.Get("student_id"), Course: query.Get("course")}
+	if v := query.Get("open"); v != "" {
+		var err error
+		if filter.OpenOnly, err = strconv.ParseBool(v); err != nil {
+			writeProblem(w, r, http.StatusBadRequest, CodeInvalidQuery, "Invalid open")
+			return
+		}
+	}
+
+	alerts, err := h.Attendance.ListAlerts(r.Context(), filter)
+	if err != nil {
+		writeError(w, r, err, "Failed to list attendance alerts")
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(alerts); err != nil {
+		log.Errorf("Error encoding response: %v", err)
+	}
+}
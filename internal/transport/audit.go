@@ -0,0 +1,66 @@
+package transport
+
+import (
+	"encoding/json"
+	"golang-assignment/internal/student"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+// StudentHistory lists the changes made to a student, newest first
+func (h *Handler) StudentHistory(w http.ResponseWriter, r *http.Request) {
+	pageSize, ok := pageSizeFromQuery(w, r)
+	if !ok {
+		return
+	}
+
+	page, err := h.Service.StudentHistory(r.Context(), mux.Vars(r)["id"], r.URL.Query().Get("page_token"), pageSize)
+	if err != nil {
+		writeError(w, r, err, "Failed to fetch the student history")
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(page); err != nil {
+		log.Errorf("Error encoding response: %v", err)
+	}
+}
+
+// ListAuditEntries queries the audit trail of every student, filtered by
+// student_id, actor and a from/to time range
+func (h *Handler) ListAuditEntries(w http.ResponseWriter, r *http.Request) {
+	pageSize, ok := pageSizeFromQuery(w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	filter := student.AuditFilter{
+		StudentID: query.Get("student_id"),
+		Actor:     query.Get("actor"),
+	}
+	var err error
+	if v := query.Get("from"); v != "" {
+		if filter.From, err = parseQueryTime(v, false); err != nil {
+			writeProblem(w, r, http.StatusBadRequest, CodeInvalidQuery, "invalid from")
+			return
+		}
+	}
+	if v := query.Get("to"); v != "" {
+		if filter.To, err = parseQueryTime(v, true); err != nil {
+			writeProblem(w, r, http.StatusBadRequest, CodeInvalidQuery, "invalid to")
+			return
+		}
+	}
+
+	page, err := h.Service.ListAuditEntries(r.Context(), filter, query.Get("page_token"), pageSize)
+	if err != nil {
+		writeError(w, r, err, "Failed to list audit entries")
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(page); err != nil {
+		log.Errorf("Error encoding response: %v", err)
+	}
+}
@@ -1,67 +1,95 @@
 package transport
 
 import (
+	"context"
 	"encoding/json"
-	"errors"
+	"golang-assignment/internal/auth"
+	"golang-assignment/internal/student"
 	"net/http"
-	"os"
 	"strings"
 
-	"github.com/golang-jwt/jwt/v5"
 	log "github.com/sirupsen/logrus"
 )
 
-func JWTAuth(next http.HandlerFunc) http.HandlerFunc {
+// TokenService verifies the access tokens and publishes its public keys
+type TokenService interface {
+	Parse(ctx context.Context, token string) (*auth.Claims, error)
+	JWKS() auth.JWKS
+}
+
+// JWTAuth accepts requests carrying a valid access token which was not revoked
+// and whose user is not disabled
+func (h *Handler) JWTAuth(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
-			unauthorizedResponse(w, "Authorization header missing")
+			unauthorizedResponse(w, r, CodeUnauthorized, "Authorization header missing")
 			log.Error("Authorization header missing")
 			return
 		}
 
 		authHeaderParts := strings.Split(authHeader, " ")
 		if len(authHeaderParts) != 2 || strings.ToLower(authHeaderParts[0]) != "bearer" {
-			unauthorizedResponse(w, "Invalid authorization header format")
+			unauthorizedResponse(w, r, CodeUnauthorized, "Invalid authorization header format")
 			log.Error("Invalid authorization header format")
 			return
 		}
 
 		tokenStr := authHeaderParts[1]
-		if validateToken(tokenStr) {
-			next(w, r)
-		} else {
-			unauthorizedResponse(w, "Invalid JWT token")
-			log.Error("Invalid JWT token")
+		claims, err := h.Tokens.Parse(r.Context(), tokenStr)
+		if err != nil {
+			unauthorizedResponse(w, r, CodeInvalidToken, "Invalid JWT token")
+			log.Errorf("Invalid JWT token: %v", err)
+			return
+		}
+		// tokens without an ID cannot be revoked
+		if claims.ID == "" {
+			unauthorizedResponse(w, r, CodeInvalidToken, "Invalid JWT token")
+			log.Error("JWT token without jti")
 			return
 		}
-	}
-}
 
-func validateToken(accessToken string) bool {
-	mySigningKey := []byte(os.Getenv("JWT_SECRET"))
-	if len(mySigningKey) == 0 {
-		log.Error("JWT_SECRET environment variable not set")
-		return false
+		revoked, err := h.Service.IsTokenRevoked(r.Context(), claims.ID, claims.UserID)
+		if err != nil {
+			writeProblem(w, r, http.StatusServiceUnavailable, CodeServiceUnavailable, "Could not check the JWT token")
+			return
+		}
+		if revoked {
+			unauthorizedResponse(w, r, CodeTokenRevoked, "JWT token has been revoked")
+			log.Error("Revoked JWT token")
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), "claims", claims)
+		ctx = context.WithValue(ctx, "userID", claims.UserID)
+		ctx = context.WithValue(ctx, "role", claims.Role)
+		next(w, r.WithContext(ctx))
 	}
+}
 
-	token, err := jwt.Parse(accessToken, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected signing method")
-		}
-		return mySigningKey, nil
-	})
+// claimsFromContext returns the claims of the token accepted by JWTAuth
+func claimsFromContext(ctx context.Context) (*auth.Claims, bool) {
+	claims, ok := ctx.Value("claims").(*auth.Claims)
+	return claims, ok && claims != nil
+}
 
-	if err != nil {
-		log.Errorf("Error parsing JWT token: %v", err)
-		return false
+// JWKS publishes the public keys verifying our tokens so that other services
+// can check them
+func (h *Handler) JWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	if err := json.NewEncoder(w).Encode(h.Tokens.JWKS()); err != nil {
+		log.Errorf("Error encoding response: %v", err)
 	}
+}
 
-	return token.Valid
+func unauthorizedResponse(w http.ResponseWriter, r *http.Request, code ErrorCode, message string) {
+	w.Header().Set("WWW-Authenticate", "Bearer")
+	writeProblem(w, r, http.StatusUnauthorized, code, message)
 }
 
-func unauthorizedResponse(w http.ResponseWriter, message string) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusUnauthorized)
-	json.NewEncoder(w).Encode(map[string]string{"error": message})
+func forbiddenResponse(w http.ResponseWriter, r *http.Request, message string, required student.Permission, role string) {
+	p := newProblem(r, http.StatusForbidden, CodeForbidden, message)
+	p.RequiredPermission = string(required)
+	p.Role = role
+	writeProblemBody(w, p)
 }
@@ -0,0 +1,187 @@
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"golang-assignment/internal/cohort"
+	"golang-assignment/internal/student"
+	"net/http"
+	"strconv"
+
+	util "golang-assignment/utils"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+type CohortService interface {
+	ListCohorts(ctx context.Context) ([]cohort.Cohort, error)
+	GetCohort(ctx context.Context, id int64) (cohort.Cohort, error)
+	CreateCohort(ctx context.Context, c cohort.Cohort, createdBy string) (cohort.Cohort, error)
+	UpdateCohort(ctx context.Context, id int64, c cohort.Cohort, updatedBy string) (cohort.Cohort, error)
+	DeleteCohort(ctx context.Context, id int64) error
+	CohortStudents(ctx context.Context, id int64, pageToken string, pageSize int) (student.SearchPage, error)
+	EachStudent(ctx context.Context, id int64, emit func(student.Student) error) error
+}
+
+// CohortRequest is the body of both the creation and the replacement of a
+// cohort. Query holds the search parameters of GET /students, like
+// "course=CS101&tag=scholarship".
+type CohortRequest struct {
+	Name        string `json:"name" validate:"required,max=255"`
+	Description string `json:"description" validate:"max=500"`
+	Query       string `json:"query" validate:"required,max=2000"`
+}
+
+// cohortFromRequest returns the cohort of the request, answering 400 when its query
+// cannot be read
+func cohortFromRequest(w http.ResponseWriter, r *http.Request, req CohortRequest) (cohort.Cohort, bool) {
+	filter, err := SearchFilterFromText(req.Query)
+	if err != nil {
+		writeError(w, r, fmt.Errorf("%w: %v", cohort.ErrInvalidCohort, err), "")
+		return cohort.Cohort{}, false
+	}
+	return cohort.Cohort{Name: req.Name, Description: req.Description, Filter: filter}, true
+}
+
+// CohortResponse is a cohort with its filter written back as the search
+// parameters of GET /students
+type CohortResponse struct {
+	cohort.Cohort
+	Query string `json:"query"`
+}
+
+func newCohortResponse(c cohort.Cohort) CohortResponse {
+	return CohortResponse{Cohort: c, Query: searchQuery(c.Filter).Encode()}
+}
+
+// cohortID returns the id of the cohort of the route, answering 404 when it
+// is not a number
+func cohortID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		writeError(w, r, cohort.ErrCohortNotFound, "")
+		return 0, false
+	}
+	return id, true
+}
+
+func (h *Handler) ListCohorts(w http.ResponseWriter, r *http.Request) {
+	cohorts, err := h.Cohorts.ListCohorts(r.Context())
+	if err != nil {
+		writeError(w, r, err, "Failed to list cohorts")
+		return
+	}
+
+	responses := make([]CohortResponse, 0, len(cohorts))
+	for _, c := range cohorts {
+		responses = append(responses, newCohortResponse(c))
+	}
+	if err := json.NewEncoder(w).Encode(responses); err != nil {
+		log.Errorf("Error encoding response: %v", err)
+	}
+}
+
+func (h *Handler) GetCohort(w http.ResponseWriter, r *http.Request) {
+	id, ok := cohortID(w, r)
+	if !ok {
+		return
+	}
+
+	c, err := h.Cohorts.GetCohort(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err, "Failed to get cohort")
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(newCohortResponse(c)); err != nil {
+		log.Errorf("Error encoding response: %v", err)
+	}
+}
+
+func (h *Handler) CreateCohort(w http.ResponseWriter, r *http.Request) {
+	var req CohortRequest
+	if !decodeAndValidate(w, r, &req) {
+		return
+	}
+
+	c, ok := cohortFromRequest(w, r, req)
+	if !ok {
+		return
+	}
+	c, err := h.Cohorts.CreateCohort(r.Context(), c, util.GetCurrentUserID(r.Context()))
+	if err != nil {
+		writeError(w, r, err, "Failed to create cohort")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(newCohortResponse(c)); err != nil {
+		log.Errorf("Error encoding response: %v", err)
+	}
+}
+
+func (h *Handler) UpdateCohort(w http.ResponseWriter, r *http.Request) {
+	id, ok := cohortID(w, r)
+	if !ok {
+		return
+	}
+	var req CohortRequest
+	if !decodeAndValidate(w, r, &req) {
+		return
+	}
+
+	c, ok := cohortFromRequest(w, r, req)
+	if !ok {
+		return
+	}
+	c, err := h.Cohorts.UpdateCohort(r.Context(), id, c, util.GetCurrentUserID(r.Context()))
+	if err != nil {
+		writeError(w, r, err, "Failed to update cohort")
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(newCohortResponse(c)); err != nil {
+		log.Errorf("Error encoding response: %v", err)
+	}
+}
+
+func (h *Handler) DeleteCohort(w http.ResponseWriter, r *http.Request) {
+	id, ok := cohortID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.Cohorts.DeleteCohort(r.Context(), id); err != nil {
+		writeError(w, r, err, "Failed to delete cohort")
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(Response{Message: "Successfully Deleted"}); err != nil {
+		log.Errorf("Error encoding response: %v", err)
+	}
+}
+
+// CohortStudents lists the students matching the search of the cohort now,
+// paged like a search of GET /students
+func (h *Handler) CohortStudents(w http.ResponseWriter, r *http.Request) {
+	id, ok := cohortID(w, r)
+	if !ok {
+		return
+	}
+	pageSize, ok := pageSizeFromQuery(w, r)
+	if !ok {
+		return
+	}
+
+	page, err := h.Cohorts.CohortStudents(r.Context(), id, r.URL.Query().Get("page_token"), pageSize)
+	if err != nil {
+		writeError(w, r, err, "Failed to list cohort students")
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(page); err != nil {
+		log.Errorf("Error encoding response: %v", err)
+	}
+}
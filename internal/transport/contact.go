@@ -0,0 +1,153 @@
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"golang-assignment/internal/contact"
+	"net/http"
+	"strconv"
+
+	util "golang-assignment/utils"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+type ContactService interface {
+	ListContacts(ctx context.Context, studentID string) ([]contact.Contact, error)
+	GetContact(ctx context.Context, studentID string, id int64) (contact.Contact, error)
+	CreateContact(ctx context.Context, c contact.Contact, createdBy string) (contact.Contact, error)
+	UpdateContact(ctx context.Context, c contact.Contact, updatedBy string) (contact.Contact, error)
+	DeleteContact(ctx context.Context, studentID string, id int64) error
+}
+
+type ConsentsRequest struct {
+	Pickup  bool `json:"pickup"`
+	Medical bool `json:"medical"`
+	Records bool `json:"records"`
+}
+
+// ContactRequest is the body of both the creation and the replacement of a
+// contact, phone and email are checked further by the service
+type ContactRequest struct {
+	Name         string          `json:"name" validate:"required,max=255"`
+	Relationship string          `json:"relationship" validate:"required,oneof=parent guardian grandparent sibling relative other"`
+	Phone        string          `json:"phone" validate:"max=32"`
+	Email        string          `json:"email" validate:"omitempty,email,max=255"`
+	Address      string          `json:"address" validate:"max=500"`
+	Primary      bool            `json:"primary"`
+	Emergency    bool            `json:"emergency"`
+	Consents     ConsentsRequest `json:"consents"`
+}
+
+func contactFromContactRequest(studentID string, req ContactRequest) contact.Contact {
+	return contact.Contact{
+		StudentID:    studentID,
+		Name:         req.Name,
+		Relationship: contact.Relationship(req.Relationship),
+		Phone:        req.Phone,
+		Email:        req.Email,
+		Address:      req.Address,
+		Primary:      req.Primary,
+		Emergency:    req.Emergency,
+		Consents:     contact.Consents(req.Consents),
+	}
+}
+
+// contactID returns the id of the contact of the route, answering 404 when
+// it is not a number
+func contactID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(mux.Vars(r)["contactID"], 10, 64)
+	if err != nil {
+		writeError(w, r, contact.ErrContactNotFound, "")
+		return 0, false
+	}
+	return id, true
+}
+
+func (h *Handler) ListContacts(w http.ResponseWriter, r *http.Request) {
+	contacts, err := h.Contacts.ListContacts(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, r, err, "Failed to list contacts")
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(contacts); err != nil {
+		log.Errorf("Error encoding response: %v", err)
+	}
+}
+
+func (h *Handler) GetContact(w http.ResponseWriter, r *http.Request) {
+	id, ok := contactID(w, r)
+	if !ok {
+		return
+	}
+
+	c, err := h.Contacts.GetContact(r.Context(), mux.Vars(r)["id"], id)
+	if err != nil {
+		writeError(w, r, err, "Failed to get contact")
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(c); err != nil {
+		log.Errorf("Error encoding response: %v", err)
+	}
+}
+
+func (h *Handler) CreateContact(w http.ResponseWriter, r *http.Request) {
+	var req ContactRequest
+	if !decodeAndValidate(w, r, &req) {
+		return
+	}
+
+	c, err := h.Contacts.CreateContact(r.Context(), contactFromContactRequest(mux.Vars(r)["id"], req), util.GetCurrentUserID(r.Context()))
+	if err != nil {
+		writeError(w, r, err, "Failed to create contact")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(c); err != nil {
+		log.Errorf("Error encoding response: %v", err)
+	}
+}
+
+func (h *Handler) UpdateContact(w http.ResponseWriter, r *http.Request) {
+	id, ok := contactID(w, r)
+	if !ok {
+		return
+	}
+
+	var req ContactRequest
+	if !decodeAndValidate(w, r, &req) {
+		return
+	}
+
+	c := contactFromContactRequest(mux.Vars(r)["id"], req)
+	c.ID = id
+	c, err := h.Contacts.UpdateContact(r.Context(), c, util.GetCurrentUserID(r.Context()))
+	if err != nil {
+		writeError(w, r, err, "Failed to update contact")
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(c); err != nil {
+		log.Errorf("Error encoding response: %v", err)
+	}
+}
+
+func (h *Handler) DeleteContact(w http.ResponseWriter, r *http.Request) {
+	id, ok := contactID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.Contacts.DeleteContact(r.Context(), mux.Vars(r)["id"], id); err != nil {
+		writeError(w, r, err, "Failed to delete contact")
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(Response{Message: "Successfully Deleted"}); err != nil {
+		log.Errorf("Error encoding response: %v", err)
+	}
+}
@@ -0,0 +1,123 @@
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"golang-assignment/internal/course"
+	"net/http"
+	"strconv"
+
+	util "golang-assignment/utils"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+type CourseService interface {
+	GetCourse(ctx context.Context, code string) (course.Course, error)
+	ListCourses(ctx context.Context, includeInactive bool) ([]course.Course, error)
+	CreateCourse(ctx context.Context, c course.Course, createdBy string) (course.Course, error)
+	UpdateCourse(ctx context.Context, code string, c course.Course, updatedBy string) (course.Course, error)
+	DeleteCourse(ctx context.Context, code string) error
+}
+
+type CreateCourseRequest struct {
+	Code     string `json:"code" validate:"required,max=32"`
+	Title    string `json:"title" validate:"required,max=255"`
+	Credits  int    `json:"credits" validate:"gte=0"`
+	Capacity int    `json:"capacity" validate:"gte=0"`
+	// a new course is active unless told otherwise
+	Active *bool `json:"active"`
+}
+
+type UpdateCourseRequest struct {
+	Title    string `json:"title" validate:"required,max=255"`
+	Credits  int    `json:"credits" validate:"gte=0"`
+	Capacity int    `json:"capacity" validate:"gte=0"`
+	Active   *bool  `json:"active" validate:"required"`
+}
+
+func (h *Handler) ListCourses(w http.ResponseWriter, r *http.Request) {
+	includeInactive := false
+	if v := r.URL.Query().Get("include_inactive"); v != "" {
+		var err error
+		if includeInactive, err = strconv.ParseBool(v); err != nil {
+			writeProblem(w, r, http.StatusBadRequest, CodeInvalidQuery, "Invalid include_inactive")
+			return
+		}
+	}
+
+	courses, err := h.Courses.ListCourses(r.Context(), includeInactive)
+	if err != nil {
+		writeError(w, r, err, "Failed to list courses")
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(courses); err != nil {
+		log.Errorf("Error encoding response: %v", err)
+	}
+}
+
+func (h *Handler) GetCourse(w http.ResponseWriter, r *http.Request) {
+	c, err := h.Courses.GetCourse(r.Context(), mux.Vars(r)["code"])
+	if err != nil {
+		writeError(w, r, err, "Failed to get course")
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(c); err != nil {
+		log.Errorf("Error encoding response: %v", err)
+	}
+}
+
+func (h *Handler) CreateCourse(w http.ResponseWriter, r *http.Request) {
+	var req CreateCourseRequest
+	if !decodeAndValidate(w, r, &req) {
+		return
+	}
+
+	c := course.Course{Code: req.Code, Title: req.Title, Credits: req.Credits, Capacity: req.Capacity, Active: true}
+	if req.Active != nil {
+		c.Active = *req.Active
+	}
+	c, err := h.Courses.CreateCourse(r.Context(), c, util.GetCurrentUserID(r.Context()))
+	if err != nil {
+		writeError(w, r, err, "Failed to create course")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(c); err != nil {
+		log.Errorf("Error encoding response: %v", err)
+	}
+}
+
+func (h *Handler) UpdateCourse(w http.ResponseWriter, r *http.Request) {
+	var req UpdateCourseRequest
+	if !decodeAndValidate(w, r, &req) {
+		return
+	}
+
+	c := course.Course{Title: req.Title, Credits: req.Credits, Capacity: req.Capacity, Active: *req.Active}
+	c, err := h.Courses.UpdateCourse(r.Context(), mux.Vars(r)["code"], c, util.GetCurrentUserID(r.Context()))
+	if err != nil {
+		writeError(w, r, err, "Failed to update course")
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(c); err != nil {
+		log.Errorf("Error encoding response: %v", err)
+	}
+}
+
+func (h *Handler) DeleteCourse(w http.ResponseWriter, r *http.Request) {
+	if err := h.Courses.DeleteCourse(r.Context(), mux.Vars(r)["code"]); err != nil {
+		writeError(w, r, err, "Failed to delete course")
+		return
+	}
+
+	response := Response{Message: "Successfully Deleted"}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Errorf("Error encoding response: %v", err)
+	}
+}
@@ -0,0 +1,119 @@
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"golang-assignment/internal/customfield"
+	"net/http"
+
+	util "golang-assignment/utils"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+type CustomFieldService interface {
+	ListFields(ctx context.Context) ([]customfield.Definition, error)
+	GetField(ctx context.Context, name string) (customfield.Definition, error)
+	CreateField(ctx context.Context, d customfield.Definition, createdBy string) (customfield.Definition, error)
+	UpdateField(ctx context.Context, name string, d customfield.Definition, updatedBy string) (customfield.Definition, error)
+	DeleteField(ctx context.Context, name string) error
+}
+
+type CreateCustomFieldRequest struct {
+	Name       string   `json:"name" validate:"required,max=64"`
+	Label      string   `json:"label" validate:"required,max=255"`
+	Type       string   `json:"type" validate:"required,oneof=string number integer boolean date enum"`
+	Required   bool     `json:"required"`
+	EnumValues []string `json:"enum_values" validate:"max=100,dive,max=255"`
+	Pattern    string   `json:"pattern" validate:"max=500"`
+}
+
+// UpdateCustomFieldRequest replaces a definition, its name and type never
+// change
+type UpdateCustomFieldRequest struct {
+	Label      string   `json:"label" validate:"required,max=255"`
+	Required   bool     `json:"required"`
+	EnumValues []string `json:"enum_values" validate:"max=100,dive,max=255"`
+	Pattern    string   `json:"pattern" validate:"max=500"`
+}
+
+func (h *Handler) ListCustomFields(w http.ResponseWriter, r *http.Request) {
+	fields, err := h.Fields.ListFields(r.Context())
+	if err != nil {
+		writeError(w, r, err, "Failed to list custom fields")
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(fields); err != nil {
+		log.Errorf("Error encoding response: %v", err)
+	}
+}
+
+func (h *Handler) GetCustomField(w http.ResponseWriter, r *http.Request) {
+	d, err := h.Fields.GetField(r.Context(), mux.Vars(r)["name"])
+	if err != nil {
+		writeError(w, r, err, "Failed to get custom field")
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(d); err != nil {
+		log.Errorf("Error encoding response: %v", err)
+	}
+}
+
+func (h *Handler) CreateCustomField(w http.ResponseWriter, r *http.Request) {
+	var req CreateCustomFieldRequest
+	if !decodeAndValidate(w, r, &req) {
+		return
+	}
+
+	d := customfield.Definition{
+		Name:       req.Name,
+		Label:      req.Label,
+		Type:       customfield.Type(req.Type),
+		Required:   req.Required,
+		EnumValues: req.EnumValues,
+		Pattern:    req.Pattern,
+	}
+	d, err := h.Fields.CreateField(r.Context(), d, util.GetCurrentUserID(r.Context()))
+	if err != nil {
+		writeError(w, r, err, "Failed to create custom field")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(d); err != nil {
+		log.Errorf("Error encoding response: %v", err)
+	}
+}
+
+func (h *Handler) UpdateCustomField(w http.ResponseWriter, r *http.Request) {
+	var req UpdateCustomFieldRequest
+	if !decodeAndValidate(w, r, &req) {
+		return
+	}
+
+	d := customfield.Definition{Label: req.Label, Required: req.Required, EnumValues: req.EnumValues, Pattern: req.Pattern}
+	d, err := h.Fields.UpdateField(r.Context(), mux.Vars(r)["name"], d, util.GetCurrentUserID(r.Context()))
+	if err != nil {
+		writeError(w, r, err, "Failed to update custom field")
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(d); err != nil {
+		log.Errorf("Error encoding response: %v", err)
+	}
+}
+
+// DeleteCustomField removes a field along with the values of every student
+func (h *Handler) DeleteCustomField(w http.ResponseWriter, r *http.Request) {
+	if err := h.Fields.DeleteField(r.Context(), mux.Vars(r)["name"]); err != nil {
+		writeError(w, r, err, "Failed to delete custom field")
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(Response{Message: "Successfully Deleted"}); err != nil {
+		log.Errorf("Error encoding response: %v", err)
+	}
+}
@@ -0,0 +1,130 @@
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"golang-assignment/internal/enrollment"
+	"net/http"
+	"strconv"
+
+	util "golang-assignment/utils"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+type EnrollmentService interface {
+	GetEnrollment(ctx context.Context, id int64) (enrollment.Enrollment, error)
+	Enroll(ctx context.Context, studentID, code, term, enrolledBy string) (enrollment.Enrollment, error)
+	ChangeStatus(ctx context.Context, id int64, status enrollment.Status, updatedBy string) (enrollment.Enrollment, error)
+	ListStudentEnrollments(ctx context.Context, studentID, term string) ([]enrollment.Enrollment, error)
+	ListRoster(ctx context.Context, code string, filter enrollment.RosterFilter) ([]enrollment.Enrollment, error)
+}
+
+type EnrollRequest struct {
+	Course string `json:"course" validate:"required,max=255"`
+	Term   string `json:"term" validate:"required,max=32"`
+}
+
+type SetEnrollmentStatusRequest struct {
+	Status string `json:"status" validate:"required,oneof=withdrawn completed"`
+}
+
+// enrollmentID returns the id of the enrollment of the route, answering 404
+// when it is not a number
+func enrollmentID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		writeError(w, r, enrollment.ErrEnrollmentNotFound, "")
+		return 0, false
+	}
+	return id, true
+}
+
+func (h *Handler) Enroll(w http.ResponseWriter, r *http.Request) {
+	var req EnrollRequest
+	if !decodeAndValidate(w, r, &req) {
+		return
+	}
+
+	e, err := h.Enrollments.Enroll(r.Context(), mux.Vars(r)["id"], req.Course, req.Term, util.GetCurrentUserID(r.Context()))
+	if err != nil {
+		writeError(w, r, err, "Failed to enroll student")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(e); err != nil {
+		log.Errorf("Error encoding response: %v", err)
+	}
+}
+
+func (h *Handler) ListStudentEnrollments(w http.ResponseWriter, r *http.Request) {
+	enrollments, err := h.Enrollments.ListStudentEnrollments(r.Context(), mux.Vars(r)["id"], r.URL.Query().Get("term"))
+	if err != nil {
+		writeError(w, r, err, "Failed to list enrollments")
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(enrollments); err != nil {
+		log.Errorf("Error encoding response: %v", err)
+	}
+}
+
+func (h *Handler) GetEnrollment(w http.ResponseWriter, r *http.Request) {
+	id, ok := enrollmentID(w, r)
+	if !ok {
+		return
+	}
+
+	e, err := h.Enrollments.GetEnrollment(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err, "Failed to get enrollment")
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(e); err != nil {
+		log.Errorf("Error encoding response: %v", err)
+	}
+}
+
+func (h *Handler) SetEnrollmentStatus(w http.ResponseWriter, r *http.Request) {
+	id, ok := enrollmentID(w, r)
+	if !ok {
+		return
+	}
+
+	var req SetEnrollmentStatusRequest
+	if !decodeAndValidate(w, r, &req) {
+		return
+	}
+
+	e, err := h.Enrollments.ChangeStatus(r.Context(), id, enrollment.Status(req.Status), util.GetCurrentUserID(r.Context()))
+	if err != nil {
+		writeError(w, r, err, "Failed to change enrollment")
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(e); err != nil {
+		log.Errorf("Error encoding response: %v", err)
+	}
+}
+
+func (h *Handler) ListRoster(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := enrollment.RosterFilter{Term: query.Get("term"), Status: enrollment.Status(query.Get("status"))}
+	if filter.Status != "" && !filter.Status.Valid() {
+		writeProblem(w, r, http.StatusBadRequest, CodeInvalidQuery, "Invalid status")
+		return
+	}
+
+	roster, err := h.Enrollments.ListRoster(r.Context(), mux.Vars(r)["code"], filter)
+	if err != nil {
+		writeError(w, r, err, "Failed to list the roster")
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(roster); err != nil {
+		log.Errorf("Error encoding response: %v", err)
+	}
+}
@@ -0,0 +1,294 @@
+package transport
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"golang-assignment/internal/attachment"
+	"golang-assignment/internal/attendance"
+	"golang-assignment/internal/cohort"
+	"golang-assignment/internal/contact"
+	"golang-assignment/internal/course"
+	"golang-assignment/internal/customfield"
+	"golang-assignment/internal/enrollment"
+	"golang-assignment/internal/student"
+	"golang-assignment/internal/tag"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ErrorCode is the machine readable code of a problem. Codes are part of the
+// API contract, clients switch on them, so existing ones must not change.
+type ErrorCode string
+
+const (
+	CodeInvalidRequestBody   ErrorCode = "invalid_request_body"
+	CodeValidationFailed     ErrorCode = "validation_failed"
+	CodeInvalidQuery         ErrorCode = "invalid_query"
+	CodeInvalidPageToken     ErrorCode = "invalid_page_token"
+	CodeUnauthorized         ErrorCode = "unauthorized"
+	CodeInvalidToken         ErrorCode = "invalid_token"
+	CodeTokenRevoked         ErrorCode = "token_revoked"
+	CodeInvalidCredentials   ErrorCode = "invalid_credentials"
+	CodeRefreshTokenReused   ErrorCode = "refresh_token_reused"
+	CodeForbidden            ErrorCode = "forbidden"
+	CodeStudentNotFound      ErrorCode = "student_not_found"
+	CodeStudentExists        ErrorCode = "student_exists"
+	CodeDuplicateEmail       ErrorCode = "duplicate_email"
+	CodeInvalidStudent       ErrorCode = "invalid_student"
+	CodePreconditionNeeded   ErrorCode = "precondition_required"
+	CodePreconditionFailed   ErrorCode = "precondition_failed"
+	CodeUnsupportedMediaType ErrorCode = "unsupported_media_type"
+	CodeInvalidPatch         ErrorCode = "invalid_patch"
+	CodePatchTestFailed      ErrorCode = "patch_test_failed"
+	CodeCourseNotFound       ErrorCode = "course_not_found"
+	CodeCourseExists         ErrorCode = "course_exists"
+	CodeInvalidCourse        ErrorCode = "invalid_course"
+	CodeCourseInUse          ErrorCode = "course_in_use"
+	CodeEnrollmentNotFound   ErrorCode = "enrollment_not_found"
+	CodeAlreadyEnrolled      ErrorCode = "already_enrolled"
+	CodeInvalidEnrollment    ErrorCode = "invalid_enrollment"
+	CodeInvalidGrade         ErrorCode = "invalid_grade"
+	CodeInvalidAttendance    ErrorCode = "invalid_attendance"
+	CodeNotEnrolled          ErrorCode = "not_enrolled"
+	CodeContactNotFound      ErrorCode = "contact_not_found"
+	CodeInvalidContact       ErrorCode = "invalid_contact"
+	CodeAttachmentNotFound   ErrorCode = "attachment_not_found"
+	CodeInvalidAttachment    ErrorCode = "invalid_attachment"
+	CodeAttachmentTooLarge   ErrorCode = "attachment_too_large"
+	CodeCustomFieldNotFound  ErrorCode = "custom_field_not_found"
+	CodeCustomFieldExists    ErrorCode = "custom_field_exists"
+	CodeInvalidCustomField   ErrorCode = "invalid_custom_field"
+	CodeTagNotFound          ErrorCode = "tag_not_found"
+	CodeTagExists            ErrorCode = "tag_exists"
+	CodeInvalidTag           ErrorCode = "invalid_tag"
+	CodeCohortNotFound       ErrorCode = "cohort_not_found"
+	CodeCohortExists         ErrorCode = "cohort_exists"
+	CodeInvalidCohort        ErrorCode = "invalid_cohort"
+	CodeInvalidImport        ErrorCode = "invalid_import"
+	CodeImportTooLarge       ErrorCode = "import_too_large"
+	CodeUserNotFound         ErrorCode = "user_not_found"
+	CodeUserExists           ErrorCode = "user_exists"
+	CodeWeakPassword         ErrorCode = "weak_password"
+	CodeInvalidRole          ErrorCode = "invalid_role"
+	CodeCannotDisableSelf    ErrorCode = "cannot_disable_self"
+	CodeRouteNotFound        ErrorCode = "route_not_found"
+	CodeMethodNotAllowed     ErrorCode = "method_not_allowed"
+	CodeServiceUnavailable   ErrorCode = "service_unavailable"
+	CodeInternal             ErrorCode = "internal_error"
+)
+
+// Problem is an RFC 7807 problem details body, extended with a stable code
+// and the list of the fields failing validation
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Code     ErrorCode    `json:"code"`
+	Errors   []FieldError `json:"errors,omitempty"`
+
+	RequiredPermission string `json:"required_permission,omitempty"`
+	Role               string `json:"role,omitempty"`
+	ConflictingID      string `json:"conflicting_id,omitempty"`
+}
+
+// FieldError describes one field of the request failing one rule
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+func newProblem(r *http.Request, status int, code ErrorCode, detail string) Problem {
+	return Problem{
+		Type:     "/problems/" + string(code),
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: r.URL.Path,
+		Code:     code,
+	}
+}
+
+func writeProblemBody(w http.ResponseWriter, p Problem) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(p.Status)
+	if err := json.NewEncoder(w).Encode(p); err != nil {
+		log.Errorf("failed to encode problem: %v", err)
+	}
+}
+
+func writeProblem(w http.ResponseWriter, r *http.Request, status int, code ErrorCode, detail string) {
+	writeProblemBody(w, newProblem(r, status, code, detail))
+}
+
+// errorMapping is the answer to the errors wrapping err. An empty detail
+// means the message of the error is shown.
+type errorMapping struct {
+	err    error
+	status int
+	code   ErrorCode
+	detail string
+}
+
+// errorMappings lists the domain errors of the service in the order they are
+// checked, any other error is an internal one
+var errorMappings = []errorMapping{
+	{err: student.ErrStoreUnavailable, status: http.StatusServiceUnavailable, code: CodeServiceUnavailable, detail: "The service is temporarily unavailable, retry later"},
+	{err: student.ErrNoStudentFound, status: http.StatusNotFound, code: CodeStudentNotFound, detail: "Student not found"},
+	{err: student.ErrDuplicateStudent, status: http.StatusConflict, code: CodeStudentExists},
+	{err: student.ErrDuplicateEmail, status: http.StatusConflict, code: CodeDuplicateEmail},
+	{err: student.ErrVersionMismatch, status: http.StatusPreconditionFailed, code: CodePreconditionFailed},
+	{err: student.ErrInvalidStudent, status: http.StatusUnprocessableEntity, code: CodeInvalidStudent},
+	{err: student.ErrInvalidPageToken, status: http.StatusBadRequest, code: CodeInvalidPageToken},
+	{err: student.ErrInvalidPageSize, status: http.StatusBadRequest, code: CodeInvalidQuery},
+	{err: student.ErrInvalidSearch, status: http.StatusBadRequest, code: CodeInvalidQuery},
+	{err: student.ErrInvalidAudit, status: http.StatusBadRequest, code: CodeInvalidQuery},
+	{err: student.ErrInvalidImport, status: http.StatusBadRequest, code: CodeInvalidImport},
+	{err: course.ErrCourseNotFound, status: http.StatusNotFound, code: CodeCourseNotFound, detail: "Course not found"},
+	{err: course.ErrDuplicateCourse, status: http.StatusConflict, code: CodeCourseExists},
+	{err: course.ErrInvalidCourse, status: http.StatusUnprocessableEntity, code: CodeInvalidCourse},
+	{err: course.ErrCourseInUse, status: http.StatusConflict, code: CodeCourseInUse},
+	{err: enrollment.ErrEnrollmentNotFound, status: http.StatusNotFound, code: CodeEnrollmentNotFound, detail: "Enrollment not found"},
+	{err: enrollment.ErrAlreadyEnrolled, status: http.StatusConflict, code: CodeAlreadyEnrolled},
+	{err: enrollment.ErrInvalidEnrollment, status: http.StatusUnprocessableEntity, code: CodeInvalidEnrollment},
+	{err: student.ErrInvalidGrade, status: http.StatusUnprocessableEntity, code: CodeInvalidGrade},
+	{err: attendance.ErrInvalidAttendance, status: http.StatusUnprocessableEntity, code: CodeInvalidAttendance},
+	{err: attendance.ErrNotEnrolled, status: http.StatusUnprocessableEntity, code: CodeNotEnrolled},
+	{err: contact.ErrContactNotFound, status: http.StatusNotFound, code: CodeContactNotFound, detail: "Contact not found"},
+	{err: contact.ErrInvalidContact, status: http.StatusUnprocessableEntity, code: CodeInvalidContact},
+	{err: attachment.ErrAttachmentNotFound, status: http.StatusNotFound, code: CodeAttachmentNotFound, detail: "Attachment not found"},
+	{err: attachment.ErrInvalidAttachment, status: http.StatusUnprocessableEntity, code: CodeInvalidAttachment},
+	{err: attachment.ErrUnsupportedType, status: http.StatusUnsupportedMediaType, code: CodeUnsupportedMediaType},
+	{err: attachment.ErrAttachmentTooLarge, status: http.StatusRequestEntityTooLarge, code: CodeAttachmentTooLarge},
+	{err: customfield.ErrFieldNotFound, status: http.StatusNotFound, code: CodeCustomFieldNotFound, detail: "Custom field not found"},
+	{err: customfield.ErrDuplicateField, status: http.StatusConflict, code: CodeCustomFieldExists},
+	{err: customfield.ErrInvalidField, status: http.StatusUnprocessableEntity, code: CodeInvalidCustomField},
+	{err: tag.ErrTagNotFound, status: http.StatusNotFound, code: CodeTagNotFound, detail: "Tag not found"},
+	{err: tag.ErrDuplicateTag, status: http.StatusConflict, code: CodeTagExists},
+	{err: tag.ErrInvalidTag, status: http.StatusUnprocessableEntity, code: CodeInvalidTag},
+	{err: cohort.ErrCohortNotFound, status: http.StatusNotFound, code: CodeCohortNotFound, detail: "Cohort not found"},
+	{err: cohort.ErrDuplicateCohort, status: http.StatusConflict, code: CodeCohortExists},
+	{err: cohort.ErrInvalidCohort, status: http.StatusUnprocessableEntity, code: CodeInvalidCohort},
+	{err: student.ErrUserNotFound, status: http.StatusNotFound, code: CodeUserNotFound},
+	{err: student.ErrDuplicateUser, status: http.StatusConflict, code: CodeUserExists},
+	{err: student.ErrWeakPassword, status: http.StatusBadRequest, code: CodeWeakPassword},
+	{err: student.ErrInvalidRole, status: http.StatusBadRequest, code: CodeInvalidRole},
+}
+
+// writeError answers with the problem matching the domain error wrapped by
+// err, or with a 500 problem carrying fallback when there is none
+func writeError(w http.ResponseWriter, r *http.Request, err error, fallback string) {
+	for _, m := range errorMappings {
+		if !errors.Is(err, m.err) {
+			continue
+		}
+		detail := m.detail
+		if detail == "" {
+			detail = err.Error()
+		}
+		if m.status == http.StatusServiceUnavailable {
+			w.Header().Set("Retry-After", "5")
+		}
+		p := newProblem(r, m.status, m.code, detail)
+		var conflict *student.ConflictError
+		if errors.As(err, &conflict) {
+			p.ConflictingID = conflict.ConflictingID
+		}
+		writeProblemBody(w, p)
+		return
+	}
+	log.Errorf("%s: %v", fallback, err)
+	writeProblem(w, r, http.StatusInternalServerError, CodeInternal, fallback)
+}
+
+// validate is shared by the handlers, it reports fields by their JSON name
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return field.Name
+		}
+		return name
+	})
+	return v
+}
+
+func fieldErrorMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "gt":
+		return "must be greater than " + fe.Param()
+	case "gte", "min":
+		if fe.Kind() == reflect.String {
+			return "must be at least " + fe.Param() + " characters long"
+		}
+		return "must be at least " + fe.Param()
+	case "lte", "max":
+		if fe.Kind() == reflect.String {
+			return "must be at most " + fe.Param() + " characters long"
+		}
+		return "must be at most " + fe.Param()
+	case "oneof":
+		return "must be one of " + strings.ReplaceAll(fe.Param(), " ", ", ")
+	}
+	return fmt.Sprintf("does not satisfy the %s rule", fe.Tag())
+}
+
+// fieldErrors turns the errors of the validator into one entry per failing
+// field and rule
+func fieldErrors(err error) []FieldError {
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return nil
+	}
+	fields := make([]FieldError, 0, len(validationErrors))
+	for _, fe := range validationErrors {
+		// drop the name of the request struct
+		_, field, _ := strings.Cut(fe.Namespace(), ".")
+		fields = append(fields, FieldError{
+			Field:   field,
+			Rule:    fe.Tag(),
+			Param:   fe.Param(),
+			Message: fieldErrorMessage(fe),
+		})
+	}
+	return fields
+}
+
+func writeValidationProblem(w http.ResponseWriter, r *http.Request, err error) {
+	p := newProblem(r, http.StatusBadRequest, CodeValidationFailed, "Validation failed")
+	p.Errors = fieldErrors(err)
+	writeProblemBody(w, p)
+}
+
+// decodeAndValidate reads the JSON body into dst and runs the validator rules
+// of dst, it writes the problem and returns false when either fails
+func decodeAndValidate(w http.ResponseWriter, r *http.Request, dst interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(dst); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, CodeInvalidRequestBody, "Invalid request body")
+		return false
+	}
+	if err := validate.Struct(dst); err != nil {
+		writeValidationProblem(w, r, err)
+		return false
+	}
+	return true
+}
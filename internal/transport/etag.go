@@ -0,0 +1,76 @@
+package transport
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"golang-assignment/internal/student"
+)
+
+// studentETag is the strong entity tag of a student version
+func studentETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+func setETag(w http.ResponseWriter, version int64) {
+	w.Header().Set("ETag", studentETag(version))
+}
+
+// ifMatch is the condition of an If-Match header (RFC 9110 section 13.1.1),
+// either "*", which any stored version satisfies, or a list of entity tags
+type ifMatch struct {
+	anyVersion bool
+	versions   []int64
+}
+
+// parseIfMatch reads the If-Match header a write is conditioned on. It writes
+// the problem and returns false when the header is missing or is not "*" nor
+// a list of entity tags.
+func parseIfMatch(w http.ResponseWriter, r *http.Request) (ifMatch, bool) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" {
+		writeProblem(w, r, http.StatusPreconditionRequired, CodePreconditionNeeded,
+			"The If-Match header is required, send the ETag of the student as read")
+		return ifMatch{}, false
+	}
+	if header == "*" {
+		return ifMatch{anyVersion: true}, true
+	}
+
+	var m ifMatch
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		// weak tags are allowed but never match, If-Match compares strongly
+		weak := strings.HasPrefix(tag, "W/")
+		tag = strings.TrimPrefix(tag, "W/")
+		if len(tag) < 2 || !strings.HasPrefix(tag, `"`) || !strings.HasSuffix(tag, `"`) {
+			writeProblem(w, r, http.StatusPreconditionFailed, CodePreconditionFailed, `If-Match must be "*" or a list of ETags`)
+			return ifMatch{}, false
+		}
+		// a tag which is not one of ours matches no version
+		version, err := strconv.ParseInt(tag[1:len(tag)-1], 10, 64)
+		if !weak && err == nil && version > 0 {
+			m.versions = append(m.versions, version)
+		}
+	}
+	return m, true
+}
+
+// version returns the version of the stored student the write is to compare
+// and swap against. It writes the problem and returns false when the student
+// does not satisfy the condition.
+func (m ifMatch) version(w http.ResponseWriter, r *http.Request, stored student.Student) (int64, bool) {
+	if m.anyVersion {
+		return stored.Version, true
+	}
+	for _, version := range m.versions {
+		if version == stored.Version {
+			return version, true
+		}
+	}
+	writeProblem(w, r, http.StatusPreconditionFailed, CodePreconditionFailed,
+		fmt.Sprintf("student with ID %s is at version %d, which If-Match does not list", stored.ID, stored.Version))
+	return 0, false
+}
@@ -0,0 +1,67 @@
+package transport
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang-assignment/internal/student"
+)
+
+func TestIfMatch(t *testing.T) {
+	stored := student.Student{ID: "s1", Version: 3}
+	tests := []struct {
+		name   string
+		header string
+		// parsed tells whether parseIfMatch accepts the header
+		parsed bool
+		// status is the problem answered, 0 when the write goes ahead at
+		// the stored version
+		status int
+	}{
+		{"Missing", "", false, http.StatusPreconditionRequired},
+		{"Blank", "   ", false, http.StatusPreconditionRequired},
+		{"Any", "*", true, 0},
+		{"AnyPadded", " * ", true, 0},
+		{"Current", `"3"`, true, 0},
+		{"Stale", `"2"`, true, http.StatusPreconditionFailed},
+		{"ListWithCurrent", `"1", "2","3"`, true, 0},
+		{"ListWithoutCurrent", `"1", "2"`, true, http.StatusPreconditionFailed},
+		{"WeakCurrent", `W/"3"`, true, http.StatusPreconditionFailed},
+		{"WeakAndStrong", `W/"3", "3"`, true, 0},
+		{"ForeignTag", `"abc"`, true, http.StatusPreconditionFailed},
+		{"ZeroVersion", `"0"`, true, http.StatusPreconditionFailed},
+		{"Unquoted", `3`, false, http.StatusPreconditionFailed},
+		{"UnquotedInList", `"3", 4`, false, http.StatusPreconditionFailed},
+		{"LoneQuote", `"`, false, http.StatusPreconditionFailed},
+		{"EmptyMember", `"3",`, false, http.StatusPreconditionFailed},
+		{"StarInList", `*, "3"`, false, http.StatusPreconditionFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPut, "/students/s1", nil)
+			if tt.header != "" {
+				r.Header.Set("If-Match", tt.header)
+			}
+			w := httptest.NewRecorder()
+
+			status := 0
+			m, ok := parseIfMatch(w, r)
+			if ok != tt.parsed {
+				t.Fatalf("If-Match %q: parsed %t, want %t", tt.header, ok, tt.parsed)
+			}
+			if ok {
+				var version int64
+				if version, ok = m.version(w, r, stored); ok && version != stored.Version {
+					t.Fatalf("got version %d, want %d", version, stored.Version)
+				}
+			}
+			if !ok {
+				status = w.Code
+			}
+			if status != tt.status {
+				t.Fatalf("If-Match %q: got status %d, want %d", tt.header, status, tt.status)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package transport
+
+import (
+	"fmt"
+	"golang-assignment/internal/export"
+	"golang-assignment/internal/student"
+	"net/http"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ExportStudents streams the students matching the search parameters of
+// GET /students as a file in the format query parameter, csv by default.
+// The cohort parameter exports the students of a saved cohort instead.
+func (h *Handler) ExportStudents(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	format := query.Get("format")
+	if format == "" {
+		format = export.FormatCSV
+	}
+	contentType, ok := export.ContentType(format)
+	if !ok {
+		writeProblem(w, r, http.StatusBadRequest, CodeInvalidQuery, export.ErrUnsupportedFormat.Error())
+		return
+	}
+	each, ok := h.exportedStudents(w, r)
+	if !ok {
+		return
+	}
+	fields, err := h.Fields.ListFields(r.Context())
+	if err != nil {
+		writeError(w, r, err, "Failed to export students")
+		return
+	}
+	names := make([]string, len(fields))
+	for i, d := range fields {
+		names[i] = d.Name
+	}
+
+	// the file is only started with the first student, so that an error
+	// before it is still answered with a problem
+	var out export.Writer
+	start := func() error {
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition",
+			fmt.Sprintf(`attachment; filename="students-%s.%s"`, time.Now().UTC().Format("20060102"), format))
+		// the write timeout of the server is meant for the other routes
+		if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+			log.Warnf("failed to lift the write deadline of the export: %v", err)
+		}
+		writer, err := export.NewWriter(format, w, names)
+		out = writer
+		return err
+	}
+
+	err = each(func(s student.Student) error {
+		if out == nil {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+		return out.Write(s)
+	})
+	if err == nil && out == nil {
+		err = start()
+	}
+	if err != nil {
+		if out == nil {
+			writeError(w, r, err, "Failed to export students")
+			return
+		}
+		// the status is sent already, dropping the connection is the only
+		// way to tell the client its file is truncated
+		log.Errorf("export of students stopped halfway: %v", err)
+		panic(http.ErrAbortHandler)
+	}
+
+	if err := out.Close(); err != nil {
+		log.Errorf("Error completing the export: %v", err)
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// exportedStudents returns how the export reaches its students, either the
+// ones of the cohort parameter or the ones matching the search parameters
+func (h *Handler) exportedStudents(w http.ResponseWriter, r *http.Request) (func(emit func(student.Student) error) error, bool) {
+	query := r.URL.Query()
+	if !query.Has("cohort") {
+		filter, err := searchFilterFromQuery(query)
+		if err != nil {
+			writeError(w, r, err, "Failed to export students")
+			return nil, false
+		}
+		return func(emit func(student.Student) error) error {
+			return h.Service.ExportStudents(r.Context(), filter, emit)
+		}, true
+	}
+
+	id, err := strconv.ParseInt(query.Get("cohort"), 10, 64)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, CodeInvalidQuery, "Invalid cohort")
+		return nil, false
+	}
+	for name := range query {
+		if isSearchParam(name) {
+			writeProblem(w, r, http.StatusBadRequest, CodeInvalidQuery, "cohort cannot be combined with "+name)
+			return nil, false
+		}
+	}
+	return func(emit func(student.Student) error) error {
+		return h.Cohorts.EachStudent(r.Context(), id, emit)
+	}, true
+}
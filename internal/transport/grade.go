@@ -0,0 +1,221 @@
+package transport
+
+import (
+	"encoding/json"
+	"fmt"
+	"golang-assignment/internal/pdf"
+	"golang-assignment/internal/student"
+	"net/http"
+	"strconv"
+	"strings"
+
+	util "golang-assignment/utils"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+type AddAssessmentRequest struct {
+	Name     string  `json:"name" validate:"required,max=255"`
+	Score    float64 `json:"score" validate:"gte=0"`
+	MaxScore float64 `json:"max_score" validate:"gt=0"`
+	// assessments weigh the same unless told otherwise
+	Weight *float64 `json:"weight" validate:"omitempty,gt=0"`
+}
+
+// SetFinalGradeRequest gives the letter of the grade, or nothing to grade the
+// enrollment on its assessments
+type SetFinalGradeRequest struct {
+	Scale  string `json:"scale" validate:"omitempty,max=32"`
+	Letter string `json:"letter" validate:"omitempty,max=8"`
+}
+
+func (h *Handler) ListGradeScales(w http.ResponseWriter, r *http.Request) {
+	if err := json.NewEncoder(w).Encode(student.GradeScales); err != nil {
+		log.Errorf("Error encoding response: %v", err)
+	}
+}
+
+func (h *Handler) AddAssessment(w http.ResponseWriter, r *http.Request) {
+	id, ok := enrollmentID(w, r)
+	if !ok {
+		return
+	}
+
+	var req AddAssessmentRequest
+	if !decodeAndValidate(w, r, &req) {
+		return
+	}
+
+	a := student.Assessment{EnrollmentID: id, Name: req.Name, Score: req.Score, MaxScore: req.MaxScore, Weight: 1}
+	if req.Weight != nil {
+		a.Weight = *req.Weight
+	}
+	a, err := h.Service.AddAssessment(r.Context(), a, util.GetCurrentUserID(r.Context()))
+	if err != nil {
+		writeError(w, r, err, "Failed to record assessment")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(a); err != nil {
+		log.Errorf("Error encoding response: %v", err)
+	}
+}
+
+func (h *Handler) ListAssessments(w http.ResponseWriter, r *http.Request) {
+	id, ok := enrollmentID(w, r)
+	if !ok {
+		return
+	}
+
+	assessments, err := h.Service.ListAssessments(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err, "Failed to list assessments")
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(assessments); err != nil {
+		log.Errorf("Error encoding response: %v", err)
+	}
+}
+
+func (h *Handler) SetFinalGrade(w http.ResponseWriter, r *http.Request) {
+	id, ok := enrollmentID(w, r)
+	if !ok {
+		return
+	}
+
+	var req SetFinalGradeRequest
+	if !decodeAndValidate(w, r, &req) {
+		return
+	}
+
+	grade, err := h.Service.SetFinalGrade(r.Context(), id, req.Scale, req.Letter, util.GetCurrentUserID(r.Context()))
+	if err != nil {
+		writeError(w, r, err, "Failed to record final grade")
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(grade); err != nil {
+		log.Errorf("Error encoding response: %v", err)
+	}
+}
+
+// Transcript answers JSON, or a printable PDF for ?format=pdf or a request
+// accepting application/pdf
+func (h *Handler) Transcript(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" && strings.Contains(r.Header.Get("Accept"), "application/pdf") {
+		format = "pdf"
+	}
+	if format != "" && format != "json" && format != "pdf" {
+		writeProblem(w, r, http.StatusBadRequest, CodeInvalidQuery, "format must be json or pdf")
+		return
+	}
+
+	t, err := h.Service.Transcript(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, r, err, "Failed to build the transcript")
+		return
+	}
+
+	if format == "pdf" {
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="transcript-%s.pdf"`, t.StudentID))
+		if _, err := transcriptPDF(t).WriteTo(w); err != nil {
+			log.Errorf("Error writing the transcript: %v", err)
+		}
+		return
+	}
+	if err := json.NewEncoder(w).Encode(t); err != nil {
+		log.Errorf("Error encoding response: %v", err)
+	}
+}
+
+// transcriptPDF lays the transcript out on as many pages as it needs
+func transcriptPDF(t student.Transcript) *pdf.Document {
+	const (
+		margin  = 50.0
+		leading = 16.0
+	)
+	// the left edge of the course, title, credits and grade columns
+	columns := []float64{margin, margin + 80, margin + 360, margin + 430}
+
+	doc := pdf.New()
+	y := 0.0
+	newPage := func() {
+		doc.AddPage()
+		y = pdf.PageHeight - margin
+	}
+	// room makes sure the next lines fit on the page
+	room := func(lines int) {
+		if y-float64(lines)*leading < margin {
+			newPage()
+		}
+	}
+	row := func(bold bool, cells ...string) {
+		for i, cell := range cells {
+			doc.Text(columns[i], y, 10, bold, cell)
+		}
+		y -= leading
+	}
+
+	newPage()
+	doc.Text(margin, y, 18, true, "Academic Transcript")
+	y -= 2 * leading
+	doc.Text(margin, y, 11, false, fmt.Sprintf("%s <%s>", t.Name, t.Email))
+	y -= leading
+	doc.Text(margin, y, 9, false, fmt.Sprintf("Student %s, generated %s", t.StudentID, t.GeneratedOn.Format("2006-01-02 15:04 MST")))
+	y -= 2 * leading
+
+	if len(t.Terms) == 0 {
+		doc.Text(margin, y, 11, false, "No courses.")
+	}
+	for _, term := range t.Terms {
+		room(4)
+		doc.Text(margin, y, 13, true, term.Term)
+		y -= leading
+		row(true, "Course", "Title", "Credits", "Grade")
+		doc.Line(margin, y+leading-4, pdf.PageWidth-margin, y+leading-4)
+		for _, c := range term.Courses {
+			room(1)
+			row(false, c.Course, truncate(c.Title, 48), strconv.Itoa(c.Credits), transcriptGrade(c))
+		}
+		room(1)
+		doc.Text(margin, y, 10, false, gpaLine("Term", term.GPASummary))
+		y -= 2 * leading
+	}
+	room(2)
+	doc.Line(margin, y+leading-4, pdf.PageWidth-margin, y+leading-4)
+	doc.Text(margin, y, 11, true, gpaLine("Cumulative", t.Cumulative))
+	return doc
+}
+
+// transcriptGrade is the grade column, W for a withdrawal and IP for a course
+// in progress
+func transcriptGrade(c student.TranscriptCourse) string {
+	switch {
+	case c.Grade != nil:
+		return c.Grade.Letter
+	case c.Status == "withdrawn":
+		return "W"
+	}
+	return "IP"
+}
+
+func gpaLine(label string, g student.GPASummary) string {
+	gpa := "n/a"
+	if g.GPA != nil {
+		gpa = strconv.FormatFloat(*g.GPA, 'f', 2, 64)
+	}
+	return fmt.Sprintf("%s GPA %s, %d credit(s) attempted, %d earned", label, gpa, g.AttemptedCredits, g.EarnedCredits)
+}
+
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-3]) + "..."
+}
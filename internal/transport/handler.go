@@ -13,22 +13,41 @@ import (
 )
 
 type Handler struct {
-	Router  *mux.Router
-	Service StudentService
-	Server  *http.Server
+	Router      *mux.Router
+	Service     StudentService
+	Courses     CourseService
+	Enrollments EnrollmentService
+	Attendance  AttendanceService
+	Contacts    ContactService
+	Attachments AttachmentService
+	Fields      CustomFieldService
+	Tags        TagService
+	Cohorts     CohortService
+	Tokens      TokenService
+	Server      *http.Server
 }
 
 type Response struct {
 	Message string `json:"message"`
 }
 
-func NewHandler(service StudentService) *Handler {
+func NewHandler(service StudentService, courses CourseService, enrollments EnrollmentService, attendance AttendanceService, contacts ContactService, attachments AttachmentService, fields CustomFieldService, tags TagService, cohorts CohortService, tokens TokenService) *Handler {
 	log.Info("setting up our handler")
 	h := &Handler{
-		Service: service,
+		Service:     service,
+		Courses:     courses,
+		Enrollments: enrollments,
+		Attendance:  attendance,
+		Contacts:    contacts,
+		Attachments: attachments,
+		Fields:      fields,
+		Tags:        tags,
+		Cohorts:     cohorts,
+		Tokens:      tokens,
 	}
 
 	h.Router = mux.NewRouter()
+	h.Router.Use(RequestIDMiddleware)
 	h.Router.Use(JSONMiddleware)
 	h.Router.Use(LoggingMiddleware)
 	h.Router.Use(TimeoutMiddleware)
@@ -48,14 +67,96 @@ func NewHandler(service StudentService) *Handler {
 }
 
 func (h *Handler) mapRoutes() {
+	h.Router.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeProblem(w, r, http.StatusNotFound, CodeRouteNotFound, "No route matches the request")
+	})
+	h.Router.MethodNotAllowedHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeProblem(w, r, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "The route does not allow this method")
+	})
 	h.Router.HandleFunc("/alive", h.AliveCheck).Methods("GET")
 	h.Router.HandleFunc("/ready", h.ReadyCheck).Methods("GET")
-	h.Router.HandleFunc("/addStudent", JWTAuth(UserIDMiddleware(h.PostStudent))).Methods("POST")
-	h.Router.HandleFunc("/getStudent/{id}", JWTAuth(h.GetStudent)).Methods("GET")
-	h.Router.HandleFunc("/updateStudent/{id}", JWTAuth(UserIDMiddleware(h.UpdateStudent))).Methods("PUT")
-	h.Router.HandleFunc("/deleteStudent/{id}", JWTAuth(h.DeleteStudent)).Methods("DELETE")
+	h.Router.HandleFunc("/addStudent", h.JWTAuth(Authorize(UserIDMiddleware(h.PostStudent)))).Methods("POST")
+	h.Router.HandleFunc("/getStudent/{id}", h.JWTAuth(Authorize(h.GetStudent))).Methods("GET")
+	h.Router.HandleFunc("/updateStudent/{id}", h.JWTAuth(Authorize(UserIDMiddleware(h.UpdateStudent)))).Methods("PUT")
+	h.Router.HandleFunc("/deleteStudent/{id}", h.JWTAuth(Authorize(h.DeleteStudent))).Methods("DELETE")
+	h.Router.HandleFunc("/students", h.JWTAuth(Authorize(h.ListStudents))).Methods("GET")
+	h.Router.HandleFunc("/students/{id}", h.JWTAuth(Authorize(UserIDMiddleware(h.PatchStudent)))).Methods("PATCH")
+	h.Router.HandleFunc("/students/export", h.JWTAuth(Authorize(h.ExportStudents))).Methods("GET")
+	h.Router.HandleFunc("/students/import", h.JWTAuth(Authorize(UserIDMiddleware(h.ImportStudents)))).Methods("POST")
+	h.Router.HandleFunc("/students/trash", h.JWTAuth(Authorize(h.ListDeletedStudents))).Methods("GET")
+	h.Router.HandleFunc("/students/{id}/restore", h.JWTAuth(Authorize(UserIDMiddleware(h.RestoreStudent)))).Methods("POST")
+	h.Router.HandleFunc("/students/{id}/history", h.JWTAuth(Authorize(h.StudentHistory))).Methods("GET")
+	h.Router.HandleFunc("/audit", h.JWTAuth(Authorize(h.ListAuditEntries))).Methods("GET")
+
+	h.Router.HandleFunc("/courses", h.JWTAuth(Authorize(h.ListCourses))).Methods("GET")
+	h.Router.HandleFunc("/courses", h.JWTAuth(Authorize(UserIDMiddleware(h.CreateCourse)))).Methods("POST")
+	h.Router.HandleFunc("/courses/{code}", h.JWTAuth(Authorize(h.GetCourse))).Methods("GET")
+	h.Router.HandleFunc("/courses/{code}", h.JWTAuth(Authorize(UserIDMiddleware(h.UpdateCourse)))).Methods("PUT")
+	h.Router.HandleFunc("/courses/{code}", h.JWTAuth(Authorize(h.DeleteCourse))).Methods("DELETE")
+	h.Router.HandleFunc("/courses/{code}/roster", h.JWTAuth(Authorize(h.ListRoster))).Methods("GET")
+
+	h.Router.HandleFunc("/students/{id}/enrollments", h.JWTAuth(Authorize(h.ListStudentEnrollments))).Methods("GET")
+	h.Router.HandleFunc("/students/{id}/enrollments", h.JWTAuth(Authorize(UserIDMiddleware(h.Enroll)))).Methods("POST")
+	h.Router.HandleFunc("/enrollments/{id}", h.JWTAuth(Authorize(h.GetEnrollment))).Methods("GET")
+	h.Router.HandleFunc("/enrollments/{id}/status", h.JWTAuth(Authorize(UserIDMiddleware(h.SetEnrollmentStatus)))).Methods("PUT")
+	h.Router.HandleFunc("/enrollments/{id}/assessments", h.JWTAuth(Authorize(h.ListAssessments))).Methods("GET")
+	h.Router.HandleFunc("/enrollments/{id}/assessments", h.JWTAuth(Authorize(UserIDMiddleware(h.AddAssessment)))).Methods("POST")
+	h.Router.HandleFunc("/enrollments/{id}/final-grade", h.JWTAuth(Authorize(UserIDMiddleware(h.SetFinalGrade)))).Methods("PUT")
+	h.Router.HandleFunc("/students/{id}/transcript", h.JWTAuth(Authorize(h.Transcript))).Methods("GET")
+	h.Router.HandleFunc("/grade-scales", h.JWTAuth(Authorize(h.ListGradeScales))).Methods("GET")
+
+	h.Router.HandleFunc("/courses/{code}/attendance", h.JWTAuth(Authorize(UserIDMiddleware(h.MarkAttendance)))).Methods("POST")
+	h.Router.HandleFunc("/courses/{code}/attendance", h.JWTAuth(Authorize(h.SessionAttendance))).Methods("GET")
+	h.Router.HandleFunc("/students/{id}/attendance", h.JWTAuth(Authorize(h.StudentAttendance))).Methods("GET")
+	h.Router.HandleFunc("/attendance/alerts", h.JWTAuth(Authorize(h.ListAttendanceAlerts))).Methods("GET")
+
+	h.Router.HandleFunc("/students/{id}/contacts", h.JWTAuth(Authorize(h.ListContacts))).Methods("GET")
+	h.Router.HandleFunc("/students/{id}/contacts", h.JWTAuth(Authorize(UserIDMiddleware(h.CreateContact)))).Methods("POST")
+	h.Router.HandleFunc("/students/{id}/contacts/{contactID}", h.JWTAuth(Authorize(h.GetContact))).Methods("GET")
+	h.Router.HandleFunc("/students/{id}/contacts/{contactID}", h.JWTAuth(Authorize(UserIDMiddleware(h.UpdateContact)))).Methods("PUT")
+	h.Router.HandleFunc("/students/{id}/contacts/{contactID}", h.JWTAuth(Authorize(h.DeleteContact))).Methods("DELETE")
+
+	h.Router.HandleFunc("/students/{id}/attachments", h.JWTAuth(Authorize(h.ListAttachments))).Methods("GET")
+	h.Router.HandleFunc("/students/{id}/attachments", h.JWTAuth(Authorize(UserIDMiddleware(h.UploadAttachment)))).Methods("POST")
+	h.Router.HandleFunc("/students/{id}/attachments/{attachmentID}", h.JWTAuth(Authorize(h.GetAttachment))).Methods("GET")
+	h.Router.HandleFunc("/students/{id}/attachments/{attachmentID}/content", h.JWTAuth(Authorize(h.DownloadAttachment))).Methods("GET")
+	h.Router.HandleFunc("/students/{id}/attachments/{attachmentID}", h.JWTAuth(Authorize(h.DeleteAttachment))).Methods("DELETE")
+
+	h.Router.HandleFunc("/custom-fields", h.JWTAuth(Authorize(h.ListCustomFields))).Methods("GET")
+	h.Router.HandleFunc("/custom-fields", h.JWTAuth(Authorize(UserIDMiddleware(h.CreateCustomField)))).Methods("POST")
+	h.Router.HandleFunc("/custom-fields/{name}", h.JWTAuth(Authorize(h.GetCustomField))).Methods("GET")
+	h.Router.HandleFunc("/custom-fields/{name}", h.JWTAuth(Authorize(UserIDMiddleware(h.UpdateCustomField)))).Methods("PUT")
+	h.Router.HandleFunc("/custom-fields/{name}", h.JWTAuth(Authorize(h.DeleteCustomField))).Methods("DELETE")
+
+	h.Router.HandleFunc("/tags", h.JWTAuth(Authorize(h.ListTags))).Methods("GET")
+	h.Router.HandleFunc("/tags", h.JWTAuth(Authorize(UserIDMiddleware(h.CreateTag)))).Methods("POST")
+	h.Router.HandleFunc("/tags/{name}", h.JWTAuth(Authorize(h.GetTag))).Methods("GET")
+	h.Router.HandleFunc("/tags/{name}", h.JWTAuth(Authorize(UserIDMiddleware(h.UpdateTag)))).Methods("PUT")
+	h.Router.HandleFunc("/tags/{name}", h.JWTAuth(Authorize(h.DeleteTag))).Methods("DELETE")
+	h.Router.HandleFunc("/tags/{name}/students", h.JWTAuth(Authorize(UserIDMiddleware(h.TagStudents)))).Methods("POST")
+	h.Router.HandleFunc("/tags/{name}/students/remove", h.JWTAuth(Authorize(UserIDMiddleware(h.UntagStudents)))).Methods("POST")
+	h.Router.HandleFunc("/students/{id}/tags", h.JWTAuth(Authorize(h.ListStudentTags))).Methods("GET")
+	h.Router.HandleFunc("/students/{id}/tags/{name}", h.JWTAuth(Authorize(UserIDMiddleware(h.AddStudentTag)))).Methods("PUT")
+	h.Router.HandleFunc("/students/{id}/tags/{name}", h.JWTAuth(Authorize(h.RemoveStudentTag))).Methods("DELETE")
+
+	h.Router.HandleFunc("/cohorts", h.JWTAuth(Authorize(h.ListCohorts))).Methods("GET")
+	h.Router.HandleFunc("/cohorts", h.JWTAuth(Authorize(UserIDMiddleware(h.CreateCohort)))).Methods("POST")
+	h.Router.HandleFunc("/cohorts/{id}", h.JWTAuth(Authorize(h.GetCohort))).Methods("GET")
+	h.Router.HandleFunc("/cohorts/{id}", h.JWTAuth(Authorize(UserIDMiddleware(h.UpdateCohort)))).Methods("PUT")
+	h.Router.HandleFunc("/cohorts/{id}", h.JWTAuth(Authorize(h.DeleteCohort))).Methods("DELETE")
+	h.Router.HandleFunc("/cohorts/{id}/students", h.JWTAuth(Authorize(h.CohortStudents))).Methods("GET")
 
 	h.Router.HandleFunc("/login", h.Login).Methods("POST")
+	h.Router.HandleFunc("/token/refresh", h.RefreshToken).Methods("POST")
+	h.Router.HandleFunc("/logout", h.JWTAuth(h.Logout)).Methods("POST")
+	h.Router.HandleFunc("/.well-known/jwks.json", h.JWKS).Methods("GET")
+
+	h.Router.HandleFunc("/users", h.JWTAuth(Authorize(h.ListUsers))).Methods("GET")
+	h.Router.HandleFunc("/users", h.JWTAuth(Authorize(UserIDMiddleware(h.CreateUser)))).Methods("POST")
+	h.Router.HandleFunc("/users/{id}/role", h.JWTAuth(Authorize(UserIDMiddleware(h.SetUserRole)))).Methods("PUT")
+	h.Router.HandleFunc("/users/{id}/password", h.JWTAuth(Authorize(UserIDMiddleware(h.ResetPassword)))).Methods("PUT")
+	h.Router.HandleFunc("/users/{id}/disable", h.JWTAuth(Authorize(UserIDMiddleware(h.setUserDisabled(true))))).Methods("POST")
+	h.Router.HandleFunc("/users/{id}/enable", h.JWTAuth(Authorize(UserIDMiddleware(h.setUserDisabled(false))))).Methods("POST")
 }
 
 func (h *Handler) AliveCheck(w http.ResponseWriter, r *http.Request) {
@@ -68,7 +169,7 @@ func (h *Handler) AliveCheck(w http.ResponseWriter, r *http.Request) {
 
 func (h *Handler) ReadyCheck(w http.ResponseWriter, r *http.Request) {
 	if err := h.Service.ReadyCheck(r.Context()); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		writeProblem(w, r, http.StatusServiceUnavailable, CodeServiceUnavailable, "Server is not ready")
 		return
 	}
 
@@ -0,0 +1,322 @@
+package transport
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"golang-assignment/internal/export"
+	"golang-assignment/internal/student"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	util "golang-assignment/utils"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	importFormatCSV   = "csv"
+	importFormatJSONL = "jsonl"
+
+	// maxImportBytes bounds the whole multipart body
+	maxImportBytes = 10 << 20
+	// maxImportLineBytes bounds one line of a JSON Lines file
+	maxImportLineBytes = 1 << 20
+)
+
+var errInvalidImportFile = errors.New("invalid import file")
+
+// importColumns are the CSV columns an import understands, named like the
+// fields of PostStudentRequest. Only id may be left out. A cf.<name> column
+// holds the custom field of that name.
+var importColumns = map[string]bool{"id": false, "name": true, "email": true, "age": true, "course": true}
+
+// importFormat picks the format from the format query parameter, else from
+// the extension or the media type of the uploaded file
+func importFormat(r *http.Request, filename, contentType string) (string, bool) {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return format, format == importFormatCSV || format == importFormatJSONL
+	}
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return importFormatCSV, true
+	case ".jsonl", ".ndjson":
+		return importFormatJSONL, true
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "text/csv":
+		return importFormatCSV, true
+	case "application/jsonl", "application/x-ndjson":
+		return importFormatJSONL, true
+	}
+	return "", false
+}
+
+// importRow runs the validator rules of PostStudentRequest on a decoded row.
+// Errors already found decoding a field replace the rules of that field.
+func importRow(line int, req PostStudentRequest, errs []student.ImportError) student.ImportRow {
+	decoded := map[string]bool{}
+	for _, e := range errs {
+		decoded[e.Field] = true
+	}
+	if err := validate.Struct(req); err != nil {
+		for _, fe := range fieldErrors(err) {
+			if !decoded[fe.Field] {
+				errs = append(errs, student.ImportError{Field: fe.Field, Message: fe.Message})
+			}
+		}
+	}
+	return student.ImportRow{Line: line, Student: studentFromPostStudentRequest(req), Errors: errs}
+}
+
+// readCSVImport reads a CSV file whose first line names the columns
+func readCSVImport(r io.Reader) ([]student.ImportRow, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidImportFile, err)
+	}
+	columns := make([]string, len(header))
+	seen := map[string]bool{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if _, ok := importColumns[name]; !ok && !strings.HasPrefix(name, customFieldPrefix) {
+			return nil, fmt.Errorf("%w: unknown column %q", errInvalidImportFile, name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("%w: column %q appears twice", errInvalidImportFile, name)
+		}
+		seen[name] = true
+		columns[i] = name
+	}
+	for name, required := range importColumns {
+		if required && !seen[name] {
+			return nil, fmt.Errorf("%w: missing column %q", errInvalidImportFile, name)
+		}
+	}
+
+	var rows []student.ImportRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil && !errors.Is(err, csv.ErrFieldCount) {
+			return nil, fmt.Errorf("%w: %v", errInvalidImportFile, err)
+		}
+		if len(rows) == student.MaxImportRows {
+			return nil, fmt.Errorf("%w: the file has more than %d rows", errInvalidImportFile, student.MaxImportRows)
+		}
+		line, _ := reader.FieldPos(0)
+		if err != nil {
+			rows = append(rows, student.ImportRow{Line: line, Errors: []student.ImportError{
+				{Message: fmt.Sprintf("has %d fields but the header has %d", len(record), len(columns))},
+			}})
+			continue
+		}
+
+		var req PostStudentRequest
+		var errs []student.ImportError
+		var texts map[string]string
+		for i, value := range record {
+			value = strings.TrimSpace(value)
+			switch columns[i] {
+			case "id":
+				req.ID = value
+			case "name":
+				req.Name = value
+			case "email":
+				req.Email = value
+			case "age":
+				if value == "" {
+					continue
+				}
+				if req.Age, err = strconv.Atoi(value); err != nil {
+					errs = append(errs, student.ImportError{Field: "age", Message: "must be a whole number"})
+				}
+			case "course":
+				req.Course = value
+			default:
+				if texts == nil {
+					texts = map[string]string{}
+				}
+				texts[strings.TrimPrefix(columns[i], customFieldPrefix)] = value
+			}
+		}
+		row := importRow(line, req, errs)
+		row.FieldTexts = texts
+		rows = append(rows, row)
+	}
+}
+
+// readJSONLImport reads a JSON Lines file holding one student object per
+// line, blank lines are skipped
+func readJSONLImport(r io.Reader) ([]student.ImportRow, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxImportLineBytes)
+
+	var rows []student.ImportRow
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		if len(rows) == student.MaxImportRows {
+			return nil, fmt.Errorf("%w: the file has more than %d rows", errInvalidImportFile, student.MaxImportRows)
+		}
+
+		var req PostStudentRequest
+		dec := json.NewDecoder(strings.NewReader(text))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&req); err != nil {
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &typeErr) && typeErr.Field != "" {
+				rows = append(rows, importRow(line, req, []student.ImportError{
+					{Field: typeErr.Field, Message: "must be a " + jsonTypeName(typeErr.Type.Kind())},
+				}))
+				continue
+			}
+			rows = append(rows, student.ImportRow{Line: line, Errors: []student.ImportError{
+				{Message: "is not a valid student object: " + err.Error()},
+			}})
+			continue
+		}
+		rows = append(rows, importRow(line, req, nil))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidImportFile, err)
+	}
+	return rows, nil
+}
+
+// jsonTypeName names a Go kind the way a JSON document spells it
+func jsonTypeName(kind reflect.Kind) string {
+	switch kind {
+	case reflect.Int, reflect.Int64, reflect.Float64:
+		return "number"
+	case reflect.Bool:
+		return "boolean"
+	}
+	return kind.String()
+}
+
+// wantsCSVReport tells whether the client asked for the rejected rows as a
+// CSV file rather than for the JSON report
+func wantsCSVReport(r *http.Request) bool {
+	if r.URL.Query().Get("report") == importFormatCSV {
+		return true
+	}
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		if mediaType, _, _ := mime.ParseMediaType(strings.TrimSpace(accept)); mediaType == "text/csv" {
+			return true
+		}
+	}
+	return false
+}
+
+// writeCSVReport writes one line per reason a row was rejected. The IDs and
+// emails come from the uploaded file, so the cells are escaped as an export's.
+func writeCSVReport(w http.ResponseWriter, report student.ImportReport) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="import-report.csv"`)
+	out := csv.NewWriter(w)
+	out.Write([]string{"line", "id", "email", "field", "message", "conflicting_id"})
+	for _, row := range report.Rejected {
+		for _, e := range row.Errors {
+			cells := []string{strconv.Itoa(row.Line), row.ID, row.Email, e.Field, e.Message, e.ConflictingID}
+			for i := range cells {
+				cells[i] = export.EscapeCell(cells[i])
+			}
+			out.Write(cells)
+		}
+	}
+	out.Flush()
+	if err := out.Error(); err != nil {
+		log.Errorf("Error writing the import report: %v", err)
+	}
+}
+
+// ImportStudents creates the students of a CSV or JSON Lines file sent as
+// the file field of a multipart form, see student.Service.ImportStudents
+func (h *Handler) ImportStudents(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	opts := student.ImportOptions{ImportedBy: util.GetCurrentUserID(r.Context())}
+	if v := query.Get("dry_run"); v != "" {
+		dryRun, err := strconv.ParseBool(v)
+		if err != nil {
+			writeProblem(w, r, http.StatusBadRequest, CodeInvalidQuery, "Invalid dry_run")
+			return
+		}
+		opts.DryRun = dryRun
+	}
+	if v := query.Get("batch_size"); v != "" {
+		batchSize, err := strconv.Atoi(v)
+		if err != nil {
+			writeProblem(w, r, http.StatusBadRequest, CodeInvalidQuery, "Invalid batch_size")
+			return
+		}
+		opts.BatchSize = batchSize
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBytes)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		switch {
+		case errors.As(err, &tooLarge):
+			writeProblem(w, r, http.StatusRequestEntityTooLarge, CodeImportTooLarge,
+				fmt.Sprintf("The upload must be at most %d bytes", maxImportBytes))
+		case errors.Is(err, http.ErrNotMultipart):
+			writeProblem(w, r, http.StatusUnsupportedMediaType, CodeUnsupportedMediaType,
+				"The Content-Type must be multipart/form-data")
+		default:
+			writeProblem(w, r, http.StatusBadRequest, CodeInvalidRequestBody, "The form must have a file field")
+		}
+		return
+	}
+	defer file.Close()
+
+	format, ok := importFormat(r, header.Filename, header.Header.Get("Content-Type"))
+	if !ok {
+		writeProblem(w, r, http.StatusUnsupportedMediaType, CodeUnsupportedMediaType,
+			"The file must be CSV (.csv, text/csv) or JSON Lines (.jsonl, application/jsonl)")
+		return
+	}
+	var rows []student.ImportRow
+	if format == importFormatCSV {
+		rows, err = readCSVImport(file)
+	} else {
+		rows, err = readJSONLImport(file)
+	}
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, CodeInvalidImport, err.Error())
+		return
+	}
+
+	report, err := h.Service.ImportStudents(r.Context(), rows, opts)
+	if err != nil {
+		writeError(w, r, err, "Failed to import students")
+		return
+	}
+
+	if wantsCSVReport(r) {
+		writeCSVReport(w, report)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		log.Errorf("Error encoding response: %v", err)
+	}
+}
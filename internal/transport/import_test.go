@@ -0,0 +1,196 @@
+package transport
+
+import (
+	"errors"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang-assignment/internal/student"
+)
+
+// importResult is what a test expects of a row, the fields of its errors
+// with "" for an error about the whole row
+type importResult struct {
+	line   int
+	fields []string
+}
+
+// wantImportRows fails the test unless rows are the expected ones
+func wantImportRows(t *testing.T, rows []student.ImportRow, want []importResult) {
+	t.Helper()
+	if len(rows) != len(want) {
+		t.Fatalf("got %d rows, want %d: %+v", len(rows), len(want), rows)
+	}
+	for i, row := range rows {
+		fields := []string{}
+		for _, e := range row.Errors {
+			fields = append(fields, e.Field)
+		}
+		wantFields := want[i].fields
+		if wantFields == nil {
+			wantFields = []string{}
+		}
+		if row.Line != want[i].line || !reflect.DeepEqual(fields, wantFields) {
+			t.Fatalf("row %d: got line %d with errors %+v, want line %d with errors on %q",
+				i, row.Line, row.Errors, want[i].line, wantFields)
+		}
+	}
+}
+
+func TestReadCSVImport(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		// invalid tells that the file as a whole is refused
+		invalid bool
+		want    []importResult
+	}{
+		{"Empty", "", false, nil},
+		{"HeaderOnly", "name,email,age,course\n", false, nil},
+		{"Valid", "name,email,age,course\nAnn,ann@x.io,20,CS101\nBob,bob@x.io,21,CS101\n", false,
+			[]importResult{{line: 2}, {line: 3}}},
+		{"ByteOrderMark", "\ufeffName, Email ,age,course\nAnn,ann@x.io,20,CS101\n", false,
+			[]importResult{{line: 2}}},
+		{"ColumnsInAnyOrder", "course,age,email,name,id\nCS101,20,ann@x.io,Ann,s1\n", false,
+			[]importResult{{line: 2}}},
+		{"UnknownColumn", "name,email,age,course,grade\n", true, nil},
+		{"DuplicateColumn", "name,email,age,course,email\n", true, nil},
+		{"MissingColumn", "name,email,age\n", true, nil},
+		{"TooFewFields", "name,email,age,course\nAnn,ann@x.io,20\nBob,bob@x.io,21,CS101\n", false,
+			[]importResult{{line: 2, fields: []string{""}}, {line: 3}}},
+		{"TooManyFields", "name,email,age,course\nAnn,ann@x.io,20,CS101,extra\n", false,
+			[]importResult{{line: 2, fields: []string{""}}}},
+		{"AgeNotNumber", "name,email,age,course\nAnn,ann@x.io,twenty,CS101\n", false,
+			[]importResult{{line: 2, fields: []string{"age"}}}},
+		{"AgeMissing", "name,email,age,course\nAnn,ann@x.io,,CS101\n", false,
+			[]importResult{{line: 2, fields: []string{"age"}}}},
+		{"RuleErrors", "name,email,age,course\n,not-an-email,20,CS101\n", false,
+			[]importResult{{line: 2, fields: []string{"name", "email"}}}},
+		{"UnterminatedQuote", "name,email,age,course\n\"Ann,ann@x.io,20,CS101\n", true, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := readCSVImport(strings.NewReader(tt.input))
+			if tt.invalid {
+				if !errors.Is(err, errInvalidImportFile) {
+					t.Fatalf("got error %v, want %v", err, errInvalidImportFile)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readCSVImport: %v", err)
+			}
+			wantImportRows(t, rows, tt.want)
+		})
+	}
+}
+
+func TestReadCSVImportValues(t *testing.T) {
+	rows, err := readCSVImport(strings.NewReader("\ufeffid,name,email,age,course,cf.nationality\n s1 , Ann ,ann@x.io, 20 ,CS101, NP \n"))
+	if err != nil {
+		t.Fatalf("readCSVImport: %v", err)
+	}
+	wantImportRows(t, rows, []importResult{{line: 2}})
+	want := student.Student{ID: "s1", Name: "Ann", Email: "ann@x.io", Age: 20, Course: "CS101"}
+	if !reflect.DeepEqual(rows[0].Student, want) {
+		t.Fatalf("got student %+v, want %+v", rows[0].Student, want)
+	}
+	if texts := rows[0].FieldTexts; !reflect.DeepEqual(texts, map[string]string{"nationality": "NP"}) {
+		t.Fatalf("got custom field cells %v", texts)
+	}
+}
+
+func TestReadJSONLImport(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		invalid bool
+		want    []importResult
+	}{
+		{"Empty", "", false, nil},
+		{"Valid", `{"name":"Ann","email":"ann@x.io","age":20,"course":"CS101"}` + "\n" +
+			`{"name":"Bob","email":"bob@x.io","age":21,"course":"CS101"}`, false,
+			[]importResult{{line: 1}, {line: 2}}},
+		{"BlankLinesKeepLineNumbers", "\n" + `{"name":"Ann","email":"ann@x.io","age":20,"course":"CS101"}` + "\n  \n" +
+			`{"name":"Bob","email":"bob@x.io","age":21,"course":"CS101"}` + "\n", false,
+			[]importResult{{line: 2}, {line: 4}}},
+		{"AgeNotNumber", `{"name":"Ann","email":"ann@x.io","age":"20","course":"CS101"}`, false,
+			[]importResult{{line: 1, fields: []string{"age"}}}},
+		{"NameNotString", `{"name":7,"email":"ann@x.io","age":20,"course":"CS101"}`, false,
+			[]importResult{{line: 1, fields: []string{"name"}}}},
+		{"UnknownField", `{"name":"Ann","email":"ann@x.io","age":20,"course":"CS101","grade":"A"}`, false,
+			[]importResult{{line: 1, fields: []string{""}}}},
+		{"NotJSON", `name,email,age,course`, false,
+			[]importResult{{line: 1, fields: []string{""}}}},
+		{"RuleErrors", `{"email":"ann@x.io","age":0,"course":"CS101"}`, false,
+			[]importResult{{line: 1, fields: []string{"name", "age"}}}},
+		{"LineTooLong", `{"name":"` + strings.Repeat("a", maxImportLineBytes) + `"}`, true, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := readJSONLImport(strings.NewReader(tt.input))
+			if tt.invalid {
+				if !errors.Is(err, errInvalidImportFile) {
+					t.Fatalf("got error %v, want %v", err, errInvalidImportFile)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readJSONLImport: %v", err)
+			}
+			wantImportRows(t, rows, tt.want)
+		})
+	}
+}
+
+func TestImportRowCap(t *testing.T) {
+	csvFile := func(rows int) string {
+		var b strings.Builder
+		b.WriteString("name,email,age,course\n")
+		for i := 0; i < rows; i++ {
+			b.WriteString("Ann,ann@x.io,20,CS101\n")
+		}
+		return b.String()
+	}
+	jsonlFile := func(rows int) string {
+		return strings.Repeat(`{"name":"Ann","email":"ann@x.io","age":20,"course":"CS101"}`+"\n", rows)
+	}
+	readers := []struct {
+		name string
+		file func(rows int) string
+		read func(string) ([]student.ImportRow, error)
+	}{
+		{"CSV", csvFile, func(s string) ([]student.ImportRow, error) { return readCSVImport(strings.NewReader(s)) }},
+		{"JSONL", jsonlFile, func(s string) ([]student.ImportRow, error) { return readJSONLImport(strings.NewReader(s)) }},
+	}
+	for _, r := range readers {
+		t.Run(r.name, func(t *testing.T) {
+			rows, err := r.read(r.file(student.MaxImportRows))
+			if err != nil || len(rows) != student.MaxImportRows {
+				t.Fatalf("at the cap: got %d rows and error %v, want %d rows", len(rows), err, student.MaxImportRows)
+			}
+			if _, err := r.read(r.file(student.MaxImportRows + 1)); !errors.Is(err, errInvalidImportFile) {
+				t.Fatalf("over the cap: got error %v, want %v", err, errInvalidImportFile)
+			}
+		})
+	}
+}
+
+func TestWriteCSVReportEscapesCells(t *testing.T) {
+	w := httptest.NewRecorder()
+	writeCSVReport(w, student.ImportReport{Rejected: []student.RejectedRow{{
+		Line:   2,
+		ID:     "=HYPERLINK(\"http://x.io\")",
+		Email:  "@x.io",
+		Errors: []student.ImportError{{Field: "email", Message: "-must be a valid email"}, {Message: "+1"}},
+	}}})
+
+	want := "line,id,email,field,message,conflicting_id\n" +
+		"2,\"'=HYPERLINK(\"\"http://x.io\"\")\",'@x.io,email,'-must be a valid email,\n" +
+		"2,\"'=HYPERLINK(\"\"http://x.io\"\")\",'@x.io,,'+1,\n"
+	if got := w.Body.String(); got != want {
+		t.Fatalf("got report\n%s\nwant\n%s", got, want)
+	}
+}
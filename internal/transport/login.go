@@ -2,9 +2,11 @@ package transport
 
 import (
 	"encoding/json"
+	"errors"
+	"golang-assignment/internal/student"
 	"net/http"
 
-	"github.com/go-playground/validator/v10"
+	log "github.com/sirupsen/logrus"
 )
 
 type LoginRequest struct {
@@ -13,7 +15,27 @@ type LoginRequest struct {
 }
 
 type LoginResponse struct {
-	Token string `json:"token"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+func loginResponseFromTokenPair(t student.TokenPair) LoginResponse {
+	return LoginResponse{
+		Token:        t.AccessToken,
+		RefreshToken: t.RefreshToken,
+		TokenType:    t.TokenType,
+		ExpiresIn:    t.ExpiresIn,
+	}
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
 }
 
 type User struct {
@@ -23,33 +45,80 @@ type User struct {
 
 func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 	var req LoginRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
-		return
-	}
-
-	validate := validator.New()
-	if err := validate.Struct(req); err != nil {
-		http.Error(w, "Validation failed", http.StatusBadRequest)
+	if !decodeAndValidate(w, r, &req) {
 		return
 	}
 
 	// Authenticate the user
 	user, err := h.Service.AuthenticateUser(r.Context(), req.UserID, req.Password)
 	if err != nil {
-		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+		if !errors.Is(err, student.ErrInvalidCredentials) {
+			writeError(w, r, err, "Failed to authenticate")
+			return
+		}
+		writeProblem(w, r, http.StatusUnauthorized, CodeInvalidCredentials, "Invalid credentials")
 		return
 	}
 
-	// Generate JWT token
-	token, err := h.Service.GenerateJWT(user)
+	// Generate the access and refresh tokens
+	tokens, err := h.Service.IssueTokens(r.Context(), user)
 	if err != nil {
-		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+		log.Error(err)
+		writeProblem(w, r, http.StatusInternalServerError, CodeInternal, "Failed to generate token")
 		return
 	}
 
 	// Return token
-	resp := LoginResponse{Token: token}
+	resp := loginResponseFromTokenPair(tokens)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(resp)
 }
+
+// RefreshToken trades a refresh token for a new pair of tokens, the refresh
+// token is rotated so it only works once
+func (h *Handler) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	var req RefreshTokenRequest
+	if !decodeAndValidate(w, r, &req) {
+		return
+	}
+
+	tokens, err := h.Service.RefreshTokens(r.Context(), req.RefreshToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, student.ErrRefreshTokenReused):
+			unauthorizedResponse(w, r, CodeRefreshTokenReused, err.Error())
+		case errors.Is(err, student.ErrInvalidRefreshToken):
+			unauthorizedResponse(w, r, CodeInvalidToken, err.Error())
+		default:
+			writeProblem(w, r, http.StatusInternalServerError, CodeInternal, "Failed to refresh token")
+		}
+		return
+	}
+
+	json.NewEncoder(w).Encode(loginResponseFromTokenPair(tokens))
+}
+
+// Logout revokes the access token of the request, and the refresh token
+// family when a refresh token is given in the body
+func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
+	var req LogoutRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeProblem(w, r, http.StatusBadRequest, CodeInvalidRequestBody, "Invalid request body")
+			return
+		}
+	}
+
+	claims, ok := claimsFromContext(r.Context())
+	if !ok {
+		unauthorizedResponse(w, r, CodeInvalidToken, "Invalid JWT token")
+		return
+	}
+
+	if err := h.Service.Logout(r.Context(), claims.ID, claims.ExpiresAt.Time, req.RefreshToken); err != nil {
+		writeProblem(w, r, http.StatusInternalServerError, CodeInternal, "Failed to log out")
+		return
+	}
+
+	json.NewEncoder(w).Encode(Response{Message: "Logged out"})
+}
@@ -2,11 +2,11 @@ package transport
 
 import (
 	"context"
-	util "golang-assignment/utils"
+	"crypto/rand"
+	"encoding/hex"
 	"net/http"
 	"time"
 
-	"github.com/golang-jwt/jwt"
 	"github.com/rs/cors"
 	log "github.com/sirupsen/logrus"
 )
@@ -18,6 +18,23 @@ func JSONMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// RequestIDMiddleware gives every request an ID, the one of the X-Request-ID
+// header when the client sent a usable one, echoed back in the response and
+// recorded with the changes the request makes
+func RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get("X-Request-ID")
+		if requestID == "" || len(requestID) > 64 {
+			b := make([]byte, 16)
+			rand.Read(b)
+			requestID = hex.EncodeToString(b)
+		}
+		w.Header().Set("X-Request-ID", requestID)
+		ctx := context.WithValue(r.Context(), "requestID", requestID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
 func LoggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		log.Infof("Received request: %s %s", r.Method, r.URL)
@@ -25,8 +42,20 @@ func LoggingMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// streamingRoutes send their response, or read their request, for as long as
+// the data takes, the timeout of the other routes would cut them off halfway
+var streamingRoutes = map[string]bool{
+	"GET /students/export":                                  true,
+	"POST /students/{id}/attachments":                       true,
+	"GET /students/{id}/attachments/{attachmentID}/content": true,
+}
+
 func TimeoutMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if key, ok := routeKey(r); ok && streamingRoutes[key] {
+			next.ServeHTTP(w, r)
+			return
+		}
 		ctx, cancel := context.WithTimeout(r.Context(), 15*time.Second)
 		defer cancel()
 		next.ServeHTTP(w, r.WithContext(ctx))
@@ -37,20 +66,9 @@ func TimeoutMiddleware(next http.Handler) http.Handler {
 // so this function is used only in the POST and PUT methods
 func UserIDMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		tokenString := util.ExtractTokenFromHeader(r)
-
-		if tokenString == "" {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
-			return
-		}
-
-		claims := &util.Claims{}
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			return util.JwtKey, nil
-		})
-
-		if err != nil || !token.Valid {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		claims, ok := claimsFromContext(r.Context())
+		if !ok {
+			unauthorizedResponse(w, r, CodeUnauthorized, "Unauthorized")
 			return
 		}
 
@@ -62,8 +80,9 @@ func UserIDMiddleware(next http.HandlerFunc) http.HandlerFunc {
 func CORSMiddleware(next http.Handler) http.Handler {
 	c := cors.New(cors.Options{
 		AllowedOrigins:   []string{"*"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Content-Type", "Authorization"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Content-Type", "Authorization", "If-Match", "X-Request-ID"},
+		ExposedHeaders:   []string{"ETag", "X-Request-ID"},
 		AllowCredentials: true,
 	})
 
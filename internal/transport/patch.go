@@ -0,0 +1,300 @@
+package transport
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"golang-assignment/internal/student"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	util "golang-assignment/utils"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	mediaTypeMergePatch = "application/merge-patch+json"
+	mediaTypeJSONPatch  = "application/json-patch+json"
+)
+
+var (
+	errUnsupportedMediaType = errors.New("unsupported media type")
+	errMalformedPatch       = errors.New("malformed patch")
+	errInvalidPatch         = errors.New("invalid patch")
+	errPatchTestFailed      = errors.New("patch test failed")
+)
+
+// patchableFields are the fields of a student a patch may change, every
+// other field is managed by the server
+var patchableFields = map[string]bool{"name": true, "email": true, "age": true, "course": true, "custom_fields": true}
+
+// applyMergePatch applies an RFC 7396 JSON Merge Patch to target
+func applyMergePatch(target, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = map[string]interface{}{}
+	}
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+			continue
+		}
+		targetObject[key] = applyMergePatch(targetObject[key], value)
+	}
+	return targetObject
+}
+
+// jsonPatchOperation is one operation of an RFC 6902 JSON Patch
+type jsonPatchOperation struct {
+	Op    string           `json:"op"`
+	Path  string           `json:"path"`
+	From  string           `json:"from"`
+	Value *json.RawMessage `json:"value"`
+}
+
+// patchMember returns the member of the document a JSON pointer names. A
+// student is a flat object so only pointers to its members are valid.
+func patchMember(pointer string) (string, error) {
+	if !strings.HasPrefix(pointer, "/") || strings.Count(pointer, "/") != 1 {
+		return "", fmt.Errorf("%w: path %q does not name a field of the student", errInvalidPatch, pointer)
+	}
+	return strings.NewReplacer("~1", "/", "~0", "~").Replace(pointer[1:]), nil
+}
+
+// applyJSONPatch applies the operations of an RFC 6902 JSON Patch in order,
+// the document is left untouched when one of them fails
+func applyJSONPatch(doc map[string]interface{}, ops []jsonPatchOperation) (map[string]interface{}, error) {
+	result := make(map[string]interface{}, len(doc))
+	for k, v := range doc {
+		result[k] = v
+	}
+
+	for i, op := range ops {
+		member, err := patchMember(op.Path)
+		if err != nil {
+			return nil, err
+		}
+		var value interface{}
+		if op.Op == "add" || op.Op == "replace" || op.Op == "test" {
+			if op.Value == nil {
+				return nil, fmt.Errorf("%w: operation %d (%s) has no value", errInvalidPatch, i, op.Op)
+			}
+			if err := json.Unmarshal(*op.Value, &value); err != nil {
+				return nil, fmt.Errorf("%w: operation %d has an invalid value", errInvalidPatch, i)
+			}
+		}
+
+		_, exists := result[member]
+		switch op.Op {
+		case "add":
+			result[member] = value
+		case "replace":
+			if !exists {
+				return nil, fmt.Errorf("%w: operation %d replaces the missing field %q", errInvalidPatch, i, member)
+			}
+			result[member] = value
+		case "remove":
+			if !exists {
+				return nil, fmt.Errorf("%w: operation %d removes the missing field %q", errInvalidPatch, i, member)
+			}
+			delete(result, member)
+		case "move", "copy":
+			from, err := patchMember(op.From)
+			if err != nil {
+				return nil, err
+			}
+			fromValue, ok := result[from]
+			if !ok {
+				return nil, fmt.Errorf("%w: operation %d reads the missing field %q", errInvalidPatch, i, from)
+			}
+			if op.Op == "move" {
+				delete(result, from)
+			}
+			result[member] = fromValue
+		case "test":
+			if !exists || !reflect.DeepEqual(result[member], value) {
+				return nil, fmt.Errorf("%w: operation %d, field %q does not have the tested value", errPatchTestFailed, i, member)
+			}
+		default:
+			return nil, fmt.Errorf("%w: operation %d has the unknown op %q", errInvalidPatch, i, op.Op)
+		}
+	}
+	return result, nil
+}
+
+// studentDocument is the JSON object of a student as the API shows it
+func studentDocument(stu student.Student) (map[string]interface{}, error) {
+	encoded, err := json.Marshal(stu)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(encoded, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// patchDocument applies the patch body of r, of the media type given by its
+// Content-Type, to the JSON document of stu
+func patchDocument(r *http.Request, stu student.Student) (map[string]interface{}, error) {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || (mediaType != mediaTypeMergePatch && mediaType != mediaTypeJSONPatch) {
+		return nil, errUnsupportedMediaType
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: could not read the body", errMalformedPatch)
+	}
+
+	doc, err := studentDocument(stu)
+	if err != nil {
+		return nil, err
+	}
+
+	if mediaType == mediaTypeMergePatch {
+		var patch interface{}
+		if err := json.Unmarshal(body, &patch); err != nil {
+			return nil, fmt.Errorf("%w: the body is not JSON", errMalformedPatch)
+		}
+		if _, ok := patch.(map[string]interface{}); !ok {
+			return nil, fmt.Errorf("%w: a merge patch of a student must be an object", errMalformedPatch)
+		}
+		return applyMergePatch(doc, patch).(map[string]interface{}), nil
+	}
+
+	var ops []jsonPatchOperation
+	if err := json.Unmarshal(body, &ops); err != nil {
+		return nil, fmt.Errorf("%w: a JSON patch must be an array of operations", errMalformedPatch)
+	}
+	return applyJSONPatch(doc, ops)
+}
+
+// studentPatchFromDocument compares the patched document with the stored
+// student, it fails when the patch touches a field managed by the server
+func studentPatchFromDocument(doc map[string]interface{}, stu student.Student) (UpdateStudentRequest, error) {
+	original, err := studentDocument(stu)
+	if err != nil {
+		return UpdateStudentRequest{}, err
+	}
+	for key, value := range original {
+		if !patchableFields[key] && !reflect.DeepEqual(doc[key], value) {
+			return UpdateStudentRequest{}, fmt.Errorf("%w: the field %q cannot be changed", errInvalidPatch, key)
+		}
+	}
+	for key := range doc {
+		if _, ok := original[key]; !ok && !patchableFields[key] {
+			return UpdateStudentRequest{}, fmt.Errorf("%w: the student has no field %q", errInvalidPatch, key)
+		}
+	}
+
+	encoded, err := json.Marshal(doc)
+	if err != nil {
+		return UpdateStudentRequest{}, err
+	}
+	var req UpdateStudentRequest
+	if err := json.Unmarshal(encoded, &req); err != nil {
+		return UpdateStudentRequest{}, fmt.Errorf("%w: a field has the wrong type", errInvalidPatch)
+	}
+	return req, nil
+}
+
+// changedFields builds the patch holding only the fields req changes in stu
+func changedFields(req UpdateStudentRequest, stu student.Student) student.StudentPatch {
+	var patch student.StudentPatch
+	if req.Name != stu.Name {
+		patch.Name = &req.Name
+	}
+	if req.Email != stu.Email {
+		patch.Email = &req.Email
+	}
+	if req.Age != stu.Age {
+		patch.Age = &req.Age
+	}
+	if req.Course != stu.Course {
+		patch.Course = &req.Course
+	}
+	if (len(req.CustomFields) > 0 || len(stu.CustomFields) > 0) && !reflect.DeepEqual(req.CustomFields, stu.CustomFields) {
+		patch.CustomFields = &req.CustomFields
+	}
+	return patch
+}
+
+// PatchStudent partially updates a student with a JSON Merge Patch or a JSON
+// Patch. The patch applies to the student as stored, the result must pass the
+// same rules as a full update and only the changed columns are written.
+func (h *Handler) PatchStudent(w http.ResponseWriter, r *http.Request) {
+	studentID := mux.Vars(r)["id"]
+
+	condition, ok := parseIfMatch(w, r)
+	if !ok {
+		return
+	}
+
+	existingStudent, err := h.Service.GetStudent(r.Context(), studentID)
+	if err != nil {
+		writeError(w, r, err, "Failed to fetch student")
+		return
+	}
+	// the patch is swapped against the version of existingStudent
+	if _, ok := condition.version(w, r, existingStudent); !ok {
+		return
+	}
+
+	doc, err := patchDocument(r, existingStudent)
+	if err != nil {
+		writePatchError(w, r, err)
+		return
+	}
+	req, err := studentPatchFromDocument(doc, existingStudent)
+	if err != nil {
+		writePatchError(w, r, err)
+		return
+	}
+	if err := validate.Struct(req); err != nil {
+		writeValidationProblem(w, r, err)
+		return
+	}
+
+	patch := changedFields(req, existingStudent)
+	patch.UpdatedBy = util.GetCurrentUserID(r.Context())
+	patch.UpdatedOn = time.Now()
+	patchedStudent, err := h.Service.PatchStudent(r.Context(), existingStudent, patch)
+	if err != nil {
+		writeError(w, r, err, "Failed to update student")
+		return
+	}
+
+	setETag(w, patchedStudent.Version)
+	if err := json.NewEncoder(w).Encode(patchedStudent); err != nil {
+		log.Errorf("Error encoding response: %v", err)
+	}
+}
+
+func writePatchError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, errUnsupportedMediaType):
+		w.Header().Set("Accept-Patch", mediaTypeMergePatch+", "+mediaTypeJSONPatch)
+		writeProblem(w, r, http.StatusUnsupportedMediaType, CodeUnsupportedMediaType,
+			"The Content-Type must be "+mediaTypeMergePatch+" or "+mediaTypeJSONPatch)
+	case errors.Is(err, errMalformedPatch):
+		writeProblem(w, r, http.StatusBadRequest, CodeInvalidRequestBody, err.Error())
+	case errors.Is(err, errPatchTestFailed):
+		writeProblem(w, r, http.StatusConflict, CodePatchTestFailed, err.Error())
+	case errors.Is(err, errInvalidPatch):
+		writeProblem(w, r, http.StatusUnprocessableEntity, CodeInvalidPatch, err.Error())
+	default:
+		writeError(w, r, err, "Failed to apply the patch")
+	}
+}
@@ -0,0 +1,141 @@
+package transport
+
+import (
+	"golang-assignment/internal/student"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+// policy maps every protected route, as "METHOD path template", to the
+// permission a caller needs. A route missing from it is refused to everyone.
+var policy = map[string]student.Permission{
+	"GET /students":               student.PermReadStudents,
+	"GET /getStudent/{id}":        student.PermReadStudents,
+	"POST /addStudent":            student.PermWriteStudents,
+	"PUT /updateStudent/{id}":     student.PermWriteStudents,
+	"PATCH /students/{id}":        student.PermWriteStudents,
+	"GET /students/export":        student.PermReadStudents,
+	"POST /students/import":       student.PermWriteStudents,
+	"DELETE /deleteStudent/{id}":  student.PermDeleteStudents,
+	"GET /students/trash":         student.PermDeleteStudents,
+	"POST /students/{id}/restore": student.PermDeleteStudents,
+	"GET /students/{id}/history":  student.PermReadStudents,
+	"GET /audit":                  student.PermReadAudit,
+
+	"GET /courses":               student.PermReadCourses,
+	"POST /courses":              student.PermManageCourses,
+	"GET /courses/{code}":        student.PermReadCourses,
+	"PUT /courses/{code}":        student.PermManageCourses,
+	"DELETE /courses/{code}":     student.PermManageCourses,
+	"GET /courses/{code}/roster": student.PermReadStudents,
+
+	"GET /students/{id}/enrollments":     student.PermReadStudents,
+	"POST /students/{id}/enrollments":    student.PermWriteStudents,
+	"GET /enrollments/{id}":              student.PermReadStudents,
+	"PUT /enrollments/{id}/status":       student.PermWriteStudents,
+	"GET /enrollments/{id}/assessments":  student.PermReadStudents,
+	"POST /enrollments/{id}/assessments": student.PermWriteStudents,
+	"PUT /enrollments/{id}/final-grade":  student.PermWriteStudents,
+	"GET /students/{id}/transcript":      student.PermReadStudents,
+	"GET /grade-scales":                  student.PermReadStudents,
+
+	"POST /courses/{code}/attendance": student.PermWriteStudents,
+	"GET /courses/{code}/attendance":  student.PermReadStudents,
+	"GET /students/{id}/attendance":   student.PermReadStudents,
+	"GET /attendance/alerts":          student.PermReadStudents,
+
+	"GET /students/{id}/contacts":                student.PermReadStudents,
+	"POST /students/{id}/contacts":               student.PermWriteStudents,
+	"GET /students/{id}/contacts/{contactID}":    student.PermReadStudents,
+	"PUT /students/{id}/contacts/{contactID}":    student.PermWriteStudents,
+	"DELETE /students/{id}/contacts/{contactID}": student.PermWriteStudents,
+
+	"GET /students/{id}/attachments":                        student.PermReadStudents,
+	"POST /students/{id}/attachments":                       student.PermWriteStudents,
+	"GET /students/{id}/attachments/{attachmentID}":         student.PermReadStudents,
+	"GET /students/{id}/attachments/{attachmentID}/content": student.PermReadStudents,
+	"DELETE /students/{id}/attachments/{attachmentID}":      student.PermWriteStudents,
+
+	"GET /custom-fields":           student.PermReadStudents,
+	"POST /custom-fields":          student.PermManageFields,
+	"GET /custom-fields/{name}":    student.PermReadStudents,
+	"PUT /custom-fields/{name}":    student.PermManageFields,
+	"DELETE /custom-fields/{name}": student.PermManageFields,
+
+	"GET /tags":                         student.PermReadStudents,
+	"POST /tags":                        student.PermWriteStudents,
+	"GET /tags/{name}":                  student.PermReadStudents,
+	"PUT /tags/{name}":                  student.PermWriteStudents,
+	"DELETE /tags/{name}":               student.PermWriteStudents,
+	"POST /tags/{name}/students":        student.PermWriteStudents,
+	"POST /tags/{name}/students/remove": student.PermWriteStudents,
+	"GET /students/{id}/tags":           student.PermReadStudents,
+	"PUT /students/{id}/tags/{name}":    student.PermWriteStudents,
+	"DELETE /students/{id}/tags/{name}": student.PermWriteStudents,
+
+	"GET /cohorts":               student.PermReadStudents,
+	"POST /cohorts":              student.PermWriteStudents,
+	"GET /cohorts/{id}":          student.PermReadStudents,
+	"PUT /cohorts/{id}":          student.PermWriteStudents,
+	"DELETE /cohorts/{id}":       student.PermWriteStudents,
+	"GET /cohorts/{id}/students": student.PermReadStudents,
+
+	"GET /users":               student.PermManageUsers,
+	"POST /users":              student.PermManageUsers,
+	"PUT /users/{id}/role":     student.PermManageUsers,
+	"PUT /users/{id}/password": student.PermManageUsers,
+	"POST /users/{id}/disable": student.PermManageUsers,
+	"POST /users/{id}/enable":  student.PermManageUsers,
+}
+
+// routeKey names the route matched by the request as "METHOD path template"
+func routeKey(r *http.Request) (string, bool) {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return "", false
+	}
+	template, err := route.GetPathTemplate()
+	if err != nil {
+		return "", false
+	}
+	return r.Method + " " + template, true
+}
+
+func requiredPermission(r *http.Request) (student.Permission, bool) {
+	key, ok := routeKey(r)
+	if !ok {
+		return "", false
+	}
+	perm, ok := policy[key]
+	return perm, ok
+}
+
+// Authorize lets the request through when the role in its token grants the
+// permission the policy requires for the route. It runs after JWTAuth.
+func Authorize(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := claimsFromContext(r.Context())
+		if !ok {
+			unauthorizedResponse(w, r, CodeInvalidToken, "Invalid JWT token")
+			return
+		}
+
+		perm, ok := requiredPermission(r)
+		if !ok {
+			log.Errorf("no policy for %s %s", r.Method, r.URL.Path)
+			forbiddenResponse(w, r, "This route is not allowed by the access policy", "", claims.Role)
+			return
+		}
+
+		role := student.Role(claims.Role)
+		if !role.Can(perm) {
+			log.Warnf("user %s with role %q denied %s %s", claims.UserID, claims.Role, r.Method, r.URL.Path)
+			forbiddenResponse(w, r, "Your role does not allow this action", perm, claims.Role)
+			return
+		}
+
+		next(w, r)
+	}
+}
@@ -3,14 +3,18 @@ package transport
 import (
 	"context"
 	"encoding/json"
-	"errors"
+	"fmt"
 	"golang-assignment/internal/student"
+	"golang-assignment/internal/tag"
 	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	util "golang-assignment/utils"
 
-	"github.com/go-playground/validator/v10"
 	"github.com/gorilla/mux"
 	log "github.com/sirupsen/logrus"
 )
@@ -19,67 +23,81 @@ type StudentService interface {
 	GetStudent(ctx context.Context, ID string) (student.Student, error)
 	PostStudent(ctx context.Context, stu student.Student) (student.Student, error)
 	UpdateStudent(ctx context.Context, ID string, newStu student.Student) (student.Student, error)
-	DeleteStudent(ctx context.Context, ID string) error
+	DeleteStudent(ctx context.Context, ID string, version int64, deletedBy string) error
+	PatchStudent(ctx context.Context, current student.Student, patch student.StudentPatch) (student.Student, error)
+	ListStudents(ctx context.Context, pageToken string, pageSize int) (student.StudentPage, error)
+	SearchStudents(ctx context.Context, filter student.SearchFilter, pageToken string, pageSize int) (student.SearchPage, error)
+	ListDeletedStudents(ctx context.Context, pageToken string, pageSize int) (student.StudentPage, error)
+	RestoreStudent(ctx context.Context, ID, restoredBy string) (student.Student, error)
+	ExportStudents(ctx context.Context, filter student.SearchFilter, emit func(student.Student) error) error
+	ImportStudents(ctx context.Context, rows []student.ImportRow, opts student.ImportOptions) (student.ImportReport, error)
+	StudentHistory(ctx context.Context, ID string, pageToken string, pageSize int) (student.AuditPage, error)
+	ListAuditEntries(ctx context.Context, filter student.AuditFilter, pageToken string, pageSize int) (student.AuditPage, error)
+	AddAssessment(ctx context.Context, a student.Assessment, recordedBy string) (student.Assessment, error)
+	ListAssessments(ctx context.Context, enrollmentID int64) ([]student.Assessment, error)
+	SetFinalGrade(ctx context.Context, enrollmentID int64, scale, letter, gradedBy string) (student.FinalGrade, error)
+	Transcript(ctx context.Context, studentID string) (student.Transcript, error)
 	ReadyCheck(ctx context.Context) error
 	AuthenticateUser(ctx context.Context, userID, password string) (student.User, error)
-	GenerateJWT(user student.User) (string, error)
+	IssueTokens(ctx context.Context, user student.User) (student.TokenPair, error)
+	RefreshTokens(ctx context.Context, refreshToken string) (student.TokenPair, error)
+	Logout(ctx context.Context, jti string, expiresOn time.Time, refreshToken string) error
+	IsTokenRevoked(ctx context.Context, jti, userID string) (bool, error)
+	ListUsers(ctx context.Context) ([]student.User, error)
+	CreateUser(ctx context.Context, user student.User, password, createdBy string) (student.User, error)
+	SetUserRole(ctx context.Context, userID string, role student.Role, updatedBy string) (student.User, error)
+	ResetPassword(ctx context.Context, userID, password, updatedBy string) (student.User, error)
+	SetUserDisabled(ctx context.Context, userID string, disabled bool, updatedBy string) (student.User, error)
 }
 
 func (h *Handler) GetStudent(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
 	if id == "" {
-		http.Error(w, "Student ID is required", http.StatusBadRequest)
+		writeProblem(w, r, http.StatusBadRequest, CodeInvalidQuery, "Student ID is required")
 		return
 	}
 
 	stu, err := h.Service.GetStudent(r.Context(), id)
 	if err != nil {
-		if errors.Is(err, student.ErrFetchingStudent) {
-			http.Error(w, "Student not found", http.StatusNotFound)
-			return
-		}
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		writeError(w, r, err, "Failed to fetch student")
 		return
 	}
 
+	setETag(w, stu.Version)
 	if err := json.NewEncoder(w).Encode(stu); err != nil {
-		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		log.Errorf("Error encoding response: %v", err)
 	}
 }
 
 type PostStudentRequest struct {
-	ID        string    `json:"id"`
-	Name      string    `json:"name" validate:"required"`
-	Email     string    `json:"email" validate:"required,email"`
-	Age       int       `json:"age" validate:"required,gt=0"`
-	Course    string    `json:"course" validate:"required"`
-	CreatedBy string    `json:"created_by"`
-	CreatedOn time.Time `json:"created_on"`
-	UpdatedBy string    `json:"updated_by"`
-	UpdatedOn time.Time `json:"updated_on"`
+	ID     string `json:"id" validate:"omitempty,max=64"`
+	Name   string `json:"name" validate:"required"`
+	Email  string `json:"email" validate:"required,email"`
+	Age    int    `json:"age" validate:"required,gt=0"`
+	Course string `json:"course" validate:"required"`
+	// CustomFields are checked against the definitions by the service
+	CustomFields student.CustomFields `json:"custom_fields"`
+	CreatedBy    string               `json:"created_by"`
+	CreatedOn    time.Time            `json:"created_on"`
+	UpdatedBy    string               `json:"updated_by"`
+	UpdatedOn    time.Time            `json:"updated_on"`
 }
 
 func studentFromPostStudentRequest(u PostStudentRequest) student.Student {
 	return student.Student{
-		ID:     u.ID,
-		Name:   u.Name,
-		Email:  u.Email,
-		Age:    u.Age,
-		Course: u.Course,
+		ID:           u.ID,
+		Name:         u.Name,
+		Email:        u.Email,
+		Age:          u.Age,
+		Course:       u.Course,
+		CustomFields: u.CustomFields,
 	}
 }
 
 func (h *Handler) PostStudent(w http.ResponseWriter, r *http.Request) {
 	var postStuReq PostStudentRequest
-	if err := json.NewDecoder(r.Body).Decode(&postStuReq); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
-		return
-	}
-
-	validate := validator.New()
-	if err := validate.Struct(postStuReq); err != nil {
-		http.Error(w, "Validation failed", http.StatusBadRequest)
+	if !decodeAndValidate(w, r, &postStuReq) {
 		return
 	}
 
@@ -92,29 +110,33 @@ func (h *Handler) PostStudent(w http.ResponseWriter, r *http.Request) {
 	stu.UpdatedOn = stu.CreatedOn
 	stu, err := h.Service.PostStudent(r.Context(), stu)
 	if err != nil {
-		log.Error(err)
-		http.Error(w, "Failed to create student", http.StatusInternalServerError)
+		writeError(w, r, err, "Failed to create student")
 		return
 	}
+	setETag(w, stu.Version)
 	w.WriteHeader(http.StatusCreated)
 	if err := json.NewEncoder(w).Encode(stu); err != nil {
-		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		log.Errorf("Error encoding response: %v", err)
 	}
 }
 
+// UpdateStudentRequest replaces a student, custom fields left out are
+// removed
 type UpdateStudentRequest struct {
-	Name   string `json:"name" validate:"required"`
-	Email  string `json:"email" validate:"required,email"`
-	Age    int    `json:"age" validate:"required,gt=0"`
-	Course string `json:"course" validate:"required"`
+	Name         string               `json:"name" validate:"required"`
+	Email        string               `json:"email" validate:"required,email"`
+	Age          int                  `json:"age" validate:"required,gt=0"`
+	Course       string               `json:"course" validate:"required"`
+	CustomFields student.CustomFields `json:"custom_fields"`
 }
 
 func studentFromUpdateStudentRequest(u UpdateStudentRequest) student.Student {
 	return student.Student{
-		Name:   u.Name,
-		Email:  u.Email,
-		Age:    u.Age,
-		Course: u.Course,
+		Name:         u.Name,
+		Email:        u.Email,
+		Age:          u.Age,
+		Course:       u.Course,
+		CustomFields: u.CustomFields,
 	}
 }
 
@@ -122,41 +144,44 @@ func (h *Handler) UpdateStudent(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	studentID := vars["id"]
 
-	var updateStuRequest UpdateStudentRequest
-	if err := json.NewDecoder(r.Body).Decode(&updateStuRequest); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	condition, ok := parseIfMatch(w, r)
+	if !ok {
 		return
 	}
 
-	validate := validator.New()
-	if err := validate.Struct(updateStuRequest); err != nil {
-		http.Error(w, "Validation failed", http.StatusBadRequest)
+	var updateStuRequest UpdateStudentRequest
+	if !decodeAndValidate(w, r, &updateStuRequest) {
 		return
 	}
 
 	existingStudent, err := h.Service.GetStudent(r.Context(), studentID)
 	if err != nil {
-		http.Error(w, "Student not found", http.StatusNotFound)
+		writeError(w, r, err, "Failed to fetch student")
+		return
+	}
+	// the store still refuses the update when someone writes in between
+	version, ok := condition.version(w, r, existingStudent)
+	if !ok {
 		return
 	}
 
 	stu := studentFromUpdateStudentRequest(updateStuRequest)
 	stu.CreatedBy = existingStudent.CreatedBy
 	stu.ID = studentID
+	stu.Version = version
 	stu.UpdatedBy = util.GetCurrentUserID(r.Context())
 	stu.UpdatedOn = time.Now()
 
 	updatedStu, err := h.Service.UpdateStudent(r.Context(), studentID, stu)
 	if err != nil {
-		log.Printf("Error updating student: %v", err)
-		http.Error(w, "Failed to update student", http.StatusInternalServerError)
+		writeError(w, r, err, "Failed to update student")
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	setETag(w, updatedStu.Version)
 	if err := json.NewEncoder(w).Encode(updatedStu); err != nil {
 		log.Printf("Error encoding response: %v", err)
-		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
 	}
 }
 
@@ -165,24 +190,228 @@ func (h *Handler) DeleteStudent(w http.ResponseWriter, r *http.Request) {
 	studentID := vars["id"]
 
 	if studentID == "" {
-		http.Error(w, "Student ID is required", http.StatusBadRequest)
+		writeProblem(w, r, http.StatusBadRequest, CodeInvalidQuery, "Student ID is required")
 		return
 	}
 
-	_, err := h.Service.GetStudent(r.Context(), studentID)
+	condition, ok := parseIfMatch(w, r)
+	if !ok {
+		return
+	}
+	existingStudent, err := h.Service.GetStudent(r.Context(), studentID)
 	if err != nil {
-		http.Error(w, "Student not found", http.StatusNotFound)
+		writeError(w, r, err, "Failed to fetch student")
+		return
+	}
+	version, ok := condition.version(w, r, existingStudent)
+	if !ok {
 		return
 	}
 
-	err = h.Service.DeleteStudent(r.Context(), studentID)
-	if err != nil {
-		http.Error(w, "Failed to delete student", http.StatusInternalServerError)
+	if err := h.Service.DeleteStudent(r.Context(), studentID, version, util.GetCurrentUserID(r.Context())); err != nil {
+		writeError(w, r, err, "Failed to delete student")
 		return
 	}
 
 	response := Response{Message: "Successfully Deleted"}
 	if err := json.NewEncoder(w).Encode(response); err != nil {
-		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		log.Errorf("Error encoding response: %v", err)
+	}
+}
+
+// parseQueryTime reads an RFC 3339 timestamp or a plain day (2006-01-02), a
+// plain day used as an upper bound includes the whole day
+func parseQueryTime(v string, upperBound bool) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, v)
+	if err == nil {
+		return t, nil
+	}
+	day, err := time.Parse("2006-01-02", v)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if upperBound {
+		return day.Add(24*time.Hour - time.Nanosecond), nil
+	}
+	return day, nil
+}
+
+// customFieldPrefix starts the search parameters comparing a custom
+// field, cf.nationality=NP keeps the students whose nationality is NP, and
+// the import columns holding one
+const customFieldPrefix = "cf."
+
+// searchParams are the search parameters besides the custom field ones
+var searchParams = map[string]bool{
+	"q": true, "tag": true, "course": true, "created_by": true, "min_age": true, "max_age": true,
+	"created_from": true, "created_to": true, "updated_from": true, "updated_to": true, "sort": true,
+}
+
+// isSearchParam reports whether name is a parameter searchFilterFromQuery
+// reads
+func isSearchParam(name string) bool {
+	return searchParams[name] || strings.HasPrefix(name, customFieldPrefix)
+}
+
+// searchFilterFromQuery reads the search parameters of GET /students, other
+// parameters are ignored. Dates are either RFC 3339 timestamps or plain days
+// (2006-01-02), a plain day used as an upper bound includes the whole day.
+// The tag parameter may be repeated.
+func searchFilterFromQuery(query url.Values) (student.SearchFilter, error) {
+	f := student.SearchFilter{
+		Query:     strings.TrimSpace(query.Get("q")),
+		Course:    query.Get("course"),
+		CreatedBy: query.Get("created_by"),
+	}
+
+	for _, name := range query["tag"] {
+		if name = tag.NormalizeName(name); name == "" {
+			return f, fmt.Errorf("%w: invalid tag", student.ErrInvalidSearch)
+		}
+		f.Tags = append(f.Tags, name)
+	}
+
+	for name, dst := range map[string]*int{"min_age": &f.MinAge, "max_age": &f.MaxAge} {
+		if v := query.Get(name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return f, fmt.Errorf("%w: invalid %s", student.ErrInvalidSearch, name)
+			}
+			*dst = n
+		}
+	}
+
+	for name, dst := range map[string]*time.Time{
+		"created_from": &f.CreatedFrom, "created_to": &f.CreatedTo,
+		"updated_from": &f.UpdatedFrom, "updated_to": &f.UpdatedTo,
+	} {
+		v := query.Get(name)
+		if v == "" {
+			continue
+		}
+		t, err := parseQueryTime(v, strings.HasSuffix(name, "_to"))
+		if err != nil {
+			return f, fmt.Errorf("%w: invalid %s", student.ErrInvalidSearch, name)
+		}
+		*dst = t
+	}
+
+	for name, values := range query {
+		if field, ok := strings.CutPrefix(name, customFieldPrefix); ok {
+			if field == "" || len(values) != 1 {
+				return f, fmt.Errorf("%w: invalid %s", student.ErrInvalidSearch, name)
+			}
+			f.CustomFields = append(f.CustomFields, student.FieldFilter{Name: field, Text: values[0]})
+		}
+	}
+	sort.Slice(f.CustomFields, func(i, j int) bool { return f.CustomFields[i].Name < f.CustomFields[j].Name })
+
+	if sortBy := query.Get("sort"); sortBy != "" {
+		f.SortDesc = strings.HasPrefix(sortBy, "-")
+		f.SortBy = strings.TrimPrefix(sortBy, "-")
+	}
+	return f, nil
+}
+
+// SearchFilterFromText reads search parameters sent as text in a body, like
+// "course=CS101&tag=scholarship", refusing the parameters
+// searchFilterFromQuery would ignore. The database also reads the query of the
+// cohorts saved before their filter was stored with it.
+func SearchFilterFromText(text string) (student.SearchFilter, error) {
+	values, err := url.ParseQuery(strings.TrimPrefix(strings.TrimSpace(text), "?"))
+	if err != nil {
+		return student.SearchFilter{}, fmt.Errorf("%w: query is not url encoded", student.ErrInvalidSearch)
+	}
+	for name := range values {
+		if !isSearchParam(name) {
+			return student.SearchFilter{}, fmt.Errorf("%w: %q is not a search parameter", student.ErrInvalidSearch, name)
+		}
+	}
+	return searchFilterFromQuery(values)
+}
+
+// searchQuery writes the filter back as the search parameters
+// searchFilterFromQuery reads
+func searchQuery(f student.SearchFilter) url.Values {
+	query := url.Values{}
+	set := func(name, value string) {
+		if value != "" {
+			query.Set(name, value)
+		}
+	}
+	set("q", f.Query)
+	if len(f.Tags) > 0 {
+		query["tag"] = f.Tags
+	}
+	set("course", f.Course)
+	set("created_by", f.CreatedBy)
+	for name, n := range map[string]int{"min_age": f.MinAge, "max_age": f.MaxAge} {
+		if n != 0 {
+			query.Set(name, strconv.Itoa(n))
+		}
+	}
+	for name, t := range map[string]time.Time{
+		"created_from": f.CreatedFrom, "created_to": f.CreatedTo,
+		"updated_from": f.UpdatedFrom, "updated_to": f.UpdatedTo,
+	} {
+		if !t.IsZero() {
+			query.Set(name, t.Format(time.RFC3339Nano))
+		}
+	}
+	for _, cf := range f.CustomFields {
+		query.Set(customFieldPrefix+cf.Name, cf.Text)
+	}
+	if f.SortBy != "" {
+		if f.SortDesc {
+			query.Set("sort", "-"+f.SortBy)
+		} else {
+			query.Set("sort", f.SortBy)
+		}
+	}
+	return query
+}
+
+// pageSizeFromQuery reads the page_size parameter, 0 when it is missing
+func pageSizeFromQuery(w http.ResponseWriter, r *http.Request) (int, bool) {
+	v := r.URL.Query().Get("page_size")
+	if v == "" {
+		return 0, true
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, CodeInvalidQuery, "Invalid page_size")
+		return 0, false
+	}
+	return n, true
+}
+
+func (h *Handler) ListStudents(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	pageSize, ok := pageSizeFromQuery(w, r)
+	if !ok {
+		return
+	}
+
+	filter, err := searchFilterFromQuery(query)
+	if err != nil {
+		writeError(w, r, err, "Failed to list students")
+		return
+	}
+
+	// without any filter the cheaper keyset pagination is used
+	var page interface{}
+	if filter.IsEmpty() {
+		page, err = h.Service.ListStudents(r.Context(), query.Get("page_token"), pageSize)
+	} else {
+		page, err = h.Service.SearchStudents(r.Context(), filter, query.Get("page_token"), pageSize)
+	}
+	if err != nil {
+		writeError(w, r, err, "Failed to list students")
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(page); err != nil {
+		log.Errorf("Error encoding response: %v", err)
 	}
 }
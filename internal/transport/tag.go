@@ -0,0 +1,214 @@
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"golang-assignment/internal/tag"
+	"net/http"
+
+	util "golang-assignment/utils"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+type TagService interface {
+	ListTags(ctx context.Context) ([]tag.Tag, error)
+	GetTag(ctx context.Context, name string) (tag.Tag, error)
+	CreateTag(ctx context.Context, t tag.Tag, createdBy string) (tag.Tag, error)
+	UpdateTag(ctx context.Context, name string, t tag.Tag, updatedBy string) (tag.Tag, error)
+	DeleteTag(ctx context.Context, name string) error
+	TagStudents(ctx context.Context, name string, bulk tag.Bulk, taggedBy string) (tag.BulkResult, error)
+	UntagStudents(ctx context.Context, name string, bulk tag.Bulk) (tag.BulkResult, error)
+	AddStudentTag(ctx context.Context, studentID, name, taggedBy string) error
+	RemoveStudentTag(ctx context.Context, studentID, name string) error
+	ListStudentTags(ctx context.Context, studentID string) ([]tag.StudentTag, error)
+}
+
+type CreateTagRequest struct {
+	Name        string `json:"name" validate:"required,max=64"`
+	Description string `json:"description" validate:"max=500"`
+}
+
+// UpdateTagRequest replaces the description of a tag, its name never changes
+type UpdateTagRequest struct {
+	Description string `json:"description" validate:"max=500"`
+}
+
+// BulkTagRequest selects the students either by ID or by the search
+// parameters of GET /students, like "course=CS101&min_age=18"
+type BulkTagRequest struct {
+	StudentIDs []string `json:"student_ids" validate:"max=5000,dive,required,max=64"`
+	Query      string   `json:"query" validate:"max=2000"`
+}
+
+// bulkSelection returns the students the request selects, answering 400
+// when its query cannot be read
+func bulkSelection(w http.ResponseWriter, r *http.Request, req BulkTagRequest) (tag.Bulk, bool) {
+	bulk := tag.Bulk{StudentIDs: req.StudentIDs}
+	if req.Query != "" {
+		filter, err := SearchFilterFromText(req.Query)
+		if err != nil {
+			writeError(w, r, fmt.Errorf("%w: %v", tag.ErrInvalidTag, err), "")
+			return tag.Bulk{}, false
+		}
+		bulk.Filter = &filter
+	}
+	return bulk, true
+}
+
+func (h *Handler) ListTags(w http.ResponseWriter, r *http.Request) {
+	tags, err := h.Tags.ListTags(r.Context())
+	if err != nil {
+		writeError(w, r, err, "Failed to list tags")
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(tags); err != nil {
+		log.Errorf("Error encoding response: %v", err)
+	}
+}
+
+func (h *Handler) GetTag(w http.ResponseWriter, r *http.Request) {
+	t, err := h.Tags.GetTag(r.Context(), mux.Vars(r)["name"])
+	if err != nil {
+		writeError(w, r, err, "Failed to get tag")
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(t); err != nil {
+		log.Errorf("Error encoding response: %v", err)
+	}
+}
+
+func (h *Handler) CreateTag(w http.ResponseWriter, r *http.Request) {
+	var req CreateTagRequest
+	if !decodeAndValidate(w, r, &req) {
+		return
+	}
+
+	t, err := h.Tags.CreateTag(r.Context(), tag.Tag{Name: req.Name, Description: req.Description}, util.GetCurrentUserID(r.Context()))
+	if err != nil {
+		writeError(w, r, err, "Failed to create tag")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(t); err != nil {
+		log.Errorf("Error encoding response: %v", err)
+	}
+}
+
+func (h *Handler) UpdateTag(w http.ResponseWriter, r *http.Request) {
+	var req UpdateTagRequest
+	if !decodeAndValidate(w, r, &req) {
+		return
+	}
+
+	t, err := h.Tags.UpdateTag(r.Context(), mux.Vars(r)["name"], tag.Tag{Description: req.Description}, util.GetCurrentUserID(r.Context()))
+	if err != nil {
+		writeError(w, r, err, "Failed to update tag")
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(t); err != nil {
+		log.Errorf("Error encoding response: %v", err)
+	}
+}
+
+// DeleteTag removes a tag from every student and then the tag itself
+func (h *Handler) DeleteTag(w http.ResponseWriter, r *http.Request) {
+	if err := h.Tags.DeleteTag(r.Context(), mux.Vars(r)["name"]); err != nil {
+		writeError(w, r, err, "Failed to delete tag")
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(Response{Message: "Successfully Deleted"}); err != nil {
+		log.Errorf("Error encoding response: %v", err)
+	}
+}
+
+// TagStudents gives the tag to many students at once and answers how many
+// gained it
+func (h *Handler) TagStudents(w http.ResponseWriter, r *http.Request) {
+	var req BulkTagRequest
+	if !decodeAndValidate(w, r, &req) {
+		return
+	}
+
+	bulk, ok := bulkSelection(w, r, req)
+	if !ok {
+		return
+	}
+	result, err := h.Tags.TagStudents(r.Context(), mux.Vars(r)["name"], bulk, util.GetCurrentUserID(r.Context()))
+	if err != nil {
+		writeError(w, r, err, "Failed to tag students")
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		log.Errorf("Error encoding response: %v", err)
+	}
+}
+
+// UntagStudents takes the tag away from many students at once and answers
+// how many lost it
+func (h *Handler) UntagStudents(w http.ResponseWriter, r *http.Request) {
+	var req BulkTagRequest
+	if !decodeAndValidate(w, r, &req) {
+		return
+	}
+
+	bulk, ok := bulkSelection(w, r, req)
+	if !ok {
+		return
+	}
+	result, err := h.Tags.UntagStudents(r.Context(), mux.Vars(r)["name"], bulk)
+	if err != nil {
+		writeError(w, r, err, "Failed to untag students")
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		log.Errorf("Error encoding response: %v", err)
+	}
+}
+
+func (h *Handler) ListStudentTags(w http.ResponseWriter, r *http.Request) {
+	tags, err := h.Tags.ListStudentTags(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, r, err, "Failed to list student tags")
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(tags); err != nil {
+		log.Errorf("Error encoding response: %v", err)
+	}
+}
+
+// AddStudentTag gives a tag to a student, a student having it already is
+// left as it is
+func (h *Handler) AddStudentTag(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	if err := h.Tags.AddStudentTag(r.Context(), vars["id"], vars["name"], util.GetCurrentUserID(r.Context())); err != nil {
+		writeError(w, r, err, "Failed to tag student")
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(Response{Message: "Successfully Tagged"}); err != nil {
+		log.Errorf("Error encoding response: %v", err)
+	}
+}
+
+func (h *Handler) RemoveStudentTag(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	if err := h.Tags.RemoveStudentTag(r.Context(), vars["id"], vars["name"]); err != nil {
+		writeError(w, r, err, "Failed to untag student")
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(Response{Message: "Successfully Deleted"}); err != nil {
+		log.Errorf("Error encoding response: %v", err)
+	}
+}
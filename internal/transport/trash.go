@@ -0,0 +1,46 @@
+package transport
+
+import (
+	"encoding/json"
+	"net/http"
+
+	util "golang-assignment/utils"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+// ListDeletedStudents lists the students in the trash, page by page like
+// GET /students
+func (h *Handler) ListDeletedStudents(w http.ResponseWriter, r *http.Request) {
+	pageSize, ok := pageSizeFromQuery(w, r)
+	if !ok {
+		return
+	}
+
+	page, err := h.Service.ListDeletedStudents(r.Context(), r.URL.Query().Get("page_token"), pageSize)
+	if err != nil {
+		writeError(w, r, err, "Failed to list deleted students")
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(page); err != nil {
+		log.Errorf("Error encoding response: %v", err)
+	}
+}
+
+// RestoreStudent takes a student out of the trash
+func (h *Handler) RestoreStudent(w http.ResponseWriter, r *http.Request) {
+	studentID := mux.Vars(r)["id"]
+
+	stu, err := h.Service.RestoreStudent(r.Context(), studentID, util.GetCurrentUserID(r.Context()))
+	if err != nil {
+		writeError(w, r, err, "Failed to restore student")
+		return
+	}
+
+	setETag(w, stu.Version)
+	if err := json.NewEncoder(w).Encode(stu); err != nil {
+		log.Errorf("Error encoding response: %v", err)
+	}
+}
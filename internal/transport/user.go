@@ -0,0 +1,116 @@
+package transport
+
+import (
+	"encoding/json"
+	"golang-assignment/internal/student"
+	"net/http"
+
+	util "golang-assignment/utils"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+type CreateUserRequest struct {
+	ID       string `json:"id" validate:"required,max=64"`
+	Name     string `json:"name" validate:"required"`
+	Role     string `json:"role" validate:"required,oneof=viewer registrar admin"`
+	Password string `json:"password" validate:"required,max=72"`
+}
+
+type SetUserRoleRequest struct {
+	Role string `json:"role" validate:"required,oneof=viewer registrar admin"`
+}
+
+type ResetPasswordRequest struct {
+	Password string `json:"password" validate:"required,max=72"`
+}
+
+func (h *Handler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	users, err := h.Service.ListUsers(r.Context())
+	if err != nil {
+		writeError(w, r, err, "Failed to list users")
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(users); err != nil {
+		log.Errorf("Error encoding response: %v", err)
+	}
+}
+
+func (h *Handler) CreateUser(w http.ResponseWriter, r *http.Request) {
+	var req CreateUserRequest
+	if !decodeAndValidate(w, r, &req) {
+		return
+	}
+
+	user, err := h.Service.CreateUser(r.Context(), student.User{ID: req.ID, Name: req.Name, Role: student.Role(req.Role)}, req.Password, util.GetCurrentUserID(r.Context()))
+	if err != nil {
+		log.Error(err)
+		writeError(w, r, err, "Failed to manage users")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(user); err != nil {
+		log.Errorf("Error encoding response: %v", err)
+	}
+}
+
+func (h *Handler) SetUserRole(w http.ResponseWriter, r *http.Request) {
+	userID := mux.Vars(r)["id"]
+
+	var req SetUserRoleRequest
+	if !decodeAndValidate(w, r, &req) {
+		return
+	}
+
+	user, err := h.Service.SetUserRole(r.Context(), userID, student.Role(req.Role), util.GetCurrentUserID(r.Context()))
+	if err != nil {
+		writeError(w, r, err, "Failed to manage users")
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(user); err != nil {
+		log.Errorf("Error encoding response: %v", err)
+	}
+}
+
+func (h *Handler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	userID := mux.Vars(r)["id"]
+
+	var req ResetPasswordRequest
+	if !decodeAndValidate(w, r, &req) {
+		return
+	}
+
+	if _, err := h.Service.ResetPassword(r.Context(), userID, req.Password, util.GetCurrentUserID(r.Context())); err != nil {
+		writeError(w, r, err, "Failed to manage users")
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(Response{Message: "Password reset"}); err != nil {
+		log.Errorf("Error encoding response: %v", err)
+	}
+}
+
+func (h *Handler) setUserDisabled(disabled bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID := mux.Vars(r)["id"]
+		currentUserID := util.GetCurrentUserID(r.Context())
+		if disabled && userID == currentUserID {
+			writeProblem(w, r, http.StatusBadRequest, CodeCannotDisableSelf, "You cannot disable yourself")
+			return
+		}
+
+		user, err := h.Service.SetUserDisabled(r.Context(), userID, disabled, currentUserID)
+		if err != nil {
+			writeError(w, r, err, "Failed to manage users")
+			return
+		}
+
+		if err := json.NewEncoder(w).Encode(user); err != nil {
+			log.Errorf("Error encoding response: %v", err)
+		}
+	}
+}
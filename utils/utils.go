@@ -14,6 +14,14 @@ func GetCurrentUserID(ctx context.Context) string {
 	return ""
 }
 
+// This is used to record the request that made a change in the audit trail
+func GetRequestID(ctx context.Context) string {
+	if requestID, ok := ctx.Value("requestID").(string); ok {
+		return requestID
+	}
+	return ""
+}
+
 // This is used extract token to in userIDMiddleware in transport/middleware
 func ExtractTokenFromHeader(r *http.Request) string {
 